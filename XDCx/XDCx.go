@@ -29,11 +29,19 @@ const (
 	overflowIdx        // Indicator of message queue overflow
 	defaultCacheLimit  = 1024
 	MaximumTxMatchSize = 1000
+	// maximum number of trigger orders activated by a single order
+	MaximumTriggeredOrders = 100
+	// maximum number of expired orders cancelled per order book by a block
+	MaximumExpiredOrders = 100
 )
 
 var (
 	ErrNonceTooHigh = errors.New("nonce too high")
 	ErrNonceTooLow  = errors.New("nonce too low")
+
+	ErrPostOnlyWouldTake  = errors.New("post-only order would take liquidity")
+	ErrFillOrKillUnfilled = errors.New("fill-or-kill order can't be filled entirely")
+	ErrSelfTrade          = errors.New("self-trade prevented")
 )
 
 type Config struct {
@@ -201,6 +209,11 @@ func (XDCx *XDCX) ProcessOrderPending(header *types.Header, coinbase common.Addr
 			Type:            tx.Type(),
 			Hash:            tx.OrderHash(),
 			OrderID:         tx.OrderID(),
+			TimeInForce:     tx.TimeInForce(),
+			PostOnly:        tx.PostOnly(),
+			TriggerPrice:    tx.TriggerPrice(),
+			ExpiresAt:       tx.ExpiresAt(),
+			SelfTrade:       tx.SelfTrade(),
 			Signature: &tradingstate.Signature{
 				V: byte(n),
 				R: common.BigToHash(R),
@@ -378,6 +391,11 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 		if trade == nil {
 			continue
 		}
+		// trades of the trigger orders activated by the taker order aren't its own
+		if takerHash, ok := trade[tradingstate.TradeTakerOrderHash]; ok && common.HexToHash(takerHash) != updatedTakerOrder.Hash {
+			log.Debug("Skip trade of triggered order", "takerOrder", takerHash, "txhash", txHash.Hex())
+			continue
+		}
 		tradeRecord := &tradingstate.Trade{}
 		quantity := tradingstate.ToBigInt(trade[tradingstate.TradeQuantity])
 		price := tradingstate.ToBigInt(trade[tradingstate.TradePrice])
@@ -497,8 +515,12 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 		var rejectedHashes []string
 		// updateRejectedOrders
 		for _, rejectedOrder := range rejectedOrders {
-			rejectedHashes = append(rejectedHashes, rejectedOrder.Hash.Hex())
-			if updatedTakerOrder.Hash == rejectedOrder.Hash && !txMatchTime.Before(updatedTakerOrder.UpdatedAt) {
+			if updatedTakerOrder.Hash != rejectedOrder.Hash {
+				rejectedHashes = append(rejectedHashes, rejectedOrder.Hash.Hex())
+				continue
+			}
+			// the taker is updated here, reloading it from db would drop the fills of this tx
+			if !txMatchTime.Before(updatedTakerOrder.UpdatedAt) {
 				// cache order history for handling reorg
 				orderHistoryRecord := tradingstate.OrderHistoryItem{
 					TxHash:       updatedTakerOrder.TxHash,
@@ -507,9 +529,12 @@ func (XDCx *XDCX) SyncDataToSDKNode(takerOrderInTx *tradingstate.OrderItem, txHa
 					UpdatedAt:    updatedTakerOrder.UpdatedAt,
 				}
 				XDCx.UpdateOrderCache(updatedTakerOrder.BaseToken, updatedTakerOrder.QuoteToken, updatedTakerOrder.Hash, txHash, orderHistoryRecord)
+				// a taker cancelled by the self-trade prevention keeps its fills, status = CANCELLED
 				// if whole order is rejected, status = REJECTED
 				// otherwise, status = FILLED
-				if updatedTakerOrder.FilledAmount.Sign() > 0 {
+				if rejectedOrder.Status == tradingstate.OrderStatusCancelled {
+					updatedTakerOrder.Status = tradingstate.OrderStatusCancelled
+				} else if updatedTakerOrder.FilledAmount.Sign() > 0 {
 					updatedTakerOrder.Status = tradingstate.OrderStatusFilled
 				} else {
 					updatedTakerOrder.Status = tradingstate.OrderStatusRejected
@@ -622,6 +647,34 @@ func (XDCx *XDCX) UpdateOrderCache(baseToken, quoteToken common.Address, orderHa
 	XDCx.orderCache.Add(txhash, orderCacheAtTxHash)
 }
 
+// SyncExpiredOrdersToSDKNode marks the orders expired by a block as cancelled,
+// under txHash, the hash of the trading state transaction of the block.
+func (XDCx *XDCX) SyncExpiredOrdersToSDKNode(txHash common.Hash, txTime time.Time, expiredOrders []*tradingstate.OrderItem) error {
+	db := XDCx.GetMongoDB()
+	db.InitBulk()
+	for _, expiredOrder := range expiredOrders {
+		val, err := db.GetObject(expiredOrder.Hash, &tradingstate.OrderItem{})
+		if err != nil || val == nil {
+			log.Debug("SDKNode: expired order not found", "hash", expiredOrder.Hash.Hex(), "err", err)
+			continue
+		}
+		order := val.(*tradingstate.OrderItem)
+		XDCx.UpdateOrderCache(order.BaseToken, order.QuoteToken, order.Hash, txHash, tradingstate.OrderHistoryItem{
+			TxHash:       order.TxHash,
+			FilledAmount: tradingstate.CloneBigInt(order.FilledAmount),
+			Status:       order.Status,
+			UpdatedAt:    order.UpdatedAt,
+		})
+		order.Status = tradingstate.OrderStatusCancelled
+		order.TxHash = txHash
+		order.UpdatedAt = txTime
+		if err := db.PutObject(order.Hash, order); err != nil {
+			return fmt.Errorf("SDKNode: failed to put expired order. Hash: %s Error: %s", order.Hash.Hex(), err.Error())
+		}
+	}
+	return db.CommitBulk()
+}
+
 func (XDCx *XDCX) RollbackReorgTxMatch(txhash common.Hash) error {
 	db := XDCx.GetMongoDB()
 	db.InitBulk()
@@ -1,8 +1,10 @@
 package XDCx
 
 import (
+	"bytes"
 	"encoding/json"
 	"math/big"
+	"sort"
 	"strconv"
 	"time"
 
@@ -56,9 +58,17 @@ func (XDCx *XDCX) ApplyOrder(header *types.Header, coinbase common.Address, chai
 			statedb.RevertToSnapshot(dbSnap)
 		}
 	}()
+	tracer := tradingStateDB.Tracer()
+	if tracer != nil {
+		tracer.CaptureStart(tradingstate.TraceKindOrder, orderBook, order.Hash)
+		defer func() { tracer.CaptureEnd(err) }()
+	}
 
-	if err := order.VerifyOrder(statedb); err != nil {
+	if err := order.VerifyOrder(statedb, chain.Config(), header.Number); err != nil {
 		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, err.Error())
+		}
 		return trades, rejects, nil
 	}
 	if order.Status == tradingstate.OrderStatusCancelled {
@@ -66,24 +76,71 @@ func (XDCx *XDCX) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		if err != nil || reject {
 			log.Debug("Reject cancelled order", "err", err)
 			rejects = append(rejects, order)
+			if tracer != nil {
+				reason := "cancellation fee not affordable"
+				if err != nil {
+					reason = err.Error()
+				}
+				tracer.CaptureReject(order.Hash, reason)
+			}
 		}
 		return trades, rejects, nil
 	}
-	if order.Type != tradingstate.Market {
+	if tradingstate.ActivatedOrderType(order.Type) != tradingstate.Market {
 		if order.Price.Sign() == 0 || common.BigToHash(order.Price).Big().Cmp(order.Price) != 0 {
 			log.Debug("Reject order price invalid", "price", order.Price)
 			rejects = append(rejects, order)
+			if tracer != nil {
+				tracer.CaptureReject(order.Hash, "invalid price")
+			}
 			return trades, rejects, nil
 		}
 	}
 	if order.Quantity.Sign() == 0 || common.BigToHash(order.Quantity).Big().Cmp(order.Quantity) != 0 {
 		log.Debug("Reject order quantity invalid", "quantity", order.Quantity)
 		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, "invalid quantity")
+		}
+		return trades, rejects, nil
+	}
+	if order.ExpiresAt > 0 && !chain.Config().IsXDCxOrderExpiry(header.Number) {
+		log.Debug("Reject order expiry before XDCxOrderExpiry fork", "expiresAt", order.ExpiresAt)
+		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, "order expiry not supported")
+		}
+		return trades, rejects, nil
+	}
+	if order.SelfTrade != "" && !chain.Config().IsXDCxSelfTrade(header.Number) {
+		log.Debug("Reject self-trade prevention before XDCxSelfTrade fork", "selfTrade", order.SelfTrade)
+		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, "self-trade prevention not supported")
+		}
+		return trades, rejects, nil
+	}
+	if order.ExpiresAt > 0 && order.ExpiresAt <= header.Time.Uint64() {
+		log.Debug("Reject order expired", "expiresAt", order.ExpiresAt, "blockTime", header.Time)
+		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, "order expired")
+		}
 		return trades, rejects, nil
 	}
 	orderType := order.Type
 	// if we do not use auto-increment orderid, we must set price slot to avoid conflict
-	if orderType == tradingstate.Market {
+	if tradingstate.IsTriggerOrderType(orderType) {
+		log.Debug("Process trigger order", "side", order.Side, "quantity", order.Quantity, "triggerPrice", order.TriggerPrice)
+		orderId := tradingStateDB.GetNonce(orderBook)
+		order.OrderID = orderId + 1
+		tradingStateDB.SetNonce(orderBook, orderId+1)
+		orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
+		tradingStateDB.InsertOrderItem(orderBook, orderIdHash, *order)
+		if order.ExpiresAt > 0 {
+			tradingStateDB.InsertExpiryTime(orderBook, order.ExpiresAt, orderIdHash)
+		}
+	} else if orderType == tradingstate.Market {
 		log.Debug("Process maket order", "side", order.Side, "quantity", order.Quantity, "price", order.Price)
 		trades, rejects, err = XDCx.processMarketOrder(coinbase, chain, statedb, tradingStateDB, orderBook, order)
 		if err != nil {
@@ -100,10 +157,65 @@ func (XDCx *XDCX) ApplyOrder(header *types.Header, coinbase common.Address, chai
 			rejects = append(rejects, order)
 		}
 	}
+	if err == nil && chain.Config().IsXDCxTriggerOrders(header.Number) {
+		newTrades, newRejects := XDCx.processTriggeredOrders(coinbase, chain, statedb, tradingStateDB, orderBook)
+		trades = append(trades, newTrades...)
+		rejects = append(rejects, newRejects...)
+	}
 
 	return trades, rejects, nil
 }
 
+// processTriggeredOrders activates the trigger orders crossed by the last price
+// of the order book, matching them as market or limit orders. A triggered order
+// failing to match is dropped from the book and rejected.
+func (XDCx *XDCX) processTriggeredOrders(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash) ([]map[string]string, []*tradingstate.OrderItem) {
+	var (
+		trades  []map[string]string
+		rejects []*tradingstate.OrderItem
+	)
+	for i := 0; i < MaximumTriggeredOrders; i++ {
+		triggered, ok := tradingStateDB.GetTriggeredOrder(orderBook, tradingStateDB.GetLastPrice(orderBook))
+		if !ok {
+			break
+		}
+		if err := tradingStateDB.CancelOrder(orderBook, &triggered); err != nil {
+			log.Warn("[processTriggeredOrders] Failed to remove triggered order", "orderbook", orderBook.Hex(), "orderId", triggered.OrderID, "err", err)
+			break
+		}
+		order := triggered
+		order.Type = tradingstate.ActivatedOrderType(triggered.Type)
+		order.Quantity = tradingstate.CloneBigInt(triggered.Quantity)
+
+		XDCxSnap := tradingStateDB.Snapshot()
+		dbSnap := statedb.Snapshot()
+		var (
+			newTrades  []map[string]string
+			newRejects []*tradingstate.OrderItem
+			err        error
+		)
+		log.Debug("Process triggered order", "side", order.Side, "type", order.Type, "quantity", order.Quantity, "triggerPrice", order.TriggerPrice)
+		if order.Type == tradingstate.Market {
+			newTrades, newRejects, err = XDCx.processMarketOrder(coinbase, chain, statedb, tradingStateDB, orderBook, &order)
+		} else {
+			newTrades, newRejects, err = XDCx.processLimitOrder(coinbase, chain, statedb, tradingStateDB, orderBook, &order)
+		}
+		if err != nil {
+			log.Debug("Reject triggered order", "err", err, "order", tradingstate.ToJSON(&order))
+			tradingStateDB.RevertToSnapshot(XDCxSnap)
+			statedb.RevertToSnapshot(dbSnap)
+			rejects = append(rejects, &order)
+			if tracer := tradingStateDB.Tracer(); tracer != nil {
+				tracer.CaptureReject(order.Hash, err.Error())
+			}
+			continue
+		}
+		trades = append(trades, newTrades...)
+		rejects = append(rejects, newRejects...)
+	}
+	return trades, rejects
+}
+
 // processMarketOrder : process the market order
 func (XDCx *XDCX) processMarketOrder(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, orderBook common.Hash, order *tradingstate.OrderItem) ([]map[string]string, []*tradingstate.OrderItem, error) {
 	var (
@@ -144,6 +256,9 @@ func (XDCx *XDCX) processMarketOrder(coinbase common.Address, chain consensus.Ch
 			log.Debug("processMarketOrder ", "side", side, "bestPrice", bestPrice, "quantityToTrade", quantityToTrade, "volume", volume)
 		}
 	}
+	if order.TimeInForce == tradingstate.FillOrKill && (quantityToTrade.Sign() > 0 || isRejected(rejects, order)) {
+		return nil, nil, ErrFillOrKillUnfilled
+	}
 	return trades, rejects, nil
 }
 
@@ -195,13 +310,20 @@ func (XDCx *XDCX) processLimitOrder(coinbase common.Address, chain consensus.Cha
 			log.Debug("processLimitOrder ", "side", side, "maxPrice", maxPrice, "orderPrice", price, "volume", volume)
 		}
 	}
-	if quantityToTrade.Cmp(zero) > 0 {
+	if order.TimeInForce == tradingstate.FillOrKill && (quantityToTrade.Cmp(zero) > 0 || isRejected(rejects, order)) {
+		return nil, nil, ErrFillOrKillUnfilled
+	}
+	// the unmatched part of an immediate-or-cancel order is dropped
+	if quantityToTrade.Cmp(zero) > 0 && order.TimeInForce != tradingstate.ImmediateOrCancel {
 		orderId := tradingStateDB.GetNonce(orderBook)
 		order.OrderID = orderId + 1
 		order.Quantity = quantityToTrade
 		tradingStateDB.SetNonce(orderBook, orderId+1)
 		orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.OrderID))
 		tradingStateDB.InsertOrderItem(orderBook, orderIdHash, *order)
+		if order.ExpiresAt > 0 {
+			tradingStateDB.InsertExpiryTime(orderBook, order.ExpiresAt, orderIdHash)
+		}
 		log.Debug("After matching, order (unmatched part) is now added to tree", "side", order.Side, "order", order)
 	}
 	return trades, rejects, nil
@@ -209,6 +331,10 @@ func (XDCx *XDCX) processLimitOrder(coinbase common.Address, chain consensus.Cha
 
 // processOrderList : process the order list
 func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB, side string, orderBook common.Hash, price *big.Int, quantityStillToTrade *big.Int, order *tradingstate.OrderItem) (*big.Int, []map[string]string, []*tradingstate.OrderItem, error) {
+	// a post-only order is only processed as long as it doesn't cross the book
+	if order.PostOnly {
+		return nil, nil, nil, ErrPostOnlyWouldTake
+	}
 	quantityToTrade := tradingstate.CloneBigInt(quantityStillToTrade)
 	log.Debug("Process matching between order and orderlist", "quantityToTrade", quantityToTrade)
 	var (
@@ -216,6 +342,13 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 
 		rejects []*tradingstate.OrderItem
 	)
+	tracer := tradingStateDB.Tracer()
+	reject := func(item *tradingstate.OrderItem, reason string) {
+		rejects = append(rejects, item)
+		if tracer != nil {
+			tracer.CaptureReject(item.Hash, reason)
+		}
+	}
 	for quantityToTrade.Sign() > 0 {
 		orderId, amount, _ := tradingStateDB.GetBestOrderIdAndAmount(orderBook, price, side)
 		var oldestOrder tradingstate.OrderItem
@@ -226,6 +359,42 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 		if oldestOrder.Quantity == nil || oldestOrder.Quantity.Sign() == 0 && amount.Sign() == 0 {
 			break
 		}
+		// self-trade prevention follows the mode of the taker, no trade nor fee is settled
+		if order.SelfTrade != "" && oldestOrder.UserAddress == order.UserAddress {
+			cancelMaker := false
+			switch order.SelfTrade {
+			case tradingstate.CancelOldest:
+				cancelMaker = true
+			case tradingstate.CancelBoth:
+				reject(cancelledTaker(order, quantityToTrade), ErrSelfTrade.Error())
+				quantityToTrade = tradingstate.Zero
+				cancelMaker = true
+			case tradingstate.DecrementAndCancel:
+				if quantityToTrade.Cmp(amount) < 0 {
+					if err := tradingStateDB.SubAmountOrderItem(orderBook, orderId, price, quantityToTrade, side); err != nil {
+						return nil, nil, nil, err
+					}
+					reject(cancelledTaker(order, quantityToTrade), ErrSelfTrade.Error())
+					quantityToTrade = tradingstate.Zero
+				} else {
+					if quantityToTrade.Cmp(amount) == 0 {
+						reject(cancelledTaker(order, quantityToTrade), ErrSelfTrade.Error())
+					}
+					quantityToTrade = tradingstate.Sub(quantityToTrade, amount)
+					cancelMaker = true
+				}
+			default: // tradingstate.CancelNewest
+				reject(cancelledTaker(order, quantityToTrade), ErrSelfTrade.Error())
+				quantityToTrade = tradingstate.Zero
+			}
+			if cancelMaker {
+				reject(&oldestOrder, ErrSelfTrade.Error())
+				if err := tradingStateDB.CancelOrder(orderBook, &oldestOrder); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			continue
+		}
 		var (
 			tradedQuantity    *big.Int
 			maxTradedQuantity *big.Int
@@ -255,24 +424,24 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 		} else {
 			quotePrice = common.BasePrice
 		}
-		tradedQuantity, rejectMaker, settleBalanceResult, err := XDCx.getTradeQuantity(quotePrice, coinbase, chain, statedb, order, &oldestOrder, maxTradedQuantity)
+		tradedQuantity, rejectMaker, settleBalanceResult, err := XDCx.getTradeQuantity(quotePrice, coinbase, chain, statedb, order, &oldestOrder, maxTradedQuantity, tracer)
 		if err != nil && err == tradingstate.ErrQuantityTradeTooSmall {
 			if tradedQuantity.Cmp(maxTradedQuantity) == 0 {
 				if quantityToTrade.Cmp(amount) == 0 { // reject Taker & maker
-					rejects = append(rejects, order)
+					reject(order, err.Error())
 					quantityToTrade = tradingstate.Zero
-					rejects = append(rejects, &oldestOrder)
+					reject(&oldestOrder, err.Error())
 					err = tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
 						return nil, nil, nil, err
 					}
 					break
 				} else if quantityToTrade.Cmp(amount) < 0 { // reject Taker
-					rejects = append(rejects, order)
+					reject(order, err.Error())
 					quantityToTrade = tradingstate.Zero
 					break
 				} else { // reject maker
-					rejects = append(rejects, &oldestOrder)
+					reject(&oldestOrder, err.Error())
 					err = tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
 						return nil, nil, nil, err
@@ -281,14 +450,14 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 				}
 			} else {
 				if rejectMaker { // reject maker
-					rejects = append(rejects, &oldestOrder)
+					reject(&oldestOrder, err.Error())
 					err = tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 					if err != nil {
 						return nil, nil, nil, err
 					}
 					continue
 				} else { // reject Taker
-					rejects = append(rejects, order)
+					reject(order, err.Error())
 					quantityToTrade = tradingstate.Zero
 					break
 				}
@@ -298,7 +467,7 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 		}
 		if tradedQuantity.Sign() == 0 && !rejectMaker {
 			log.Debug("Reject order Taker ", "tradedQuantity", tradedQuantity, "rejectMaker", rejectMaker)
-			rejects = append(rejects, order)
+			reject(order, "insufficient balance or relayer fee")
 			quantityToTrade = tradingstate.Zero
 			break
 		}
@@ -331,6 +500,14 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 			tradeRecord[tradingstate.TradePrice] = oldestOrder.Price.String()
 			tradeRecord[tradingstate.MakerOrderType] = oldestOrder.Type
 			trades = append(trades, tradeRecord)
+			var takerFee, makerFee *big.Int
+			if settleBalanceResult != nil {
+				takerFee, makerFee = settleBalanceResult.Taker.Fee, settleBalanceResult.Maker.Fee
+			}
+			tradingStateDB.AddLog(tradingstate.NewTradeLog(orderBook, order, &oldestOrder, oldestOrder.Price, tradedQuantity, takerFee, makerFee))
+			if tracer != nil {
+				tracer.CaptureMatch(order.Hash, oldestOrder.Hash, oldestOrder.Price, tradedQuantity)
+			}
 
 			oldAveragePrice, oldTotalQuantity := tradingStateDB.GetMediumPriceAndTotalAmount(orderBook)
 
@@ -355,7 +532,7 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 			tradingStateDB.SetMediumPrice(orderBook, newAveragePrice, newTotalQuantity)
 		}
 		if rejectMaker {
-			rejects = append(rejects, &oldestOrder)
+			reject(&oldestOrder, "insufficient balance or relayer fee")
 			err := tradingStateDB.CancelOrder(orderBook, &oldestOrder)
 			if err != nil {
 				return nil, nil, nil, err
@@ -365,7 +542,30 @@ func (XDCx *XDCX) processOrderList(coinbase common.Address, chain consensus.Chai
 	return quantityToTrade, trades, rejects, nil
 }
 
-func (XDCx *XDCX) getTradeQuantity(quotePrice *big.Int, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, takerOrder *tradingstate.OrderItem, makerOrder *tradingstate.OrderItem, quantityToTrade *big.Int) (*big.Int, bool, *tradingstate.SettleBalance, error) {
+// cancelledTaker returns the taker dropped by the self-trade prevention with the
+// given quantity left. A taker which already matched part of its quantity, by
+// trades or by the decrements of the prevention, is reported as cancelled,
+// keeping its fills, instead of being rejected as a whole.
+func cancelledTaker(order *tradingstate.OrderItem, quantityToTrade *big.Int) *tradingstate.OrderItem {
+	if quantityToTrade.Cmp(order.Quantity) >= 0 {
+		return order
+	}
+	cancelled := *order
+	cancelled.Status = tradingstate.OrderStatusCancelled
+	return &cancelled
+}
+
+// isRejected reports whether the order is among the rejected ones
+func isRejected(rejects []*tradingstate.OrderItem, order *tradingstate.OrderItem) bool {
+	for _, reject := range rejects {
+		if reject.Hash == order.Hash {
+			return true
+		}
+	}
+	return false
+}
+
+func (XDCx *XDCX) getTradeQuantity(quotePrice *big.Int, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, takerOrder *tradingstate.OrderItem, makerOrder *tradingstate.OrderItem, quantityToTrade *big.Int, tracer tradingstate.Tracer) (*big.Int, bool, *tradingstate.SettleBalance, error) {
 	baseTokenDecimal, err := XDCx.GetTokenDecimal(chain, statedb, makerOrder.BaseToken)
 	if err != nil || baseTokenDecimal.Sign() == 0 {
 		return tradingstate.Zero, false, nil, fmt.Errorf("Fail to get tokenDecimal. Token: %v . Err: %v", makerOrder.BaseToken.String(), err)
@@ -414,7 +614,7 @@ func (XDCx *XDCX) getTradeQuantity(quotePrice *big.Int, coinbase common.Address,
 		settleBalanceResult, err = tradingstate.GetSettleBalance(quotePrice, takerOrder.Side, takerFeeRate, makerOrder.BaseToken, makerOrder.QuoteToken, makerOrder.Price, makerFeeRate, baseTokenDecimal, quoteTokenDecimal, quantity)
 		log.Debug("GetSettleBalance", "settleBalanceResult", settleBalanceResult, "err", err)
 		if err == nil {
-			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb)
+			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb, tracer)
 		}
 		return quantity, rejectMaker, settleBalanceResult, err
 	}
@@ -517,7 +717,7 @@ func GetTradeQuantity(takerSide string, takerFeeRate *big.Int, takerBalance *big
 	}
 }
 
-func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *tradingstate.OrderItem, settleBalance *tradingstate.SettleBalance, statedb *state.StateDB) error {
+func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *tradingstate.OrderItem, settleBalance *tradingstate.SettleBalance, statedb *state.StateDB, tracer tradingstate.Tracer) error {
 	takerExOwner := tradingstate.GetRelayerOwner(takerOrder.ExchangeAddress, statedb)
 	makerExOwner := tradingstate.GetRelayerOwner(makerOrder.ExchangeAddress, statedb)
 	matchingFee := big.NewInt(0)
@@ -625,6 +825,18 @@ func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *tradingsta
 	if err != nil {
 		log.Warn("DoSettleBalance SetTokenBalance", "err", err, "makerExOwner", makerExOwner, "newMakerFee", *newMakerFee, "makerOrder.QuoteToken", makerOrder.QuoteToken)
 	}
+	if tracer != nil {
+		XDCToken := common.HexToAddress(common.XDCNativeAddress)
+		tracer.CaptureBalanceChange(takerOrder.UserAddress, settleBalance.Taker.InToken, settleBalance.Taker.InTotal, tradingstate.ReasonSettlement)
+		tracer.CaptureBalanceChange(takerOrder.UserAddress, settleBalance.Taker.OutToken, new(big.Int).Neg(settleBalance.Taker.OutTotal), tradingstate.ReasonSettlement)
+		tracer.CaptureBalanceChange(makerOrder.UserAddress, settleBalance.Maker.InToken, settleBalance.Maker.InTotal, tradingstate.ReasonSettlement)
+		tracer.CaptureBalanceChange(makerOrder.UserAddress, settleBalance.Maker.OutToken, new(big.Int).Neg(settleBalance.Maker.OutTotal), tradingstate.ReasonSettlement)
+		tracer.CaptureBalanceChange(takerExOwner, makerOrder.QuoteToken, settleBalance.Taker.Fee, tradingstate.ReasonTradingFee)
+		tracer.CaptureBalanceChange(makerExOwner, makerOrder.QuoteToken, settleBalance.Maker.Fee, tradingstate.ReasonTradingFee)
+		tracer.CaptureBalanceChange(takerOrder.ExchangeAddress, XDCToken, new(big.Int).Neg(common.RelayerFee), tradingstate.ReasonRelayerFee)
+		tracer.CaptureBalanceChange(makerOrder.ExchangeAddress, XDCToken, new(big.Int).Neg(common.RelayerFee), tradingstate.ReasonRelayerFee)
+		tracer.CaptureBalanceChange(masternodeOwner, XDCToken, matchingFee, tradingstate.ReasonMatchingFee)
+	}
 	return nil
 }
 
@@ -705,6 +917,17 @@ func (XDCx *XDCX) ProcessCancelOrder(header *types.Header, tradingStateDB *tradi
 		}
 	default:
 	}
+	if tracer := tradingStateDB.Tracer(); tracer != nil {
+		XDCToken := common.HexToAddress(common.XDCNativeAddress)
+		feeToken := originOrder.QuoteToken
+		if originOrder.Side == tradingstate.Ask {
+			feeToken = originOrder.BaseToken
+		}
+		tracer.CaptureBalanceChange(originOrder.ExchangeAddress, XDCToken, new(big.Int).Neg(common.RelayerCancelFee), tradingstate.ReasonRelayerFee)
+		tracer.CaptureBalanceChange(masternodeOwner, XDCToken, common.RelayerCancelFee, tradingstate.ReasonMatchingFee)
+		tracer.CaptureBalanceChange(originOrder.UserAddress, feeToken, new(big.Int).Neg(tokenCancelFee), tradingstate.ReasonCancelFee)
+		tracer.CaptureBalanceChange(relayerOwner, feeToken, tokenCancelFee, tradingstate.ReasonCancelFee)
+	}
 	// update cancel fee
 	extraData, _ := json.Marshal(struct {
 		CancelFee       string
@@ -733,7 +956,12 @@ func getCancelFeeV1(baseTokenDecimal *big.Int, feeRate *big.Int, order *tradings
 		// BUY 1 BTC => XDC with Price : 10000
 		// quoteTokenQuantity = 10000 && fee rate =2
 		// => cancel fee =2
-		quoteTokenQuantity := new(big.Int).Mul(order.Quantity, order.Price)
+		// the price of a stop or take-profit market order is its trigger price
+		price := order.Price
+		if (price == nil || price.Sign() == 0) && order.TriggerPrice != nil {
+			price = order.TriggerPrice
+		}
+		quoteTokenQuantity := new(big.Int).Mul(order.Quantity, price)
 		quoteTokenQuantity = new(big.Int).Div(quoteTokenQuantity, baseTokenDecimal)
 		// Fee
 		// makerFee = quoteTokenQuantity * feeRate / baseFee = quantityToTrade * makerPrice / baseTokenDecimal * feeRate / baseFee
@@ -817,3 +1045,67 @@ func (XDCx *XDCX) LogEpochPrice(epochNumber uint64, epochPriceResult map[common.
 	}
 	return nil
 }
+
+// ProcessExpiredOrders cancels the resting orders whose expiry time is reached
+// by the block. At most MaximumExpiredOrders entries of the expiry index are
+// processed per order book, the others are left to the next blocks.
+// An expiration is free: no cancellation fee is charged.
+func (XDCx *XDCX) ProcessExpiredOrders(header *types.Header, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB) (expiredOrders []*tradingstate.OrderItem, err error) {
+	expiredOrders = []*tradingstate.OrderItem{}
+	tracer := tradingStateDB.Tracer()
+	if tracer != nil {
+		tracer.CaptureStart(tradingstate.TraceKindExpiry, common.Hash{}, common.Hash{})
+		defer func() { tracer.CaptureEnd(err) }()
+	}
+	allPairs, err := tradingstate.GetAllTradingPairs(statedb)
+	if err != nil {
+		log.Debug("Not found all trading pairs", "error", err)
+		return expiredOrders, nil
+	}
+	time := header.Time.Uint64()
+	// sweep the books in a fixed order, all nodes report the expired orders alike
+	books := make([]common.Hash, 0, len(allPairs))
+	for book := range allPairs {
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool {
+		return bytes.Compare(books[i][:], books[j][:]) < 0
+	})
+	for _, orderBook := range books {
+		// most books have no good-till-time order, don't open their expiry trie
+		if !tradingStateDB.HasExpiryTimes(orderBook) {
+			continue
+		}
+		processed := 0
+		for processed < MaximumExpiredOrders {
+			expiresAt, orderIds := tradingStateDB.GetLowestExpiryTime(orderBook, time)
+			if len(orderIds) == 0 {
+				break
+			}
+			for _, orderId := range orderIds {
+				if processed >= MaximumExpiredOrders {
+					break
+				}
+				processed++
+				if err := tradingStateDB.RemoveExpiryTime(orderBook, expiresAt, orderId); err != nil {
+					return expiredOrders, err
+				}
+				// the index isn't cleaned when an order is filled or cancelled
+				order := tradingStateDB.GetOrder(orderBook, orderId)
+				if order.Quantity == nil || order.Quantity.Sign() == 0 || order.ExpiresAt != expiresAt {
+					continue
+				}
+				if err := tradingStateDB.CancelOrder(orderBook, &order); err != nil {
+					log.Error("Fail when cancel expired order", "orderBook", orderBook.Hex(), "orderId", orderId.Hex(), "error", err)
+					return expiredOrders, err
+				}
+				order.Status = tradingstate.OrderStatusCancelled
+				expiredOrders = append(expiredOrders, &order)
+				if tracer != nil {
+					tracer.CaptureReject(order.Hash, "expired")
+				}
+			}
+		}
+	}
+	return expiredOrders, nil
+}
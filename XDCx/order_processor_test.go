@@ -1,9 +1,12 @@
 package XDCx
 
 import (
+	"errors"
 	"github.com/XinFinOrg/XDC-Subnet/XDCx/tradingstate"
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"math/big"
 	"reflect"
 	"testing"
@@ -572,3 +575,542 @@ func TestGetTradeQuantity(t *testing.T) {
 		})
 	}
 }
+
+// Tests that the settlement of a match is reported to the tracer, the balance
+// changes of every token summing up to zero, and that the changes are dropped
+// if the order is reverted.
+func TestDoSettleBalanceTracer(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	var (
+		XDCToken = common.HexToAddress(common.XDCNativeAddress)
+		tokenA   = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		relayer  = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		owner    = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		taker    = common.HexToAddress("0x0000000000000000000000000000000000000013")
+		maker    = common.HexToAddress("0x0000000000000000000000000000000000000014")
+	)
+	// Register the relayer with an owner and enough deposit to pay the fees
+	smc := common.HexToAddress(common.RelayerRegistrationSMC)
+	loc := tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+	statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_owner"])), owner.Hash())
+	statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_deposit"])), common.BigToHash(common.BasePrice))
+
+	statedb.CreateAccount(tokenA)
+	tradingstate.SetTokenBalance(taker, big.NewInt(1000), XDCToken, statedb)
+	tradingstate.SetTokenBalance(maker, big.NewInt(1000), tokenA, statedb)
+
+	// The taker buys 10 tokenA for 20 XDC, both sides paying 1 XDC of fee
+	takerOrder := &tradingstate.OrderItem{UserAddress: taker, ExchangeAddress: relayer, BaseToken: tokenA, QuoteToken: XDCToken}
+	makerOrder := &tradingstate.OrderItem{UserAddress: maker, ExchangeAddress: relayer, BaseToken: tokenA, QuoteToken: XDCToken}
+	settleBalance := &tradingstate.SettleBalance{
+		Taker: tradingstate.TradeResult{Fee: big.NewInt(1), InToken: tokenA, InTotal: big.NewInt(10), OutToken: XDCToken, OutTotal: big.NewInt(21)},
+		Maker: tradingstate.TradeResult{Fee: big.NewInt(1), InToken: XDCToken, InTotal: big.NewInt(19), OutToken: tokenA, OutTotal: big.NewInt(10)},
+	}
+	tracer := tradingstate.NewFrameTracer()
+	tracer.CaptureStart(tradingstate.TraceKindOrder, common.Hash{}, common.Hash{})
+	if err := DoSettleBalance(common.Address{}, takerOrder, makerOrder, settleBalance, statedb, tracer); err != nil {
+		t.Fatalf("failed to settle balance: %v", err)
+	}
+	if balance := tradingstate.GetTokenBalance(taker, tokenA, statedb); balance.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("taker balance mismatch: have %v, want %v", balance, 10)
+	}
+	frames := tracer.Frames()
+	if len(frames) != 1 {
+		t.Fatalf("frame count mismatch: have %d, want %d", len(frames), 1)
+	}
+	reasons := make(map[string]int)
+	sums := make(map[common.Address]*big.Int)
+	for _, change := range frames[0].BalanceChanges {
+		reasons[change.Reason]++
+		if sums[change.Token] == nil {
+			sums[change.Token] = new(big.Int)
+		}
+		sums[change.Token].Add(sums[change.Token], change.Amount.ToInt())
+	}
+	want := map[string]int{
+		tradingstate.ReasonSettlement:  4,
+		tradingstate.ReasonTradingFee:  2,
+		tradingstate.ReasonRelayerFee:  2,
+		tradingstate.ReasonMatchingFee: 1,
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("balance changes mismatch: have %v, want %v", reasons, want)
+	}
+	for token, sum := range sums {
+		if sum.Sign() != 0 {
+			t.Errorf("token %x balance changes don't sum up to zero: %v", token, sum)
+		}
+	}
+	// A reverted order only reports its rejection
+	tracer.CaptureEnd(errors.New("reverted"))
+	if frame := frames[0]; len(frame.BalanceChanges) != 0 || len(frame.Rejects) != 1 || frame.Error != "reverted" {
+		t.Errorf("reverted frame mismatch: %d balance changes, %d rejects, error %q", len(frame.BalanceChanges), len(frame.Rejects), frame.Error)
+	}
+}
+
+// Tests that the time in force and the post-only flag of a limit order decide
+// whether it takes liquidity and what happens to its unmatched part.
+func TestProcessLimitOrderTimeInForce(t *testing.T) {
+	var (
+		XDCToken = common.HexToAddress(common.XDCNativeAddress)
+		tokenA   = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		relayer  = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		owner    = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		taker    = common.HexToAddress("0x0000000000000000000000000000000000000013")
+		maker    = common.HexToAddress("0x0000000000000000000000000000000000000014")
+		price    = common.BasePrice
+		unit     = common.BasePrice
+	)
+	XDCx := New(&DefaultConfig)
+	XDCx.SetTokenDecimal(tokenA, common.BasePrice)
+	orderBook := tradingstate.GetTradingOrderBookHash(tokenA, XDCToken)
+
+	// newBook creates a book with an ask of 10 tokenA resting at the price
+	newBook := func() (*state.StateDB, *tradingstate.TradingStateDB) {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+		smc := common.HexToAddress(common.RelayerRegistrationSMC)
+		loc := tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+		statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_owner"])), owner.Hash())
+		statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_deposit"])), common.BigToHash(new(big.Int).Mul(common.BasePrice, new(big.Int).Add(common.RelayerLockedFund, common.Big1))))
+		statedb.CreateAccount(tokenA)
+		tradingstate.SetTokenBalance(taker, new(big.Int).Mul(big.NewInt(100), unit), XDCToken, statedb)
+		tradingstate.SetTokenBalance(maker, new(big.Int).Mul(big.NewInt(100), unit), tokenA, statedb)
+
+		tradingStateDb, _ := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+		ask := tradingstate.OrderItem{
+			Quantity:        new(big.Int).Mul(big.NewInt(10), unit),
+			Price:           price,
+			ExchangeAddress: relayer,
+			UserAddress:     maker,
+			BaseToken:       tokenA,
+			QuoteToken:      XDCToken,
+			Side:            tradingstate.Ask,
+			Type:            tradingstate.Limit,
+			Hash:            common.HexToHash("0x01"),
+			OrderID:         1,
+		}
+		tradingStateDb.SetNonce(orderBook, 1)
+		tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(1)), ask)
+		return statedb, tradingStateDb
+	}
+	tests := []struct {
+		timeInForce string
+		postOnly    bool
+		price       *big.Int
+		err         error
+		trades      int
+		resting     *big.Int // quantity of the order left on the book
+	}{
+		{"", false, price, nil, 1, new(big.Int).Mul(big.NewInt(5), unit)},
+		{tradingstate.GoodTillCancel, false, price, nil, 1, new(big.Int).Mul(big.NewInt(5), unit)},
+		{tradingstate.ImmediateOrCancel, false, price, nil, 1, nil},
+		{tradingstate.FillOrKill, false, price, ErrFillOrKillUnfilled, 0, nil},
+		{"", true, price, ErrPostOnlyWouldTake, 0, nil},
+		{"", true, new(big.Int).Sub(price, big.NewInt(1)), nil, 0, new(big.Int).Mul(big.NewInt(15), unit)},
+	}
+	for i, tt := range tests {
+		statedb, tradingStateDb := newBook()
+		order := &tradingstate.OrderItem{
+			Quantity:        new(big.Int).Mul(big.NewInt(15), unit),
+			Price:           tt.price,
+			ExchangeAddress: relayer,
+			UserAddress:     taker,
+			BaseToken:       tokenA,
+			QuoteToken:      XDCToken,
+			Side:            tradingstate.Bid,
+			Type:            tradingstate.Limit,
+			Hash:            common.HexToHash("0x02"),
+			TimeInForce:     tt.timeInForce,
+			PostOnly:        tt.postOnly,
+		}
+		trades, _, err := XDCx.processLimitOrder(common.Address{}, nil, statedb, tradingStateDb, orderBook, order)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if len(trades) != tt.trades {
+			t.Errorf("test %d: trade count mismatch: have %d, want %d", i, len(trades), tt.trades)
+		}
+		bestBid, volume := tradingStateDb.GetBestBidPrice(orderBook)
+		if tt.resting == nil {
+			if bestBid.Sign() != 0 {
+				t.Errorf("test %d: order rests on the book at %v", i, bestBid)
+			}
+		} else if bestBid.Cmp(tt.price) != 0 || volume.Cmp(tt.resting) != 0 {
+			t.Errorf("test %d: resting order mismatch: have %v at %v, want %v at %v", i, volume, bestBid, tt.resting, tt.price)
+		}
+	}
+}
+
+// TestProcessLimitOrderSelfTrade checks how the self-trade prevention mode of
+// a taker handles the makers of the same user.
+func TestProcessLimitOrderSelfTrade(t *testing.T) {
+	var (
+		XDCToken = common.HexToAddress(common.XDCNativeAddress)
+		tokenA   = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		relayer  = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		owner    = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		user     = common.HexToAddress("0x0000000000000000000000000000000000000013")
+		other    = common.HexToAddress("0x0000000000000000000000000000000000000014")
+		price    = common.BasePrice
+		unit     = common.BasePrice
+		makerQty = new(big.Int).Mul(big.NewInt(10), unit)
+	)
+	XDCx := New(&DefaultConfig)
+	XDCx.SetTokenDecimal(tokenA, common.BasePrice)
+	orderBook := tradingstate.GetTradingOrderBookHash(tokenA, XDCToken)
+
+	// newBook creates a book with an ask of 10 tokenA of the user resting at the price,
+	// below an ask of the filled quantity of another user at half the price
+	newBook := func(filled int64) (*state.StateDB, *tradingstate.TradingStateDB, tradingstate.OrderItem) {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+		smc := common.HexToAddress(common.RelayerRegistrationSMC)
+		loc := tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+		statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_owner"])), owner.Hash())
+		statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_deposit"])), common.BigToHash(new(big.Int).Mul(common.BasePrice, new(big.Int).Add(common.RelayerLockedFund, common.Big1))))
+		statedb.CreateAccount(tokenA)
+		tradingstate.SetTokenBalance(user, new(big.Int).Mul(big.NewInt(100), unit), XDCToken, statedb)
+		tradingstate.SetTokenBalance(user, new(big.Int).Mul(big.NewInt(100), unit), tokenA, statedb)
+		tradingstate.SetTokenBalance(other, new(big.Int).Mul(big.NewInt(100), unit), XDCToken, statedb)
+		tradingstate.SetTokenBalance(other, new(big.Int).Mul(big.NewInt(100), unit), tokenA, statedb)
+
+		tradingStateDb, _ := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+		ask := tradingstate.OrderItem{
+			Quantity:        makerQty,
+			Price:           price,
+			ExchangeAddress: relayer,
+			UserAddress:     user,
+			BaseToken:       tokenA,
+			QuoteToken:      XDCToken,
+			Side:            tradingstate.Ask,
+			Type:            tradingstate.Limit,
+			Hash:            common.HexToHash("0x01"),
+			OrderID:         1,
+		}
+		tradingStateDb.SetNonce(orderBook, 1)
+		tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(1)), ask)
+		if filled > 0 {
+			otherAsk := ask
+			otherAsk.Quantity = new(big.Int).Mul(big.NewInt(filled), unit)
+			otherAsk.Price = new(big.Int).Div(price, big.NewInt(2))
+			otherAsk.UserAddress = other
+			otherAsk.Hash = common.HexToHash("0x03")
+			otherAsk.OrderID = 2
+			tradingStateDb.SetNonce(orderBook, 2)
+			tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(2)), otherAsk)
+		}
+		return statedb, tradingStateDb, ask
+	}
+	tests := []struct {
+		selfTrade   string
+		quantity    int64
+		filled      int64 // quantity traded with another user before meeting the maker
+		trades      int
+		rejectTaker bool
+		cancelTaker bool // whether the dropped taker is reported as cancelled
+		rejectMaker bool
+		restingAsk  *big.Int // quantity of the maker left on the book
+		restingBid  *big.Int // quantity of the taker left on the book
+	}{
+		{"", 15, 0, 1, false, false, false, nil, new(big.Int).Mul(big.NewInt(5), unit)},
+		{tradingstate.CancelNewest, 15, 0, 0, true, false, false, makerQty, nil},
+		{tradingstate.CancelOldest, 15, 0, 0, false, false, true, nil, new(big.Int).Mul(big.NewInt(15), unit)},
+		{tradingstate.CancelBoth, 15, 0, 0, true, false, true, nil, nil},
+		{tradingstate.DecrementAndCancel, 15, 0, 0, false, false, true, nil, new(big.Int).Mul(big.NewInt(5), unit)},
+		{tradingstate.DecrementAndCancel, 10, 0, 0, true, false, true, nil, nil},
+		{tradingstate.DecrementAndCancel, 5, 0, 0, true, false, false, new(big.Int).Mul(big.NewInt(5), unit), nil},
+		{tradingstate.CancelNewest, 15, 5, 1, true, true, false, makerQty, nil},
+		{tradingstate.CancelBoth, 15, 5, 1, true, true, true, nil, nil},
+		{tradingstate.DecrementAndCancel, 15, 5, 1, true, true, true, nil, nil},
+		{tradingstate.DecrementAndCancel, 10, 5, 1, true, true, false, new(big.Int).Mul(big.NewInt(5), unit), nil},
+	}
+	for i, tt := range tests {
+		statedb, tradingStateDb, ask := newBook(tt.filled)
+		order := &tradingstate.OrderItem{
+			Quantity:        new(big.Int).Mul(big.NewInt(tt.quantity), unit),
+			Price:           price,
+			ExchangeAddress: relayer,
+			UserAddress:     user,
+			BaseToken:       tokenA,
+			QuoteToken:      XDCToken,
+			Side:            tradingstate.Bid,
+			Type:            tradingstate.Limit,
+			Hash:            common.HexToHash("0x02"),
+			SelfTrade:       tt.selfTrade,
+		}
+		trades, rejects, err := XDCx.processLimitOrder(common.Address{}, nil, statedb, tradingStateDb, orderBook, order)
+		if err != nil {
+			t.Errorf("test %d: failed to process limit order: %v", i, err)
+			continue
+		}
+		if len(trades) != tt.trades {
+			t.Errorf("test %d: trade count mismatch: have %d, want %d", i, len(trades), tt.trades)
+		}
+		rejected := map[common.Hash]bool{}
+		cancelled := false
+		for _, reject := range rejects {
+			rejected[reject.Hash] = true
+			if reject.Hash == order.Hash {
+				cancelled = reject.Status == tradingstate.OrderStatusCancelled
+			}
+		}
+		if rejected[order.Hash] != tt.rejectTaker || rejected[ask.Hash] != tt.rejectMaker {
+			t.Errorf("test %d: rejects mismatch: have taker %v, maker %v, want taker %v, maker %v", i, rejected[order.Hash], rejected[ask.Hash], tt.rejectTaker, tt.rejectMaker)
+		}
+		if cancelled != tt.cancelTaker {
+			t.Errorf("test %d: taker cancellation mismatch: have %v, want %v", i, cancelled, tt.cancelTaker)
+		}
+		for _, resting := range []struct {
+			side     string
+			quantity *big.Int
+		}{{tradingstate.Ask, tt.restingAsk}, {tradingstate.Bid, tt.restingBid}} {
+			var bestPrice, volume *big.Int
+			if resting.side == tradingstate.Ask {
+				bestPrice, volume = tradingStateDb.GetBestAskPrice(orderBook)
+			} else {
+				bestPrice, volume = tradingStateDb.GetBestBidPrice(orderBook)
+			}
+			if resting.quantity == nil {
+				if bestPrice.Sign() != 0 {
+					t.Errorf("test %d: %s order rests on the book at %v", i, resting.side, bestPrice)
+				}
+			} else if bestPrice.Cmp(price) != 0 || volume.Cmp(resting.quantity) != 0 {
+				t.Errorf("test %d: resting %s order mismatch: have %v at %v, want %v at %v", i, resting.side, volume, bestPrice, resting.quantity, price)
+			}
+		}
+	}
+}
+
+func TestProcessTriggeredOrders(t *testing.T) {
+	var (
+		XDCToken = common.HexToAddress(common.XDCNativeAddress)
+		tokenA   = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		relayer  = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		owner    = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		taker    = common.HexToAddress("0x0000000000000000000000000000000000000013")
+		maker    = common.HexToAddress("0x0000000000000000000000000000000000000014")
+		stopper  = common.HexToAddress("0x0000000000000000000000000000000000000015")
+		price    = common.BasePrice
+		unit     = common.BasePrice
+	)
+	XDCx := New(&DefaultConfig)
+	XDCx.SetTokenDecimal(tokenA, common.BasePrice)
+	orderBook := tradingstate.GetTradingOrderBookHash(tokenA, XDCToken)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	smc := common.HexToAddress(common.RelayerRegistrationSMC)
+	loc := tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+	statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_owner"])), owner.Hash())
+	statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_deposit"])), common.BigToHash(new(big.Int).Mul(common.BasePrice, new(big.Int).Add(common.RelayerLockedFund, common.Big1))))
+	statedb.CreateAccount(tokenA)
+	tradingstate.SetTokenBalance(taker, new(big.Int).Mul(big.NewInt(100), unit), XDCToken, statedb)
+	tradingstate.SetTokenBalance(maker, new(big.Int).Mul(big.NewInt(100), unit), tokenA, statedb)
+	tradingstate.SetTokenBalance(maker, new(big.Int).Mul(big.NewInt(100), unit), XDCToken, statedb)
+	tradingstate.SetTokenBalance(stopper, new(big.Int).Mul(big.NewInt(100), unit), tokenA, statedb)
+
+	// The book holds an ask at the price and a bid at half of it, with a stop
+	// order selling once the last price falls to the price
+	tradingStateDb, _ := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+	orders := []tradingstate.OrderItem{
+		{Quantity: new(big.Int).Mul(big.NewInt(10), unit), Price: price, UserAddress: maker, Side: tradingstate.Ask, Type: tradingstate.Limit, Hash: common.HexToHash("0x01")},
+		{Quantity: new(big.Int).Mul(big.NewInt(10), unit), Price: new(big.Int).Div(price, big.NewInt(2)), UserAddress: maker, Side: tradingstate.Bid, Type: tradingstate.Limit, Hash: common.HexToHash("0x02")},
+		{Quantity: new(big.Int).Mul(big.NewInt(5), unit), Price: new(big.Int), TriggerPrice: price, UserAddress: stopper, Side: tradingstate.Ask, Type: tradingstate.StopMarket, Hash: common.HexToHash("0x03")},
+	}
+	for i := range orders {
+		orders[i].OrderID = uint64(i + 1)
+		orders[i].ExchangeAddress, orders[i].BaseToken, orders[i].QuoteToken = relayer, tokenA, XDCToken
+		tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(int64(i+1))), orders[i])
+	}
+	tradingStateDb.SetNonce(orderBook, uint64(len(orders)))
+
+	// Nothing is triggered before the last price reaches the trigger price
+	if trades, rejects := XDCx.processTriggeredOrders(common.Address{}, nil, statedb, tradingStateDb, orderBook); len(trades) != 0 || len(rejects) != 0 {
+		t.Fatalf("trigger order activated early: %d trades, %d rejects", len(trades), len(rejects))
+	}
+	order := &tradingstate.OrderItem{
+		Quantity:        new(big.Int).Mul(big.NewInt(5), unit),
+		Price:           price,
+		ExchangeAddress: relayer,
+		UserAddress:     taker,
+		BaseToken:       tokenA,
+		QuoteToken:      XDCToken,
+		Side:            tradingstate.Bid,
+		Type:            tradingstate.Limit,
+		Hash:            common.HexToHash("0x04"),
+	}
+	if _, _, err := XDCx.processLimitOrder(common.Address{}, nil, statedb, tradingStateDb, orderBook, order); err != nil {
+		t.Fatalf("failed to process limit order: %v", err)
+	}
+	trades, rejects := XDCx.processTriggeredOrders(common.Address{}, nil, statedb, tradingStateDb, orderBook)
+	if len(trades) != 1 || len(rejects) != 0 {
+		t.Fatalf("triggered order mismatch: have %d trades, %d rejects, want 1 trade", len(trades), len(rejects))
+	}
+	if hash := trades[0][tradingstate.TradeTakerOrderHash]; hash != orders[2].Hash.Hex() {
+		t.Errorf("taker order mismatch: have %s, want %s", hash, orders[2].Hash.Hex())
+	}
+	if hash := trades[0][tradingstate.TradeMakerOrderHash]; hash != orders[1].Hash.Hex() {
+		t.Errorf("maker order mismatch: have %s, want %s", hash, orders[1].Hash.Hex())
+	}
+	if _, ok := tradingStateDb.GetTriggeredOrder(orderBook, big.NewInt(1)); ok {
+		t.Errorf("triggered order left in the trigger tree")
+	}
+	if _, volume := tradingStateDb.GetBestBidPrice(orderBook); volume.Cmp(new(big.Int).Mul(big.NewInt(5), unit)) != 0 {
+		t.Errorf("bid volume mismatch: have %v, want %v", volume, new(big.Int).Mul(big.NewInt(5), unit))
+	}
+}
+
+func TestProcessExpiredOrders(t *testing.T) {
+	var (
+		XDCToken = common.HexToAddress(common.XDCNativeAddress)
+		tokenA   = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		relayer  = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		maker    = common.HexToAddress("0x0000000000000000000000000000000000000014")
+		price    = common.BasePrice
+	)
+	XDCx := New(&DefaultConfig)
+	orderBook := tradingstate.GetTradingOrderBookHash(tokenA, XDCToken)
+
+	// A single relayer lists the tokenA/XDC pair
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	smc := common.HexToAddress(common.RelayerRegistrationSMC)
+	statedb.SetState(smc, common.BigToHash(new(big.Int).SetUint64(tradingstate.RelayerMappingSlot["RelayerCount"])), common.BigToHash(common.Big1))
+	statedb.SetState(smc, common.BytesToHash(state.GetLocMappingAtKey(common.BigToHash(common.Big0), tradingstate.RelayerMappingSlot["RELAYER_COINBASES"]).Bytes()), relayer.Hash())
+	loc := tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+	for field, token := range map[string]common.Address{"_fromTokens": tokenA, "_toTokens": XDCToken} {
+		slot := common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot[field]))
+		statedb.SetState(smc, slot, common.BigToHash(common.Big1))
+		statedb.SetState(smc, state.GetLocDynamicArrAtElement(slot, 0, 1), token.Hash())
+	}
+
+	tradingStateDb, _ := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+	orders := []tradingstate.OrderItem{
+		{Price: price, ExpiresAt: 100, Hash: common.HexToHash("0x01")},
+		{Price: new(big.Int).Mul(price, big.NewInt(2)), ExpiresAt: 200, Hash: common.HexToHash("0x02")},
+		{Price: new(big.Int).Mul(price, big.NewInt(3)), Hash: common.HexToHash("0x03")},
+	}
+	for i := range orders {
+		orders[i].OrderID = uint64(i + 1)
+		orders[i].Quantity, orders[i].UserAddress, orders[i].Side, orders[i].Type = big.NewInt(1), maker, tradingstate.Ask, tradingstate.Limit
+		orders[i].ExchangeAddress, orders[i].BaseToken, orders[i].QuoteToken = relayer, tokenA, XDCToken
+		orderId := common.BigToHash(big.NewInt(int64(i + 1)))
+		tradingStateDb.InsertOrderItem(orderBook, orderId, orders[i])
+		if orders[i].ExpiresAt > 0 {
+			tradingStateDb.InsertExpiryTime(orderBook, orders[i].ExpiresAt, orderId)
+		}
+	}
+	// A stale index entry doesn't cancel an order expiring at another time
+	tradingStateDb.InsertExpiryTime(orderBook, 100, common.BigToHash(big.NewInt(3)))
+	tradingStateDb.SetNonce(orderBook, uint64(len(orders)))
+
+	expired, err := XDCx.ProcessExpiredOrders(&types.Header{Time: big.NewInt(99)}, statedb, tradingStateDb)
+	if err != nil || len(expired) != 0 {
+		t.Fatalf("orders expired early: %d, err: %v", len(expired), err)
+	}
+	expired, err = XDCx.ProcessExpiredOrders(&types.Header{Time: big.NewInt(150)}, statedb, tradingStateDb)
+	if err != nil {
+		t.Fatalf("failed to process expired orders: %v", err)
+	}
+	if len(expired) != 1 || expired[0].Hash != orders[0].Hash || expired[0].Status != tradingstate.OrderStatusCancelled {
+		t.Fatalf("expired orders mismatch: have %v, want [%x]", expired, orders[0].Hash)
+	}
+	if best, _ := tradingStateDb.GetBestAskPrice(orderBook); best.Cmp(orders[1].Price) != 0 {
+		t.Errorf("best ask mismatch: have %v, want %v", best, orders[1].Price)
+	}
+	if order := tradingStateDb.GetOrder(orderBook, common.BigToHash(big.NewInt(3))); order.Quantity.Sign() == 0 {
+		t.Errorf("order without expiry cancelled")
+	}
+	if lowest, orderIds := tradingStateDb.GetLowestExpiryTime(orderBook, 150); lowest != 200 || len(orderIds) != 0 {
+		t.Errorf("lowest expiry mismatch: have %d (%d orders), want 200 (0 orders)", lowest, len(orderIds))
+	}
+}
+
+// TestProcessLimitOrderTradeLog checks the trade log recorded for a match against
+// the transaction being processed.
+func TestProcessLimitOrderTradeLog(t *testing.T) {
+	var (
+		XDCToken = common.HexToAddress(common.XDCNativeAddress)
+		tokenA   = common.HexToAddress("0x1000000000000000000000000000000000000002")
+		relayer  = common.HexToAddress("0x0000000000000000000000000000000000000011")
+		owner    = common.HexToAddress("0x0000000000000000000000000000000000000012")
+		taker    = common.HexToAddress("0x0000000000000000000000000000000000000013")
+		maker    = common.HexToAddress("0x0000000000000000000000000000000000000014")
+		price    = common.BasePrice
+		unit     = common.BasePrice
+		txHash   = common.HexToHash("0xaa")
+	)
+	XDCx := New(&DefaultConfig)
+	XDCx.SetTokenDecimal(tokenA, common.BasePrice)
+	orderBook := tradingstate.GetTradingOrderBookHash(tokenA, XDCToken)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	smc := common.HexToAddress(common.RelayerRegistrationSMC)
+	loc := tradingstate.GetLocMappingAtKey(relayer.Hash(), tradingstate.RelayerMappingSlot["RELAYER_LIST"])
+	statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_owner"])), owner.Hash())
+	statedb.SetState(smc, common.BigToHash(new(big.Int).Add(loc, tradingstate.RelayerStructMappingSlot["_deposit"])), common.BigToHash(new(big.Int).Mul(common.BasePrice, new(big.Int).Add(common.RelayerLockedFund, common.Big1))))
+	statedb.CreateAccount(tokenA)
+	tradingstate.SetTokenBalance(taker, new(big.Int).Mul(big.NewInt(100), unit), XDCToken, statedb)
+	tradingstate.SetTokenBalance(maker, new(big.Int).Mul(big.NewInt(100), unit), tokenA, statedb)
+
+	tradingStateDb, _ := tradingstate.New(common.Hash{}, tradingstate.NewDatabase(rawdb.NewMemoryDatabase()))
+	ask := tradingstate.OrderItem{
+		Quantity:        new(big.Int).Mul(big.NewInt(10), unit),
+		Price:           price,
+		ExchangeAddress: relayer,
+		UserAddress:     maker,
+		BaseToken:       tokenA,
+		QuoteToken:      XDCToken,
+		Side:            tradingstate.Ask,
+		Type:            tradingstate.Limit,
+		Hash:            common.HexToHash("0x01"),
+		OrderID:         1,
+	}
+	tradingStateDb.SetNonce(orderBook, 1)
+	tradingStateDb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(1)), ask)
+	tradingStateDb.Prepare(txHash)
+
+	order := &tradingstate.OrderItem{
+		Quantity:        new(big.Int).Mul(big.NewInt(4), unit),
+		Price:           price,
+		ExchangeAddress: relayer,
+		UserAddress:     taker,
+		BaseToken:       tokenA,
+		QuoteToken:      XDCToken,
+		Side:            tradingstate.Bid,
+		Type:            tradingstate.Limit,
+		Hash:            common.HexToHash("0x02"),
+	}
+	trades, _, err := XDCx.processLimitOrder(common.Address{}, nil, statedb, tradingStateDb, orderBook, order)
+	if err != nil {
+		t.Fatalf("failed to process limit order: %v", err)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("trade count mismatch: have %d, want 1", len(trades))
+	}
+	logs := tradingStateDb.GetLogs(txHash)
+	if len(logs) != 1 {
+		t.Fatalf("log count mismatch: have %d, want 1", len(logs))
+	}
+	l := logs[0]
+	if l.Address != common.HexToAddress(common.XDCXAddr) || l.TxHash != txHash {
+		t.Errorf("log origin mismatch: have %x in %x", l.Address, l.TxHash)
+	}
+	wantTopics := []common.Hash{tradingstate.TradeLogTopic, orderBook, common.BytesToHash(taker.Bytes()), common.BytesToHash(maker.Bytes())}
+	if !reflect.DeepEqual(l.Topics, wantTopics) {
+		t.Errorf("topics mismatch: have %x, want %x", l.Topics, wantTopics)
+	}
+	if len(l.Data) != 6*common.HashLength {
+		t.Fatalf("data length mismatch: have %d, want %d", len(l.Data), 6*common.HashLength)
+	}
+	word := func(i int) common.Hash {
+		return common.BytesToHash(l.Data[i*common.HashLength : (i+1)*common.HashLength])
+	}
+	if word(0) != order.Hash || word(1) != ask.Hash {
+		t.Errorf("order hashes mismatch: have %x/%x, want %x/%x", word(0), word(1), order.Hash, ask.Hash)
+	}
+	if word(2).Big().Cmp(price) != 0 {
+		t.Errorf("price mismatch: have %v, want %v", word(2).Big(), price)
+	}
+	if have, want := word(3).Big(), new(big.Int).Mul(big.NewInt(4), unit); have.Cmp(want) != 0 {
+		t.Errorf("quantity mismatch: have %v, want %v", have, want)
+	}
+}
@@ -23,6 +23,22 @@ var (
 	Limit     = "LO"
 	Cancel    = "CANCELLED"
 	OrderNew  = "NEW"
+
+	// trigger orders, matched as market or limit orders once activated
+	StopMarket       = "SMO"
+	StopLimit        = "SLO"
+	TakeProfitMarket = "TPMO"
+	TakeProfitLimit  = "TPLO"
+
+	GoodTillCancel    = "GTC"
+	ImmediateOrCancel = "IOC"
+	FillOrKill        = "FOK"
+
+	// self-trade prevention modes, applied by the taker against the makers of the same user
+	CancelNewest       = "CN" // cancel the taker
+	CancelOldest       = "CO" // cancel the maker
+	CancelBoth         = "CB" // cancel both the taker and the maker
+	DecrementAndCancel = "DC" // decrement both by the smaller quantity, cancelling the smaller order
 )
 
 var EmptyHash = common.Hash{}
@@ -43,18 +59,43 @@ var EmptyOrder = OrderItem{
 }
 
 var (
-	ErrInvalidSignature = errors.New("verify order: invalid signature")
-	ErrInvalidPrice     = errors.New("verify order: invalid price")
-	ErrInvalidQuantity  = errors.New("verify order: invalid quantity")
-	ErrInvalidRelayer   = errors.New("verify order: invalid relayer")
-	ErrInvalidOrderType = errors.New("verify order: unsupported order type")
-	ErrInvalidOrderSide = errors.New("verify order: invalid order side")
-	ErrInvalidStatus    = errors.New("verify order: invalid status")
+	ErrInvalidSignature   = errors.New("verify order: invalid signature")
+	ErrInvalidPrice       = errors.New("verify order: invalid price")
+	ErrInvalidQuantity    = errors.New("verify order: invalid quantity")
+	ErrInvalidRelayer     = errors.New("verify order: invalid relayer")
+	ErrInvalidOrderType   = errors.New("verify order: unsupported order type")
+	ErrInvalidOrderSide   = errors.New("verify order: invalid order side")
+	ErrInvalidStatus      = errors.New("verify order: invalid status")
+	ErrInvalidTimeInForce = errors.New("verify order: unsupported time in force")
+	ErrInvalidPostOnly    = errors.New("verify order: post-only order must be a good-till-cancel limit order")
+	ErrInvalidTrigger     = errors.New("verify order: invalid trigger price")
+	ErrInvalidSelfTrade   = errors.New("verify order: unsupported self-trade prevention mode")
 
 	// supported order types
 	MatchingOrderType = map[string]bool{
-		Market: true,
-		Limit:  true,
+		Market:           true,
+		Limit:            true,
+		StopMarket:       true,
+		StopLimit:        true,
+		TakeProfitMarket: true,
+		TakeProfitLimit:  true,
+	}
+
+	// supported time in force, the default one depending on the order type
+	MatchingTimeInForce = map[string]bool{
+		"":                true,
+		GoodTillCancel:    true,
+		ImmediateOrCancel: true,
+		FillOrKill:        true,
+	}
+
+	// supported self-trade prevention modes, self-trades being allowed by default
+	MatchingSelfTradePrevention = map[string]bool{
+		"":                 true,
+		CancelNewest:       true,
+		CancelOldest:       true,
+		CancelBoth:         true,
+		DecrementAndCancel: true,
 	}
 )
 
@@ -78,6 +119,9 @@ type tradingExchangeObject struct {
 	BidRoot                common.Hash // merkle root of the storage trie
 	OrderRoot              common.Hash
 	LiquidationPriceRoot   common.Hash
+	TriggerAboveRoot       common.Hash `rlp:"optional"` // trigger orders activated by a rise of the last price
+	TriggerBelowRoot       common.Hash `rlp:"optional"` // trigger orders activated by a fall of the last price
+	ExpiryTimeRoot         common.Hash `rlp:"optional"` // resting orders by expiry time
 }
 
 var (
@@ -218,3 +262,37 @@ func GetTradingOrderBookHash(baseToken common.Address, quoteToken common.Address
 func GetMatchingResultCacheKey(order *OrderItem) common.Hash {
 	return crypto.Keccak256Hash(order.UserAddress.Bytes(), order.Nonce.Bytes())
 }
+
+// IsTriggerOrderType reports whether the orders of the given type wait in the
+// trigger trees for the last price to reach their trigger price.
+func IsTriggerOrderType(orderType string) bool {
+	switch orderType {
+	case StopMarket, StopLimit, TakeProfitMarket, TakeProfitLimit:
+		return true
+	}
+	return false
+}
+
+// ActivatedOrderType returns the type an order is matched as, which is the
+// market or limit type of the activated trigger orders.
+func ActivatedOrderType(orderType string) string {
+	switch orderType {
+	case StopMarket, TakeProfitMarket:
+		return Market
+	case StopLimit, TakeProfitLimit:
+		return Limit
+	}
+	return orderType
+}
+
+// TriggersAbove reports whether a trigger order is activated by the last price
+// rising to its trigger price, as a buy stop or a sell take-profit, rather
+// than falling to it.
+func TriggersAbove(order *OrderItem) bool {
+	switch order.Type {
+	case StopMarket, StopLimit:
+		return order.Side == Bid
+	default:
+		return order.Side == Ask
+	}
+}
@@ -76,6 +76,19 @@ type (
 		lendingBook common.Hash
 		tradeId     uint64
 	}
+	insertExpiryTime struct {
+		orderBook common.Hash
+		expiresAt uint64
+		orderId   common.Hash
+	}
+	removeExpiryTime struct {
+		orderBook common.Hash
+		expiresAt uint64
+		orderId   common.Hash
+	}
+	addLogChange struct {
+		txhash common.Hash
+	}
 )
 
 func (ch insertOrder) undo(s *TradingStateDB) {
@@ -96,6 +109,15 @@ func (ch insertLiquidationPrice) undo(s *TradingStateDB) {
 func (ch removeLiquidationPrice) undo(s *TradingStateDB) {
 	s.InsertLiquidationPrice(ch.orderBook, ch.price, ch.lendingBook, ch.tradeId)
 }
+func (ch insertExpiryTime) undo(s *TradingStateDB) {
+	err := s.RemoveExpiryTime(ch.orderBook, ch.expiresAt, ch.orderId)
+	if err != nil {
+		log.Warn("undo RemoveExpiryTime", "err", err, "ch.orderBook", ch.orderBook, "ch.expiresAt", ch.expiresAt, "ch.orderId", ch.orderId)
+	}
+}
+func (ch removeExpiryTime) undo(s *TradingStateDB) {
+	s.InsertExpiryTime(ch.orderBook, ch.expiresAt, ch.orderId)
+}
 func (ch subAmountOrder) undo(s *TradingStateDB) {
 	priceHash := common.BigToHash(ch.order.Price)
 	stateOrderBook := s.getStateExchangeObject(ch.orderBook)
@@ -126,3 +148,11 @@ func (ch mediumPriceChange) undo(s *TradingStateDB) {
 func (ch mediumPriceBeforeEpochChange) undo(s *TradingStateDB) {
 	s.SetMediumPriceBeforeEpoch(ch.hash, ch.prevPrice)
 }
+func (ch addLogChange) undo(s *TradingStateDB) {
+	logs := s.logs[ch.txhash]
+	if len(logs) == 1 {
+		delete(s.logs, ch.txhash)
+	} else {
+		s.logs[ch.txhash] = logs[:len(logs)-1]
+	}
+}
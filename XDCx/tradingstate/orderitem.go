@@ -11,6 +11,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
 	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/params"
 	"github.com/globalsign/mgo/bson"
 )
 
@@ -43,6 +44,11 @@ type OrderItem struct {
 	UpdatedAt       time.Time      `json:"updatedAt,omitempty"`
 	OrderID         uint64         `json:"orderID,omitempty"`
 	ExtraData       string         `json:"extraData,omitempty"`
+	TimeInForce     string         `json:"timeInForce,omitempty" rlp:"optional"`
+	PostOnly        bool           `json:"postOnly,omitempty" rlp:"optional"`
+	TriggerPrice    *big.Int       `json:"triggerPrice,omitempty" rlp:"optional"`
+	ExpiresAt       uint64         `json:"expiresAt,omitempty" rlp:"optional"`
+	SelfTrade       string         `json:"selfTradePrevention,omitempty" rlp:"optional"`
 }
 
 // Signature struct
@@ -77,6 +83,11 @@ type OrderItemBSON struct {
 	UpdatedAt       time.Time        `json:"updatedAt,omitempty" bson:"updatedAt"`
 	OrderID         string           `json:"orderID,omitempty" bson:"orderID"`
 	ExtraData       string           `json:"extraData,omitempty" bson:"extraData"`
+	TimeInForce     string           `json:"timeInForce,omitempty" bson:"timeInForce"`
+	PostOnly        bool             `json:"postOnly,omitempty" bson:"postOnly"`
+	TriggerPrice    string           `json:"triggerPrice,omitempty" bson:"triggerPrice"`
+	ExpiresAt       string           `json:"expiresAt,omitempty" bson:"expiresAt"`
+	SelfTrade       string           `json:"selfTradePrevention,omitempty" bson:"selfTradePrevention"`
 }
 
 func (o *OrderItem) GetBSON() (interface{}, error) {
@@ -97,6 +108,17 @@ func (o *OrderItem) GetBSON() (interface{}, error) {
 		UpdatedAt:       o.UpdatedAt,
 		OrderID:         strconv.FormatUint(o.OrderID, 10),
 		ExtraData:       o.ExtraData,
+		TimeInForce:     o.TimeInForce,
+		PostOnly:        o.PostOnly,
+		SelfTrade:       o.SelfTrade,
+	}
+
+	if o.TriggerPrice != nil {
+		or.TriggerPrice = o.TriggerPrice.String()
+	}
+
+	if o.ExpiresAt > 0 {
+		or.ExpiresAt = strconv.FormatUint(o.ExpiresAt, 10)
 	}
 
 	if o.FilledAmount != nil {
@@ -137,6 +159,11 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 		UpdatedAt       time.Time        `json:"updatedAt" bson:"updatedAt"`
 		OrderID         string           `json:"orderID" bson:"orderID"`
 		ExtraData       string           `json:"extraData,omitempty" bson:"extraData"`
+		TimeInForce     string           `json:"timeInForce,omitempty" bson:"timeInForce"`
+		PostOnly        bool             `json:"postOnly,omitempty" bson:"postOnly"`
+		TriggerPrice    string           `json:"triggerPrice,omitempty" bson:"triggerPrice"`
+		ExpiresAt       string           `json:"expiresAt,omitempty" bson:"expiresAt"`
+		SelfTrade       string           `json:"selfTradePrevention,omitempty" bson:"selfTradePrevention"`
 	})
 
 	err := raw.Unmarshal(decoded)
@@ -184,12 +211,25 @@ func (o *OrderItem) SetBSON(raw bson.Raw) error {
 	}
 	o.OrderID = uint64(orderID)
 	o.ExtraData = decoded.ExtraData
+	o.TimeInForce = decoded.TimeInForce
+	o.PostOnly = decoded.PostOnly
+	o.SelfTrade = decoded.SelfTrade
+	if decoded.TriggerPrice != "" {
+		o.TriggerPrice = ToBigInt(decoded.TriggerPrice)
+	}
+	if decoded.ExpiresAt != "" {
+		expiresAt, err := strconv.ParseUint(decoded.ExpiresAt, 10, 64)
+		if err != nil {
+			return err
+		}
+		o.ExpiresAt = expiresAt
+	}
 	return nil
 }
 
 // VerifyOrder verify orderItem
-func (o *OrderItem) VerifyOrder(state *state.StateDB) error {
-	if err := o.VerifyBasicOrderInfo(); err != nil {
+func (o *OrderItem) VerifyOrder(state *state.StateDB, config *params.ChainConfig, number *big.Int) error {
+	if err := o.VerifyBasicOrderInfo(config, number); err != nil {
 		return err
 	}
 	if err := o.verifyRelayer(state); err != nil {
@@ -203,22 +243,32 @@ func (o *OrderItem) VerifyOrder(state *state.StateDB) error {
 	return nil
 }
 
-// VerifyBasicOrderInfo verify basic info
-func (o *OrderItem) VerifyBasicOrderInfo() error {
+// VerifyBasicOrderInfo verify basic info, the order fields added by a fork are
+// rejected before the fork block
+func (o *OrderItem) VerifyBasicOrderInfo(config *params.ChainConfig, number *big.Int) error {
 
 	if o.Status == OrderNew {
-		if o.Type == Limit {
+		if ActivatedOrderType(o.Type) == Limit {
 			if err := o.verifyPrice(); err != nil {
 				return err
 			}
 		}
+		if err := o.verifyTriggerPrice(); err != nil {
+			return err
+		}
 		if err := o.verifyQuantity(); err != nil {
 			return err
 		}
 		if err := o.verifyOrderSide(); err != nil {
 			return err
 		}
-		if err := o.verifyOrderType(); err != nil {
+		if err := o.verifyOrderType(config, number); err != nil {
+			return err
+		}
+		if err := o.verifyTimeInForce(config, number); err != nil {
+			return err
+		}
+		if err := o.verifySelfTrade(config, number); err != nil {
 			return err
 		}
 	}
@@ -251,7 +301,7 @@ func (o *OrderItem) verifySignature() error {
 	S := o.Signature.S.Big()
 
 	tx := types.NewOrderTransaction(uint64(n), o.Quantity, o.Price, o.ExchangeAddress, o.UserAddress,
-		o.BaseToken, o.QuoteToken, o.Status, o.Side, o.Type, o.Hash, o.OrderID)
+		o.BaseToken, o.QuoteToken, o.Status, o.Side, o.Type, o.Hash, o.OrderID, o.TimeInForce, o.PostOnly, o.TriggerPrice, o.ExpiresAt, o.SelfTrade)
 	tx.ImportSignature(V, R, S)
 	from, _ := types.OrderSender(types.OrderTxSigner{}, tx)
 	if from != tx.UserAddress() {
@@ -261,11 +311,74 @@ func (o *OrderItem) verifySignature() error {
 }
 
 // verify order type
-func (o *OrderItem) verifyOrderType() error {
+func (o *OrderItem) verifyOrderType(config *params.ChainConfig, number *big.Int) error {
 	if _, ok := MatchingOrderType[o.Type]; !ok {
 		log.Debug("Invalid order type", "type", o.Type)
 		return ErrInvalidOrderType
 	}
+	if IsTriggerOrderType(o.Type) && !config.IsXDCxTriggerOrders(number) {
+		log.Debug("Trigger order before XDCxTriggerOrders fork", "type", o.Type)
+		return ErrInvalidOrderType
+	}
+	return nil
+}
+
+// verify time in force and post-only flag
+// market orders can't rest on the book and post-only orders can't take
+func (o *OrderItem) verifyTimeInForce(config *params.ChainConfig, number *big.Int) error {
+	if !config.IsXDCxOrderFlags(number) {
+		if o.TimeInForce != "" {
+			log.Debug("Time in force before XDCxOrderFlags fork", "timeInForce", o.TimeInForce)
+			return ErrInvalidTimeInForce
+		}
+		if o.PostOnly {
+			log.Debug("Post-only order before XDCxOrderFlags fork")
+			return ErrInvalidPostOnly
+		}
+		return nil
+	}
+	if _, ok := MatchingTimeInForce[o.TimeInForce]; !ok {
+		log.Debug("Invalid time in force", "timeInForce", o.TimeInForce)
+		return ErrInvalidTimeInForce
+	}
+	orderType := ActivatedOrderType(o.Type)
+	if orderType == Market && o.TimeInForce == GoodTillCancel {
+		log.Debug("Invalid time in force for market order", "timeInForce", o.TimeInForce)
+		return ErrInvalidTimeInForce
+	}
+	if o.PostOnly && (orderType != Limit || (o.TimeInForce != "" && o.TimeInForce != GoodTillCancel)) {
+		log.Debug("Invalid post-only order", "type", o.Type, "timeInForce", o.TimeInForce)
+		return ErrInvalidPostOnly
+	}
+	return nil
+}
+
+// verify self-trade prevention mode
+func (o *OrderItem) verifySelfTrade(config *params.ChainConfig, number *big.Int) error {
+	if o.SelfTrade != "" && !config.IsXDCxSelfTrade(number) {
+		log.Debug("Self-trade prevention before XDCxSelfTrade fork", "selfTradePrevention", o.SelfTrade)
+		return ErrInvalidSelfTrade
+	}
+	if _, ok := MatchingSelfTradePrevention[o.SelfTrade]; !ok {
+		log.Debug("Invalid self-trade prevention mode", "selfTradePrevention", o.SelfTrade)
+		return ErrInvalidSelfTrade
+	}
+	return nil
+}
+
+// verifyTriggerPrice make sure trigger orders, and only them, have a positive trigger price
+func (o *OrderItem) verifyTriggerPrice() error {
+	if !IsTriggerOrderType(o.Type) {
+		if o.TriggerPrice != nil && o.TriggerPrice.Sign() != 0 {
+			log.Debug("Trigger price of non trigger order", "type", o.Type, "triggerPrice", o.TriggerPrice)
+			return ErrInvalidTrigger
+		}
+		return nil
+	}
+	if o.TriggerPrice == nil || o.TriggerPrice.Sign() <= 0 || common.BigToHash(o.TriggerPrice).Big().Cmp(o.TriggerPrice) != 0 {
+		log.Debug("Invalid trigger price", "triggerPrice", o.TriggerPrice)
+		return ErrInvalidTrigger
+	}
 	return nil
 }
 
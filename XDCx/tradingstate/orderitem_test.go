@@ -0,0 +1,61 @@
+package tradingstate
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+// signOrder signs a new order with the given fields by a random user.
+func signOrder(t *testing.T, order OrderItem) *OrderItem {
+	key, _ := crypto.GenerateKey()
+	order.Nonce = big.NewInt(0)
+	order.Quantity = big.NewInt(100)
+	order.Price = big.NewInt(100)
+	order.ExchangeAddress = common.HexToAddress("0x0000000000000000000000000000000000000011")
+	order.UserAddress = crypto.PubkeyToAddress(key.PublicKey)
+	order.BaseToken = common.HexToAddress("0x1000000000000000000000000000000000000002")
+	order.QuoteToken = common.HexToAddress(common.XDCNativeAddress)
+	order.Status = OrderNew
+	order.Side = Bid
+
+	tx := types.NewOrderTransaction(order.Nonce.Uint64(), order.Quantity, order.Price, order.ExchangeAddress, order.UserAddress,
+		order.BaseToken, order.QuoteToken, order.Status, order.Side, order.Type, common.Hash{}, 0, order.TimeInForce, order.PostOnly, order.TriggerPrice, order.ExpiresAt, order.SelfTrade)
+	tx, err := types.OrderSignTx(tx, types.OrderTxSigner{}, key)
+	if err != nil {
+		t.Fatalf("Failed to sign order: %v", err)
+	}
+	V, R, S := tx.Signature()
+	order.Hash = tx.OrderHash()
+	order.Signature = &Signature{V: byte(V.Uint64()), R: common.BigToHash(R), S: common.BigToHash(S)}
+	return &order
+}
+
+// Tests that the order fields added by a fork are only accepted from the fork block.
+func TestVerifyBasicOrderInfoForks(t *testing.T) {
+	config := &params.ChainConfig{XDCxOrderFlagsBlock: big.NewInt(10), XDCxTriggerOrdersBlock: big.NewInt(10), XDCxSelfTradeBlock: big.NewInt(10)}
+	tests := []struct {
+		order OrderItem
+		err   error
+	}{
+		{OrderItem{Type: Limit}, nil},
+		{OrderItem{Type: Limit, TimeInForce: ImmediateOrCancel}, ErrInvalidTimeInForce},
+		{OrderItem{Type: Limit, PostOnly: true}, ErrInvalidPostOnly},
+		{OrderItem{Type: StopLimit, TriggerPrice: big.NewInt(90)}, ErrInvalidOrderType},
+		{OrderItem{Type: TakeProfitMarket, TriggerPrice: big.NewInt(110)}, ErrInvalidOrderType},
+		{OrderItem{Type: Limit, SelfTrade: CancelNewest}, ErrInvalidSelfTrade},
+	}
+	for i, tt := range tests {
+		order := signOrder(t, tt.order)
+		if err := order.VerifyBasicOrderInfo(config, big.NewInt(9)); err != tt.err {
+			t.Errorf("test %d: error before fork mismatch: have %v, want %v", i, err, tt.err)
+		}
+		if err := order.VerifyBasicOrderInfo(config, big.NewInt(10)); err != nil {
+			t.Errorf("test %d: error after fork mismatch: have %v, want nil", i, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package tradingstate
+
+import (
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/state/pruner"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+)
+
+// ResolvePruneLeaf is the leaf resolver of the trading state trie for the state
+// pruner. It mirrors the references set up by Commit: an exchange references its
+// asks, bids, orders, liquidation price, trigger and expiry time tries.
+func ResolvePruneLeaf(leaf []byte) ([]pruner.Subtrie, []common.Hash) {
+	var exchange tradingExchangeObject
+	if err := rlp.DecodeBytes(leaf, &exchange); err != nil {
+		return nil, nil
+	}
+	return []pruner.Subtrie{
+		{Root: exchange.AskRoot, Resolve: resolveOrderList},
+		{Root: exchange.BidRoot, Resolve: resolveOrderList},
+		{Root: exchange.OrderRoot},
+		{Root: exchange.LiquidationPriceRoot, Resolve: resolveLiquidationPrice},
+		{Root: exchange.TriggerAboveRoot, Resolve: resolveOrderList},
+		{Root: exchange.TriggerBelowRoot, Resolve: resolveOrderList},
+		{Root: exchange.ExpiryTimeRoot, Resolve: resolveOrderList},
+	}, nil
+}
+
+// resolveOrderList resolves the leaves of the asks and bids tries, a price level
+// references the trie of its orders.
+func resolveOrderList(leaf []byte) ([]pruner.Subtrie, []common.Hash) {
+	var data orderList
+	if err := rlp.DecodeBytes(leaf, &data); err != nil {
+		return nil, nil
+	}
+	return []pruner.Subtrie{{Root: data.Root}}, nil
+}
+
+// resolveLiquidationPrice resolves the leaves of the liquidation price trie, a
+// price references the trie of its lending books, which reference the tries of
+// their lending trades.
+func resolveLiquidationPrice(leaf []byte) ([]pruner.Subtrie, []common.Hash) {
+	var data orderList
+	if err := rlp.DecodeBytes(leaf, &data); err != nil {
+		return nil, nil
+	}
+	return []pruner.Subtrie{{Root: data.Root, Resolve: resolveOrderList}}, nil
+}
@@ -62,9 +62,31 @@ type tradingExchanges struct {
 	liquidationPriceStates      map[common.Hash]*liquidationPriceState
 	liquidationPriceStatesDirty map[common.Hash]struct{}
 
+	triggersAbove triggerTree
+	triggersBelow triggerTree
+
+	expiryTimeTrie        Trie
+	expiryTimeStates      map[common.Hash]*stateOrderList
+	expiryTimeStatesDirty map[common.Hash]struct{}
+
 	onDirty func(hash common.Hash) // Callback method to mark a state object newly dirty
 }
 
+// triggerTree caches the trigger orders of an order book activated in one
+// direction of the last price, keyed by trigger price like the asks and bids.
+type triggerTree struct {
+	trie         Trie // storage trie, which becomes non-nil on first access
+	objects      map[common.Hash]*stateOrderList
+	objectsDirty map[common.Hash]struct{}
+}
+
+func newTriggerTree() triggerTree {
+	return triggerTree{
+		objects:      make(map[common.Hash]*stateOrderList),
+		objectsDirty: make(map[common.Hash]struct{}),
+	}
+}
+
 // empty returns whether the orderId is considered empty.
 func (s *tradingExchanges) empty() bool {
 	if s.data.Nonce != 0 {
@@ -97,6 +119,12 @@ func (s *tradingExchanges) empty() bool {
 	if !common.EmptyHash(s.data.LiquidationPriceRoot) {
 		return false
 	}
+	if !common.EmptyHash(s.data.TriggerAboveRoot) || !common.EmptyHash(s.data.TriggerBelowRoot) {
+		return false
+	}
+	if !common.EmptyHash(s.data.ExpiryTimeRoot) {
+		return false
+	}
 	return true
 }
 
@@ -114,6 +142,10 @@ func newStateExchanges(db *TradingStateDB, hash common.Hash, data tradingExchang
 		stateBidObjectsDirty:        make(map[common.Hash]struct{}),
 		stateOrderObjectsDirty:      make(map[common.Hash]struct{}),
 		liquidationPriceStatesDirty: make(map[common.Hash]struct{}),
+		triggersAbove:               newTriggerTree(),
+		triggersBelow:               newTriggerTree(),
+		expiryTimeStates:            make(map[common.Hash]*stateOrderList),
+		expiryTimeStatesDirty:       make(map[common.Hash]struct{}),
 		onDirty:                     onDirty,
 	}
 }
@@ -357,6 +389,28 @@ func (self *tradingExchanges) deepCopy(db *TradingStateDB, onDirty func(hash com
 	for price := range self.liquidationPriceStatesDirty {
 		stateExchanges.liquidationPriceStatesDirty[price] = struct{}{}
 	}
+	for _, above := range []bool{true, false} {
+		tree, _ := self.triggerTree(above)
+		treeCopy, _ := stateExchanges.triggerTree(above)
+		if tree.trie != nil {
+			treeCopy.trie = db.db.CopyTrie(tree.trie)
+		}
+		for price, triggerObject := range tree.objects {
+			treeCopy.objects[price] = triggerObject.deepCopy(db, stateExchanges.triggerObjectDirtyFunc(above))
+		}
+		for price := range tree.objectsDirty {
+			treeCopy.objectsDirty[price] = struct{}{}
+		}
+	}
+	if self.expiryTimeTrie != nil {
+		stateExchanges.expiryTimeTrie = db.db.CopyTrie(self.expiryTimeTrie)
+	}
+	for time, orderList := range self.expiryTimeStates {
+		stateExchanges.expiryTimeStates[time] = orderList.deepCopy(db, stateExchanges.MarkStateExpiryTimeDirty)
+	}
+	for time := range self.expiryTimeStatesDirty {
+		stateExchanges.expiryTimeStatesDirty[time] = struct{}{}
+	}
 	return stateExchanges
 }
 
@@ -812,3 +866,341 @@ func (self *tradingExchanges) setLendingCount(volume *big.Int) {
 		self.onDirty = nil
 	}
 }
+
+// triggerTree returns the trigger orders activated by a rise or a fall of the
+// last price, along with the root of their trie.
+func (self *tradingExchanges) triggerTree(above bool) (*triggerTree, *common.Hash) {
+	if above {
+		return &self.triggersAbove, &self.data.TriggerAboveRoot
+	}
+	return &self.triggersBelow, &self.data.TriggerBelowRoot
+}
+
+func (self *tradingExchanges) getTriggerTrie(db Database, above bool) Trie {
+	tree, root := self.triggerTree(above)
+	if tree.trie == nil {
+		var err error
+		tree.trie, err = db.OpenStorageTrie(self.orderBookHash, *root)
+		if err != nil {
+			tree.trie, _ = db.OpenStorageTrie(self.orderBookHash, EmptyHash)
+			self.setError(fmt.Errorf("can't create trigger trie: %v", err))
+		}
+	}
+	return tree.trie
+}
+
+// hasTriggers reports whether the trigger trie may hold orders, without
+// opening it.
+func (self *tradingExchanges) hasTriggers(above bool) bool {
+	tree, root := self.triggerTree(above)
+	return tree.trie != nil || (!common.EmptyHash(*root) && *root != EmptyRoot)
+}
+
+// Retrieve the trigger orders of a trigger price. Returns nil if not found.
+func (self *tradingExchanges) getStateTriggerObject(db Database, above bool, price common.Hash) *stateOrderList {
+	tree, _ := self.triggerTree(above)
+	// Prefer 'live' objects.
+	if obj := tree.objects[price]; obj != nil {
+		return obj
+	}
+	// Load the object from the database.
+	enc, err := self.getTriggerTrie(db, above).TryGet(price[:])
+	if len(enc) == 0 {
+		self.setError(err)
+		return nil
+	}
+	var data orderList
+	if err := rlp.DecodeBytes(enc, &data); err != nil {
+		log.Error("Failed to decode state trigger list object", "price", price, "err", err)
+		return nil
+	}
+	// Insert into the live set.
+	obj := newStateOrderList(self.db, "", self.orderBookHash, price, data, self.triggerObjectDirtyFunc(above))
+	tree.objects[price] = obj
+	return obj
+}
+
+func (self *tradingExchanges) triggerObjectDirtyFunc(above bool) func(price common.Hash) {
+	return func(price common.Hash) {
+		self.MarkStateTriggerObjectDirty(above, price)
+	}
+}
+
+// MarkStateTriggerObjectDirty adds the specified trigger list to the dirty map.
+func (self *tradingExchanges) MarkStateTriggerObjectDirty(above bool, price common.Hash) {
+	tree, _ := self.triggerTree(above)
+	tree.objectsDirty[price] = struct{}{}
+	if self.onDirty != nil {
+		self.onDirty(self.Hash())
+		self.onDirty = nil
+	}
+}
+
+// createStateTriggerObject creates the list of the trigger orders of a trigger price.
+func (self *tradingExchanges) createStateTriggerObject(db Database, above bool, price common.Hash) (newobj *stateOrderList) {
+	tree, _ := self.triggerTree(above)
+	newobj = newStateOrderList(self.db, "", self.orderBookHash, price, orderList{Volume: Zero}, self.triggerObjectDirtyFunc(above))
+	tree.objects[price] = newobj
+	tree.objectsDirty[price] = struct{}{}
+	data, err := rlp.EncodeToBytes(newobj)
+	if err != nil {
+		panic(fmt.Errorf("can't encode trigger list object at %x: %v", price[:], err))
+	}
+	self.setError(self.getTriggerTrie(db, above).TryUpdate(price[:], data))
+	if self.onDirty != nil {
+		self.onDirty(self.Hash())
+		self.onDirty = nil
+	}
+	return newobj
+}
+
+func (self *tradingExchanges) removeStateTriggerObject(db Database, above bool, stateOrderList *stateOrderList) {
+	self.setError(self.getTriggerTrie(db, above).TryDelete(stateOrderList.price[:]))
+}
+
+// getBestTriggerPrice returns the trigger price reached first by the last
+// price: the lowest one of the orders activated by a rise, the highest one of
+// the orders activated by a fall.
+func (self *tradingExchanges) getBestTriggerPrice(db Database, above bool) common.Hash {
+	if !self.hasTriggers(above) {
+		return EmptyHash
+	}
+	trie := self.getTriggerTrie(db, above)
+	var (
+		encKey, encValue []byte
+		err              error
+	)
+	if above {
+		encKey, encValue, err = trie.TryGetBestLeftKeyAndValue()
+	} else {
+		encKey, encValue, err = trie.TryGetBestRightKeyAndValue()
+	}
+	if err != nil {
+		log.Error("Failed find best trigger price", "orderbook", self.orderBookHash.Hex(), "above", above)
+		return EmptyHash
+	}
+	if len(encKey) == 0 || len(encValue) == 0 {
+		return EmptyHash
+	}
+	price := common.BytesToHash(encKey)
+	if self.getStateTriggerObject(db, above, price) == nil {
+		return EmptyHash
+	}
+	return price
+}
+
+// updateTriggerTrie writes cached trigger list modifications into the trigger trie.
+func (self *tradingExchanges) updateTriggerTrie(db Database, above bool) Trie {
+	tree, _ := self.triggerTree(above)
+	tr := self.getTriggerTrie(db, above)
+	for price, orderList := range tree.objects {
+		if _, isDirty := tree.objectsDirty[price]; isDirty {
+			delete(tree.objectsDirty, price)
+			if orderList.empty() {
+				self.setError(tr.TryDelete(price[:]))
+				continue
+			}
+			err := orderList.updateRoot(db)
+			if err != nil {
+				log.Warn("updateTriggerTrie updateRoot", "err", err, "price", price, "orderList", *orderList)
+			}
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ := rlp.EncodeToBytes(orderList)
+			self.setError(tr.TryUpdate(price[:], v))
+		}
+	}
+	return tr
+}
+
+// optionalRoot returns the root stored for a trie added to the order books
+// after their first release, like the trigger and expiry time tries. The
+// roots of these tries are left zero while empty, so that the encoding of
+// the order books not using them doesn't change.
+func optionalRoot(hash common.Hash) common.Hash {
+	if hash == EmptyRoot {
+		return EmptyHash
+	}
+	return hash
+}
+
+// updateTriggerRoots updates the roots of the trigger tries accessed.
+func (self *tradingExchanges) updateTriggerRoots(db Database) {
+	for _, above := range []bool{true, false} {
+		tree, root := self.triggerTree(above)
+		if tree.trie == nil {
+			continue
+		}
+		*root = optionalRoot(self.updateTriggerTrie(db, above).Hash())
+	}
+}
+
+// CommitTriggerTries the trigger tries of the object to db.
+// This updates the trie roots.
+func (self *tradingExchanges) CommitTriggerTries(db Database) error {
+	for _, above := range []bool{true, false} {
+		tree, root := self.triggerTree(above)
+		if tree.trie == nil {
+			continue
+		}
+		self.updateTriggerTrie(db, above)
+		if self.dbErr != nil {
+			return self.dbErr
+		}
+		hash, err := tree.trie.Commit(func(leaf []byte, parent common.Hash) error {
+			var orderList orderList
+			if err := rlp.DecodeBytes(leaf, &orderList); err != nil {
+				return nil
+			}
+			if orderList.Root != EmptyRoot {
+				db.TrieDB().Reference(orderList.Root, parent)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		*root = optionalRoot(hash)
+	}
+	return nil
+}
+
+func (self *tradingExchanges) getExpiryTimeTrie(db Database) Trie {
+	if self.expiryTimeTrie == nil {
+		var err error
+		self.expiryTimeTrie, err = db.OpenStorageTrie(self.orderBookHash, self.data.ExpiryTimeRoot)
+		if err != nil {
+			self.expiryTimeTrie, _ = db.OpenStorageTrie(self.orderBookHash, EmptyHash)
+			self.setError(fmt.Errorf("can't create expiry time trie: %v", err))
+		}
+	}
+	return self.expiryTimeTrie
+}
+
+// Retrieve the orders expiring at a time. Returns nil if not found.
+func (self *tradingExchanges) getStateExpiryTime(db Database, time common.Hash) *stateOrderList {
+	// Prefer 'live' objects.
+	if obj := self.expiryTimeStates[time]; obj != nil {
+		return obj
+	}
+	// Load the object from the database.
+	enc, err := self.getExpiryTimeTrie(db).TryGet(time[:])
+	if len(enc) == 0 {
+		self.setError(err)
+		return nil
+	}
+	var data orderList
+	if err := rlp.DecodeBytes(enc, &data); err != nil {
+		log.Error("Failed to decode state expiry time", "time", time, "err", err)
+		return nil
+	}
+	// Insert into the live set.
+	obj := newStateOrderList(self.db, "", self.orderBookHash, time, data, self.MarkStateExpiryTimeDirty)
+	self.expiryTimeStates[time] = obj
+	return obj
+}
+
+// MarkStateExpiryTimeDirty adds the specified expiry time to the dirty map.
+func (self *tradingExchanges) MarkStateExpiryTimeDirty(time common.Hash) {
+	self.expiryTimeStatesDirty[time] = struct{}{}
+	if self.onDirty != nil {
+		self.onDirty(self.Hash())
+		self.onDirty = nil
+	}
+}
+
+// createStateExpiryTime creates the list of the orders expiring at a time.
+func (self *tradingExchanges) createStateExpiryTime(db Database, time common.Hash) (newobj *stateOrderList) {
+	newobj = newStateOrderList(self.db, "", self.orderBookHash, time, orderList{Volume: Zero}, self.MarkStateExpiryTimeDirty)
+	self.expiryTimeStates[time] = newobj
+	self.expiryTimeStatesDirty[time] = struct{}{}
+	data, err := rlp.EncodeToBytes(newobj)
+	if err != nil {
+		panic(fmt.Errorf("can't encode expiry time object at %x: %v", time[:], err))
+	}
+	self.setError(self.getExpiryTimeTrie(db).TryUpdate(time[:], data))
+	if self.onDirty != nil {
+		self.onDirty(self.Hash())
+		self.onDirty = nil
+	}
+	return newobj
+}
+
+// hasExpiryTimes reports whether the order book may index resting orders by
+// expiry time, the expiry time trie is only opened by the books having one.
+func (self *tradingExchanges) hasExpiryTimes() bool {
+	return self.expiryTimeTrie != nil || !(common.EmptyHash(self.data.ExpiryTimeRoot) || self.data.ExpiryTimeRoot == EmptyRoot)
+}
+
+// getLowestExpiryTime returns the earliest expiry time of the resting orders
+// of the order book.
+func (self *tradingExchanges) getLowestExpiryTime(db Database) (common.Hash, *stateOrderList) {
+	if !self.hasExpiryTimes() {
+		return EmptyHash, nil
+	}
+	encKey, encValue, err := self.getExpiryTimeTrie(db).TryGetBestLeftKeyAndValue()
+	if err != nil {
+		log.Error("Failed find lowest expiry time", "orderbook", self.orderBookHash.Hex())
+		return EmptyHash, nil
+	}
+	if len(encKey) == 0 || len(encValue) == 0 {
+		return EmptyHash, nil
+	}
+	time := common.BytesToHash(encKey)
+	return time, self.getStateExpiryTime(db, time)
+}
+
+// updateExpiryTimeTrie writes cached expiry time modifications into the expiry time trie.
+func (self *tradingExchanges) updateExpiryTimeTrie(db Database) Trie {
+	tr := self.getExpiryTimeTrie(db)
+	for time, orderList := range self.expiryTimeStates {
+		if _, isDirty := self.expiryTimeStatesDirty[time]; isDirty {
+			delete(self.expiryTimeStatesDirty, time)
+			if orderList.empty() {
+				self.setError(tr.TryDelete(time[:]))
+				continue
+			}
+			err := orderList.updateRoot(db)
+			if err != nil {
+				log.Warn("updateExpiryTimeTrie updateRoot", "err", err, "time", time, "orderList", *orderList)
+			}
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ := rlp.EncodeToBytes(orderList)
+			self.setError(tr.TryUpdate(time[:], v))
+		}
+	}
+	return tr
+}
+
+// updateExpiryTimeRoot sets the expiry time trie root to the current root hash.
+func (self *tradingExchanges) updateExpiryTimeRoot(db Database) {
+	if self.expiryTimeTrie == nil {
+		return
+	}
+	self.data.ExpiryTimeRoot = optionalRoot(self.updateExpiryTimeTrie(db).Hash())
+}
+
+// CommitExpiryTimeTrie the expiry time trie of the object to db.
+// This updates the trie root.
+func (self *tradingExchanges) CommitExpiryTimeTrie(db Database) error {
+	if self.expiryTimeTrie == nil {
+		return nil
+	}
+	self.updateExpiryTimeTrie(db)
+	if self.dbErr != nil {
+		return self.dbErr
+	}
+	root, err := self.expiryTimeTrie.Commit(func(leaf []byte, parent common.Hash) error {
+		var orderList orderList
+		if err := rlp.DecodeBytes(leaf, &orderList); err != nil {
+			return nil
+		}
+		if orderList.Root != EmptyRoot {
+			db.TrieDB().Reference(orderList.Root, parent)
+		}
+		return nil
+	})
+	if err == nil {
+		self.data.ExpiryTimeRoot = optionalRoot(root)
+	}
+	return err
+}
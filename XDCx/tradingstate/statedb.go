@@ -18,16 +18,31 @@
 package tradingstate
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"sort"
 	"sync"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
 )
 
+// proofList collects the trie nodes of a Merkle proof in order.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
 type revision struct {
 	id           int
 	journalIndex int
@@ -59,6 +74,13 @@ type TradingStateDB struct {
 	validRevisions []revision
 	nextRevisionId int
 
+	// Tracer notified of the matches and settlements, nil if not tracing.
+	tracer Tracer
+
+	// Logs of the matches, keyed by the hash of the transaction carrying them.
+	thash common.Hash
+	logs  map[common.Hash][]*types.Log
+
 	lock sync.Mutex
 }
 
@@ -74,6 +96,7 @@ func New(root common.Hash, db Database) (*TradingStateDB, error) {
 		trie:                     tr,
 		stateExhangeObjects:      make(map[common.Hash]*tradingExchanges),
 		stateExhangeObjectsDirty: make(map[common.Hash]struct{}),
+		logs:                     make(map[common.Hash][]*types.Log),
 	}, nil
 }
 
@@ -184,19 +207,34 @@ func (self *TradingStateDB) SetMediumPriceBeforeEpoch(addr common.Hash, price *b
 }
 
 func (self *TradingStateDB) InsertOrderItem(orderBook common.Hash, orderId common.Hash, order OrderItem) {
-	priceHash := common.BigToHash(order.Price)
 	stateExchange := self.getStateExchangeObject(orderBook)
 	if stateExchange == nil {
 		stateExchange = self.createExchangeObject(orderBook)
 	}
-	var stateOrderList *stateOrderList
-	switch order.Side {
-	case Ask:
+	var (
+		stateOrderList *stateOrderList
+		priceHash      common.Hash
+	)
+	if !IsTriggerOrderType(order.Type) {
+		priceHash = common.BigToHash(order.Price)
+	}
+	switch {
+	case IsTriggerOrderType(order.Type):
+		// trigger orders wait for the last price in their own tree
+		if order.Side != Ask && order.Side != Bid {
+			return
+		}
+		above, triggerHash := TriggersAbove(&order), common.BigToHash(order.TriggerPrice)
+		stateOrderList = stateExchange.getStateTriggerObject(self.db, above, triggerHash)
+		if stateOrderList == nil {
+			stateOrderList = stateExchange.createStateTriggerObject(self.db, above, triggerHash)
+		}
+	case order.Side == Ask:
 		stateOrderList = stateExchange.getStateOrderListAskObject(self.db, priceHash)
 		if stateOrderList == nil {
 			stateOrderList = stateExchange.createStateOrderListAskObject(self.db, priceHash)
 		}
-	case Bid:
+	case order.Side == Bid:
 		stateOrderList = stateExchange.getStateBidOrderListObject(self.db, priceHash)
 		if stateOrderList == nil {
 			stateOrderList = stateExchange.createStateBidOrderListObject(self.db, priceHash)
@@ -288,15 +326,25 @@ func (self *TradingStateDB) CancelOrder(orderBook common.Hash, order *OrderItem)
 	if stateOrderItem == nil || stateOrderItem.empty() {
 		return fmt.Errorf("Order item empty  order book : %s , order id  : %s ", orderBook, orderIdHash.Hex())
 	}
-	priceHash := common.BigToHash(stateOrderItem.data.Price)
-	var stateOrderList *stateOrderList
-	switch stateOrderItem.data.Side {
-	case Ask:
+	isTrigger, above := IsTriggerOrderType(stateOrderItem.data.Type), TriggersAbove(&stateOrderItem.data)
+	var (
+		stateOrderList *stateOrderList
+		priceHash      common.Hash
+	)
+	if isTrigger {
+		priceHash = common.BigToHash(stateOrderItem.data.TriggerPrice)
+	} else {
+		priceHash = common.BigToHash(stateOrderItem.data.Price)
+	}
+	switch {
+	case stateOrderItem.data.Side != Ask && stateOrderItem.data.Side != Bid:
+		return fmt.Errorf("Order side not found : %s ", order.Side)
+	case isTrigger:
+		stateOrderList = stateObject.getStateTriggerObject(self.db, above, priceHash)
+	case stateOrderItem.data.Side == Ask:
 		stateOrderList = stateObject.getStateOrderListAskObject(self.db, priceHash)
-	case Bid:
-		stateOrderList = stateObject.getStateBidOrderListObject(self.db, priceHash)
 	default:
-		return fmt.Errorf("Order side not found : %s ", order.Side)
+		stateOrderList = stateObject.getStateBidOrderListObject(self.db, priceHash)
 	}
 	if stateOrderList == nil || stateOrderList.empty() {
 		return fmt.Errorf("Order list empty  order book : %s , order id  : %s , price  : %s ", orderBook, orderIdHash.Hex(), priceHash.Hex())
@@ -321,10 +369,12 @@ func (self *TradingStateDB) CancelOrder(orderBook common.Hash, order *OrderItem)
 	stateOrderList.subVolume(currentAmount)
 	stateOrderList.removeOrderItem(self.db, orderIdHash)
 	if stateOrderList.empty() {
-		switch stateOrderItem.data.Side {
-		case Ask:
+		switch {
+		case isTrigger:
+			stateObject.removeStateTriggerObject(self.db, above, stateOrderList)
+		case stateOrderItem.data.Side == Ask:
 			stateObject.removeStateOrderListAskObject(self.db, stateOrderList)
-		case Bid:
+		case stateOrderItem.data.Side == Bid:
 			stateObject.removeStateOrderListBidObject(self.db, stateOrderList)
 		default:
 		}
@@ -332,6 +382,40 @@ func (self *TradingStateDB) CancelOrder(orderBook common.Hash, order *OrderItem)
 	return nil
 }
 
+// GetTriggeredOrder returns the oldest trigger order of the order book
+// activated by the last price, the ones activated by a rise coming first.
+func (self *TradingStateDB) GetTriggeredOrder(orderBook common.Hash, lastPrice *big.Int) (OrderItem, bool) {
+	stateObject := self.getStateExchangeObject(orderBook)
+	if stateObject == nil || lastPrice == nil || lastPrice.Sign() <= 0 {
+		return EmptyOrder, false
+	}
+	for _, above := range []bool{true, false} {
+		priceHash := stateObject.getBestTriggerPrice(self.db, above)
+		if common.EmptyHash(priceHash) {
+			continue
+		}
+		triggerPrice := new(big.Int).SetBytes(priceHash.Bytes())
+		if (above && triggerPrice.Cmp(lastPrice) > 0) || (!above && triggerPrice.Cmp(lastPrice) < 0) {
+			continue
+		}
+		stateOrderList := stateObject.getStateTriggerObject(self.db, above, priceHash)
+		if stateOrderList == nil {
+			continue
+		}
+		key, _, err := stateOrderList.getTrie(self.db).TryGetBestLeftKeyAndValue()
+		if err != nil || len(key) == 0 {
+			log.Error("Failed find triggered order", "orderbook", orderBook.Hex(), "price", triggerPrice, "err", err)
+			continue
+		}
+		stateOrderItem := stateObject.getStateOrderObject(self.db, common.BytesToHash(key))
+		if stateOrderItem == nil || stateOrderItem.empty() {
+			continue
+		}
+		return stateOrderItem.data, true
+	}
+	return EmptyOrder, false
+}
+
 func (self *TradingStateDB) GetVolume(orderBook common.Hash, price *big.Int, orderType string) *big.Int {
 	stateObject := self.GetOrNewStateExchangeObject(orderBook)
 	var volume *big.Int = nil
@@ -474,6 +558,93 @@ func (self *TradingStateDB) createExchangeObject(hash common.Hash) (newobj *trad
 	return newobj
 }
 
+// SetTracer sets the tracer notified of the matches and settlements applied on
+// top of the state, or disables tracing if nil. The tracer isn't copied along
+// with the state.
+func (self *TradingStateDB) SetTracer(tracer Tracer) {
+	self.tracer = tracer
+}
+
+// Tracer returns the tracer of the state, nil if not tracing.
+func (self *TradingStateDB) Tracer() Tracer {
+	return self.tracer
+}
+
+// Prepare sets the hash of the transaction the logs added from now on belong to.
+func (self *TradingStateDB) Prepare(thash common.Hash) {
+	self.thash = thash
+}
+
+// AddLog records a log of a match against the current transaction. The log is
+// dropped again if the state is reverted to an earlier snapshot.
+func (self *TradingStateDB) AddLog(l *types.Log) {
+	self.journal = append(self.journal, addLogChange{txhash: self.thash})
+	l.TxHash = self.thash
+	l.Index = uint(len(self.logs[self.thash]))
+	self.logs[self.thash] = append(self.logs[self.thash], l)
+}
+
+// GetLogs returns the logs recorded against the given transaction.
+func (self *TradingStateDB) GetLogs(hash common.Hash) []*types.Log {
+	return self.logs[hash]
+}
+
+// MoveLogs re-keys the logs recorded against a transaction to another one, as
+// the matching transactions are only signed once the matching is done.
+func (self *TradingStateDB) MoveLogs(from, to common.Hash) {
+	logs, ok := self.logs[from]
+	if !ok {
+		return
+	}
+	delete(self.logs, from)
+	for _, l := range logs {
+		l.TxHash = to
+	}
+	self.logs[to] = append(self.logs[to], logs...)
+}
+
+// GetProof returns the Merkle proof of an order book in the trading trie.
+func (self *TradingStateDB) GetProof(orderBook common.Hash) ([][]byte, error) {
+	var proof proofList
+	err := self.trie.Prove(orderBook[:], 0, &proof)
+	return proof, err
+}
+
+// GetOrderProof returns the Merkle proof of an order in the orders trie of an
+// order book.
+func (self *TradingStateDB) GetOrderProof(orderBook common.Hash, orderId common.Hash) ([][]byte, error) {
+	var proof proofList
+	stateObject := self.getStateExchangeObject(orderBook)
+	if stateObject == nil {
+		return proof, errors.New("order book not found")
+	}
+	tr := stateObject.deepCopy(self, nil).updateOrdersTrie(self.db)
+	err := tr.Prove(orderId[:], 0, &proof)
+	return proof, err
+}
+
+// GetPriceLevelProof returns the Merkle proof of a price level in the asks or
+// bids trie of an order book.
+func (self *TradingStateDB) GetPriceLevelProof(orderBook common.Hash, price *big.Int, side string) ([][]byte, error) {
+	var proof proofList
+	stateObject := self.getStateExchangeObject(orderBook)
+	if stateObject == nil {
+		return proof, errors.New("order book not found")
+	}
+	var tr Trie
+	switch side {
+	case Ask:
+		tr = stateObject.deepCopy(self, nil).updateAsksTrie(self.db)
+	case Bid:
+		tr = stateObject.deepCopy(self, nil).updateBidsTrie(self.db)
+	default:
+		return proof, fmt.Errorf("invalid side %s", side)
+	}
+	key := common.BigToHash(price)
+	err := tr.Prove(key[:], 0, &proof)
+	return proof, err
+}
+
 // Copy creates a deep, independent copy of the state.
 // Snapshots of the copied state cannot be applied to the copy.
 func (self *TradingStateDB) Copy() *TradingStateDB {
@@ -486,6 +657,8 @@ func (self *TradingStateDB) Copy() *TradingStateDB {
 		trie:                     self.db.CopyTrie(self.trie),
 		stateExhangeObjects:      make(map[common.Hash]*tradingExchanges, len(self.stateExhangeObjectsDirty)),
 		stateExhangeObjectsDirty: make(map[common.Hash]struct{}, len(self.stateExhangeObjectsDirty)),
+		thash:                    self.thash,
+		logs:                     make(map[common.Hash][]*types.Log, len(self.logs)),
 	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range self.stateExhangeObjectsDirty {
@@ -494,6 +667,14 @@ func (self *TradingStateDB) Copy() *TradingStateDB {
 	for addr, exchangeObject := range self.stateExhangeObjects {
 		state.stateExhangeObjects[addr] = exchangeObject.deepCopy(state, state.MarkStateExchangeObjectDirty)
 	}
+	for hash, logs := range self.logs {
+		cpy := make([]*types.Log, len(logs))
+		for i, l := range logs {
+			cpy[i] = new(types.Log)
+			*cpy[i] = *l
+		}
+		state.logs[hash] = cpy
+	}
 
 	return state
 }
@@ -546,6 +727,8 @@ func (s *TradingStateDB) Finalise() {
 			stateObject.updateBidsRoot(s.db)
 			stateObject.updateOrdersRoot(s.db)
 			stateObject.updateLiquidationPriceRoot(s.db)
+			stateObject.updateTriggerRoots(s.db)
+			stateObject.updateExpiryTimeRoot(s.db)
 			// Update the object in the main orderId trie.
 			s.updateStateExchangeObject(stateObject)
 			//delete(s.stateExhangeObjectsDirty, addr)
@@ -581,6 +764,12 @@ func (s *TradingStateDB) Commit() (root common.Hash, err error) {
 			if err := stateObject.CommitLiquidationPriceTrie(s.db); err != nil {
 				return EmptyHash, err
 			}
+			if err := stateObject.CommitTriggerTries(s.db); err != nil {
+				return EmptyHash, err
+			}
+			if err := stateObject.CommitExpiryTimeTrie(s.db); err != nil {
+				return EmptyHash, err
+			}
 			// Update the object in the main orderId trie.
 			s.updateStateExchangeObject(stateObject)
 			delete(s.stateExhangeObjectsDirty, addr)
@@ -604,6 +793,11 @@ func (s *TradingStateDB) Commit() (root common.Hash, err error) {
 		if exchange.LiquidationPriceRoot != EmptyRoot {
 			s.db.TrieDB().Reference(exchange.LiquidationPriceRoot, parent)
 		}
+		for _, optionalRoot := range []common.Hash{exchange.TriggerAboveRoot, exchange.TriggerBelowRoot, exchange.ExpiryTimeRoot} {
+			if !common.EmptyHash(optionalRoot) && optionalRoot != EmptyRoot {
+				s.db.TrieDB().Reference(optionalRoot, parent)
+			}
+		}
 		return nil
 	})
 	log.Debug("Trading State Trie cache stats after commit", "root", root.Hex())
@@ -730,3 +924,87 @@ func (self *TradingStateDB) RemoveLiquidationPrice(orderBook common.Hash, price
 	})
 	return nil
 }
+
+// InsertExpiryTime indexes a resting order of the order book by the time it expires at.
+func (self *TradingStateDB) InsertExpiryTime(orderBook common.Hash, expiresAt uint64, orderId common.Hash) {
+	timeHash := common.Uint64ToHash(expiresAt)
+	orderBookState := self.getStateExchangeObject(orderBook)
+	if orderBookState == nil {
+		orderBookState = self.createExchangeObject(orderBook)
+	}
+	expiryTimeState := orderBookState.getStateExpiryTime(self.db, timeHash)
+	if expiryTimeState == nil {
+		expiryTimeState = orderBookState.createStateExpiryTime(self.db, timeHash)
+	} else if !common.EmptyHash(expiryTimeState.GetOrderAmount(self.db, orderId)) {
+		// a triggered order resting its remainder is already indexed
+		return
+	} else if expiryTimeState.empty() {
+		// the time was removed from the trie along with its last order
+		data, _ := rlp.EncodeToBytes(expiryTimeState)
+		self.setError(orderBookState.getExpiryTimeTrie(self.db).TryUpdate(timeHash[:], data))
+	}
+	expiryTimeState.insertOrderItem(self.db, orderId, orderId)
+	expiryTimeState.AddVolume(One)
+	self.journal = append(self.journal, insertExpiryTime{
+		orderBook: orderBook,
+		expiresAt: expiresAt,
+		orderId:   orderId,
+	})
+}
+
+// RemoveExpiryTime removes an order from the expiry time index of the order book.
+func (self *TradingStateDB) RemoveExpiryTime(orderBook common.Hash, expiresAt uint64, orderId common.Hash) error {
+	timeHash := common.Uint64ToHash(expiresAt)
+	orderBookState := self.getStateExchangeObject(orderBook)
+	if orderBookState == nil {
+		return fmt.Errorf("order book not found : %s ", orderBook.Hex())
+	}
+	expiryTimeState := orderBookState.getStateExpiryTime(self.db, timeHash)
+	if expiryTimeState == nil || expiryTimeState.empty() {
+		return fmt.Errorf("expiry time not found : %s , %d ", orderBook.Hex(), expiresAt)
+	}
+	if common.EmptyHash(expiryTimeState.GetOrderAmount(self.db, orderId)) {
+		return fmt.Errorf("order id not found : %s , %d , %s ", orderBook.Hex(), expiresAt, orderId.Hex())
+	}
+	expiryTimeState.removeOrderItem(self.db, orderId)
+	expiryTimeState.subVolume(One)
+	if expiryTimeState.empty() {
+		err := orderBookState.getExpiryTimeTrie(self.db).TryDelete(timeHash[:])
+		if err != nil {
+			log.Warn("RemoveExpiryTime getExpiryTimeTrie.TryDelete", "err", err, "timeHash", timeHash[:])
+		}
+	}
+	self.journal = append(self.journal, removeExpiryTime{
+		orderBook: orderBook,
+		expiresAt: expiresAt,
+		orderId:   orderId,
+	})
+	return nil
+}
+
+// HasExpiryTimes reports whether the order book may hold orders with an expiry
+// time, it is false for the books without any expiry time index.
+func (self *TradingStateDB) HasExpiryTimes(orderBook common.Hash) bool {
+	orderBookState := self.getStateExchangeObject(orderBook)
+	return orderBookState != nil && orderBookState.hasExpiryTimes()
+}
+
+// GetLowestExpiryTime returns the earliest expiry time indexed in the order
+// book, along with the ids of the orders expiring at it if it is not after time.
+func (self *TradingStateDB) GetLowestExpiryTime(orderBook common.Hash, time uint64) (uint64, []common.Hash) {
+	orderIds := []common.Hash{}
+	orderBookState := self.getStateExchangeObject(orderBook)
+	if orderBookState == nil {
+		return 0, orderIds
+	}
+	lowestTimeHash, expiryTimeState := orderBookState.getLowestExpiryTime(self.db)
+	lowestTime := new(big.Int).SetBytes(lowestTimeHash[:]).Uint64()
+	if expiryTimeState == nil || lowestTime == 0 || lowestTime > time {
+		return lowestTime, orderIds
+	}
+	it := trie.NewIterator(expiryTimeState.getTrie(self.db).NodeIterator(nil))
+	for it.Next() {
+		orderIds = append(orderIds, common.BytesToHash(it.Key))
+	}
+	return lowestTime, orderIds
+}
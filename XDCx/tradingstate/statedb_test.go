@@ -17,10 +17,16 @@
 package tradingstate
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/common/math"
 	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb/memorydb"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
 	"math/big"
 	"testing"
 )
@@ -300,3 +306,262 @@ func TestDumpState(t *testing.T) {
 	fmt.Println("bidTrie", bidTrie)
 	db.Close()
 }
+
+func verifyProof(t *testing.T, root common.Hash, key []byte, proof [][]byte) []byte {
+	proofDb := memorydb.New()
+	for _, node := range proof {
+		proofDb.Put(crypto.Keccak256(node), node)
+	}
+	value, err := trie.VerifyProof(root, key, proofDb)
+	if err != nil {
+		t.Fatalf("invalid proof for key %x: %v", key, err)
+	}
+	return value
+}
+
+func TestGetProof(t *testing.T) {
+	orderBook := common.StringToHash("BTC/XDC")
+	db := rawdb.NewMemoryDatabase()
+	stateCache := NewDatabase(db)
+	statedb, _ := New(common.Hash{}, stateCache)
+	for i := 0; i < 5; i++ {
+		orderIdHash := common.BigToHash(big.NewInt(int64(i) + 1))
+		statedb.InsertOrderItem(orderBook, orderIdHash, OrderItem{OrderID: uint64(i) + 1, Quantity: big.NewInt(int64(2*i + 1)), Price: big.NewInt(int64(2*i + 1)), Side: Ask, Signature: &Signature{V: 1, R: common.HexToHash("111111"), S: common.HexToHash("222222222222")}})
+	}
+	statedb.SetNonce(common.StringToHash("ETH/XDC"), 1)
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("Error when commit state: %v", err)
+	}
+	statedb, err = New(root, stateCache)
+	if err != nil {
+		t.Fatalf("Error when get trie in database: %s , err: %v", root.Hex(), err)
+	}
+
+	proof, err := statedb.GetProof(orderBook)
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	var exchange tradingExchangeObject
+	if err := rlp.DecodeBytes(verifyProof(t, root, orderBook[:], proof), &exchange); err != nil {
+		t.Fatalf("Failed to decode order book: %v", err)
+	}
+
+	orderId := common.BigToHash(big.NewInt(3))
+	proof, err = statedb.GetOrderProof(orderBook, orderId)
+	if err != nil {
+		t.Fatalf("GetOrderProof failed: %v", err)
+	}
+	var order OrderItem
+	if err := rlp.DecodeBytes(verifyProof(t, exchange.OrderRoot, orderId[:], proof), &order); err != nil {
+		t.Fatalf("Failed to decode order: %v", err)
+	}
+	if order.OrderID != 3 || order.Quantity.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Wrong order proven: id %d quantity %v", order.OrderID, order.Quantity)
+	}
+
+	price := big.NewInt(5)
+	proof, err = statedb.GetPriceLevelProof(orderBook, price, Ask)
+	if err != nil {
+		t.Fatalf("GetPriceLevelProof failed: %v", err)
+	}
+	var level orderList
+	if err := rlp.DecodeBytes(verifyProof(t, exchange.AskRoot, common.BigToHash(price).Bytes(), proof), &level); err != nil {
+		t.Fatalf("Failed to decode price level: %v", err)
+	}
+	if level.Volume.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Wrong price level volume: have %v, want 5", level.Volume)
+	}
+
+	// Proofs of absence verify to an empty value
+	proof, err = statedb.GetOrderProof(orderBook, common.BigToHash(big.NewInt(10)))
+	if err != nil {
+		t.Fatalf("GetOrderProof failed: %v", err)
+	}
+	if value := verifyProof(t, exchange.OrderRoot, common.BigToHash(big.NewInt(10)).Bytes(), proof); value != nil {
+		t.Errorf("Missing order proven with value %x", value)
+	}
+	if _, err := statedb.GetOrderProof(common.StringToHash("XRP/XDC"), orderId); err == nil {
+		t.Errorf("Expected error for a missing order book")
+	}
+	db.Close()
+}
+
+func TestTriggerOrders(t *testing.T) {
+	orderBook := common.StringToHash("BTC/XDC")
+	signature := &Signature{V: 1, R: common.HexToHash("111111"), S: common.HexToHash("222222222222")}
+	stateCache := NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := New(common.Hash{}, stateCache)
+	statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(1)), OrderItem{OrderID: 1, Quantity: big.NewInt(1), Price: big.NewInt(10), Side: Ask, Type: Limit, Signature: signature})
+	if _, ok := statedb.GetTriggeredOrder(orderBook, big.NewInt(10)); ok {
+		t.Fatalf("Triggered order found in an order book without trigger orders")
+	}
+	// The order books without trigger orders keep their encoding
+	statedb.IntermediateRoot()
+	data := statedb.getStateExchangeObject(orderBook).data
+	have, _ := rlp.EncodeToBytes(&data)
+	want, _ := rlp.EncodeToBytes([]interface{}{data.Nonce, data.LastPrice, data.MediumPriceBeforeEpoch, data.MediumPrice, data.TotalQuantity, data.LendingCount, data.AskRoot, data.BidRoot, data.OrderRoot, data.LiquidationPriceRoot})
+	if !bytes.Equal(have, want) {
+		t.Errorf("Order book encoding mismatch: have %x, want %x", have, want)
+	}
+
+	triggers := []OrderItem{
+		{OrderID: 2, Quantity: big.NewInt(1), TriggerPrice: big.NewInt(8), Side: Ask, Type: StopMarket, Hash: common.HexToHash("0x02"), Signature: signature},
+		{OrderID: 3, Quantity: big.NewInt(1), Price: big.NewInt(12), TriggerPrice: big.NewInt(12), Side: Ask, Type: TakeProfitLimit, Hash: common.HexToHash("0x03"), Signature: signature},
+		{OrderID: 4, Quantity: big.NewInt(1), Price: big.NewInt(16), TriggerPrice: big.NewInt(15), Side: Bid, Type: StopLimit, Hash: common.HexToHash("0x04"), Signature: signature},
+	}
+	for _, order := range triggers {
+		statedb.InsertOrderItem(orderBook, common.BigToHash(new(big.Int).SetUint64(order.OrderID)), order)
+	}
+	// Trigger orders don't rest on the book
+	if price, _ := statedb.GetBestAskPrice(orderBook); price.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("Best ask mismatch: have %v, want 10", price)
+	}
+	if price, _ := statedb.GetBestBidPrice(orderBook); price.Sign() != 0 {
+		t.Errorf("Best bid mismatch: have %v, want 0", price)
+	}
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("Error when commit state: %v", err)
+	}
+	statedb, err = New(root, stateCache)
+	if err != nil {
+		t.Fatalf("Error when get trie in database: %s , err: %v", root.Hex(), err)
+	}
+	tests := []struct {
+		lastPrice int64
+		orderId   uint64 // zero if no order is triggered
+	}{
+		{10, 0},
+		{9, 0},
+		{8, 2},
+		{1, 2},
+		{12, 3},
+		{20, 3},
+	}
+	for i, tt := range tests {
+		order, ok := statedb.GetTriggeredOrder(orderBook, big.NewInt(tt.lastPrice))
+		if ok != (tt.orderId != 0) || order.OrderID != tt.orderId {
+			t.Errorf("test %d: triggered order mismatch: have %d (%v), want %d", i, order.OrderID, ok, tt.orderId)
+		}
+	}
+	// Cancelling a trigger order removes it from its trigger tree
+	if err := statedb.CancelOrder(orderBook, &triggers[1]); err != nil {
+		t.Fatalf("Failed to cancel trigger order: %v", err)
+	}
+	if order, _ := statedb.GetTriggeredOrder(orderBook, big.NewInt(20)); order.OrderID != 4 {
+		t.Errorf("Triggered order mismatch after cancel: have %d, want 4", order.OrderID)
+	}
+	for _, order := range []OrderItem{triggers[0], triggers[2]} {
+		if err := statedb.CancelOrder(orderBook, &order); err != nil {
+			t.Fatalf("Failed to cancel trigger order: %v", err)
+		}
+	}
+	if _, err := statedb.Commit(); err != nil {
+		t.Fatalf("Error when commit state: %v", err)
+	}
+	data = statedb.getStateExchangeObject(orderBook).data
+	if !common.EmptyHash(data.TriggerAboveRoot) || !common.EmptyHash(data.TriggerBelowRoot) {
+		t.Errorf("Trigger roots of empty trigger trees not left zero: %x %x", data.TriggerAboveRoot, data.TriggerBelowRoot)
+	}
+}
+
+func TestExpiryTimeIndex(t *testing.T) {
+	orderBook := common.StringToHash("BTC/XDC")
+	stateCache := NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := New(common.Hash{}, stateCache)
+	statedb.InsertOrderItem(orderBook, common.BigToHash(big.NewInt(1)), OrderItem{OrderID: 1, Quantity: big.NewInt(1), Price: big.NewInt(10), Side: Ask, Type: Limit})
+	// The order books without expiring orders keep an empty expiry root
+	statedb.IntermediateRoot()
+	if root := statedb.getStateExchangeObject(orderBook).data.ExpiryTimeRoot; !common.EmptyHash(root) {
+		t.Fatalf("Expiry root of an order book without expiring orders not left zero: %x", root)
+	}
+	if statedb.HasExpiryTimes(orderBook) {
+		t.Fatalf("Order book without expiring orders reported having expiry times")
+	}
+	ids := []common.Hash{common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(2)), common.BigToHash(big.NewInt(3))}
+	statedb.InsertExpiryTime(orderBook, 200, ids[0])
+	statedb.InsertExpiryTime(orderBook, 100, ids[1])
+	statedb.InsertExpiryTime(orderBook, 100, ids[2])
+	// Indexing an order twice is a no-op
+	statedb.InsertExpiryTime(orderBook, 100, ids[2])
+
+	root, err := statedb.Commit()
+	if err != nil {
+		t.Fatalf("Error when commit state: %v", err)
+	}
+	statedb, err = New(root, stateCache)
+	if err != nil {
+		t.Fatalf("Error when get trie in database: %s , err: %v", root.Hex(), err)
+	}
+	if !statedb.HasExpiryTimes(orderBook) {
+		t.Errorf("Order book with expiring orders reported without expiry times")
+	}
+	if lowest, orderIds := statedb.GetLowestExpiryTime(orderBook, 99); lowest != 100 || len(orderIds) != 0 {
+		t.Errorf("Expiry before the lowest time mismatch: have %d (%d orders), want 100 (0 orders)", lowest, len(orderIds))
+	}
+	lowest, orderIds := statedb.GetLowestExpiryTime(orderBook, 100)
+	if lowest != 100 || len(orderIds) != 2 {
+		t.Fatalf("Lowest expiry mismatch: have %d (%d orders), want 100 (2 orders)", lowest, len(orderIds))
+	}
+	snap := statedb.Snapshot()
+	for _, orderId := range orderIds {
+		if err := statedb.RemoveExpiryTime(orderBook, lowest, orderId); err != nil {
+			t.Fatalf("Failed to remove expiry time: %v", err)
+		}
+	}
+	if err := statedb.RemoveExpiryTime(orderBook, lowest, ids[1]); err == nil {
+		t.Errorf("Removed an order missing from the expiry index")
+	}
+	if lowest, orderIds := statedb.GetLowestExpiryTime(orderBook, 300); lowest != 200 || len(orderIds) != 1 || orderIds[0] != ids[0] {
+		t.Errorf("Lowest expiry after removal mismatch: have %d (%v), want 200 ([%x])", lowest, orderIds, ids[0])
+	}
+	statedb.RevertToSnapshot(snap)
+	if lowest, orderIds := statedb.GetLowestExpiryTime(orderBook, 100); lowest != 100 || len(orderIds) != 2 {
+		t.Errorf("Lowest expiry after revert mismatch: have %d (%d orders), want 100 (2 orders)", lowest, len(orderIds))
+	}
+	for _, expiry := range []struct {
+		time    uint64
+		orderId common.Hash
+	}{{100, ids[1]}, {100, ids[2]}, {200, ids[0]}} {
+		if err := statedb.RemoveExpiryTime(orderBook, expiry.time, expiry.orderId); err != nil {
+			t.Fatalf("Failed to remove expiry time: %v", err)
+		}
+	}
+	root, err = statedb.Commit()
+	if err != nil {
+		t.Fatalf("Error when commit state: %v", err)
+	}
+	if root := statedb.getStateExchangeObject(orderBook).data.ExpiryTimeRoot; !common.EmptyHash(root) {
+		t.Errorf("Expiry root of an empty expiry tree not left zero: %x", root)
+	}
+	if statedb, _ = New(root, stateCache); statedb.HasExpiryTimes(orderBook) {
+		t.Errorf("Order book with an empty expiry tree reported having expiry times")
+	}
+}
+
+func TestMatchingLogs(t *testing.T) {
+	statedb, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	pending, txHash := common.HexToHash(common.XDCXAddr), common.HexToHash("0xaa")
+
+	statedb.Prepare(pending)
+	statedb.AddLog(&types.Log{Data: []byte{1}})
+	snap := statedb.Snapshot()
+	statedb.AddLog(&types.Log{Data: []byte{2}})
+	statedb.AddLog(&types.Log{Data: []byte{3}})
+	statedb.RevertToSnapshot(snap)
+	if logs := statedb.GetLogs(pending); len(logs) != 1 || logs[0].Data[0] != 1 {
+		t.Fatalf("Logs after revert mismatch: have %d logs, want 1", len(logs))
+	}
+	cpy := statedb.Copy()
+	statedb.MoveLogs(pending, txHash)
+	if logs := statedb.GetLogs(pending); len(logs) != 0 {
+		t.Errorf("Logs left under the moved hash: %d", len(logs))
+	}
+	if logs := statedb.GetLogs(txHash); len(logs) != 1 || logs[0].TxHash != txHash {
+		t.Errorf("Moved logs mismatch: have %d logs", len(logs))
+	}
+	if logs := cpy.GetLogs(pending); len(logs) != 1 || logs[0].TxHash != pending {
+		t.Errorf("Copied logs changed by the original state")
+	}
+}
@@ -0,0 +1,142 @@
+package tradingstate
+
+import (
+	"math/big"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+)
+
+// Kinds of the operations reported to a Tracer.
+const (
+	TraceKindOrder       = "order"
+	TraceKindLending     = "lending"
+	TraceKindLiquidation = "liquidation"
+	TraceKindExpiry      = "expiry"
+)
+
+// Reasons of the balance changes reported to a Tracer.
+const (
+	ReasonSettlement  = "settlement"  // tokens exchanged between the taker and the maker
+	ReasonTradingFee  = "tradingFee"  // fee paid by a user to the relayer owner
+	ReasonRelayerFee  = "relayerFee"  // XDC deducted from the relayer deposit
+	ReasonMatchingFee = "matchingFee" // XDC paid to the masternode owner
+	ReasonCancelFee   = "cancelFee"   // fee paid by a user to the relayer owner for a cancellation
+	ReasonCollateral  = "collateral"  // collateral moved to or from the lending lock address
+	ReasonRepay       = "repay"       // loan and collateral moved by a repayment
+	ReasonLiquidation = "liquidation" // collateral moved by a liquidation
+)
+
+// Tracer is notified of the matches, balance changes and rejected orders of the
+// XDCx and lending engines, which settle outside of the EVM.
+//
+// Every operation is wrapped between CaptureStart and CaptureEnd. If CaptureEnd
+// is called with an error, the changes of the operation were reverted.
+type Tracer interface {
+	CaptureStart(kind string, orderBook common.Hash, orderHash common.Hash)
+	CaptureMatch(takerHash, makerHash common.Hash, price, quantity *big.Int)
+	CaptureBalanceChange(addr, token common.Address, amount *big.Int, reason string)
+	CaptureReject(orderHash common.Hash, reason string)
+	CaptureEnd(err error)
+}
+
+// TraceMatch is a match between a taker and a maker order. For lending orders
+// the price is the interest rate.
+type TraceMatch struct {
+	Taker    common.Hash  `json:"taker"`
+	Maker    common.Hash  `json:"maker"`
+	Price    *hexutil.Big `json:"price"`
+	Quantity *hexutil.Big `json:"quantity"`
+}
+
+// TraceBalanceChange is a change of the token balance of an account, negative
+// amounts being debits. XDC is reported as the native token address.
+type TraceBalanceChange struct {
+	Address common.Address `json:"address"`
+	Token   common.Address `json:"token"`
+	Amount  *hexutil.Big   `json:"amount"`
+	Reason  string         `json:"reason"`
+}
+
+// TraceReject is an order rejected by the engine.
+type TraceReject struct {
+	Order  common.Hash `json:"order"`
+	Reason string      `json:"reason"`
+}
+
+// TraceFrame is the trace of a single operation.
+type TraceFrame struct {
+	Kind           string               `json:"type"`
+	OrderBook      common.Hash          `json:"orderBook"`
+	Order          common.Hash          `json:"order"`
+	Matches        []TraceMatch         `json:"matches,omitempty"`
+	BalanceChanges []TraceBalanceChange `json:"balanceChanges,omitempty"`
+	Rejects        []TraceReject        `json:"rejects,omitempty"`
+	Error          string               `json:"error,omitempty"`
+}
+
+// FrameTracer is a Tracer collecting a frame per operation.
+type FrameTracer struct {
+	frames []*TraceFrame
+}
+
+// NewFrameTracer creates a tracer collecting trace frames.
+func NewFrameTracer() *FrameTracer {
+	return &FrameTracer{frames: []*TraceFrame{}}
+}
+
+// Frames returns the frames collected so far.
+func (t *FrameTracer) Frames() []*TraceFrame {
+	return t.frames
+}
+
+func (t *FrameTracer) current() *TraceFrame {
+	if len(t.frames) == 0 {
+		t.frames = append(t.frames, &TraceFrame{})
+	}
+	return t.frames[len(t.frames)-1]
+}
+
+func (t *FrameTracer) CaptureStart(kind string, orderBook common.Hash, orderHash common.Hash) {
+	t.frames = append(t.frames, &TraceFrame{Kind: kind, OrderBook: orderBook, Order: orderHash})
+}
+
+func (t *FrameTracer) CaptureMatch(takerHash, makerHash common.Hash, price, quantity *big.Int) {
+	frame := t.current()
+	frame.Matches = append(frame.Matches, TraceMatch{
+		Taker:    takerHash,
+		Maker:    makerHash,
+		Price:    (*hexutil.Big)(new(big.Int).Set(price)),
+		Quantity: (*hexutil.Big)(new(big.Int).Set(quantity)),
+	})
+}
+
+func (t *FrameTracer) CaptureBalanceChange(addr, token common.Address, amount *big.Int, reason string) {
+	if amount == nil || amount.Sign() == 0 {
+		return
+	}
+	frame := t.current()
+	frame.BalanceChanges = append(frame.BalanceChanges, TraceBalanceChange{
+		Address: addr,
+		Token:   token,
+		Amount:  (*hexutil.Big)(new(big.Int).Set(amount)),
+		Reason:  reason,
+	})
+}
+
+func (t *FrameTracer) CaptureReject(orderHash common.Hash, reason string) {
+	frame := t.current()
+	frame.Rejects = append(frame.Rejects, TraceReject{Order: orderHash, Reason: reason})
+}
+
+// CaptureEnd drops the matches, balance changes and rejects of a reverted
+// operation, the operation itself being rejected.
+func (t *FrameTracer) CaptureEnd(err error) {
+	if err == nil {
+		return
+	}
+	frame := t.current()
+	frame.Matches, frame.BalanceChanges = nil, nil
+	frame.Rejects = []TraceReject{{Order: frame.Order, Reason: err.Error()}}
+	frame.Error = err.Error()
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
 	"github.com/XinFinOrg/XDC-Subnet/crypto/sha3"
 	"github.com/globalsign/mgo/bson"
 )
@@ -26,6 +28,10 @@ const (
 	TakerFee            = "takerFee"
 )
 
+// TradeLogTopic is the topic of the logs emitted for the matches of the orders,
+// indexed by the order book, the taker and the maker.
+var TradeLogTopic = crypto.Keccak256Hash([]byte("Trade(bytes32,address,address,bytes32,bytes32,uint256,uint256,uint256,uint256)"))
+
 type Trade struct {
 	Taker          common.Address `json:"taker" bson:"taker"`
 	Maker          common.Address `json:"maker" bson:"maker"`
@@ -141,3 +147,28 @@ func (t *Trade) ComputeHash() common.Hash {
 	sha.Write(t.TakerOrderHash.Bytes())
 	return common.BytesToHash(sha.Sum(nil))
 }
+
+// NewTradeLog returns the log of a match between a taker and a maker order. The
+// data holds the taker and maker order hashes followed by the traded price, the
+// traded quantity and the taker and maker fees.
+func NewTradeLog(orderBook common.Hash, taker, maker *OrderItem, price, quantity, takerFee, makerFee *big.Int) *types.Log {
+	data := make([]byte, 0, 6*common.HashLength)
+	data = append(data, taker.Hash.Bytes()...)
+	data = append(data, maker.Hash.Bytes()...)
+	for _, v := range []*big.Int{price, quantity, takerFee, makerFee} {
+		if v == nil {
+			v = Zero
+		}
+		data = append(data, common.BigToHash(v).Bytes()...)
+	}
+	return &types.Log{
+		Address: common.HexToAddress(common.XDCXAddr),
+		Topics: []common.Hash{
+			TradeLogTopic,
+			orderBook,
+			common.BytesToHash(taker.UserAddress.Bytes()),
+			common.BytesToHash(maker.UserAddress.Bytes()),
+		},
+		Data: data,
+	}
+}
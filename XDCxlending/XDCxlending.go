@@ -138,6 +138,7 @@ func (l *Lending) ProcessOrderPending(header *types.Header, coinbase common.Addr
 			LendingId:       tx.LendingId(),
 			LendingTradeId:  tx.LendingTradeId(),
 			ExtraData:       tx.ExtraData(),
+			ExpiresAt:       tx.ExpiresAt(),
 			Signature: &lendingstate.Signature{
 				V: byte(n),
 				R: common.BigToHash(R),
@@ -738,6 +739,34 @@ func (l *Lending) UpdateLendingTradeCache(hash common.Hash, txhash common.Hash,
 	l.lendingTradeHistory.Add(txhash, lendingCacheAtTxHash)
 }
 
+// SyncExpiredItemsToSDKNode marks the lending items expired by a block as
+// cancelled, under txHash, the hash of the trading state transaction of the block.
+func (l *Lending) SyncExpiredItemsToSDKNode(txHash common.Hash, txTime time.Time, expiredItems []*lendingstate.LendingItem) error {
+	db := l.GetMongoDB()
+	db.InitLendingBulk()
+	for _, expiredItem := range expiredItems {
+		val, err := db.GetObject(expiredItem.Hash, &lendingstate.LendingItem{})
+		if err != nil || val == nil {
+			log.Debug("SDKNode: expired lending item not found", "hash", expiredItem.Hash.Hex(), "err", err)
+			continue
+		}
+		item := val.(*lendingstate.LendingItem)
+		l.UpdateLendingItemCache(item.LendingToken, item.CollateralToken, item.Hash, txHash, lendingstate.LendingItemHistoryItem{
+			TxHash:       item.TxHash,
+			FilledAmount: lendingstate.CloneBigInt(item.FilledAmount),
+			Status:       item.Status,
+			UpdatedAt:    item.UpdatedAt,
+		})
+		item.Status = lendingstate.LendingStatusCancelled
+		item.TxHash = txHash
+		item.UpdatedAt = txTime
+		if err := db.PutObject(item.Hash, item); err != nil {
+			return fmt.Errorf("SDKNode: failed to put expired lending item. Hash: %s Error: %s", item.Hash.Hex(), err.Error())
+		}
+	}
+	return db.CommitLendingBulk()
+}
+
 func (l *Lending) RollbackLendingData(txhash common.Hash) error {
 	db := l.GetMongoDB()
 	db.InitLendingBulk()
@@ -828,6 +857,10 @@ func (l *Lending) ProcessLiquidationData(header *types.Header, chain consensus.C
 	autoTopUpTrades = []*lendingstate.LendingTrade{}
 	autoRecallTrades = []*lendingstate.LendingTrade{}
 
+	if tracer := tradingState.Tracer(); tracer != nil {
+		tracer.CaptureStart(tradingstate.TraceKindLiquidation, common.Hash{}, common.Hash{})
+		defer func() { tracer.CaptureEnd(err) }()
+	}
 	allPairs, err := lendingstate.GetAllLendingPairs(statedb)
 	if err != nil {
 		log.Debug("Not found all trading pairs", "error", err)
@@ -38,6 +38,7 @@ type lendingObject struct {
 	LiquidationTimeRoot common.Hash
 	LendingItemRoot     common.Hash
 	LendingTradeRoot    common.Hash
+	ExpiryTimeRoot      common.Hash `rlp:"optional"` // resting lending items by expiry time
 }
 
 // liquidation reasons
@@ -76,6 +76,16 @@ type (
 		tradeId   common.Hash
 		prev      *big.Int
 	}
+	insertExpiryTime struct {
+		orderBook common.Hash
+		expiresAt uint64
+		orderId   common.Hash
+	}
+	removeExpiryTime struct {
+		orderBook common.Hash
+		expiresAt uint64
+		orderId   common.Hash
+	}
 )
 
 func (ch insertOrder) undo(s *LendingStateDB) {
@@ -142,3 +152,14 @@ func (ch collateralLockedAmount) undo(s *LendingStateDB) {
 	}
 	stateLendingTrade.SetCollateralLockedAmount(ch.prev)
 }
+
+func (ch insertExpiryTime) undo(s *LendingStateDB) {
+	err := s.RemoveExpiryTime(ch.orderBook, ch.expiresAt, ch.orderId)
+	if err != nil {
+		log.Warn("undo RemoveExpiryTime", "err", err, "ch.orderBook", ch.orderBook, "ch.expiresAt", ch.expiresAt, "ch.orderId", ch.orderId)
+	}
+}
+
+func (ch removeExpiryTime) undo(s *LendingStateDB) {
+	s.InsertExpiryTime(ch.orderBook, ch.expiresAt, ch.orderId)
+}
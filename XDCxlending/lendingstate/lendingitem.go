@@ -84,6 +84,7 @@ type LendingItem struct {
 	LendingId       uint64         `bson:"lendingId" json:"lendingId"`
 	LendingTradeId  uint64         `bson:"tradeId" json:"tradeId"`
 	ExtraData       string         `bson:"extraData" json:"extraData"`
+	ExpiresAt       uint64         `bson:"expiresAt" json:"expiresAt,omitempty" rlp:"optional"`
 }
 
 type LendingItemBSON struct {
@@ -108,6 +109,7 @@ type LendingItemBSON struct {
 	LendingId       string           `bson:"lendingId" json:"lendingId"`
 	LendingTradeId  string           `bson:"tradeId" json:"tradeId"`
 	ExtraData       string           `bson:"extraData" json:"extraData"`
+	ExpiresAt       string           `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
 }
 
 func (l *LendingItem) GetBSON() (interface{}, error) {
@@ -137,6 +139,10 @@ func (l *LendingItem) GetBSON() (interface{}, error) {
 		lr.FilledAmount = l.FilledAmount.String()
 	}
 
+	if l.ExpiresAt > 0 {
+		lr.ExpiresAt = strconv.FormatUint(l.ExpiresAt, 10)
+	}
+
 	if l.Signature != nil {
 		lr.Signature = &SignatureRecord{
 			V: l.Signature.V,
@@ -199,6 +205,13 @@ func (l *LendingItem) SetBSON(raw bson.Raw) error {
 	}
 	l.LendingTradeId = uint64(lendingTradeId)
 	l.ExtraData = decoded.ExtraData
+	if decoded.ExpiresAt != "" {
+		expiresAt, err := strconv.ParseUint(decoded.ExpiresAt, 10, 64)
+		if err != nil {
+			return err
+		}
+		l.ExpiresAt = expiresAt
+	}
 	return nil
 }
 
@@ -355,7 +368,7 @@ func (l *LendingItem) VerifyLendingSignature() error {
 
 	//(nonce uint64, quantity *big.Int, interest, duration uint64, relayerAddress, userAddress, lendingToken, collateralToken common.Address, status, side, typeLending string, hash common.Hash, id uint64
 	tx := types.NewLendingTransaction(l.Nonce.Uint64(), l.Quantity, l.Interest.Uint64(), l.Term, l.Relayer, l.UserAddress,
-		l.LendingToken, l.CollateralToken, l.AutoTopUp, l.Status, l.Side, l.Type, l.Hash, l.LendingId, l.LendingTradeId, l.ExtraData)
+		l.LendingToken, l.CollateralToken, l.AutoTopUp, l.Status, l.Side, l.Type, l.Hash, l.LendingId, l.LendingTradeId, l.ExtraData, l.ExpiresAt)
 	tx.ImportSignature(V, R, S)
 	from, _ := types.LendingSender(types.LendingTxSigner{}, tx)
 	if from != tx.UserAddress() {
@@ -0,0 +1,36 @@
+package lendingstate
+
+import (
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/state/pruner"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+)
+
+// ResolvePruneLeaf is the leaf resolver of the lending state trie for the state
+// pruner. It mirrors the references set up by Commit: a lending book references
+// its investing, borrowing, liquidation time, lending item, trade and expiry time
+// tries.
+func ResolvePruneLeaf(leaf []byte) ([]pruner.Subtrie, []common.Hash) {
+	var exchange lendingObject
+	if err := rlp.DecodeBytes(leaf, &exchange); err != nil {
+		return nil, nil
+	}
+	return []pruner.Subtrie{
+		{Root: exchange.InvestingRoot, Resolve: resolveItemList},
+		{Root: exchange.BorrowingRoot, Resolve: resolveItemList},
+		{Root: exchange.LiquidationTimeRoot, Resolve: resolveItemList},
+		{Root: exchange.LendingItemRoot},
+		{Root: exchange.LendingTradeRoot},
+		{Root: exchange.ExpiryTimeRoot, Resolve: resolveItemList},
+	}, nil
+}
+
+// resolveItemList resolves the leaves of the interest and time indexed tries,
+// each of them references the trie of its items.
+func resolveItemList(leaf []byte) ([]pruner.Subtrie, []common.Hash) {
+	var data itemList
+	if err := rlp.DecodeBytes(leaf, &data); err != nil {
+		return nil, nil
+	}
+	return []pruner.Subtrie{{Root: data.Root}}, nil
+}
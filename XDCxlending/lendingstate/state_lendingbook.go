@@ -46,6 +46,10 @@ type lendingExchangeState struct {
 	liquidationTimeStates      map[common.Hash]*liquidationTimeState
 	liquidationTimestatesDirty map[common.Hash]struct{}
 
+	expiryTimeTrie        Trie
+	expiryTimeStates      map[common.Hash]*itemListState
+	expiryTimeStatesDirty map[common.Hash]struct{}
+
 	investingStates      map[common.Hash]*itemListState
 	investingStatesDirty map[common.Hash]struct{}
 
@@ -84,6 +88,9 @@ func (s *lendingExchangeState) empty() bool {
 	if !common.EmptyHash(s.data.LiquidationTimeRoot) {
 		return false
 	}
+	if !common.EmptyHash(s.data.ExpiryTimeRoot) {
+		return false
+	}
 	return true
 }
 
@@ -102,6 +109,8 @@ func newStateExchanges(db *LendingStateDB, hash common.Hash, data lendingObject,
 		lendingItemStatesDirty:     make(map[common.Hash]struct{}),
 		lendingTradeStatesDirty:    make(map[common.Hash]struct{}),
 		liquidationTimestatesDirty: make(map[common.Hash]struct{}),
+		expiryTimeStates:           make(map[common.Hash]*itemListState),
+		expiryTimeStatesDirty:      make(map[common.Hash]struct{}),
 		onDirty:                    onDirty,
 	}
 }
@@ -650,6 +659,15 @@ func (self *lendingExchangeState) deepCopy(db *LendingStateDB, onDirty func(hash
 	for time := range self.liquidationTimestatesDirty {
 		stateExchanges.liquidationTimestatesDirty[time] = struct{}{}
 	}
+	if self.expiryTimeTrie != nil {
+		stateExchanges.expiryTimeTrie = db.db.CopyTrie(self.expiryTimeTrie)
+	}
+	for time, itemList := range self.expiryTimeStates {
+		stateExchanges.expiryTimeStates[time] = itemList.deepCopy(db, stateExchanges.MarkExpiryTimeDirty)
+	}
+	for time := range self.expiryTimeStatesDirty {
+		stateExchanges.expiryTimeStatesDirty[time] = struct{}{}
+	}
 	return stateExchanges
 }
 
@@ -799,3 +817,146 @@ func (self *lendingExchangeState) insertLendingTrade(tradeId common.Hash, order
 	}
 	return newobj
 }
+
+func (self *lendingExchangeState) getExpiryTimeTrie(db Database) Trie {
+	if self.expiryTimeTrie == nil {
+		var err error
+		self.expiryTimeTrie, err = db.OpenStorageTrie(self.lendingBook, self.data.ExpiryTimeRoot)
+		if err != nil {
+			self.expiryTimeTrie, _ = db.OpenStorageTrie(self.lendingBook, EmptyHash)
+			self.setError(fmt.Errorf("can't create expiry time trie: %v", err))
+		}
+	}
+	return self.expiryTimeTrie
+}
+
+func (self *lendingExchangeState) getExpiryTimeItemList(db Database, time common.Hash) (stateObject *itemListState) {
+	// Prefer 'live' objects.
+	if obj := self.expiryTimeStates[time]; obj != nil {
+		return obj
+	}
+
+	// Load the object from the database.
+	enc, err := self.getExpiryTimeTrie(db).TryGet(time[:])
+	if len(enc) == 0 {
+		self.setError(err)
+		return nil
+	}
+	var data itemList
+	if err := rlp.DecodeBytes(enc, &data); err != nil {
+		log.Error("Failed to decode state expiry time", "time", time, "err", err)
+		return nil
+	}
+	// Insert into the live set.
+	obj := newItemListState(self.lendingBook, time, data, self.MarkExpiryTimeDirty)
+	self.expiryTimeStates[time] = obj
+	return obj
+}
+
+func (self *lendingExchangeState) MarkExpiryTimeDirty(time common.Hash) {
+	self.expiryTimeStatesDirty[time] = struct{}{}
+	if self.onDirty != nil {
+		self.onDirty(self.Hash())
+		self.onDirty = nil
+	}
+}
+
+func (self *lendingExchangeState) createExpiryTime(db Database, time common.Hash) (newobj *itemListState) {
+	newobj = newItemListState(self.lendingBook, time, itemList{Volume: Zero}, self.MarkExpiryTimeDirty)
+	self.expiryTimeStates[time] = newobj
+	self.expiryTimeStatesDirty[time] = struct{}{}
+	data, err := rlp.EncodeToBytes(newobj)
+	if err != nil {
+		panic(fmt.Errorf("can't encode expiry time at %x: %v", time[:], err))
+	}
+	self.setError(self.getExpiryTimeTrie(db).TryUpdate(time[:], data))
+	if self.onDirty != nil {
+		self.onDirty(self.lendingBook)
+		self.onDirty = nil
+	}
+	return newobj
+}
+
+// hasExpiryTimes reports whether the lending book may index resting items by
+// expiry time, the expiry time trie is only opened by the books having one.
+func (self *lendingExchangeState) hasExpiryTimes() bool {
+	return self.expiryTimeTrie != nil || !(common.EmptyHash(self.data.ExpiryTimeRoot) || self.data.ExpiryTimeRoot == EmptyRoot)
+}
+
+func (self *lendingExchangeState) getLowestExpiryTime(db Database) (common.Hash, *itemListState) {
+	if !self.hasExpiryTimes() {
+		return EmptyHash, nil
+	}
+	encKey, encValue, err := self.getExpiryTimeTrie(db).TryGetBestLeftKeyAndValue()
+	if err != nil {
+		log.Error("Failed find lowest expiry time", "lendingBook", self.lendingBook.Hex())
+		return EmptyHash, nil
+	}
+	if len(encKey) == 0 || len(encValue) == 0 {
+		return EmptyHash, nil
+	}
+	time := common.BytesToHash(encKey)
+	return time, self.getExpiryTimeItemList(db, time)
+}
+
+func (self *lendingExchangeState) updateExpiryTimeTrie(db Database) Trie {
+	tr := self.getExpiryTimeTrie(db)
+	for time, itemList := range self.expiryTimeStates {
+		if _, isDirty := self.expiryTimeStatesDirty[time]; isDirty {
+			delete(self.expiryTimeStatesDirty, time)
+			if itemList.empty() {
+				self.setError(tr.TryDelete(time[:]))
+				continue
+			}
+			err := itemList.updateRoot(db)
+			if err != nil {
+				log.Warn("updateExpiryTimeTrie updateRoot", "err", err, "time", time, "itemList", *itemList)
+			}
+			// Encoding []byte cannot fail, ok to ignore the error.
+			v, _ := rlp.EncodeToBytes(itemList)
+			self.setError(tr.TryUpdate(time[:], v))
+		}
+	}
+	return tr
+}
+
+// optionalRoot returns the root stored for a trie added to the lending books
+// after their first release. The root is left zero while the trie is empty,
+// so that the encoding of the lending books not using it doesn't change.
+func optionalRoot(hash common.Hash) common.Hash {
+	if hash == EmptyRoot {
+		return EmptyHash
+	}
+	return hash
+}
+
+func (self *lendingExchangeState) updateExpiryTimeRoot(db Database) {
+	if self.expiryTimeTrie == nil {
+		return
+	}
+	self.data.ExpiryTimeRoot = optionalRoot(self.updateExpiryTimeTrie(db).Hash())
+}
+
+func (self *lendingExchangeState) CommitExpiryTimeTrie(db Database) error {
+	if self.expiryTimeTrie == nil {
+		return nil
+	}
+	self.updateExpiryTimeTrie(db)
+	if self.dbErr != nil {
+		return self.dbErr
+	}
+	root, err := self.expiryTimeTrie.Commit(func(leaf []byte, parent common.Hash) error {
+		var orderList itemList
+		if err := rlp.DecodeBytes(leaf, &orderList); err != nil {
+			return nil
+		}
+		if orderList.Root != EmptyRoot {
+			db.TrieDB().Reference(orderList.Root, parent)
+		}
+		return nil
+	})
+	if err == nil {
+		self.data.ExpiryTimeRoot = optionalRoot(root)
+	}
+	return err
+}
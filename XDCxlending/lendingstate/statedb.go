@@ -26,6 +26,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
 )
 
 type revision struct {
@@ -532,6 +533,7 @@ func (s *LendingStateDB) Finalise() {
 			stateObject.updateOrderRoot(s.db)
 			stateObject.updateLendingTradeRoot(s.db)
 			stateObject.updateLiquidationTimeRoot(s.db)
+			stateObject.updateExpiryTimeRoot(s.db)
 			// Update the object in the main tradeId trie.
 			s.updateLendingExchange(stateObject)
 			//delete(s.investingStatesDirty, addr)
@@ -570,6 +572,9 @@ func (s *LendingStateDB) Commit() (root common.Hash, err error) {
 			if err := stateObject.CommitLiquidationTimeTrie(s.db); err != nil {
 				return EmptyHash, err
 			}
+			if err := stateObject.CommitExpiryTimeTrie(s.db); err != nil {
+				return EmptyHash, err
+			}
 			// Update the object in the main tradeId trie.
 			s.updateLendingExchange(stateObject)
 			delete(s.lendingExchangeStatesDirty, addr)
@@ -596,6 +601,9 @@ func (s *LendingStateDB) Commit() (root common.Hash, err error) {
 		if exchange.LiquidationTimeRoot != EmptyRoot {
 			s.db.TrieDB().Reference(exchange.LiquidationTimeRoot, parent)
 		}
+		if !common.EmptyHash(exchange.ExpiryTimeRoot) && exchange.ExpiryTimeRoot != EmptyRoot {
+			s.db.TrieDB().Reference(exchange.ExpiryTimeRoot, parent)
+		}
 		return nil
 	})
 	log.Debug("Lending State Trie cache stats after commit", "root", root.Hex())
@@ -655,6 +663,87 @@ func (self *LendingStateDB) GetLowestLiquidationTime(lendingBook common.Hash, ti
 	return lowestTime, liquidationData
 }
 
+// InsertExpiryTime indexes a resting lending item of the lending book by the time it expires at.
+func (self *LendingStateDB) InsertExpiryTime(lendingBook common.Hash, expiresAt uint64, lendingId common.Hash) {
+	timeHash := common.Uint64ToHash(expiresAt)
+	lendingExchangeState := self.getLendingExchange(lendingBook)
+	if lendingExchangeState == nil {
+		lendingExchangeState = self.createLendingExchangeObject(lendingBook)
+	}
+	expiryTime := lendingExchangeState.getExpiryTimeItemList(self.db, timeHash)
+	if expiryTime == nil {
+		expiryTime = lendingExchangeState.createExpiryTime(self.db, timeHash)
+	} else if expiryTime.empty() {
+		// the time was removed from the trie along with its last item
+		data, _ := rlp.EncodeToBytes(expiryTime)
+		self.setError(lendingExchangeState.getExpiryTimeTrie(self.db).TryUpdate(timeHash[:], data))
+	}
+	expiryTime.insertLendingItem(self.db, lendingId, lendingId)
+	expiryTime.AddVolume(One)
+	self.journal = append(self.journal, insertExpiryTime{
+		orderBook: lendingBook,
+		expiresAt: expiresAt,
+		orderId:   lendingId,
+	})
+}
+
+// RemoveExpiryTime removes a lending item from the expiry time index of the lending book.
+func (self *LendingStateDB) RemoveExpiryTime(lendingBook common.Hash, expiresAt uint64, lendingId common.Hash) error {
+	timeHash := common.Uint64ToHash(expiresAt)
+	lendingExchangeState := self.getLendingExchange(lendingBook)
+	if lendingExchangeState == nil {
+		return fmt.Errorf("lending book not found : %s ", lendingBook.Hex())
+	}
+	expiryTime := lendingExchangeState.getExpiryTimeItemList(self.db, timeHash)
+	if expiryTime == nil || expiryTime.empty() {
+		return fmt.Errorf("expiry time not found : %s , %d ", lendingBook.Hex(), expiresAt)
+	}
+	if common.EmptyHash(expiryTime.GetOrderAmount(self.db, lendingId)) {
+		return fmt.Errorf("lending id not found : %s , %d , %s ", lendingBook.Hex(), expiresAt, lendingId.Hex())
+	}
+	expiryTime.removeOrderItem(self.db, lendingId)
+	expiryTime.subVolume(One)
+	if expiryTime.empty() {
+		err := lendingExchangeState.getExpiryTimeTrie(self.db).TryDelete(timeHash[:])
+		if err != nil {
+			log.Warn("RemoveExpiryTime getExpiryTimeTrie.TryDelete", "err", err, "timeHash", timeHash[:])
+		}
+	}
+	self.journal = append(self.journal, removeExpiryTime{
+		orderBook: lendingBook,
+		expiresAt: expiresAt,
+		orderId:   lendingId,
+	})
+	return nil
+}
+
+// HasExpiryTimes reports whether the lending book may hold items with an expiry
+// time, it is false for the books without any expiry time index.
+func (self *LendingStateDB) HasExpiryTimes(lendingBook common.Hash) bool {
+	lendingExchangeState := self.getLendingExchange(lendingBook)
+	return lendingExchangeState != nil && lendingExchangeState.hasExpiryTimes()
+}
+
+// GetLowestExpiryTime returns the earliest expiry time indexed in the lending
+// book, along with the ids of the items expiring at it if it is not after time.
+func (self *LendingStateDB) GetLowestExpiryTime(lendingBook common.Hash, time uint64) (uint64, []common.Hash) {
+	lendingIds := []common.Hash{}
+	lendingExchangeState := self.getLendingExchange(lendingBook)
+	if lendingExchangeState == nil {
+		return 0, lendingIds
+	}
+	lowestTimeHash, expiryTime := lendingExchangeState.getLowestExpiryTime(self.db)
+	lowestTime := new(big.Int).SetBytes(lowestTimeHash[:]).Uint64()
+	if expiryTime == nil || lowestTime == 0 || lowestTime > time {
+		return lowestTime, lendingIds
+	}
+	it := trie.NewIterator(expiryTime.getTrie(self.db).NodeIterator(nil))
+	for it.Next() {
+		lendingIds = append(lendingIds, common.BytesToHash(it.Key))
+	}
+	return lowestTime, lendingIds
+}
+
 func (self *LendingStateDB) CancelLendingTrade(orderBook common.Hash, tradeId uint64) error {
 	tradeIdHash := common.Uint64ToHash(tradeId)
 	stateObject := self.GetOrNewLendingExchangeObject(orderBook)
@@ -3,6 +3,8 @@ package lendingstate
 import (
 	"fmt"
 	"github.com/XinFinOrg/XDC-Subnet/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
 	"github.com/XinFinOrg/XDC-Subnet/crypto/sha3"
 	"math/big"
 	"strconv"
@@ -18,6 +20,10 @@ const (
 	TradeStatusLiquidated = "LIQUIDATED"
 )
 
+// LendingTradeLogTopic is the topic of the logs emitted for the matches of the
+// lending orders, indexed by the lending book, the borrower and the investor.
+var LendingTradeLogTopic = crypto.Keccak256Hash([]byte("LendingTrade(bytes32,address,address,bytes32,uint256,uint256,uint256,uint256,address,uint256,uint256,uint256)"))
+
 type LendingTrade struct {
 	Borrower               common.Address `bson:"borrower" json:"borrower"`
 	Investor               common.Address `bson:"investor" json:"investor"`
@@ -188,3 +194,36 @@ func (t *LendingTrade) ComputeHash() common.Hash {
 	sha.Write(t.BorrowingOrderHash.Bytes())
 	return common.BytesToHash(sha.Sum(nil))
 }
+
+// NewLendingTradeLog returns the log of a lending trade. The data holds the trade
+// hash followed by the trade id, the amount, the interest, the term, the
+// collateral token, the locked collateral, the liquidation time and the
+// borrowing fee.
+func NewLendingTradeLog(lendingBook common.Hash, t *LendingTrade) *types.Log {
+	word := func(v *big.Int) []byte {
+		if v == nil {
+			v = Zero
+		}
+		return common.BigToHash(v).Bytes()
+	}
+	data := make([]byte, 0, 9*common.HashLength)
+	data = append(data, t.Hash.Bytes()...)
+	data = append(data, word(new(big.Int).SetUint64(t.TradeId))...)
+	data = append(data, word(t.Amount)...)
+	data = append(data, word(new(big.Int).SetUint64(t.Interest))...)
+	data = append(data, word(new(big.Int).SetUint64(t.Term))...)
+	data = append(data, common.BytesToHash(t.CollateralToken.Bytes()).Bytes()...)
+	data = append(data, word(t.CollateralLockedAmount)...)
+	data = append(data, word(new(big.Int).SetUint64(t.LiquidationTime))...)
+	data = append(data, word(t.BorrowingFee)...)
+	return &types.Log{
+		Address: common.HexToAddress(common.XDCXLendingAddress),
+		Topics: []common.Hash{
+			LendingTradeLogTopic,
+			lendingBook,
+			common.BytesToHash(t.Borrower.Bytes()),
+			common.BytesToHash(t.Investor.Bytes()),
+		},
+		Data: data,
+	}
+}
@@ -1,8 +1,10 @@
 package XDCxlending
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/XinFinOrg/XDC-Subnet/XDCx"
 	"github.com/XinFinOrg/XDC-Subnet/XDCx/tradingstate"
 	"github.com/XinFinOrg/XDC-Subnet/XDCxlending/lendingstate"
 	"github.com/XinFinOrg/XDC-Subnet/common"
@@ -11,6 +13,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 	"math/big"
+	"sort"
 )
 
 func (l *Lending) CommitOrder(header *types.Header, coinbase common.Address, chain consensus.ChainContext, statedb *state.StateDB, lendingStateDB *lendingstate.LendingStateDB, tradingStateDb *tradingstate.TradingStateDB, lendingOrderBook common.Hash, order *lendingstate.LendingItem) ([]*lendingstate.LendingTrade, []*lendingstate.LendingItem, error) {
@@ -55,10 +58,18 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 			statedb.RevertToSnapshot(dbSnap)
 		}
 	}()
+	tracer := tradingStateDb.Tracer()
+	if tracer != nil {
+		tracer.CaptureStart(tradingstate.TraceKindLending, lendingOrderBook, order.Hash)
+		defer func() { tracer.CaptureEnd(err) }()
+	}
 
 	if err := order.VerifyLendingItem(statedb); err != nil {
 		log.Debug("invalid lending order", "order", lendingstate.ToJSON(order), "err", err)
 		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, err.Error())
+		}
 		return trades, rejects, nil
 	}
 
@@ -67,6 +78,13 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		err, reject, newLendingTrade := l.ProcessTopUp(lendingStateDB, statedb, tradingStateDb, order)
 		if err != nil || reject {
 			rejects = append(rejects, order)
+			if tracer != nil {
+				reason := "invalid top up"
+				if err != nil {
+					reason = err.Error()
+				}
+				tracer.CaptureReject(order.Hash, reason)
+			}
 		}
 		trades = append(trades, newLendingTrade)
 		return trades, rejects, nil
@@ -75,6 +93,9 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		if err != nil {
 			log.Debug("Can not process payment", "err", err)
 			rejects = append(rejects, order)
+			if tracer != nil {
+				tracer.CaptureReject(order.Hash, err.Error())
+			}
 		}
 		trades = append(trades, lendingTrade)
 		return trades, rejects, nil
@@ -85,6 +106,13 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		err, reject := l.ProcessCancelOrder(header, lendingStateDB, statedb, tradingStateDb, chain, coinbase, lendingOrderBook, order)
 		if err != nil || reject {
 			rejects = append(rejects, order)
+			if tracer != nil {
+				reason := "cancellation fee not affordable"
+				if err != nil {
+					reason = err.Error()
+				}
+				tracer.CaptureReject(order.Hash, reason)
+			}
 		}
 		return trades, rejects, nil
 	}
@@ -93,12 +121,34 @@ func (l *Lending) ApplyOrder(header *types.Header, coinbase common.Address, chai
 		if order.Interest.Sign() == 0 || common.BigToHash(order.Interest).Big().Cmp(order.Interest) != 0 {
 			log.Debug("Reject order Interest invalid", "Interest", order.Interest)
 			rejects = append(rejects, order)
+			if tracer != nil {
+				tracer.CaptureReject(order.Hash, "invalid interest")
+			}
 			return trades, rejects, nil
 		}
 	}
 	if order.Quantity.Sign() == 0 || common.BigToHash(order.Quantity).Big().Cmp(order.Quantity) != 0 {
 		log.Debug("Reject order quantity invalid", "quantity", order.Quantity)
 		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, "invalid quantity")
+		}
+		return trades, rejects, nil
+	}
+	if order.ExpiresAt > 0 && !chain.Config().IsXDCxOrderExpiry(header.Number) {
+		log.Debug("Reject lending order expiry before XDCxOrderExpiry fork", "expiresAt", order.ExpiresAt)
+		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, "order expiry not supported")
+		}
+		return trades, rejects, nil
+	}
+	if order.ExpiresAt > 0 && order.ExpiresAt <= header.Time.Uint64() {
+		log.Debug("Reject lending order expired", "expiresAt", order.ExpiresAt, "blockTime", header.Time)
+		rejects = append(rejects, order)
+		if tracer != nil {
+			tracer.CaptureReject(order.Hash, "order expired")
+		}
 		return trades, rejects, nil
 	}
 	orderType := order.Type
@@ -218,6 +268,9 @@ func (l *Lending) processLimitOrder(header *types.Header, coinbase common.Addres
 		lendingStateDB.SetNonce(lendingOrderBook, oldOrderId+1)
 		orderIdHash := common.BigToHash(new(big.Int).SetUint64(order.LendingId))
 		lendingStateDB.InsertLendingItem(lendingOrderBook, orderIdHash, *order)
+		if order.ExpiresAt > 0 {
+			lendingStateDB.InsertExpiryTime(lendingOrderBook, order.ExpiresAt, orderIdHash)
+		}
 		log.Debug("After matching, order (unmatched part) is now added to tree", "side", order.Side, "order", order)
 		investingRate, investingVolume := lendingStateDB.GetBestInvestingRate(lendingOrderBook)
 		borrowingRate, borrowingVolume := lendingStateDB.GetBestBorrowRate(lendingOrderBook)
@@ -234,6 +287,13 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 		trades  []*lendingstate.LendingTrade
 		rejects []*lendingstate.LendingItem
 	)
+	tracer := tradingStateDb.Tracer()
+	reject := func(item *lendingstate.LendingItem, reason string) {
+		rejects = append(rejects, item)
+		if tracer != nil {
+			tracer.CaptureReject(item.Hash, reason)
+		}
+	}
 	for quantityToTrade.Sign() > 0 {
 		orderId, amount, err := lendingStateDB.GetBestLendingIdAndAmount(lendingOrderBook, Interest, side)
 		if err != nil {
@@ -286,13 +346,13 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 		if collateralPrice == nil || collateralPrice.Sign() <= 0 {
 			return nil, nil, nil, fmt.Errorf("invalid collateral price")
 		}
-		tradedQuantity, collateralLockedAmount, rejectMaker, settleBalanceResult, err := l.getLendQuantity(lendTokenXDCPrice, collateralPrice, depositRate, borrowFee, coinbase, chain, header, statedb, order, &oldestOrder, maxTradedQuantity)
+		tradedQuantity, collateralLockedAmount, rejectMaker, settleBalanceResult, err := l.getLendQuantity(lendTokenXDCPrice, collateralPrice, depositRate, borrowFee, coinbase, chain, header, statedb, order, &oldestOrder, maxTradedQuantity, tracer)
 		if err != nil && err == lendingstate.ErrQuantityTradeTooSmall && tradedQuantity != nil && tradedQuantity.Sign() >= 0 {
 			if tradedQuantity.Cmp(maxTradedQuantity) == 0 {
 				if quantityToTrade.Cmp(amount) == 0 { // reject Taker & maker
-					rejects = append(rejects, order)
+					reject(order, err.Error())
 					quantityToTrade = lendingstate.Zero
-					rejects = append(rejects, &oldestOrder)
+					reject(&oldestOrder, err.Error())
 					err = lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 					log.Debug("Reject order maker", "lending id ", oldestOrder.LendingId, "err", err)
 					if err != nil {
@@ -300,11 +360,11 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 					}
 					break
 				} else if quantityToTrade.Cmp(amount) < 0 { // reject Taker
-					rejects = append(rejects, order)
+					reject(order, err.Error())
 					quantityToTrade = lendingstate.Zero
 					break
 				} else { // reject maker
-					rejects = append(rejects, &oldestOrder)
+					reject(&oldestOrder, err.Error())
 					err = lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 					log.Debug("Reject order maker", "lending id ", oldestOrder.LendingId, "err", err)
 					if err != nil {
@@ -314,7 +374,7 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 				}
 			} else {
 				if rejectMaker { // reject maker
-					rejects = append(rejects, &oldestOrder)
+					reject(&oldestOrder, err.Error())
 					err = lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 					log.Debug("Reject order maker", "lending id ", oldestOrder.LendingId, "err", err)
 					if err != nil {
@@ -322,7 +382,7 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 					}
 					continue
 				} else { // reject Taker
-					rejects = append(rejects, order)
+					reject(order, err.Error())
 					quantityToTrade = lendingstate.Zero
 					break
 				}
@@ -332,7 +392,7 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 		}
 		if tradedQuantity.Sign() == 0 && !rejectMaker {
 			log.Debug("Reject order Taker ", "tradedQuantity", tradedQuantity, "rejectMaker", rejectMaker)
-			rejects = append(rejects, order)
+			reject(order, "insufficient balance or relayer fee")
 			quantityToTrade = lendingstate.Zero
 			break
 		}
@@ -407,9 +467,13 @@ func (l *Lending) processOrderList(header *types.Header, coinbase common.Address
 			log.Debug("InsertLiquidationPrice", "TradingOrderBookHash", tradingstate.GetTradingOrderBookHash(collateralToken, order.LendingToken).Hex(), "tradingId", tradingId, "lendingOrderBook", lendingOrderBook.Hex(), "liquidationPrice", liquidationPrice)
 			tradingStateDb.InsertLiquidationPrice(tradingstate.GetTradingOrderBookHash(collateralToken, order.LendingToken), liquidationPrice, lendingOrderBook, tradingId)
 			trades = append(trades, &lendingTrade)
+			tradingStateDb.AddLog(lendingstate.NewLendingTradeLog(lendingOrderBook, &lendingTrade))
+			if tracer != nil {
+				tracer.CaptureMatch(order.Hash, oldestOrder.Hash, oldestOrder.Interest, tradedQuantity)
+			}
 		}
 		if rejectMaker {
-			rejects = append(rejects, &oldestOrder)
+			reject(&oldestOrder, "insufficient balance or relayer fee")
 			err := lendingStateDB.CancelLendingOrder(lendingOrderBook, &oldestOrder)
 			if err != nil {
 				return nil, nil, nil, err
@@ -424,7 +488,7 @@ func (l *Lending) getLendQuantity(
 	collateralPrice,
 	depositRate,
 	borrowFee *big.Int,
-	coinbase common.Address, chain consensus.ChainContext, header *types.Header, statedb *state.StateDB, takerOrder *lendingstate.LendingItem, makerOrder *lendingstate.LendingItem, quantityToTrade *big.Int) (*big.Int, *big.Int, bool, *lendingstate.LendingSettleBalance, error) {
+	coinbase common.Address, chain consensus.ChainContext, header *types.Header, statedb *state.StateDB, takerOrder *lendingstate.LendingItem, makerOrder *lendingstate.LendingItem, quantityToTrade *big.Int, tracer tradingstate.Tracer) (*big.Int, *big.Int, bool, *lendingstate.LendingSettleBalance, error) {
 	if collateralPrice == nil || collateralPrice.Sign() == 0 {
 		if takerOrder.Side == lendingstate.Borrowing {
 			log.Debug("Reject lending order taker , can not found  collateral price ")
@@ -484,7 +548,7 @@ func (l *Lending) getLendQuantity(
 		settleBalanceResult, err := lendingstate.GetSettleBalance(isXDCXLendingFork, takerOrder.Side, lendTokenXDCPrice, collateralPrice, depositRate, borrowFee, lendToken, collateralToken, LendingTokenDecimal, collateralTokenDecimal, quantity)
 		log.Debug("GetSettleBalance", "settleBalanceResult", settleBalanceResult, "err", err)
 		if err == nil {
-			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb)
+			err = DoSettleBalance(coinbase, takerOrder, makerOrder, settleBalanceResult, statedb, tracer)
 		}
 		if err != nil {
 			return quantity, lendingstate.Zero, rejectMaker, nil, err
@@ -572,7 +636,7 @@ func GetLendQuantity(takerSide string, collateralTokenDecimal *big.Int, depositR
 	}
 }
 
-func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *lendingstate.LendingItem, settleBalance *lendingstate.LendingSettleBalance, statedb *state.StateDB) error {
+func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *lendingstate.LendingItem, settleBalance *lendingstate.LendingSettleBalance, statedb *state.StateDB, tracer tradingstate.Tracer) error {
 	takerExOwner := lendingstate.GetRelayerOwner(takerOrder.Relayer, statedb)
 	makerExOwner := lendingstate.GetRelayerOwner(makerOrder.Relayer, statedb)
 	matchingFee := big.NewInt(0)
@@ -677,6 +741,26 @@ func DoSettleBalance(coinbase common.Address, takerOrder, makerOrder *lendingsta
 			}
 		}
 	}
+	if tracer != nil {
+		XDCToken := common.HexToAddress(common.XDCNativeAddress)
+		lockAddress := common.HexToAddress(common.LendingLockAddress)
+		if takerOrder.Side == lendingstate.Borrowing {
+			tracer.CaptureBalanceChange(takerOrder.Relayer, XDCToken, new(big.Int).Neg(common.RelayerLendingFee), tradingstate.ReasonRelayerFee)
+			tracer.CaptureBalanceChange(takerOrder.UserAddress, settleBalance.Taker.InToken, settleBalance.Taker.InTotal, tradingstate.ReasonSettlement)
+			tracer.CaptureBalanceChange(makerOrder.UserAddress, settleBalance.Maker.OutToken, new(big.Int).Neg(settleBalance.Maker.OutTotal), tradingstate.ReasonSettlement)
+			tracer.CaptureBalanceChange(takerExOwner, settleBalance.Taker.InToken, settleBalance.Taker.Fee, tradingstate.ReasonTradingFee)
+			tracer.CaptureBalanceChange(takerOrder.UserAddress, settleBalance.Taker.OutToken, new(big.Int).Neg(settleBalance.Taker.OutTotal), tradingstate.ReasonCollateral)
+			tracer.CaptureBalanceChange(lockAddress, settleBalance.Taker.OutToken, settleBalance.Taker.OutTotal, tradingstate.ReasonCollateral)
+		} else {
+			tracer.CaptureBalanceChange(makerOrder.Relayer, XDCToken, new(big.Int).Neg(common.RelayerLendingFee), tradingstate.ReasonRelayerFee)
+			tracer.CaptureBalanceChange(takerOrder.UserAddress, settleBalance.Taker.OutToken, new(big.Int).Neg(settleBalance.Taker.OutTotal), tradingstate.ReasonSettlement)
+			tracer.CaptureBalanceChange(makerOrder.UserAddress, settleBalance.Maker.InToken, settleBalance.Maker.InTotal, tradingstate.ReasonSettlement)
+			tracer.CaptureBalanceChange(makerExOwner, settleBalance.Maker.InToken, settleBalance.Maker.Fee, tradingstate.ReasonTradingFee)
+			tracer.CaptureBalanceChange(makerOrder.UserAddress, settleBalance.Maker.OutToken, new(big.Int).Neg(settleBalance.Maker.OutTotal), tradingstate.ReasonCollateral)
+			tracer.CaptureBalanceChange(lockAddress, settleBalance.Maker.OutToken, settleBalance.Maker.OutTotal, tradingstate.ReasonCollateral)
+		}
+		tracer.CaptureBalanceChange(masternodeOwner, XDCToken, matchingFee, tradingstate.ReasonMatchingFee)
+	}
 	return nil
 }
 
@@ -769,6 +853,17 @@ func (l *Lending) ProcessCancelOrder(header *types.Header, lendingStateDB *lendi
 		}
 	default:
 	}
+	if tracer := tradingStateDb.Tracer(); tracer != nil {
+		XDCToken := common.HexToAddress(common.XDCNativeAddress)
+		feeToken := originOrder.CollateralToken
+		if originOrder.Side == lendingstate.Investing {
+			feeToken = originOrder.LendingToken
+		}
+		tracer.CaptureBalanceChange(originOrder.Relayer, XDCToken, new(big.Int).Neg(common.RelayerLendingCancelFee), tradingstate.ReasonRelayerFee)
+		tracer.CaptureBalanceChange(masternodeOwner, XDCToken, common.RelayerLendingCancelFee, tradingstate.ReasonMatchingFee)
+		tracer.CaptureBalanceChange(originOrder.UserAddress, feeToken, new(big.Int).Neg(tokenCancelFee), tradingstate.ReasonCancelFee)
+		tracer.CaptureBalanceChange(relayerOwner, feeToken, tokenCancelFee, tradingstate.ReasonCancelFee)
+	}
 	extraData, _ := json.Marshal(struct {
 		CancelFee       string
 		TokenPriceInXDC string
@@ -861,6 +956,11 @@ func (l *Lending) LiquidationExpiredTrade(header *types.Header, chain consensus.
 	if err != nil {
 		log.Warn("LiquidationExpiredTrade AddTokenBalance", "err", err, "lendingTrade.Investor", lendingTrade.Investor, "repayAmount", repayAmount, "lendingTrade.CollateralToken", lendingTrade.CollateralToken)
 	}
+	if tracer := tradingstateDB.Tracer(); tracer != nil {
+		tracer.CaptureBalanceChange(lendingTrade.Borrower, lendingTrade.CollateralToken, recallAmount, tradingstate.ReasonLiquidation)
+		tracer.CaptureBalanceChange(common.HexToAddress(common.LendingLockAddress), lendingTrade.CollateralToken, new(big.Int).Neg(lendingTrade.CollateralLockedAmount), tradingstate.ReasonLiquidation)
+		tracer.CaptureBalanceChange(lendingTrade.Investor, lendingTrade.CollateralToken, repayAmount, tradingstate.ReasonLiquidation)
+	}
 
 	err = lendingStateDB.RemoveLiquidationTime(lendingBook, lendingTradeId, lendingTrade.LiquidationTime)
 	if err != nil {
@@ -904,6 +1004,10 @@ func (l *Lending) LiquidationTrade(lendingStateDB *lendingstate.LendingStateDB,
 	if err != nil {
 		log.Warn("LiquidationTrade AddTokenBalance", "err", err, "lendingTrade.Investor", lendingTrade.Investor, "lendingTrade.CollateralLockedAmount", *lendingTrade.CollateralLockedAmount, "lendingTrade.CollateralToken", lendingTrade.CollateralToken)
 	}
+	if tracer := tradingstateDB.Tracer(); tracer != nil {
+		tracer.CaptureBalanceChange(common.HexToAddress(common.LendingLockAddress), lendingTrade.CollateralToken, new(big.Int).Neg(lendingTrade.CollateralLockedAmount), tradingstate.ReasonLiquidation)
+		tracer.CaptureBalanceChange(lendingTrade.Investor, lendingTrade.CollateralToken, lendingTrade.CollateralLockedAmount, tradingstate.ReasonLiquidation)
+	}
 	err = lendingStateDB.RemoveLiquidationTime(lendingBook, lendingTradeId, lendingTrade.LiquidationTime)
 	if err != nil {
 		log.Debug("LiquidationTrade RemoveLiquidationTime", "err", err)
@@ -1136,6 +1240,10 @@ func (l *Lending) ProcessTopUpLendingTrade(lendingStateDB *lendingstate.LendingS
 	if err != nil {
 		log.Warn("ProcessTopUpLendingTrade AddTokenBalance", "err", err, "LendingLockAddress", common.HexToAddress(common.LendingLockAddress), "quantity", *quantity, "lendingTrade.CollateralToken", lendingTrade.CollateralToken)
 	}
+	if tracer := tradingStateDb.Tracer(); tracer != nil {
+		tracer.CaptureBalanceChange(lendingTrade.Borrower, lendingTrade.CollateralToken, new(big.Int).Neg(quantity), tradingstate.ReasonCollateral)
+		tracer.CaptureBalanceChange(common.HexToAddress(common.LendingLockAddress), lendingTrade.CollateralToken, quantity, tradingstate.ReasonCollateral)
+	}
 	oldLockedAmount := lendingTrade.CollateralLockedAmount
 	newLockedAmount := new(big.Int).Add(quantity, oldLockedAmount)
 	newLiquidationPrice := new(big.Int).Mul(lendingTrade.LiquidationPrice, oldLockedAmount)
@@ -1206,6 +1314,12 @@ func (l *Lending) ProcessRepayLendingTrade(header *types.Header, chain consensus
 		if err != nil {
 			log.Warn("ProcessRepayLendingTrade AddTokenBalance", "err", err, "lendingTrade.Borrower", lendingTrade.Borrower, "lendingTrade.CollateralLockedAmount", *lendingTrade.CollateralLockedAmount, "lendingTrade.CollateralToken", lendingTrade.CollateralToken)
 		}
+		if tracer := tradingstateDB.Tracer(); tracer != nil {
+			tracer.CaptureBalanceChange(lendingTrade.Borrower, lendingTrade.LendingToken, new(big.Int).Neg(paymentBalance), tradingstate.ReasonRepay)
+			tracer.CaptureBalanceChange(lendingTrade.Investor, lendingTrade.LendingToken, paymentBalance, tradingstate.ReasonRepay)
+			tracer.CaptureBalanceChange(common.HexToAddress(common.LendingLockAddress), lendingTrade.CollateralToken, new(big.Int).Neg(lendingTrade.CollateralLockedAmount), tradingstate.ReasonRepay)
+			tracer.CaptureBalanceChange(lendingTrade.Borrower, lendingTrade.CollateralToken, lendingTrade.CollateralLockedAmount, tradingstate.ReasonRepay)
+		}
 
 		err = lendingStateDB.RemoveLiquidationTime(lendingBook, lendingTradeId, lendingTrade.LiquidationTime)
 		if err != nil {
@@ -1258,6 +1372,10 @@ func (l *Lending) ProcessRecallLendingTrade(lendingStateDB *lendingstate.Lending
 	if err != nil {
 		log.Warn("ProcessRecallLendingTrade SubTokenBalance", "err", err, "LendingLockAddress", common.HexToAddress(common.LendingLockAddress), "recallAmount", *recallAmount, "lendingTrade.CollateralToken", lendingTrade.CollateralToken)
 	}
+	if tracer := tradingStateDb.Tracer(); tracer != nil {
+		tracer.CaptureBalanceChange(lendingTrade.Borrower, lendingTrade.CollateralToken, recallAmount, tradingstate.ReasonCollateral)
+		tracer.CaptureBalanceChange(common.HexToAddress(common.LendingLockAddress), lendingTrade.CollateralToken, new(big.Int).Neg(recallAmount), tradingstate.ReasonCollateral)
+	}
 
 	lendingStateDB.UpdateLiquidationPrice(lendingBook, lendingTrade.TradeId, newLiquidationPrice)
 	lendingStateDB.UpdateCollateralLockedAmount(lendingBook, lendingTrade.TradeId, newLockedAmount)
@@ -1268,3 +1386,66 @@ func (l *Lending) ProcessRecallLendingTrade(lendingStateDB *lendingstate.Lending
 	log.Debug("ProcessRecall", "price", newLiquidationPrice, "lockAmount", newLockedAmount, "recall amount", recallAmount)
 	return nil, false, &newLendingTrade
 }
+
+// ProcessExpiredOrders cancels the resting lending items whose expiry time is
+// reached by the block, at most XDCx.MaximumExpiredOrders per lending book.
+// An expiration is free: no cancellation fee is charged.
+func (l *Lending) ProcessExpiredOrders(header *types.Header, statedb *state.StateDB, tradingState *tradingstate.TradingStateDB, lendingState *lendingstate.LendingStateDB) (expiredItems []*lendingstate.LendingItem, err error) {
+	expiredItems = []*lendingstate.LendingItem{}
+	tracer := tradingState.Tracer()
+	if tracer != nil {
+		tracer.CaptureStart(tradingstate.TraceKindExpiry, common.Hash{}, common.Hash{})
+		defer func() { tracer.CaptureEnd(err) }()
+	}
+	allLendingBooks, err := lendingstate.GetAllLendingBooks(statedb)
+	if err != nil {
+		log.Debug("Not found all lending books", "error", err)
+		return expiredItems, nil
+	}
+	time := header.Time.Uint64()
+	// sweep the books in a fixed order, all nodes report the expired items alike
+	books := make([]common.Hash, 0, len(allLendingBooks))
+	for book := range allLendingBooks {
+		books = append(books, book)
+	}
+	sort.Slice(books, func(i, j int) bool {
+		return bytes.Compare(books[i][:], books[j][:]) < 0
+	})
+	for _, lendingBook := range books {
+		// most books have no good-till-time item, don't open their expiry trie
+		if !lendingState.HasExpiryTimes(lendingBook) {
+			continue
+		}
+		processed := 0
+		for processed < XDCx.MaximumExpiredOrders {
+			expiresAt, lendingIds := lendingState.GetLowestExpiryTime(lendingBook, time)
+			if len(lendingIds) == 0 {
+				break
+			}
+			for _, lendingId := range lendingIds {
+				if processed >= XDCx.MaximumExpiredOrders {
+					break
+				}
+				processed++
+				if err := lendingState.RemoveExpiryTime(lendingBook, expiresAt, lendingId); err != nil {
+					return expiredItems, err
+				}
+				// the index isn't cleaned when an item is filled or cancelled
+				item := lendingState.GetLendingOrder(lendingBook, lendingId)
+				if item.Quantity == nil || item.Quantity.Sign() == 0 || item.ExpiresAt != expiresAt {
+					continue
+				}
+				if err := lendingState.CancelLendingOrder(lendingBook, &item); err != nil {
+					log.Error("Fail when cancel expired lending item", "lendingBook", lendingBook.Hex(), "lendingId", lendingId.Hex(), "error", err)
+					return expiredItems, err
+				}
+				item.Status = lendingstate.LendingStatusCancelled
+				expiredItems = append(expiredItems, &item)
+				if tracer != nil {
+					tracer.CaptureReject(item.Hash, "expired")
+				}
+			}
+		}
+	}
+	return expiredItems, nil
+}
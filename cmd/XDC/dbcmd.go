@@ -0,0 +1,78 @@
+package main
+
+import (
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/cmd/utils"
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:      "db",
+		Usage:     "Low level database operations",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "freeze",
+				Usage:     "Move the finalized blocks into the ancient store",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(freezeDB),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+				},
+				Description: `
+    XDC db freeze
+
+moves the headers, bodies, receipts and total difficulties of the blocks
+finalized by the consensus engine from the key-value store into the ancient
+store, then compacts the key-value store. The most recent epochs are kept in
+the key-value store, as a running node does. It migrates an existing datadir
+at once, instead of letting the node freeze the blocks in batches while it
+runs.`,
+			},
+		},
+	}
+)
+
+// freezeDB moves all the finalized blocks into the ancient store.
+func freezeDB(ctx *cli.Context) error {
+	stack, _ := makeFullNode(ctx)
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	limit := chain.FreezeLimit()
+	if limit == 0 {
+		log.Info("No finalized blocks to freeze")
+		return nil
+	}
+	start := time.Now()
+	first, err := db.Ancients()
+	if err != nil {
+		utils.Fatalf("Database has no ancient store: %v", err)
+	}
+	frozen := first
+	for frozen < limit {
+		if frozen, err = core.FreezeAncients(db, limit); err != nil {
+			utils.Fatalf("Failed to freeze ancient blocks: %v", err)
+		}
+	}
+	log.Info("Froze ancient blocks", "blocks", frozen-first, "frozen", frozen, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// The deleted blocks only release their space once compacted
+	start = time.Now()
+	log.Info("Compacting key-value store")
+	if err := db.Compact(nil, nil); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	log.Info("Compacted key-value store", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
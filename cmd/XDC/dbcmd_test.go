@@ -0,0 +1,116 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/core"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+)
+
+const freezeGenesis = `{
+	"config": {
+		"chainId": 102,
+		"XDPoS": {
+			"period": 2,
+			"epoch": 10,
+			"reward": 10,
+			"rewardCheckpoint": 10,
+			"gap": 5,
+			"v2": {
+				"switchBlock": 0,
+				"config": {"switchRound": 0, "minePeriod": 2, "timeoutSyncThreshold": 3, "timeoutPeriod": 10, "certificateThreshold": 0.667},
+				"allConfigs": {"0": {"switchRound": 0, "minePeriod": 2, "timeoutSyncThreshold": 3, "timeoutPeriod": 10, "certificateThreshold": 0.667}}
+			}
+		}
+	},
+	"extraData": "0x00000000000000000000000000000000000000000000000000000000000000005058dfe24ef6b537b5bc47116a45f0428da182fa0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+	"difficulty": "0x1",
+	"gasLimit": "0x47b760",
+	"alloc": {}
+}`
+
+// openFreezerDB opens the chain database of the datadir with its ancient store.
+func openFreezerDB(t *testing.T, datadir string) ethdb.Database {
+	path := filepath.Join(datadir, "XDC", "chaindata")
+	db, err := rawdb.NewLevelDBDatabaseWithFreezer(path, 0, 0, filepath.Join(path, "ancient"), "")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	return db
+}
+
+// Tests that the blocks committed by the QCs of the chain are frozen while the
+// node is offline, without an engine which committed any block.
+func TestFreezeDBOffline(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	json := filepath.Join(datadir, "genesis.json")
+	if err := os.WriteFile(json, []byte(freezeGenesis), 0600); err != nil {
+		t.Fatalf("Failed to write genesis file: %v", err)
+	}
+	runXDC(t, "--datadir", datadir, "init", json).WaitExit()
+
+	// Extend the chain with v2 blocks, block 39 is mined after a timeout so the
+	// latest committed block is 36 instead of 37
+	db := openFreezerDB(t, datadir)
+	parent := core.GetBlock(db, core.GetCanonicalHash(db, 0), 0).Header()
+	parentRound := types.Round(0)
+	for i := int64(1); i <= 40; i++ {
+		round := types.Round(i)
+		if i >= 39 {
+			round += 5
+		}
+		extra, err := rlp.EncodeToBytes(&types.ExtraFields_v2{
+			Round:      round,
+			QuorumCert: &types.QuorumCert{ProposedBlockInfo: &types.BlockInfo{Hash: parent.Hash(), Round: parentRound, Number: parent.Number}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to encode extra fields: %v", err)
+		}
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(i),
+			Root:       parent.Root,
+			Difficulty: big.NewInt(1),
+			GasLimit:   parent.GasLimit,
+			Extra:      append([]byte{2}, extra...),
+		}
+		block := types.NewBlockWithHeader(header)
+		if err := core.WriteBlock(db, block); err != nil {
+			t.Fatalf("Failed to write block %d: %v", i, err)
+		}
+		if err := core.WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(i+1)); err != nil {
+			t.Fatalf("Failed to write td %d: %v", i, err)
+		}
+		if err := core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), nil); err != nil {
+			t.Fatalf("Failed to write receipts %d: %v", i, err)
+		}
+		if err := core.WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
+			t.Fatalf("Failed to write canonical hash %d: %v", i, err)
+		}
+		parent, parentRound = header, round
+	}
+	core.WriteHeadBlockHash(db, parent.Hash())
+	core.WriteHeadHeaderHash(db, parent.Hash())
+	core.WriteHeadFastBlockHash(db, parent.Hash())
+	db.Close()
+
+	runXDC(t, "--datadir", datadir, "db", "freeze").WaitExit()
+
+	// Two epochs below the committed block are kept in the key-value store
+	db = openFreezerDB(t, datadir)
+	defer db.Close()
+	if frozen, err := db.Ancients(); err != nil || frozen != 17 {
+		t.Fatalf("Frozen blocks mismatch: have %d, %v, want %d", frozen, err, 17)
+	}
+	if hash := core.GetCanonicalHash(db, 40); hash != parent.Hash() {
+		t.Fatalf("Head block mismatch: have %x, want %x", hash, parent.Hash())
+	}
+}
@@ -58,6 +58,7 @@ var (
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
 		utils.KeyStoreDirFlag,
 		//utils.NoUSBFlag,
 		//utils.EthashCacheDirFlag,
@@ -85,7 +86,14 @@ var (
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
+		utils.CheckpointFileFlag,
 		utils.GCModeFlag,
+		utils.StatePruneFlag,
+		utils.StatePruneEpochsFlag,
+		utils.BloomFilterSizeFlag,
+		utils.SnapshotFlag,
+		utils.CacheSnapshotFlag,
+		utils.ParallelTxsFlag,
 		//utils.LightServFlag,
 		//utils.LightPeersFlag,
 		//utils.LightKDFFlag,
@@ -100,6 +108,7 @@ var (
 		utils.GasPriceFlag,
 		utils.StakerThreadsFlag,
 		utils.StakingEnabledFlag,
+		utils.BLSKeyFileFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -166,6 +175,10 @@ func init() {
 		exportCommand,
 		removedbCommand,
 		dumpCommand,
+		// See dbcmd.go:
+		dbCommand,
+		// See snapshot.go:
+		snapshotCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -347,7 +360,7 @@ func startNode(ctx *cli.Context, stack *node.Node, cfg XDCConfig) {
 				log.Info("Checkpoint!!! It's time to reconcile node's state...")
 				log.Info("Update consensus parameters")
 				chain := ethereum.BlockChain()
-				engine.UpdateParams(chain.CurrentHeader())
+				engine.UpdateParams(chain, chain.CurrentHeader())
 				if common.IsTestnet {
 					ok, err = ethereum.ValidateMasternodeTestnet()
 					if err != nil {
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/XDCx"
+	"github.com/XinFinOrg/XDC-Subnet/XDCxlending"
+	"github.com/XinFinOrg/XDC-Subnet/cmd/utils"
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
+	xdposutils "github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:        "snapshot",
+		Usage:       "A set of commands based on the state",
+		ArgsUsage:   "",
+		Category:    "MISCELLANEOUS COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Prune the stale states",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(pruneState),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.XDCXDataDirFlag,
+					utils.StatePruneEpochsFlag,
+					utils.BloomFilterSizeFlag,
+				},
+				Description: `
+    XDC snapshot prune-state
+
+deletes the trie nodes and contract codes which are only reachable from the
+states older than the last --prune.epochs epochs finalized by the consensus
+engine, for the main state as well as the XDCx trading and lending states.
+The same pruning runs in the background
+of a full node started with --prune.state, without any downtime.
+
+The live states are recorded into a bloom filter of --bloomfilter.size
+megabytes, a larger filter keeps less stale data.`,
+			},
+		},
+	}
+)
+
+// pruneState prunes the stale states of a stopped node.
+func pruneState(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+
+	// The XDCx states are pruned along the main state, open their services
+	XDCX := XDCx.New(&cfg.XDCX)
+	defer XDCX.GetLevelDB().Close()
+	lending := XDCxlending.New(XDCX)
+	if engine, ok := chain.Engine().(*XDPoS.XDPoS); ok {
+		engine.GetXDCXService = func() xdposutils.TradingService { return XDCX }
+		engine.GetLendingService = func() xdposutils.LendingService { return lending }
+	}
+	defer chain.Stop()
+
+	start := time.Now()
+	if err := chain.PruneState(ctx.GlobalUint64(utils.StatePruneEpochsFlag.Name), ctx.GlobalUint64(utils.BloomFilterSizeFlag.Name)); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	log.Info("Pruned stale states", "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// The deleted entries only release their space once compacted
+	start = time.Now()
+	log.Info("Compacting databases")
+	if err := db.Compact(nil, nil); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	if err := XDCX.GetLevelDB().Compact(nil, nil); err != nil {
+		utils.Fatalf("XDCx compaction failed: %v", err)
+	}
+	log.Info("Compacted databases", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -67,12 +67,20 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.KeyStoreDirFlag,
 			//utils.NoUSBFlag,
 			utils.NetworkIdFlag,
 			//utils.TestnetFlag,
 			utils.SyncModeFlag,
+			utils.CheckpointFileFlag,
 			utils.GCModeFlag,
+			utils.StatePruneFlag,
+			utils.StatePruneEpochsFlag,
+			utils.BloomFilterSizeFlag,
+			utils.SnapshotFlag,
+			utils.CacheSnapshotFlag,
+			utils.ParallelTxsFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			//utils.LightServFlag,
@@ -186,6 +194,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
+			utils.BLSKeyFileFlag,
 		},
 	},
 	//{
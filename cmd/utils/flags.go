@@ -36,6 +36,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/ethash"
 	"github.com/XinFinOrg/XDC-Subnet/core"
+	"github.com/XinFinOrg/XDC-Subnet/core/state/pruner"
 	"github.com/XinFinOrg/XDC-Subnet/core/vm"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
 	"github.com/XinFinOrg/XDC-Subnet/eth"
@@ -129,6 +130,10 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for the ancient blocks (default = inside chaindata)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -181,11 +186,33 @@ var (
 		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
 		Value: &defaultSyncMode,
 	}
+	CheckpointFileFlag = cli.StringFlag{
+		Name:  "checkpoint.file",
+		Usage: "Fast sync from the trusted XDPoS checkpoint in the JSON file returned by XDPoS_getCheckpoint",
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	StatePruneFlag = cli.BoolFlag{
+		Name:  "prune.state",
+		Usage: "Prune the stale states in the background, keeping the states of the last --prune.epochs finalized epochs",
+	}
+	StatePruneEpochsFlag = cli.Uint64Flag{
+		Name:  "prune.epochs",
+		Usage: "Number of finalized epochs whose states are kept by the state pruning",
+		Value: core.DefaultStatePruneEpochs,
+	}
+	BloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter of the live states during the state pruning",
+		Value: pruner.DefaultBloomSize,
+	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Maintain a flat snapshot of the state to serve the state reads without the trie",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -301,6 +328,15 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	CacheSnapshotFlag = cli.IntFlag{
+		Name:  "cache.snapshot",
+		Usage: "Megabytes of memory allocated to the state snapshot entries (with --snapshot)",
+		Value: 256,
+	}
+	ParallelTxsFlag = cli.IntFlag{
+		Name:  "parallel.txs",
+		Usage: "Number of goroutines executing the transactions of a block optimistically in parallel (0 = sequential)",
+	}
 	// Miner settings
 	StakingEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -330,6 +366,10 @@ var (
 		Name:  "extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	BLSKeyFileFlag = cli.StringFlag{
+		Name:  "bls.keyfile",
+		Usage: "BLS secret key file to sign votes and timeouts after the BLS fork",
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -1110,6 +1150,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	case ctx.GlobalBool(LightModeFlag.Name):
 		cfg.SyncMode = downloader.LightSync
 	}
+	if ctx.GlobalIsSet(CheckpointFileFlag.Name) {
+		if cfg.SyncMode == downloader.LightSync {
+			Fatalf("--%s is not supported in light sync mode", CheckpointFileFlag.Name)
+		}
+		// the state before the checkpoint is never synced, only a fast sync can start from it
+		cfg.CheckpointFile = ctx.GlobalString(CheckpointFileFlag.Name)
+		cfg.SyncMode = downloader.FastSync
+	}
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
@@ -1124,12 +1172,38 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = MakeDatabaseHandles()
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
+	if ctx.GlobalBool(StatePruneFlag.Name) {
+		if cfg.NoPruning {
+			Fatalf("--%s is not supported by archive nodes", StatePruneFlag.Name)
+		}
+		if ctx.GlobalUint64(StatePruneEpochsFlag.Name) == 0 {
+			Fatalf("--%s must be positive", StatePruneEpochsFlag.Name)
+		}
+		cfg.StatePruneEpochs = ctx.GlobalUint64(StatePruneEpochsFlag.Name)
+		cfg.StatePruneBloomSize = ctx.GlobalUint64(BloomFilterSizeFlag.Name)
+	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		if ctx.GlobalInt(CacheSnapshotFlag.Name) <= 0 {
+			Fatalf("--%s must be positive", CacheSnapshotFlag.Name)
+		}
+		cfg.SnapshotCache = ctx.GlobalInt(CacheSnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(ParallelTxsFlag.Name) {
+		if ctx.GlobalInt(ParallelTxsFlag.Name) < 0 {
+			Fatalf("--%s must not be negative", ParallelTxsFlag.Name)
+		}
+		cfg.ParallelTxs = ctx.GlobalInt(ParallelTxsFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
@@ -1142,6 +1216,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(ExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(ExtraDataFlag.Name))
 	}
+	if ctx.GlobalIsSet(BLSKeyFileFlag.Name) {
+		cfg.BLSKeyFile = ctx.GlobalString(BLSKeyFileFlag.Name)
+	}
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 		if len(cfg.GasPrice.Bits()) == 0 { //IsUint64() && cfg.GasPrice.Uint64() == 0 {
@@ -1205,11 +1282,15 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 		cache   = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 		handles = MakeDatabaseHandles()
 	)
-	name := "chaindata"
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
 	if ctx.GlobalBool(LightModeFlag.Name) {
-		name = "lightchaindata"
+		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "")
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.GlobalString(AncientFlag.Name), "")
 	}
-	chainDb, err := stack.OpenDatabase(name, cache, handles, "")
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/XinFinOrg/XDC-Subnet/common/mclock"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 )
 
@@ -14,6 +15,10 @@ type CountdownTimer struct {
 	quitc           chan chan struct{}
 	initilised      bool
 	timeoutDuration time.Duration
+	// Clock driving the countdown instead of the long running goroutine, set by NewCountDownWithClock
+	clock      mclock.Clock
+	timer      mclock.Timer
+	generation uint64 // Bumped on every reset, so a timer fired in the same clock step as the reset is ignored
 	// Triggered when the countdown timer timeout for the `timeoutDuration` period, it will pass current timestamp to the callback function
 	OnTimeoutFn func(time time.Time, i interface{}) error
 }
@@ -27,8 +32,22 @@ func NewCountDown(duration time.Duration) *CountdownTimer {
 	}
 }
 
+// NewCountDownWithClock creates a countdown timer driven by the given clock. The OnTimeoutFn is called on the
+// goroutine advancing the clock, so the timeouts are reproducible with a simulated clock.
+func NewCountDownWithClock(duration time.Duration, clock mclock.Clock) *CountdownTimer {
+	return &CountdownTimer{
+		initilised:      false,
+		timeoutDuration: duration,
+		clock:           clock,
+	}
+}
+
 // Completely stop the countdown timer from running.
 func (t *CountdownTimer) StopTimer() {
+	if t.clock != nil {
+		t.stopClockTimer()
+		return
+	}
 	q := make(chan struct{})
 	t.quitc <- q
 	<-q
@@ -40,6 +59,10 @@ func (t *CountdownTimer) SetTimeoutDuration(duration time.Duration) {
 
 // Reset will start the countdown timer if it's already stopped, or simply reset the countdown time back to the defual `duration`
 func (t *CountdownTimer) Reset(i interface{}) {
+	if t.clock != nil {
+		t.resetClockTimer(i)
+		return
+	}
 	if !t.isInitilised() {
 		t.setInitilised(true)
 		go t.startTimer(i)
@@ -77,6 +100,49 @@ func (t *CountdownTimer) startTimer(i interface{}) {
 	}
 }
 
+// Restart the countdown on the clock, the timer scheduled before is discarded
+func (t *CountdownTimer) resetClockTimer(i interface{}) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.generation++
+	t.initilised = true
+	t.scheduleClockTimer(t.generation, i)
+}
+
+// Schedule the timeout on the clock, the lock must be held
+func (t *CountdownTimer) scheduleClockTimer(generation uint64, i interface{}) {
+	t.timer = t.clock.AfterFunc(t.timeoutDuration, func() {
+		t.lock.Lock()
+		if generation != t.generation {
+			t.lock.Unlock()
+			return
+		}
+		// Keep counting down until the next reset, as the goroutine timer does
+		t.scheduleClockTimer(generation, i)
+		t.lock.Unlock()
+
+		log.Debug("Countdown time reached!")
+		err := t.OnTimeoutFn(time.Now(), i)
+		if err != nil {
+			log.Error("OnTimeoutFn error", "error", err)
+		}
+	})
+}
+
+func (t *CountdownTimer) stopClockTimer() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.generation++
+	t.initilised = false
+}
+
 // Set the desired value to Initilised with lock to avoid race condition
 func (t *CountdownTimer) setInitilised(value bool) {
 	t.lock.Lock()
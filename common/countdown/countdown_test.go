@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/XinFinOrg/XDC-Subnet/common/mclock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -156,3 +157,39 @@ func TestCountdownShouldAvoidDeadlock(t *testing.T) {
 	countdown.Reset(fakeI)
 	<-called
 }
+
+func TestCountdownWithClock(t *testing.T) {
+	var fakeI interface{}
+	clock := &mclock.Simulated{}
+	called := 0
+	countdown := NewCountDownWithClock(5000*time.Millisecond, clock)
+	countdown.OnTimeoutFn = func(time.Time, interface{}) error {
+		called++
+		return nil
+	}
+	assert.False(t, countdown.isInitilised())
+	countdown.Reset(fakeI)
+	assert.True(t, countdown.isInitilised())
+
+	// Reset before the timeout postpones it
+	clock.Run(4000 * time.Millisecond)
+	countdown.Reset(fakeI)
+	clock.Run(4000 * time.Millisecond)
+	assert.Equal(t, 0, called)
+	clock.Run(1000 * time.Millisecond)
+	assert.Equal(t, 1, called)
+
+	// Keeps counting down after the timeout
+	clock.Run(5000 * time.Millisecond)
+	assert.Equal(t, 2, called)
+
+	// A reset in the same clock step as the timeout discards it
+	clock.AfterFunc(4999*time.Millisecond, func() { countdown.Reset(fakeI) })
+	clock.Run(5000 * time.Millisecond)
+	assert.Equal(t, 2, called)
+
+	countdown.StopTimer()
+	assert.False(t, countdown.isInitilised())
+	clock.Run(10000 * time.Millisecond)
+	assert.Equal(t, 2, called)
+}
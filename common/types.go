@@ -33,6 +33,10 @@ const (
 	BlockSigners                     = "0x0000000000000000000000000000000000000089"
 	MasternodeVotingSMC              = "0x0000000000000000000000000000000000000088"
 	RandomizeSMC                     = "0x0000000000000000000000000000000000000090"
+	SlashingSMC                      = "0x0000000000000000000000000000000000000095"
+	BLSRegistrySMC                   = "0x0000000000000000000000000000000000000096"
+	ConsensusConfigSMC               = "0x0000000000000000000000000000000000000097"
+	BaseFeePoolAddr                  = "0x0000000000000000000000000000000000000098"
 	FoudationAddr                    = "0x0000000000000000000000000000000000000068"
 	TeamAddr                         = "0x0000000000000000000000000000000000000099"
 	XDCXAddr                         = "0x0000000000000000000000000000000000000091"
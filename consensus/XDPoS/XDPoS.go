@@ -29,6 +29,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/consensus/clique"
 	"github.com/XinFinOrg/XDC-Subnet/core/state"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
 	"github.com/XinFinOrg/XDC-Subnet/ethdb"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 	"github.com/XinFinOrg/XDC-Subnet/params"
@@ -142,8 +143,8 @@ func NewFaker(db ethdb.Database, chainConfig *params.ChainConfig) *XDPoS {
 }
 
 // Reset parameters after checkpoint due to config may change
-func (x *XDPoS) UpdateParams(header *types.Header) {
-	x.EngineV2.UpdateParams(header)
+func (x *XDPoS) UpdateParams(chain consensus.ChainReader, header *types.Header) {
+	x.EngineV2.UpdateParams(chain, header)
 }
 
 func (x *XDPoS) Initial(chain consensus.ChainReader, header *types.Header) error {
@@ -247,6 +248,10 @@ func (x *XDPoS) Authorize(signer common.Address, signFn clique.SignerFn) {
 	x.EngineV2.Authorize(signer, signFn)
 }
 
+func (x *XDPoS) AuthorizeBLS(key *bls.SecretKey) {
+	x.EngineV2.AuthorizeBLS(key)
+}
+
 func (x *XDPoS) GetPeriod() uint64 {
 	return x.config.Period
 }
@@ -309,6 +314,15 @@ func (x *XDPoS) CalculateMissingRounds(chain consensus.ChainReader, header *type
 	return x.EngineV2.CalculateMissingRounds(chain, header)
 }
 
+func (x *XDPoS) GetQCSigners(chain consensus.ChainReader, header *types.Header) ([]common.Address, error) {
+	return x.EngineV2.GetQCSigners(chain, header)
+}
+
+// Verify a vote equivocation evidence and return the masternode who signed both votes
+func (x *XDPoS) VerifyVoteEquivocation(chain consensus.ChainReader, evidence *types.SlashingEvidence) (common.Address, error) {
+	return x.EngineV2.VerifyVoteEquivocation(chain, evidence)
+}
+
 // Same DB across all consensus engines
 func (x *XDPoS) GetDb() ethdb.Database {
 	return x.db
@@ -16,11 +16,13 @@
 package XDPoS
 
 import (
+	"context"
 	"encoding/base64"
 	"math/big"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/engines/engine_v2"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/params"
@@ -272,6 +274,95 @@ func (api *API) GetMissedRoundsInEpochByBlockNum(number *rpc.BlockNumber) (*util
 	return api.XDPoS.CalculateMissingRounds(api.chain, api.getHeaderFromApiBlockNum(number))
 }
 
+// GetEpochConfig returns the consensus config in use after the block, and the config voted at the gap block
+// of its epoch in the config contract, pending until the next epoch switch block.
+func (api *API) GetEpochConfig(number *rpc.BlockNumber) (*utils.PublicApiEpochConfig, error) {
+	header := api.getHeaderFromApiBlockNum(number)
+	if header == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+	return api.XDPoS.EngineV2.GetEpochConfig(api.chain, header)
+}
+
+// GetEpochProof returns the proof that the masternodes of the epoch are finalised, which can be verified
+// by the lightclient package with the masternodes of the previous epoch only.
+func (api *API) GetEpochProof(epoch uint64) (*types.EpochProof, error) {
+	return api.XDPoS.EngineV2.GetEpochProof(api.chain, epoch)
+}
+
+// GetCheckpoint returns the checkpoint of the epoch switch block of the given block, committed block by default.
+// A new node can fast sync from it with --checkpoint.file instead of syncing all the headers from the genesis block.
+func (api *API) GetCheckpoint(number *rpc.BlockNumber) (*engine_v2.Checkpoint, error) {
+	if number == nil {
+		committed := rpc.CommittedBlockNumber
+		number = &committed
+	}
+	header := api.getHeaderFromApiBlockNum(number)
+	if header == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+	header = api.chain.GetHeaderByNumber(header.Number.Uint64() - header.Number.Uint64()%api.XDPoS.config.Epoch)
+	if header == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+	chain, ok := api.chain.(interface {
+		GetTd(common.Hash, uint64) *big.Int
+	})
+	if !ok {
+		return nil, utils.ErrUnknownBlock
+	}
+	td := chain.GetTd(header.Hash(), header.Number.Uint64())
+	if td == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+	return api.XDPoS.EngineV2.GetCheckpoint(api.chain, header, td)
+}
+
+// GetForensicProofs returns the forensic proofs stored by the forensics module for blocks in the range
+// [fromBlock, toBlock]. forensicsType is optional and filters by proof type, i.e "QC" or "Vote".
+func (api *API) GetForensicProofs(fromBlock, toBlock *rpc.BlockNumber, forensicsType *string) ([]*types.ForensicProof, error) {
+	from := api.getHeaderFromApiBlockNum(fromBlock)
+	to := api.getHeaderFromApiBlockNum(toBlock)
+	if from == nil || to == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+	proofType := ""
+	if forensicsType != nil {
+		proofType = *forensicsType
+	}
+	return api.XDPoS.EngineV2.ForensicsProcessor.GetForensicProofs(from.Number.Uint64(), to.Number.Uint64(), proofType)
+}
+
+// ForensicProofs creates a subscription that is triggered each time the forensics module generates a new proof.
+func (api *API) ForensicProofs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		forensicsEventCh := make(chan types.ForensicsEvent)
+		sub := api.XDPoS.SubscribeForensicsEvent(forensicsEventCh)
+
+		for {
+			select {
+			case ev := <-forensicsEventCh:
+				notifier.Notify(rpcSub.ID, ev.ForensicsProof)
+			case <-rpcSub.Err():
+				sub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				sub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 func (api *API) getHeaderFromApiBlockNum(number *rpc.BlockNumber) *types.Header {
 	var header *types.Header
 	if number == nil || *number == rpc.LatestBlockNumber {
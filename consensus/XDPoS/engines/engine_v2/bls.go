@@ -0,0 +1,189 @@
+package engine_v2
+
+import (
+	"fmt"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/sha3"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+)
+
+/*
+After the BLS fork, votes and timeouts carry a BLS signature next to the secp256k1 one, which still identifies the signer.
+QC and TC only carry one aggregated BLS signature and a bitmap of the signers, it is verified with a single pairing check
+against the aggregated BLS public keys that the signers registered in the validator state at the gap block.
+*/
+
+// AuthorizeBLS injects the BLS secret key used to sign votes and timeouts after the BLS fork
+func (x *XDPoS_v2) AuthorizeBLS(key *bls.SecretKey) {
+	x.signLock.Lock()
+	defer x.signLock.Unlock()
+
+	x.blsKey = key
+}
+
+// Sign the message hash by BLS key if the BLS fork is enabled at the gap number, otherwise return nil
+func (x *XDPoS_v2) signBLSSignature(gapNumber uint64, signingHash common.Hash) ([]types.Signature, error) {
+	if !x.config.V2.IsBLS(gapNumber) {
+		return nil, nil
+	}
+	x.signLock.RLock()
+	key := x.blsKey
+	x.signLock.RUnlock()
+
+	if key == nil {
+		return nil, fmt.Errorf("BLS key is not authorized, it's required from gap number %v", gapNumber)
+	}
+	return []types.Signature{key.Sign(signingHash.Bytes()).Bytes()}, nil
+}
+
+// Verify the BLS signature of a vote or timeout message against the key registered by its signer
+func verifyBLSMessage(snap *SnapshotV2, signer common.Address, signedHash common.Hash, signatures []types.Signature) error {
+	if len(signatures) != 1 {
+		return &utils.ErrInvalidSignature{Err: fmt.Errorf("expect one BLS signature, got %v", len(signatures))}
+	}
+	publicKey, err := snap.blsPublicKey(signer)
+	if err != nil {
+		return err
+	}
+	signature, err := bls.SignatureFromBytes(signatures[0])
+	if err != nil {
+		return &utils.ErrInvalidSignature{Err: err}
+	}
+	if !bls.Verify(publicKey, signedHash.Bytes(), signature) {
+		return &utils.ErrInvalidSignature{Err: fmt.Errorf("BLS signature of %v mismatch", signer.Hex())}
+	}
+	return nil
+}
+
+// Aggregate the BLS signatures of the messages, the signer bitmap is indexed by the masternodes list
+func aggregateBLSSignatures(masternodes []common.Address, messages map[common.Address][]types.Signature) (*types.AggregatedSignature, int, error) {
+	aggregated := &types.AggregatedSignature{
+		SignerBitmap: make([]byte, (len(masternodes)+7)/8),
+	}
+	var signatures []*bls.Signature
+	for i, masternode := range masternodes {
+		blsSignature, ok := messages[masternode]
+		if !ok || aggregated.HasSigner(i) {
+			continue
+		}
+		if len(blsSignature) != 1 {
+			log.Warn("[aggregateBLSSignatures] Skip message without BLS signature", "signer", masternode.Hex())
+			continue
+		}
+		signature, err := bls.SignatureFromBytes(blsSignature[0])
+		if err != nil {
+			log.Warn("[aggregateBLSSignatures] Skip message with invalid BLS signature", "signer", masternode.Hex(), "err", err)
+			continue
+		}
+		aggregated.SetSigner(i)
+		signatures = append(signatures, signature)
+	}
+	if len(signatures) == 0 {
+		return nil, 0, fmt.Errorf("no BLS signature to aggregate")
+	}
+	aggregated.Signature = bls.AggregateSignatures(signatures).Bytes()
+	return aggregated, len(signatures), nil
+}
+
+// Verify the aggregated BLS signature of a QC or TC and return its signers.
+// The signer bitmap is indexed by the masternodes list, and their keys are taken from the snapshot of the gap number.
+func verifyAggregatedSignature(snap *SnapshotV2, masternodes []common.Address, signedHash common.Hash, aggregated []*types.AggregatedSignature) ([]common.Address, error) {
+	if len(aggregated) != 1 || aggregated[0] == nil {
+		return nil, &utils.ErrInvalidSignature{Err: fmt.Errorf("expect one aggregated signature, got %v", len(aggregated))}
+	}
+	signers, err := aggregatedSigners(masternodes, aggregated[0])
+	if err != nil {
+		return nil, &utils.ErrInvalidSignature{Err: err}
+	}
+	publicKeys := make([]*bls.PublicKey, 0, len(signers))
+	for _, signer := range signers {
+		publicKey, err := snap.blsPublicKey(signer)
+		if err != nil {
+			return nil, err
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	signature, err := bls.SignatureFromBytes(aggregated[0].Signature)
+	if err != nil {
+		return nil, &utils.ErrInvalidSignature{Err: err}
+	}
+	if !bls.VerifyAggregate(publicKeys, signedHash.Bytes(), signature) {
+		return nil, &utils.ErrInvalidSignature{Err: fmt.Errorf("aggregated BLS signature mismatch")}
+	}
+	return signers, nil
+}
+
+// Decode the signers from the bitmap of the aggregated signature
+func aggregatedSigners(masternodes []common.Address, aggregated *types.AggregatedSignature) ([]common.Address, error) {
+	if len(aggregated.SignerBitmap) != (len(masternodes)+7)/8 {
+		return nil, fmt.Errorf("signer bitmap length %v mismatch with %v masternodes", len(aggregated.SignerBitmap), len(masternodes))
+	}
+	signers := []common.Address{}
+	for i := 0; i < len(aggregated.SignerBitmap)*8; i++ {
+		if !aggregated.HasSigner(i) {
+			continue
+		}
+		if i >= len(masternodes) {
+			return nil, fmt.Errorf("signer bitmap index %v out of %v masternodes", i, len(masternodes))
+		}
+		signers = append(signers, masternodes[i])
+	}
+	return signers, nil
+}
+
+// Key of the QC in the signers cache, the same block can be certified by different sets of signatures
+func qcSignersKey(quorumCert *types.QuorumCert) common.Hash {
+	hasher := sha3.NewKeccak256()
+	hasher.Write(types.VoteSigHash(&types.VoteForSign{
+		ProposedBlockInfo: quorumCert.ProposedBlockInfo,
+		GapNumber:         quorumCert.GapNumber,
+	}).Bytes())
+	for _, signature := range quorumCert.Signatures {
+		hasher.Write(signature)
+	}
+	var hash common.Hash
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// Get the signers of a QC, from the signer bitmap after the BLS fork, otherwise by recovering each signature.
+// The recovered signers are cached, they are usually already cached by the QC verification.
+func (x *XDPoS_v2) getQCSigners(chain consensus.ChainReader, quorumCert *types.QuorumCert) ([]common.Address, error) {
+	signers := []common.Address{}
+	if len(quorumCert.Aggregated) > 0 {
+		if quorumCert.Aggregated[0] == nil {
+			return nil, fmt.Errorf("empty aggregated signature")
+		}
+		epochInfo, err := x.getEpochSwitchInfo(chain, nil, quorumCert.ProposedBlockInfo.Hash)
+		if err != nil {
+			return nil, err
+		}
+		return aggregatedSigners(epochInfo.Masternodes, quorumCert.Aggregated[0])
+	}
+	signedHash := types.VoteSigHash(&types.VoteForSign{
+		ProposedBlockInfo: quorumCert.ProposedBlockInfo,
+		GapNumber:         quorumCert.GapNumber,
+	})
+	key := qcSignersKey(quorumCert)
+	if cached, ok := x.qcSigners.Get(key); ok {
+		return cached.([]common.Address), nil
+	}
+	signatures, _ := UniqueSignatures(quorumCert.Signatures)
+	for _, signature := range signatures {
+		pubkey, err := crypto.Ecrecover(signedHash.Bytes(), signature)
+		if err != nil {
+			return nil, fmt.Errorf("Error while recovering QC signer: %v", err)
+		}
+		var signer common.Address
+		copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+		signers = append(signers, signer)
+	}
+	x.qcSigners.Add(key, signers)
+	return signers, nil
+}
@@ -0,0 +1,102 @@
+package engine_v2
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregateAndVerifyBLSSignatures(t *testing.T) {
+	masterNodes := []common.Address{{0x1}, {0x2}, {0x3}, {0x4}, {0x5}, {0x6}, {0x7}, {0x8}, {0x9}}
+	snap := newSnapshot(450, common.Hash{0x1}, masterNodes, nil, nil)
+	snap.NextEpochBLSKeys = make(map[common.Address]hexutil.Bytes)
+
+	signedHash := types.VoteSigHash(&types.VoteForSign{
+		ProposedBlockInfo: &types.BlockInfo{Hash: common.Hash{0x2}, Round: 10},
+		GapNumber:         450,
+	})
+	messages := make(map[common.Address][]types.Signature)
+	for i, masterNode := range masterNodes {
+		key, err := bls.GenerateKey()
+		assert.Nil(t, err)
+		snap.NextEpochBLSKeys[masterNode] = key.PublicKey().Bytes()
+		// the first and the last masternodes don't vote
+		if i > 0 && i < len(masterNodes)-1 {
+			messages[masterNode] = []types.Signature{key.Sign(signedHash.Bytes()).Bytes()}
+		}
+	}
+	// a message signed by a non masternode is ignored
+	messages[common.Address{0xa}] = []types.Signature{messages[masterNodes[1]][0]}
+
+	for _, signer := range masterNodes[1:3] {
+		assert.Nil(t, verifyBLSMessage(snap, signer, signedHash, messages[signer]))
+	}
+	assert.NotNil(t, verifyBLSMessage(snap, masterNodes[1], signedHash, messages[masterNodes[2]]))
+
+	aggregated, count, err := aggregateBLSSignatures(masterNodes, messages)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, count)
+	assert.Equal(t, 2, len(aggregated.SignerBitmap))
+	assert.False(t, aggregated.HasSigner(0))
+	assert.False(t, aggregated.HasSigner(8))
+
+	signers, err := verifyAggregatedSignature(snap, masterNodes, signedHash, []*types.AggregatedSignature{aggregated})
+	assert.Nil(t, err)
+	assert.Equal(t, masterNodes[1:8], signers)
+
+	// claiming an extra signer must fail the pairing check
+	forged := &types.AggregatedSignature{SignerBitmap: common.CopyBytes(aggregated.SignerBitmap), Signature: aggregated.Signature}
+	forged.SetSigner(0)
+	_, err = verifyAggregatedSignature(snap, masterNodes, signedHash, []*types.AggregatedSignature{forged})
+	assert.NotNil(t, err)
+
+	// a bitmap indexed by another masternode list is rejected
+	_, err = verifyAggregatedSignature(snap, masterNodes[:8], signedHash, []*types.AggregatedSignature{aggregated})
+	assert.NotNil(t, err)
+
+	// a different message must fail
+	_, err = verifyAggregatedSignature(snap, masterNodes, common.Hash{0x3}, []*types.AggregatedSignature{aggregated})
+	assert.NotNil(t, err)
+}
+
+func TestQCSignersCache(t *testing.T) {
+	qcSigners, _ := lru.NewARC(16)
+	x := &XDPoS_v2{qcSigners: qcSigners}
+
+	blockInfo := &types.BlockInfo{Hash: common.Hash{0x2}, Round: 10, Number: big.NewInt(910)}
+	signedHash := types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: blockInfo, GapNumber: 450})
+	var (
+		signatures []types.Signature
+		addrs      []common.Address
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		signature, err := crypto.Sign(signedHash.Bytes(), key)
+		assert.Nil(t, err)
+		signatures = append(signatures, signature)
+		addrs = append(addrs, crypto.PubkeyToAddress(key.PublicKey))
+	}
+	qc := &types.QuorumCert{ProposedBlockInfo: blockInfo, Signatures: signatures, GapNumber: 450}
+	signers, err := x.getQCSigners(nil, qc)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, addrs, signers)
+
+	// the signers are served from the cache
+	cached, ok := x.qcSigners.Get(qcSignersKey(qc))
+	assert.True(t, ok)
+	assert.ElementsMatch(t, addrs, cached)
+
+	// another set of signatures of the same block isn't mixed up with it
+	other := &types.QuorumCert{ProposedBlockInfo: blockInfo, Signatures: signatures[:2], GapNumber: 450}
+	assert.NotEqual(t, qcSignersKey(qc), qcSignersKey(other))
+	signers, err = x.getQCSigners(nil, other)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, addrs[:2], signers)
+}
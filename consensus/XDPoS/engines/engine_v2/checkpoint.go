@@ -0,0 +1,206 @@
+package engine_v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+/*
+A new node can sync the headers from a trusted epoch switch block instead of the genesis block:
+  - The checkpoint carries the snapshot of the gap block before it, which gives the epoch switch info of its epoch
+  - The headers after it are verified by their QCs. Without the state of the gap blocks, the snapshot of the next epoch
+    is built from the candidates and penalties recorded in the gap plus one header, certified by the masternodes
+  - The state is synced at a recent pivot block, the blocks after it are fully verified
+  - The checkpoint and the snapshots built from headers are stored, so the sync goes on from them after a restart
+
+Stakes, BLS keys and the voted config of the epochs synced by headers only are unknown, so the masternodes selected by stake
+are only checked against the candidates, and QCs aggregated after the BLS fork can't be verified.
+*/
+type Checkpoint struct {
+	Number          uint64      `json:"number"`
+	Hash            common.Hash `json:"hash"`
+	TotalDifficulty *big.Int    `json:"totalDifficulty"`
+	Snapshot        *SnapshotV2 `json:"snapshot"`
+}
+
+var checkpointKey = []byte("XDPoS-V2-checkpoint")
+
+// LoadCheckpoint reads the JSON checkpoint returned by XDPoS_getCheckpoint from a file
+func LoadCheckpoint(file string) (*Checkpoint, error) {
+	blob, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(Checkpoint)
+	if err := json.Unmarshal(blob, checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// loadCheckpoint loads the checkpoint the chain is synced from, stored by SetCheckpoint
+func loadCheckpoint(db ethdb.Database) (*Checkpoint, error) {
+	blob, err := db.Get(checkpointKey)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := new(Checkpoint)
+	if err := json.Unmarshal(blob, checkpoint); err != nil {
+		return nil, err
+	}
+	if checkpoint.Snapshot == nil {
+		return nil, fmt.Errorf("checkpoint %v has no snapshot", checkpoint.Number)
+	}
+	return checkpoint, nil
+}
+
+// storeCheckpoint inserts the checkpoint into the database.
+func storeCheckpoint(checkpoint *Checkpoint, db ethdb.Database) error {
+	blob, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	return db.Put(checkpointKey, blob)
+}
+
+// TrustedCheckpoint returns the checkpoint the chain is synced from, nil if synced from the genesis block
+func (x *XDPoS_v2) TrustedCheckpoint() *Checkpoint {
+	return x.checkpoint
+}
+
+// Get the checkpoint of the epoch switch block, to be exported by a synced node
+func (x *XDPoS_v2) GetCheckpoint(chain consensus.ChainReader, header *types.Header, td *big.Int) (*Checkpoint, error) {
+	isEpochSwitch, _, err := x.IsEpochSwitch(header)
+	if err != nil {
+		return nil, err
+	}
+	if !isEpochSwitch || header.Number.Cmp(x.config.V2.SwitchBlock) <= 0 {
+		return nil, fmt.Errorf("block %v is not an epoch switch block of consensus v2", header.Number)
+	}
+	snap, err := x.getSnapshot(chain, header.Number.Uint64(), false)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{
+		Number:          header.Number.Uint64(),
+		Hash:            header.Hash(),
+		TotalDifficulty: td,
+		Snapshot:        snap,
+	}, nil
+}
+
+// Trust the checkpoint to sync from, its snapshot is stored like the ones built by UpdateM1.
+// A chain already synced from a checkpoint can't switch to another one
+func (x *XDPoS_v2) SetCheckpoint(checkpoint *Checkpoint) error {
+	if x.checkpoint != nil && x.checkpoint.Hash != checkpoint.Hash {
+		return fmt.Errorf("chain is synced from checkpoint %v %v, not %v %v", x.checkpoint.Number, x.checkpoint.Hash.Hex(), checkpoint.Number, checkpoint.Hash.Hex())
+	}
+	if checkpoint.Snapshot == nil || checkpoint.TotalDifficulty == nil {
+		return fmt.Errorf("checkpoint %v has no snapshot or total difficulty", checkpoint.Number)
+	}
+	if checkpoint.Number%x.config.Epoch != 0 || checkpoint.Number <= x.config.V2.SwitchBlock.Uint64() || checkpoint.Number < x.config.Gap {
+		return fmt.Errorf("checkpoint %v is not an epoch switch block of consensus v2", checkpoint.Number)
+	}
+	if checkpoint.Snapshot.Number != checkpoint.Number-x.config.Gap {
+		return fmt.Errorf("checkpoint snapshot number %v, expect %v", checkpoint.Snapshot.Number, checkpoint.Number-x.config.Gap)
+	}
+	if err := storeSnapshot(checkpoint.Snapshot, x.db); err != nil {
+		return err
+	}
+	if err := storeCheckpoint(checkpoint, x.db); err != nil {
+		return err
+	}
+	x.snapshots.Add(checkpoint.Snapshot.Hash, checkpoint.Snapshot)
+	x.checkpoint = checkpoint
+	log.Info("[SetCheckpoint] sync from trusted checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash)
+	return nil
+}
+
+// The headers after the checkpoint may be synced without the state of their gap blocks
+func (x *XDPoS_v2) afterCheckpoint(number uint64) bool {
+	return x.checkpoint != nil && number > x.checkpoint.Number
+}
+
+// Check the snapshot of the gap block is in memory or on disk, without building it from header
+func (x *XDPoS_v2) hasSnapshot(gapBlockHash common.Hash) bool {
+	if x.snapshots.Contains(gapBlockHash) {
+		return true
+	}
+	_, err := loadSnapshot(x.db, gapBlockHash)
+	return err == nil
+}
+
+// The gap header of the checkpoint epoch is not synced, its hash is known by the snapshot
+func (x *XDPoS_v2) checkpointGapHash(gapNumber uint64) (common.Hash, bool) {
+	if x.checkpoint == nil || x.checkpoint.Snapshot.Number != gapNumber {
+		return common.Hash{}, false
+	}
+	return x.checkpoint.Snapshot.Hash, true
+}
+
+// Build the snapshot of a gap block after the checkpoint from the gap plus one header, it's stored so it's still known after
+// a restart, and UpdateM1 overwrites it once the state is synced
+func (x *XDPoS_v2) snapshotFromHeader(chain consensus.ChainReader, gapNumber uint64, gapHash common.Hash) *SnapshotV2 {
+	if !x.afterCheckpoint(gapNumber) {
+		return nil
+	}
+	header := chain.GetHeaderByNumber(gapNumber + 1)
+	if header == nil || header.ParentHash != gapHash {
+		return nil
+	}
+	snap := newSnapshot(gapNumber, gapHash, header.NextValidators, header.Penalties, nil)
+	snap.HeaderOnly = true
+	if err := storeSnapshot(snap, x.db); err != nil {
+		log.Warn("[snapshotFromHeader] fail to store snapshot", "number", gapNumber, "hash", gapHash, "err", err)
+	}
+	x.snapshots.Add(snap.Hash, snap)
+	log.Debug("[snapshotFromHeader] snapshot built from gap plus one header", "number", gapNumber, "hash", gapHash)
+	return snap
+}
+
+// Check the masternodes of an epoch switch header with a snapshot built from headers only
+func (x *XDPoS_v2) verifyMasternodesFromHeader(snap *SnapshotV2, header *types.Header) error {
+	if x.config.V2.MasternodeSelection != params.MasternodeSelectionStake {
+		masternodes := x.orderCandidates(snap, header.Number.Uint64()/x.config.Epoch)
+		if len(masternodes) > common.MaxMasternodes {
+			masternodes = masternodes[:common.MaxMasternodes]
+		}
+		if !utils.CompareSignersLists(masternodes, header.Validators) {
+			return utils.ErrValidatorsNotLegit
+		}
+		return nil
+	}
+	// the stakes are unknown, the masternodes must be as many distinct candidates as selected
+	candidates := common.RemoveItemFromArray(snap.NextEpochMasterNodes, snap.NextEpochPenalties)
+	expected := len(candidates)
+	if expected > common.MaxMasternodes {
+		expected = common.MaxMasternodes
+	}
+	if len(header.Validators) != expected {
+		return utils.ErrValidatorsNotLegit
+	}
+	selected := make(map[common.Address]struct{}, len(header.Validators))
+	for _, masternode := range header.Validators {
+		if _, ok := selected[masternode]; ok {
+			return utils.ErrValidatorsNotLegit
+		}
+		selected[masternode] = struct{}{}
+	}
+	for _, candidate := range candidates {
+		delete(selected, candidate)
+	}
+	if len(selected) != 0 {
+		return utils.ErrValidatorsNotLegit
+	}
+	return nil
+}
@@ -12,11 +12,13 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/accounts"
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/common/countdown"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/clique"
 	"github.com/XinFinOrg/XDC-Subnet/core/state"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
 	"github.com/XinFinOrg/XDC-Subnet/ethdb"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 	"github.com/XinFinOrg/XDC-Subnet/params"
@@ -35,13 +37,16 @@ type XDPoS_v2 struct {
 	signatures      *lru.ARCCache // Signatures of recent blocks to speed up mining
 	epochSwitches   *lru.ARCCache // infos of epoch: master nodes, epoch switch block info, parent of that info
 	verifiedHeaders *lru.ARCCache
+	qcSigners       *lru.ARCCache // Signers recovered from the QC signatures, so penalty checks don't recover them again
 
 	signer   common.Address  // Ethereum address of the signing key
 	signFn   clique.SignerFn // Signer function to authorize hashes with
+	blsKey   *bls.SecretKey  // BLS key to sign votes and timeouts after the BLS fork
 	lock     sync.RWMutex    // Protects the signer fields
 	signLock sync.RWMutex    // Protects the signer fields
 
 	BroadcastCh  chan interface{}
+	broadcastFn  func(msg interface{}) // Replaces the BroadcastCh in simulations, see SetBroadcastFaker
 	minePeriodCh chan int
 
 	timeoutWorker *countdown.CountdownTimer // Timer to generate broadcast timeout msg if threashold reached
@@ -60,9 +65,19 @@ type XDPoS_v2 struct {
 
 	HookReward  func(chain consensus.ChainReader, state *state.StateDB, parentState *state.StateDB, header *types.Header) (map[string]interface{}, error)
 	HookPenalty func(chain consensus.ChainReader, number *big.Int, parentHash common.Hash, candidates []common.Address, config *params.XDPoSConfig) ([]common.Address, error)
+	// Read the consensus config voted in the config contract at the gap block
+	HookEpochConfig func(chain consensus.ChainReader, header *types.Header) (*types.EpochConfig, error)
+	// Prove the BLS keys registered by the masternodes in the state of the gap block, for the epoch proofs of light clients
+	HookBLSKeysProof func(chain consensus.ChainReader, header *types.Header, masternodes []common.Address) (*types.BLSKeysProof, error)
 
 	ForensicsProcessor *Forensics
 
+	checkpoint *Checkpoint // Trusted epoch switch block the chain is synced from, nil if synced from the genesis block
+
+	baseConfigs     map[uint64]*params.V2Config // Configs given by the chain config, before any epoch config is registered
+	epochConfigHead *types.BlockInfo            // Latest canonical epoch switch block whose epoch config is registered
+	epochConfigLock sync.Mutex                  // Protects the registered epoch configs
+
 	votePoolCollectionTime time.Time
 }
 
@@ -76,6 +91,7 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database, minePeriodCh chan i
 	signatures, _ := lru.NewARC(utils.InmemorySnapshots)
 	epochSwitches, _ := lru.NewARC(int(utils.InmemoryEpochs))
 	verifiedHeaders, _ := lru.NewARC(utils.InmemorySnapshots)
+	qcSigners, _ := lru.NewARC(int(utils.InmemoryEpochs))
 
 	timeoutPool := utils.NewPool()
 	votePool := utils.NewPool()
@@ -89,8 +105,10 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database, minePeriodCh chan i
 		signatures: signatures,
 
 		verifiedHeaders: verifiedHeaders,
+		qcSigners:       qcSigners,
 		snapshots:       snapshots,
 		epochSwitches:   epochSwitches,
+		baseConfigs:     config.V2.AllConfigs,
 		timeoutWorker:   timeoutTimer,
 		BroadcastCh:     make(chan interface{}),
 		minePeriodCh:    minePeriodCh,
@@ -115,7 +133,7 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database, minePeriodCh chan i
 		},
 		highestVotedRound:  types.Round(0),
 		highestCommitBlock: nil,
-		ForensicsProcessor: NewForensics(),
+		ForensicsProcessor: NewForensics(db),
 	}
 	// Add callback to the timer
 	timeoutTimer.OnTimeoutFn = engine.OnCountdownTimeout
@@ -123,14 +141,24 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database, minePeriodCh chan i
 	engine.periodicJob()
 	config.V2.BuildConfigIndex()
 
+	// the chain synced from a checkpoint never has the headers before it, also after a restart
+	if checkpoint, err := loadCheckpoint(db); err == nil {
+		engine.checkpoint = checkpoint
+		engine.snapshots.Add(checkpoint.Snapshot.Hash, checkpoint.Snapshot)
+		log.Info("[New] restore trusted checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash)
+	}
+
 	return engine
 }
 
-func (x *XDPoS_v2) UpdateParams(header *types.Header) {
+func (x *XDPoS_v2) UpdateParams(chain consensus.ChainReader, header *types.Header) {
 	_, round, _, err := x.getExtraFields(header)
 	if err != nil {
 		log.Error("[UpdateParams] retrieve round failed", "block", header.Number.Uint64(), "err", err)
 	}
+	if err := x.syncEpochConfigs(chain, header, nil); err != nil {
+		log.Error("[UpdateParams] register epoch config failed", "block", header.Number.Uint64(), "err", err)
+	}
 	x.config.V2.UpdateConfig(uint64(round))
 
 	// Setup timeoutTimer
@@ -203,6 +231,16 @@ func (x *XDPoS_v2) initial(chain consensus.ChainReader, header *types.Header) er
 		x.currentRound = 1
 		x.highestQuorumCert = quorumCert
 
+	} else if x.checkpoint != nil && header.Number.Uint64() < x.checkpoint.Number+3 {
+		log.Info("[initial] highest QC from header after checkpoint")
+		// the blocks before the checkpoint are not synced, so the QC can't commit the grandparent of its block
+		quorumCert, _, _, err = x.getExtraFields(header)
+		if err != nil {
+			return err
+		}
+		x.highestQuorumCert = quorumCert
+		x.currentRound = quorumCert.ProposedBlockInfo.Round + 1
+
 	} else {
 		log.Info("[initial] highest QC from current header")
 		quorumCert, _, _, err = x.getExtraFields(header)
@@ -223,9 +261,13 @@ func (x *XDPoS_v2) initial(chain consensus.ChainReader, header *types.Header) er
 	}
 	lastGapHeader := chain.GetHeaderByNumber(lastGapNum)
 
-	snap, _ := loadSnapshot(x.db, lastGapHeader.Hash())
+	var snap *SnapshotV2
+	if x.checkpoint == nil || lastGapHeader != nil {
+		snap, _ = loadSnapshot(x.db, lastGapHeader.Hash())
+	}
 
-	if snap == nil {
+	// the headers before the checkpoint are not synced, the snapshots after it are given by the checkpoint
+	if snap == nil && x.checkpoint == nil {
 		checkpointHeader := chain.GetHeaderByNumber(x.config.V2.SwitchBlock.Uint64())
 
 		log.Info("[initial] init first snapshot")
@@ -235,7 +277,7 @@ func (x *XDPoS_v2) initial(chain consensus.ChainReader, header *types.Header) er
 			return err
 		}
 
-		snap := newSnapshot(lastGapNum, lastGapHeader.Hash(), masternodes, []common.Address{})
+		snap := newSnapshot(lastGapNum, lastGapHeader.Hash(), masternodes, []common.Address{}, nil)
 		x.snapshots.Add(snap.Hash, snap)
 		err = storeSnapshot(snap, x.db)
 		if err != nil {
@@ -244,6 +286,11 @@ func (x *XDPoS_v2) initial(chain consensus.ChainReader, header *types.Header) er
 		}
 	}
 
+	// Restore the consensus configs voted in all the epochs of the canonical chain
+	if err := x.syncEpochConfigs(chain, chain.CurrentHeader(), nil); err != nil {
+		log.Warn("[initial] Error while register epoch configs", "number", header.Number, "error", err)
+	}
+
 	// Initial timeout
 	log.Warn("[initial] miner wait period", "period", x.config.V2.CurrentConfig.MinePeriod)
 	// avoid deadlock
@@ -357,6 +404,21 @@ func (x *XDPoS_v2) Prepare(chain consensus.ChainReader, header *types.Header) er
 		}
 
 		header.Validators = masterNodes
+
+		// record the consensus config voted at the gap block
+		snap, err := x.getSnapshot(chain, number, false)
+		if err != nil {
+			log.Error("[Prepare] fail to get snapshot for epoch config", "blockNum", header.Number, "error", err)
+			return err
+		}
+		if snap.NextEpochConfig != nil {
+			extra.EpochConfig = []*types.EpochConfig{snap.NextEpochConfig}
+			extraBytes, err := extra.EncodeToBytes()
+			if err != nil {
+				return err
+			}
+			header.Extra = extraBytes
+		}
 	}
 
 	isGapPlusOneBlock := x.IsGapPlusOneBlock(header)
@@ -514,8 +576,16 @@ func (x *XDPoS_v2) UpdateMasternodes(chain consensus.ChainReader, header *types.
 	log.Trace("[UpdateMasternodes]")
 
 	masterNodes := []common.Address{}
+	stakes := make(map[common.Address]*big.Int)
+	blsKeys := make(map[common.Address]hexutil.Bytes)
 	for _, m := range ms {
 		masterNodes = append(masterNodes, m.Address)
+		if m.Stake != nil {
+			stakes[m.Address] = m.Stake
+		}
+		if len(m.BLSPublicKey) > 0 {
+			blsKeys[m.Address] = m.BLSPublicKey
+		}
 	}
 	// Subnet stores penalties in snapshot
 	penalties := []common.Address{}
@@ -528,8 +598,28 @@ func (x *XDPoS_v2) UpdateMasternodes(chain consensus.ChainReader, header *types.
 		}
 	}
 
+	var epochConfig *types.EpochConfig
+	if x.HookEpochConfig != nil {
+		var err error
+		epochConfig, err = x.HookEpochConfig(chain, header)
+		if err != nil {
+			log.Error("[UpdateMasternodes] Adaptor v2 HookEpochConfig has error", "err", err)
+			return err
+		}
+		if epochConfig != nil {
+			if err := validateEpochConfig(epochConfig); err != nil {
+				log.Warn("[UpdateMasternodes] Ignore invalid epoch config", "number", number, "config", epochConfig, "err", err)
+				epochConfig = nil
+			}
+		}
+	}
+
 	x.lock.RLock()
-	snap := newSnapshot(number, header.Hash(), masterNodes, penalties)
+	snap := newSnapshot(number, header.Hash(), masterNodes, penalties, stakes)
+	if len(blsKeys) > 0 {
+		snap.NextEpochBLSKeys = blsKeys
+	}
+	snap.NextEpochConfig = epochConfig
 	log.Info("[UpdateMasternodes] take snapshot", "number", number, "hash", header.Hash())
 	x.lock.RUnlock()
 
@@ -652,6 +742,16 @@ func (x *XDPoS_v2) VerifyVoteMessage(chain consensus.ChainReader, vote *types.Vo
 		log.Warn("[VerifyVoteMessage] Error while verifying vote message", "votedBlockNum", vote.ProposedBlockInfo.Number.Uint64(), "votedBlockHash", vote.ProposedBlockInfo.Hash.Hex(), "voteHash", vote.Hash(), "error", err.Error())
 		return false, err
 	}
+	if verified && x.config.V2.IsBLS(vote.GapNumber) {
+		err = verifyBLSMessage(snapshot, signer, types.VoteSigHash(&types.VoteForSign{
+			ProposedBlockInfo: vote.ProposedBlockInfo,
+			GapNumber:         vote.GapNumber,
+		}), vote.BLSSignature)
+		if err != nil {
+			log.Warn("[VerifyVoteMessage] Error while verifying vote BLS signature", "votedBlockNum", vote.ProposedBlockInfo.Number.Uint64(), "votedBlockHash", vote.ProposedBlockInfo.Hash.Hex(), "voteHash", vote.Hash(), "error", err.Error())
+			return false, err
+		}
+	}
 	vote.SetSigner(signer)
 
 	return verified, nil
@@ -696,6 +796,16 @@ func (x *XDPoS_v2) VerifyTimeoutMessage(chain consensus.ChainReader, timeoutMsg
 		log.Warn("[VerifyTimeoutMessage] cannot verify timeout signature", "err", err)
 		return false, err
 	}
+	if verified && x.config.V2.IsBLS(timeoutMsg.GapNumber) {
+		err = verifyBLSMessage(snap, signer, types.TimeoutSigHash(&types.TimeoutForSign{
+			Round:     timeoutMsg.Round,
+			GapNumber: timeoutMsg.GapNumber,
+		}), timeoutMsg.BLSSignature)
+		if err != nil {
+			log.Warn("[VerifyTimeoutMessage] cannot verify timeout BLS signature", "err", err)
+			return false, err
+		}
+	}
 
 	timeoutMsg.SetSigner(signer)
 	return verified, nil
@@ -815,6 +925,15 @@ func (x *XDPoS_v2) verifyQC(blockChainReader consensus.ChainReader, quorumCert *
 		return fmt.Errorf("Fail to verify QC due to failure in getting epoch switch info")
 	}
 
+	qcRound := quorumCert.ProposedBlockInfo.Round
+	certThreshold := x.config.V2.Config(uint64(qcRound)).CertThreshold
+	if x.config.V2.IsBLS(quorumCert.GapNumber) {
+		if err := x.verifyAggregatedQC(blockChainReader, quorumCert, epochInfo, certThreshold); err != nil {
+			return err
+		}
+		return x.verifyQCGapNumber(blockChainReader, quorumCert, epochInfo, parentHeader)
+	}
+
 	signatures, duplicates := UniqueSignatures(quorumCert.Signatures)
 	if len(duplicates) != 0 {
 		for _, d := range duplicates {
@@ -822,8 +941,6 @@ func (x *XDPoS_v2) verifyQC(blockChainReader consensus.ChainReader, quorumCert *
 		}
 	}
 
-	qcRound := quorumCert.ProposedBlockInfo.Round
-	certThreshold := x.config.V2.Config(uint64(qcRound)).CertThreshold
 	if (qcRound > 0) && (signatures == nil || float64(len(signatures)) < float64(epochInfo.MasternodesLen)*certThreshold) {
 		//First V2 Block QC, QC Signatures is initial nil
 		log.Warn("[verifyHeader] Invalid QC Signature is nil or less then config", "QC", quorumCert, "QCNumber", quorumCert.ProposedBlockInfo.Number, "Signatures len", len(signatures), "CertThreshold", float64(epochInfo.MasternodesLen)*certThreshold)
@@ -834,25 +951,27 @@ func (x *XDPoS_v2) verifyQC(blockChainReader consensus.ChainReader, quorumCert *
 	var wg sync.WaitGroup
 	wg.Add(len(signatures))
 	var haveError error
+	signers := make([]common.Address, len(signatures))
 
-	for _, signature := range signatures {
-		go func(sig types.Signature) {
+	for i, signature := range signatures {
+		go func(i int, sig types.Signature) {
 			defer wg.Done()
-			verified, _, err := x.verifyMsgSignature(types.VoteSigHash(&types.VoteForSign{
+			verified, signer, err := x.verifyMsgSignature(types.VoteSigHash(&types.VoteForSign{
 				ProposedBlockInfo: quorumCert.ProposedBlockInfo,
 				GapNumber:         quorumCert.GapNumber,
 			}), sig, epochInfo.Masternodes)
 			if err != nil {
 				log.Error("[verifyQC] Error while verfying QC message signatures", "Error", err)
-				haveError = fmt.Errorf("Error while verfying QC message signatures")
+				haveError = &utils.ErrInvalidSignature{Err: fmt.Errorf("Error while verfying QC message signatures")}
 				return
 			}
 			if !verified {
 				log.Warn("[verifyQC] Signature not verified doing QC verification", "QC", quorumCert)
-				haveError = fmt.Errorf("Fail to verify QC due to signature mis-match")
+				haveError = &utils.ErrInvalidSignature{Err: fmt.Errorf("Fail to verify QC due to signature mis-match")}
 				return
 			}
-		}(signature)
+			signers[i] = signer
+		}(i, signature)
 	}
 	wg.Wait()
 	elapsed := time.Since(start)
@@ -860,6 +979,42 @@ func (x *XDPoS_v2) verifyQC(blockChainReader consensus.ChainReader, quorumCert *
 	if haveError != nil {
 		return haveError
 	}
+	x.qcSigners.Add(qcSignersKey(quorumCert), signers)
+	return x.verifyQCGapNumber(blockChainReader, quorumCert, epochInfo, parentHeader)
+}
+
+// Verify the aggregated BLS signature of QC after the BLS fork, the signer bitmap is indexed by the epoch masternodes
+func (x *XDPoS_v2) verifyAggregatedQC(blockChainReader consensus.ChainReader, quorumCert *types.QuorumCert, epochInfo *types.EpochSwitchInfo, certThreshold float64) error {
+	// First V2 Block QC has no signature
+	if quorumCert.ProposedBlockInfo.Round == 0 && len(quorumCert.Aggregated) == 0 {
+		return nil
+	}
+	snap, err := x.getSnapshot(blockChainReader, quorumCert.GapNumber, true)
+	if err != nil {
+		log.Error("[verifyAggregatedQC] Fail to get snapshot when verifying QC", "QCGapNumber", quorumCert.GapNumber, "err", err)
+		return err
+	}
+	start := time.Now()
+	signers, err := verifyAggregatedSignature(snap, epochInfo.Masternodes, types.VoteSigHash(&types.VoteForSign{
+		ProposedBlockInfo: quorumCert.ProposedBlockInfo,
+		GapNumber:         quorumCert.GapNumber,
+	}), quorumCert.Aggregated)
+	if err != nil {
+		log.Warn("[verifyAggregatedQC] Fail to verify aggregated signature", "QCNumber", quorumCert.ProposedBlockInfo.Number, "err", err)
+		if _, ok := err.(*utils.ErrInvalidSignature); ok {
+			return &utils.ErrInvalidSignature{Err: utils.ErrInvalidQCSignatures}
+		}
+		return utils.ErrInvalidQCSignatures
+	}
+	log.Debug("[verifyAggregatedQC] time verify aggregated signature of qc", "elapsed", time.Since(start))
+	if float64(len(signers)) < float64(epochInfo.MasternodesLen)*certThreshold {
+		log.Warn("[verifyAggregatedQC] Not enough signers in QC", "QCNumber", quorumCert.ProposedBlockInfo.Number, "signers", len(signers), "CertThreshold", float64(epochInfo.MasternodesLen)*certThreshold)
+		return utils.ErrInvalidQCSignatures
+	}
+	return nil
+}
+
+func (x *XDPoS_v2) verifyQCGapNumber(blockChainReader consensus.ChainReader, quorumCert *types.QuorumCert, epochInfo *types.EpochSwitchInfo, parentHeader *types.Header) error {
 	epochSwitchNumber := epochInfo.EpochSwitchBlockInfo.Number.Uint64()
 	gapNumber := epochSwitchNumber - epochSwitchNumber%x.config.Epoch - x.config.Gap
 	// prevent overflow
@@ -931,6 +1086,10 @@ func (x *XDPoS_v2) setNewRound(blockChainReader consensus.ChainReader, round typ
 }
 
 func (x *XDPoS_v2) broadcastToBftChannel(msg interface{}) {
+	if x.broadcastFn != nil {
+		x.broadcastFn(msg)
+		return
+	}
 	go func() {
 		x.BroadcastCh <- msg
 	}()
@@ -1038,11 +1197,9 @@ func (x *XDPoS_v2) calcMasternodes(chain consensus.ChainReader, blockNum *big.In
 		log.Error("[calcMasternodes] Adaptor v2 getSnapshot has error", "err", err)
 		return nil, nil, err
 	}
-	// candidates are masternodes
-	candidates := snap.NextEpochMasterNodes
-	// penalties are from snapshot too
+	// penalties are from snapshot, candidates are ordered by the selection policy with penalties removed
 	penalties := snap.NextEpochPenalties
-	masternodes := common.RemoveItemFromArray(candidates, penalties)
+	masternodes := x.orderCandidates(snap, blockNum.Uint64()/x.config.Epoch)
 	if len(masternodes) > maxMasternodes {
 		masternodes = masternodes[:maxMasternodes]
 	}
@@ -0,0 +1,184 @@
+package engine_v2
+
+import (
+	"fmt"
+
+	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+/*
+Consensus parameters can be changed without hard fork through the config contract:
+  - At the gap block, the voted config is read from the contract state into the snapshot by HookEpochConfig
+  - The next epoch switch block records it in its header extra, which is checked against the snapshot
+  - It takes effect after the epoch switch round, registered once the header is in the canonical chain and applied by UpdateParams
+  - Side chain headers never register a config, the configs are rebuilt from the canonical epoch switch headers on reorg and restart
+*/
+
+const (
+	minEpochCertThreshold = 667  // Per mille, a certificate needs more than 2/3 of the masternodes to be safe
+	maxEpochConfigPeriod  = 3600 // Seconds, upper bound of the mine and timeout periods
+)
+
+// Check the voted config is in a safe range, otherwise it's not recorded
+func validateEpochConfig(config *types.EpochConfig) error {
+	if config.CertThreshold != 0 && (config.CertThreshold < minEpochCertThreshold || config.CertThreshold > 1000) {
+		return fmt.Errorf("cert threshold %v per mille out of range [%v, 1000]", config.CertThreshold, minEpochCertThreshold)
+	}
+	if config.MinePeriod > maxEpochConfigPeriod || config.TimeoutPeriod > maxEpochConfigPeriod {
+		return fmt.Errorf("mine period %v or timeout period %v exceeds %v seconds", config.MinePeriod, config.TimeoutPeriod, maxEpochConfigPeriod)
+	}
+	return nil
+}
+
+// Get the consensus config recorded in the epoch switch header and its round, the config is nil if there is none
+func (x *XDPoS_v2) getEpochConfig(header *types.Header) (*types.EpochConfig, types.Round, error) {
+	// last v1 block
+	if header.Number.Cmp(x.config.V2.SwitchBlock) <= 0 {
+		return nil, types.Round(0), nil
+	}
+	var decodedExtraField types.ExtraFields_v2
+	err := utils.DecodeBytesExtraFields(header.Extra, &decodedExtraField)
+	if err != nil {
+		return nil, types.Round(0), err
+	}
+	if len(decodedExtraField.EpochConfig) == 0 {
+		return nil, decodedExtraField.Round, nil
+	}
+	if len(decodedExtraField.EpochConfig) > 1 || decodedExtraField.EpochConfig[0] == nil {
+		return nil, decodedExtraField.Round, fmt.Errorf("expect one epoch config, got %v", len(decodedExtraField.EpochConfig))
+	}
+	return decodedExtraField.EpochConfig[0], decodedExtraField.Round, nil
+}
+
+// Verify the config recorded in the epoch switch header is the one voted at the gap block
+func (x *XDPoS_v2) verifyEpochConfig(chain consensus.ChainReader, header *types.Header, epochConfig *types.EpochConfig) error {
+	snap, err := x.getSnapshot(chain, header.Number.Uint64(), false)
+	if err != nil {
+		log.Error("[verifyEpochConfig] fail to get snapshot", "blockNum", header.Number, "error", err)
+		return err
+	}
+	if snap.NextEpochConfig == nil && epochConfig == nil {
+		return nil
+	}
+	if snap.NextEpochConfig == nil || epochConfig == nil || *snap.NextEpochConfig != *epochConfig {
+		log.Warn("[verifyEpochConfig] epoch config mismatch", "blockNum", header.Number, "snapshot", snap.NextEpochConfig, "header", epochConfig)
+		return utils.ErrEpochConfigNotLegit
+	}
+	return nil
+}
+
+// Register the config recorded in the epoch switch header, so it takes effect after the round of the header
+func (x *XDPoS_v2) registerEpochConfig(header *types.Header) error {
+	epochConfig, round, err := x.getEpochConfig(header)
+	if err != nil || epochConfig == nil {
+		return err
+	}
+	current := x.config.V2.Config(uint64(round) + 1)
+	config := mergeEpochConfig(current, epochConfig, round)
+	if *config == *current {
+		return nil
+	}
+	log.Info("[registerEpochConfig] register epoch config", "number", header.Number, "round", round, "config", config)
+	x.config.V2.AddConfig(config)
+	return nil
+}
+
+// Register the configs recorded in the canonical epoch switch headers up to the header, the header itself is taken as canonical.
+// The parents are the headers of the same batch leading to it, not yet in the chain, whose epoch switch headers are taken first.
+// It only walks the epoch switch headers after the latest registered one, unless it's no longer canonical and all configs are rebuilt
+func (x *XDPoS_v2) syncEpochConfigs(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	if header == nil || header.Number.Cmp(x.config.V2.SwitchBlock) <= 0 {
+		return nil
+	}
+	x.epochConfigLock.Lock()
+	defer x.epochConfigLock.Unlock()
+
+	var (
+		headers []*types.Header
+		synced  bool
+	)
+	for number := header.Number.Uint64() - header.Number.Uint64()%x.config.Epoch; number > x.config.V2.SwitchBlock.Uint64(); number -= x.config.Epoch {
+		epochSwitchHeader := header
+		if number != header.Number.Uint64() {
+			epochSwitchHeader = batchHeaderByNumber(parents, number)
+		}
+		if epochSwitchHeader == nil {
+			epochSwitchHeader = chain.GetHeaderByNumber(number)
+		}
+		// the headers before the checkpoint are not synced
+		if epochSwitchHeader == nil {
+			break
+		}
+		if head := x.epochConfigHead; head != nil && head.Number.Uint64() == number && head.Hash == epochSwitchHeader.Hash() {
+			synced = true
+			break
+		}
+		headers = append(headers, epochSwitchHeader)
+	}
+	if !synced {
+		x.config.V2.SetConfigs(x.baseConfigs)
+		x.epochConfigHead = nil
+	}
+	for i := len(headers) - 1; i >= 0; i-- {
+		if err := x.registerEpochConfig(headers[i]); err != nil {
+			return err
+		}
+		x.epochConfigHead = &types.BlockInfo{Hash: headers[i].Hash(), Number: headers[i].Number}
+	}
+	return nil
+}
+
+// Get the header with the number out of a batch of consecutive headers, nil if it's not in the batch
+func batchHeaderByNumber(headers []*types.Header, number uint64) *types.Header {
+	if len(headers) == 0 || number < headers[0].Number.Uint64() {
+		return nil
+	}
+	if i := number - headers[0].Number.Uint64(); i < uint64(len(headers)) {
+		return headers[i]
+	}
+	return nil
+}
+
+// Override the values of the config in use by the non zero values of the epoch config
+func mergeEpochConfig(current *params.V2Config, epochConfig *types.EpochConfig, round types.Round) *params.V2Config {
+	config := *current
+	config.SwitchRound = uint64(round)
+	if epochConfig.MinePeriod != 0 {
+		config.MinePeriod = int(epochConfig.MinePeriod)
+	}
+	if epochConfig.TimeoutPeriod != 0 {
+		config.TimeoutPeriod = int(epochConfig.TimeoutPeriod)
+	}
+	if epochConfig.TimeoutSyncThreshold != 0 {
+		config.TimeoutSyncThreshold = int(epochConfig.TimeoutSyncThreshold)
+	}
+	if epochConfig.CertThreshold != 0 {
+		config.CertThreshold = float64(epochConfig.CertThreshold) / 1000
+	}
+	return &config
+}
+
+// Get the config in use after the header, and the config voted at the gap block of its epoch which takes effect from the next epoch
+func (x *XDPoS_v2) GetEpochConfig(chain consensus.ChainReader, header *types.Header) (*utils.PublicApiEpochConfig, error) {
+	_, round, _, err := x.getExtraFields(header)
+	if err != nil {
+		return nil, err
+	}
+	number := header.Number.Uint64()
+	epochConfig := &utils.PublicApiEpochConfig{
+		Active: x.config.V2.Config(uint64(round) + 1),
+	}
+	if number%x.config.Epoch >= x.config.Epoch-x.config.Gap {
+		epochConfig.NextEpochBlock = number - number%x.config.Epoch + x.config.Epoch
+		snap, err := x.getSnapshot(chain, epochConfig.NextEpochBlock-x.config.Gap, true)
+		if err != nil {
+			return nil, err
+		}
+		epochConfig.Pending = snap.NextEpochConfig
+	}
+	return epochConfig, nil
+}
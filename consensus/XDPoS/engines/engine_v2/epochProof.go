@@ -0,0 +1,108 @@
+package engine_v2
+
+import (
+	"fmt"
+
+	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+)
+
+/*
+An epoch proof lets a light client follow the masternodes of the subnet from a trusted epoch:
+  - The gap plus one header of the previous epoch records the candidates and penalties for the epoch, it's committed by the previous masternodes
+  - The epoch switch header records the masternodes selected from these candidates, it's committed by the masternodes themselves
+  - The headers in between link the epoch switch header to the gap plus one header by the parent hash
+  - After the BLS fork, the keys of the masternodes are proven against the state root of the gap header, the parent of the gap plus one header
+*/
+func (x *XDPoS_v2) GetEpochProof(chain consensus.ChainReader, epoch uint64) (*types.EpochProof, error) {
+	number := epoch * x.config.Epoch
+	if number <= x.config.V2.SwitchBlock.Uint64() {
+		return nil, fmt.Errorf("epoch %v is not an epoch of consensus v2", epoch)
+	}
+	if number < x.config.Gap {
+		return nil, fmt.Errorf("epoch %v has no gap block", epoch)
+	}
+	epochSwitchHeader := chain.GetHeaderByNumber(number)
+	if epochSwitchHeader == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+	// the headers in between are collected backwards by the parent hash, down to the gap plus one header
+	gapPlusOneNumber := number - x.config.Gap + 1
+	var chainHeaders []*types.Header
+	gapPlusOneHeader := chain.GetHeader(epochSwitchHeader.ParentHash, number-1)
+	for gapPlusOneHeader != nil && gapPlusOneHeader.Number.Uint64() > gapPlusOneNumber {
+		chainHeaders = append([]*types.Header{gapPlusOneHeader}, chainHeaders...)
+		gapPlusOneHeader = chain.GetHeader(gapPlusOneHeader.ParentHash, gapPlusOneHeader.Number.Uint64()-1)
+	}
+	if gapPlusOneHeader == nil {
+		return nil, utils.ErrUnknownBlock
+	}
+
+	epochSwitch, err := x.getCommitProof(chain, epochSwitchHeader, number+x.config.Epoch)
+	if err != nil {
+		log.Debug("[GetEpochProof] epoch switch block is not committed", "number", number, "err", err)
+		return nil, err
+	}
+	nextValidators, err := x.getCommitProof(chain, gapPlusOneHeader, number)
+	if err != nil {
+		log.Debug("[GetEpochProof] gap plus one block is not committed", "number", gapPlusOneHeader.Number, "err", err)
+		return nil, err
+	}
+	proof := &types.EpochProof{
+		Epoch:          epoch,
+		Masternodes:    epochSwitchHeader.Validators,
+		EpochSwitch:    epochSwitch,
+		NextValidators: nextValidators,
+		Chain:          chainHeaders,
+	}
+	// the QCs of the epoch are aggregated with the keys registered at its gap block
+	if x.config.V2.IsBLS(number - x.config.Gap) {
+		if x.HookBLSKeysProof == nil {
+			return nil, fmt.Errorf("epoch %v needs BLS keys proof which is not supported", epoch)
+		}
+		gapHeader := chain.GetHeader(gapPlusOneHeader.ParentHash, number-x.config.Gap)
+		if gapHeader == nil {
+			return nil, utils.ErrUnknownBlock
+		}
+		proof.BLSKeys, err = x.HookBLSKeysProof(chain, gapHeader, epochSwitchHeader.Validators)
+		if err != nil {
+			log.Debug("[GetEpochProof] fail to prove BLS keys", "number", gapHeader.Number, "err", err)
+			return nil, err
+		}
+	}
+	return proof, nil
+}
+
+// Collect the canonical headers from the given one until three of them have consecutive rounds, so the given header is committed.
+// The headers must be before the end number, which is the next epoch switch block, to be certified by the same masternodes.
+func (x *XDPoS_v2) getCommitProof(chain consensus.ChainReader, header *types.Header, end uint64) (*types.CommitProof, error) {
+	_, round, _, err := x.getExtraFields(header)
+	if err != nil {
+		return nil, err
+	}
+	headers := []*types.Header{header}
+	rounds := []types.Round{round}
+	for number := header.Number.Uint64() + 1; number <= end; number++ {
+		next := chain.GetHeaderByNumber(number)
+		if next == nil {
+			break
+		}
+		quorumCert, round, _, err := x.getExtraFields(next)
+		if err != nil {
+			return nil, err
+		}
+		n := len(rounds)
+		if n >= 3 && rounds[n-3]+1 == rounds[n-2] && rounds[n-2]+1 == rounds[n-1] {
+			// the QC in the child of the last header commits the first of the three
+			return &types.CommitProof{Headers: headers, QuorumCert: quorumCert}, nil
+		}
+		if number == end {
+			break
+		}
+		headers = append(headers, next)
+		rounds = append(rounds, round)
+	}
+	return nil, fmt.Errorf("block %v is not committed before block %v", header.Number, end)
+}
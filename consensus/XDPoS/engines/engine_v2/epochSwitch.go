@@ -70,9 +70,9 @@ func (x *XDPoS_v2) getEpochSwitchInfo(chain consensus.ChainReader, header *types
 		penalties := snap.NextEpochPenalties
 		standbynodes := []common.Address{}
 		if len(masternodes) != len(candidates) {
-			standbynodes = candidates
+			// keep the same order as the selection policy used for masternodes
+			standbynodes = x.orderCandidates(snap, h.Number.Uint64()/x.config.Epoch)
 			standbynodes = common.RemoveItemFromArray(standbynodes, masternodes)
-			standbynodes = common.RemoveItemFromArray(standbynodes, penalties)
 		}
 		epochSwitchInfo := &types.EpochSwitchInfo{
 			Penalties:      penalties,
@@ -1,6 +1,7 @@
 package engine_v2
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -13,6 +14,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
 	"github.com/XinFinOrg/XDC-Subnet/event"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 )
@@ -21,16 +23,22 @@ const (
 	NUM_OF_FORENSICS_QC = 3
 )
 
+// forensicsPrefix + num (uint64 big endian) + proof id -> forensic proof
+var forensicsPrefix = []byte("XDPoS-V2-forensics-")
+
 // Forensics instance. Placeholder for future properties to be added
 type Forensics struct {
 	HighestCommittedQCs []types.QuorumCert
+	db                  ethdb.Database // Database to persist the generated forensic proofs
 	forensicsFeed       event.Feed
 	scope               event.SubscriptionScope
 }
 
 // Initiate a forensics process
-func NewForensics() *Forensics {
-	return &Forensics{}
+func NewForensics(db ethdb.Database) *Forensics {
+	return &Forensics{
+		db: db,
+	}
 }
 
 // SubscribeForensicsEvent registers a subscription of ForensicsEvent and
@@ -173,12 +181,12 @@ func (f *Forensics) SendForensicProof(chain consensus.ChainReader, engine *XDPoS
 		SmallerRoundInfo: &types.ForensicsInfo{
 			HashPath:        ancestorToLowerRoundPath,
 			QuorumCert:      lowerRoundQC,
-			SignerAddresses: f.getQcSignerAddresses(lowerRoundQC),
+			SignerAddresses: f.getQcSignerAddresses(lowerRoundQC, lowerRoundQcEpochSwitchInfo.Masternodes),
 		},
 		LargerRoundInfo: &types.ForensicsInfo{
 			HashPath:        ancestorToHigherRoundPath,
 			QuorumCert:      higherRoundQC,
-			SignerAddresses: f.getQcSignerAddresses(higherRoundQC),
+			SignerAddresses: f.getQcSignerAddresses(higherRoundQC, higherRoundQcEpochSwitchInfo.Masternodes),
 		},
 	})
 
@@ -193,6 +201,9 @@ func (f *Forensics) SendForensicProof(chain consensus.ChainReader, engine *XDPoS
 		Content:       string(content),
 	}
 	log.Info("Forensics proof report generated, sending to the stats server", "forensicsProof", forensicsProof)
+	if err := f.storeForensicProof(ancestorBlock.Number.Uint64(), forensicsProof); err != nil {
+		log.Error("[SendForensicProof] fail to store forensics proof", "id", forensicsProof.Id, "err", err)
+	}
 	go f.forensicsFeed.Send(types.ForensicsEvent{ForensicsProof: forensicsProof})
 	return nil
 }
@@ -268,10 +279,21 @@ func (f *Forensics) findQCsInSameRound(quorumCerts1 []types.QuorumCert, quorumCe
 	return false, types.QuorumCert{}, types.QuorumCert{}
 }
 
-// Find the signer list from QC signatures
-func (f *Forensics) getQcSignerAddresses(quorumCert types.QuorumCert) []string {
+// Find the signer list from QC signatures, the signer bitmap of aggregated QC is indexed by the epoch masternodes
+func (f *Forensics) getQcSignerAddresses(quorumCert types.QuorumCert, masternodes []common.Address) []string {
 	var signerList []string
 
+	if len(quorumCert.Aggregated) > 0 && quorumCert.Aggregated[0] != nil {
+		signers, err := aggregatedSigners(masternodes, quorumCert.Aggregated[0])
+		if err != nil {
+			log.Error("[getQcSignerAddresses] Fail to decode signers from the aggregated signature", "quorumCert.GapNumber", quorumCert.GapNumber, "quorumCert.ProposedBlockInfo", quorumCert.ProposedBlockInfo, "err", err)
+		}
+		for _, signer := range signers {
+			signerList = append(signerList, signer.Hex())
+		}
+		return signerList
+	}
+
 	// The QC signatures are signed by votes special struct VoteForSign
 	quorumCertSignedHash := types.VoteSigHash(&types.VoteForSign{
 		ProposedBlockInfo: quorumCert.ProposedBlockInfo,
@@ -542,10 +564,68 @@ func (f *Forensics) SendVoteEquivocationProof(vote1, vote2 *types.Vote, signer c
 		Content:       string(content),
 	}
 	log.Info("Forensics proof report generated, sending to the stats server", "forensicsProof", forensicsProof)
+	if err := f.storeForensicProof(largerRoundVote.ProposedBlockInfo.Number.Uint64(), forensicsProof); err != nil {
+		log.Error("[SendVoteEquivocationProof] fail to store forensics proof", "id", forensicsProof.Id, "err", err)
+	}
 	go f.forensicsFeed.Send(types.ForensicsEvent{ForensicsProof: forensicsProof})
 	return nil
 }
 
+// Persist the forensic proof under its block number and id, so it's still available after a node restart.
+// QC proofs are indexed by the diverging block number, vote equivocation proofs by the larger round vote's block number.
+func (f *Forensics) storeForensicProof(number uint64, proof *types.ForensicProof) error {
+	if f.db == nil {
+		return nil
+	}
+	blob, err := json.Marshal(proof)
+	if err != nil {
+		return err
+	}
+	return f.db.Put(forensicProofKey(number, proof.Id), blob)
+}
+
+// GetForensicProofs returns the persisted forensic proofs indexed between fromBlock and toBlock (both inclusive).
+// An empty forensicsType matches all types of proof.
+func (f *Forensics) GetForensicProofs(fromBlock, toBlock uint64, forensicsType string) ([]*types.ForensicProof, error) {
+	proofs := []*types.ForensicProof{}
+	if f.db == nil || fromBlock > toBlock {
+		return proofs, nil
+	}
+	it := f.db.NewIterator(forensicsPrefix, encodeForensicsBlockNumber(fromBlock))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) < len(forensicsPrefix)+8 {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(forensicsPrefix):len(forensicsPrefix)+8]) > toBlock {
+			break
+		}
+		proof := new(types.ForensicProof)
+		if err := json.Unmarshal(it.Value(), proof); err != nil {
+			log.Error("[GetForensicProofs] fail to decode stored forensics proof", "key", common.Bytes2Hex(key), "err", err)
+			return nil, err
+		}
+		if forensicsType != "" && !strings.EqualFold(proof.ForensicsType, forensicsType) {
+			continue
+		}
+		proofs = append(proofs, proof)
+	}
+	return proofs, it.Error()
+}
+
+func encodeForensicsBlockNumber(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return enc
+}
+
+func forensicProofKey(number uint64, id string) []byte {
+	key := append(append([]byte{}, forensicsPrefix...), encodeForensicsBlockNumber(number)...)
+	return append(key, []byte(id)...)
+}
+
 func GetVoteSignerAddresses(vote *types.Vote) (common.Address, error) {
 	// The QC signatures are signed by votes special struct VoteForSign
 	signHash := types.VoteSigHash(&types.VoteForSign{
@@ -11,6 +11,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/accounts"
 	"github.com/XinFinOrg/XDC-Subnet/accounts/keystore"
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
 	"github.com/stretchr/testify/assert"
@@ -141,4 +142,33 @@ func TestFindQCsInSameRound(t *testing.T) {
 	assert.Equal(t, *qc4, second)
 }
 
+func TestStoreAndGetForensicProofs(t *testing.T) {
+	forensics := NewForensics(rawdb.NewMemoryDatabase())
+
+	assert.Nil(t, forensics.storeForensicProof(900, &types.ForensicProof{Id: "qc-900", ForensicsType: "QC", Content: "{}"}))
+	assert.Nil(t, forensics.storeForensicProof(905, &types.ForensicProof{Id: "vote-905", ForensicsType: "Vote", Content: "{}"}))
+	assert.Nil(t, forensics.storeForensicProof(1000, &types.ForensicProof{Id: "qc-1000", ForensicsType: "QC", Content: "{}"}))
+	// Same id shall not be stored twice
+	assert.Nil(t, forensics.storeForensicProof(900, &types.ForensicProof{Id: "qc-900", ForensicsType: "QC", Content: "{}"}))
+
+	proofs, err := forensics.GetForensicProofs(0, 2000, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(proofs))
+	assert.Equal(t, "qc-900", proofs[0].Id)
+	assert.Equal(t, "vote-905", proofs[1].Id)
+	assert.Equal(t, "qc-1000", proofs[2].Id)
+
+	proofs, err = forensics.GetForensicProofs(901, 1000, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(proofs))
+
+	proofs, err = forensics.GetForensicProofs(0, 2000, "QC")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(proofs))
+
+	proofs, err = forensics.GetForensicProofs(1001, 2000, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(proofs))
+}
+
 // TODO: Add test for FindAncestorBlockHash
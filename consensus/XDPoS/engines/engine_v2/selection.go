@@ -0,0 +1,28 @@
+package engine_v2
+
+import (
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+// Order the snapshot candidates, with penalties removed, according to the configured masternode selection policy.
+// The first MaxMasternodes items are the masternodes of the epoch, the rest are the standby nodes.
+func (x *XDPoS_v2) orderCandidates(snap *SnapshotV2, epochNum uint64) []common.Address {
+	candidates := common.RemoveItemFromArray(snap.NextEpochMasterNodes, snap.NextEpochPenalties)
+
+	switch x.config.V2.MasternodeSelection {
+	case params.MasternodeSelectionStake:
+		return utils.OrderByStake(candidates, snap.NextEpochStakes)
+	case params.MasternodeSelectionRotation:
+		return utils.OrderByRotation(candidates, common.MaxMasternodes, epochNum)
+	case params.MasternodeSelectionShuffle:
+		return utils.OrderByShuffle(candidates, snap.Hash)
+	case params.MasternodeSelectionDefault:
+		return candidates
+	default:
+		log.Warn("[orderCandidates] Unknown masternode selection policy, use snapshot order", "policy", x.config.V2.MasternodeSelection)
+		return candidates
+	}
+}
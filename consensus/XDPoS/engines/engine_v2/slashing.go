@@ -0,0 +1,78 @@
+package engine_v2
+
+import (
+	"fmt"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+)
+
+/*
+Verify the vote equivocation evidence from a slashing transaction and return the masternode to be slashed.
+Only double voting is provable on chain without the forked blocks, so the two votes must:
+  - be in the same round and the same gap number
+  - vote for different blocks
+  - be signed by the same masternode of the epoch, checked against the masternodes of the epoch switch header
+    the voted blocks belong to
+*/
+func (x *XDPoS_v2) VerifyVoteEquivocation(chain consensus.ChainReader, evidence *types.SlashingEvidence) (common.Address, error) {
+	first, second := evidence.FirstVote, evidence.SecondVote
+	if first.ProposedBlockInfo.Round != second.ProposedBlockInfo.Round {
+		return common.Address{}, fmt.Errorf("votes are not in the same round, %v and %v", first.ProposedBlockInfo.Round, second.ProposedBlockInfo.Round)
+	}
+	if first.GapNumber != second.GapNumber {
+		return common.Address{}, fmt.Errorf("votes are not in the same epoch, gap number %v and %v", first.GapNumber, second.GapNumber)
+	}
+	if first.ProposedBlockInfo.Hash == second.ProposedBlockInfo.Hash {
+		return common.Address{}, fmt.Errorf("votes are for the same block %v", first.ProposedBlockInfo.Hash.Hex())
+	}
+
+	var signers []common.Address
+	for _, vote := range []*types.Vote{first, second} {
+		masternodes, err := x.voteMasternodes(chain, vote)
+		if err != nil {
+			return common.Address{}, err
+		}
+		verified, signer, err := x.verifyMsgSignature(types.VoteSigHash(&types.VoteForSign{
+			ProposedBlockInfo: vote.ProposedBlockInfo,
+			GapNumber:         vote.GapNumber,
+		}), vote.Signature, masternodes)
+		if err != nil {
+			return common.Address{}, err
+		}
+		if !verified {
+			return common.Address{}, fmt.Errorf("vote signer %v is not a masternode of the epoch of block %v", signer.Hex(), vote.ProposedBlockInfo.Number)
+		}
+		signers = append(signers, signer)
+	}
+	if signers[0] != signers[1] {
+		return common.Address{}, fmt.Errorf("votes are signed by different masternodes, %v and %v", signers[0].Hex(), signers[1].Hex())
+	}
+	return signers[0], nil
+}
+
+// voteMasternodes returns the masternodes of the epoch the vote was cast in, read from the canonical epoch switch
+// header of the voted block. The snapshot of the gap number only holds the candidates of the epoch.
+func (x *XDPoS_v2) voteMasternodes(chain consensus.ChainReader, vote *types.Vote) ([]common.Address, error) {
+	number := vote.ProposedBlockInfo.Number.Uint64()
+	epochSwitchNumber := number - number%x.config.Epoch
+	gapNumber := uint64(0)
+	if epochSwitchNumber > x.config.Gap {
+		gapNumber = epochSwitchNumber - x.config.Gap
+	}
+	if vote.GapNumber != gapNumber {
+		return nil, fmt.Errorf("vote gap number %v doesn't match the epoch of block %v", vote.GapNumber, number)
+	}
+	epochSwitchHeader := chain.GetHeaderByNumber(epochSwitchNumber)
+	if epochSwitchHeader == nil {
+		return nil, fmt.Errorf("epoch switch block %v of the vote not found", epochSwitchNumber)
+	}
+	epochSwitchInfo, err := x.getEpochSwitchInfo(chain, epochSwitchHeader, epochSwitchHeader.Hash())
+	if err != nil {
+		log.Error("[voteMasternodes] fail to get epoch switch info for evidence", "number", epochSwitchNumber, "error", err)
+		return nil, err
+	}
+	return epochSwitchInfo.Masternodes, nil
+}
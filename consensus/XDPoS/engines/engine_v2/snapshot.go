@@ -2,9 +2,14 @@ package engine_v2
 
 import (
 	"encoding/json"
+	"fmt"
+	"math/big"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
 	"github.com/XinFinOrg/XDC-Subnet/ethdb"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 )
@@ -20,19 +25,37 @@ type SnapshotV2 struct {
 	NextEpochMasterNodes []common.Address `json:"masterNodes"` // Set of authorized master nodes at this moment for next epoch
 	// Penalty, subnet record it during snapshot
 	NextEpochPenalties []common.Address `json:"penalties"` // Set of master nodes to be removed from master nodes for next epoch
+	// Stakes of the candidates in the validator contract at the gap block, used by the stake selection policy
+	NextEpochStakes map[common.Address]*big.Int `json:"stakes,omitempty"`
+	// BLS public keys registered by the candidates at the gap block, used to verify QC and TC after the BLS fork
+	NextEpochBLSKeys map[common.Address]hexutil.Bytes `json:"blsKeys,omitempty"`
+	// Consensus config voted in the config contract at the gap block, recorded in the next epoch switch block
+	NextEpochConfig *types.EpochConfig `json:"config,omitempty"`
+	// Built from the gap plus one header after the checkpoint, without the state of the gap block
+	HeaderOnly bool `json:"headerOnly,omitempty"`
 }
 
 // create new snapshot for next epoch to use
-func newSnapshot(number uint64, hash common.Hash, masternodes []common.Address, penalties []common.Address) *SnapshotV2 {
+func newSnapshot(number uint64, hash common.Hash, masternodes []common.Address, penalties []common.Address, stakes map[common.Address]*big.Int) *SnapshotV2 {
 	snap := &SnapshotV2{
 		Number:               number,
 		Hash:                 hash,
 		NextEpochMasterNodes: masternodes,
 		NextEpochPenalties:   penalties,
+		NextEpochStakes:      stakes,
 	}
 	return snap
 }
 
+// Get the registered BLS public key of the masternode
+func (s *SnapshotV2) blsPublicKey(masternode common.Address) (*bls.PublicKey, error) {
+	key, ok := s.NextEpochBLSKeys[masternode]
+	if !ok {
+		return nil, fmt.Errorf("masternode %v has no BLS public key registered at gap number %v", masternode.Hex(), s.Number)
+	}
+	return bls.PublicKeyFromBytes(key)
+}
+
 // loadSnapshot loads an existing snapshot from the database.
 func loadSnapshot(db ethdb.Database, hash common.Hash) (*SnapshotV2, error) {
 	blob, err := db.Get(append([]byte("XDPoS-V2-"), hash[:]...))
@@ -87,7 +110,15 @@ func (x *XDPoS_v2) getSnapshot(chain consensus.ChainReader, number uint64, isGap
 		}
 	}
 
-	gapBlockHash := chain.GetHeaderByNumber(gapBlockNum).Hash()
+	var gapBlockHash common.Hash
+	if gapBlockHeader := chain.GetHeaderByNumber(gapBlockNum); gapBlockHeader != nil {
+		gapBlockHash = gapBlockHeader.Hash()
+	} else if hash, ok := x.checkpointGapHash(gapBlockNum); ok {
+		gapBlockHash = hash
+	} else {
+		log.Error("Cannot find gap block header", "number", gapBlockNum)
+		return nil, fmt.Errorf("gap block %v not found", gapBlockNum)
+	}
 	log.Debug("get snapshot from gap block", "number", gapBlockNum, "hash", gapBlockHash.Hex())
 
 	// If an in-memory SnapshotV2 was found, use that
@@ -99,6 +130,10 @@ func (x *XDPoS_v2) getSnapshot(chain consensus.ChainReader, number uint64, isGap
 	// If an on-disk checkpoint snapshot can be found, use that
 	snap, err := loadSnapshot(x.db, gapBlockHash)
 	if err != nil {
+		// The gap block may be synced by header only after the checkpoint
+		if snap := x.snapshotFromHeader(chain, gapBlockNum, gapBlockHash); snap != nil {
+			return snap, nil
+		}
 		log.Error("Cannot find snapshot from last gap block", "err", err, "number", gapBlockNum, "hash", gapBlockHash)
 		return nil, err
 	}
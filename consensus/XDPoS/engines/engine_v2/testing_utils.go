@@ -1,7 +1,11 @@
 package engine_v2
 
 import (
+	"time"
+
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/countdown"
+	"github.com/XinFinOrg/XDC-Subnet/common/mclock"
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 )
@@ -87,3 +91,26 @@ func (x *XDPoS_v2) AuthorizeFaker(signer common.Address) {
 func (x *XDPoS_v2) GetForensicsFaker() *Forensics {
 	return x.ForensicsProcessor
 }
+
+// WARN: This function is designed for testing purpose only!
+// Drive the countdown timer by the given clock, so the timeouts of simulated nodes follow a virtual time.
+// It must be called before the engine is initialised.
+func (x *XDPoS_v2) SetClockFaker(clock mclock.Clock) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	duration := time.Duration(x.config.V2.CurrentConfig.TimeoutPeriod) * time.Second
+	timeoutTimer := countdown.NewCountDownWithClock(duration, clock)
+	timeoutTimer.OnTimeoutFn = x.OnCountdownTimeout
+	x.timeoutWorker = timeoutTimer
+}
+
+// WARN: This function is designed for testing purpose only!
+// Hand the votes, timeouts and syncInfos to the given function instead of the BroadcastCh. It is called
+// synchronously with the engine lock held, so it must not call back into the engine.
+func (x *XDPoS_v2) SetBroadcastFaker(broadcast func(msg interface{})) {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+
+	x.broadcastFn = broadcast
+}
@@ -64,6 +64,15 @@ func (x *XDPoS_v2) onTimeoutPoolThresholdReached(blockChainReader consensus.Chai
 		Signatures: signatures,
 		GapNumber:  gapNumber,
 	}
+	if x.config.V2.IsBLS(gapNumber) {
+		aggregated, err := x.aggregateTimeouts(blockChainReader, pooledTimeouts, timeoutCert)
+		if err != nil {
+			log.Warn("[onTimeoutPoolThresholdReached] Fail to aggregate BLS signatures", "TcRound", timeoutCert.Round, "GapNumber", gapNumber, "Error", err)
+			return nil
+		}
+		timeoutCert.Signatures = nil
+		timeoutCert.Aggregated = []*types.AggregatedSignature{aggregated}
+	}
 	// Process TC
 	err := x.processTC(blockChainReader, timeoutCert)
 	if err != nil {
@@ -78,6 +87,45 @@ func (x *XDPoS_v2) onTimeoutPoolThresholdReached(blockChainReader consensus.Chai
 	return nil
 }
 
+// Aggregate the BLS signatures of the pooled timeouts, indexed by the masternodes of the snapshot as verifyTC does
+func (x *XDPoS_v2) aggregateTimeouts(blockChainReader consensus.ChainReader, pooledTimeouts map[common.Hash]utils.PoolObj, timeoutCert *types.TimeoutCert) (*types.AggregatedSignature, error) {
+	snap, err := x.getSnapshot(blockChainReader, timeoutCert.GapNumber, true)
+	if err != nil {
+		return nil, err
+	}
+	signedTimeoutObj := types.TimeoutSigHash(&types.TimeoutForSign{
+		Round:     timeoutCert.Round,
+		GapNumber: timeoutCert.GapNumber,
+	})
+	blsSignatures := make(map[common.Address][]types.Signature)
+	for _, v := range pooledTimeouts {
+		timeout := v.(*types.Timeout)
+		signer := timeout.GetSigner()
+		// own timeout is not verified before getting into the pool
+		if signer == (common.Address{}) {
+			verified, recovered, err := x.verifyMsgSignature(signedTimeoutObj, timeout.Signature, snap.NextEpochMasterNodes)
+			if err != nil || !verified {
+				continue
+			}
+			signer = recovered
+		}
+		blsSignatures[signer] = timeout.BLSSignature
+	}
+	aggregated, numOfSigners, err := aggregateBLSSignatures(snap.NextEpochMasterNodes, blsSignatures)
+	if err != nil {
+		return nil, err
+	}
+	epochInfo, err := x.getEpochSwitchInfo(blockChainReader, blockChainReader.CurrentHeader(), blockChainReader.CurrentHeader().Hash())
+	if err != nil {
+		return nil, err
+	}
+	certThreshold := x.config.V2.Config(uint64(timeoutCert.Round)).CertThreshold
+	if float64(numOfSigners) < float64(epochInfo.MasternodesLen)*certThreshold {
+		return nil, fmt.Errorf("not enough BLS signatures, %v signers", numOfSigners)
+	}
+	return aggregated, nil
+}
+
 func (x *XDPoS_v2) verifyTC(chain consensus.ChainReader, timeoutCert *types.TimeoutCert) error {
 	/*
 		1. Get epoch master node list by gapNumber
@@ -87,7 +135,7 @@ func (x *XDPoS_v2) verifyTC(chain consensus.ChainReader, timeoutCert *types.Time
 					- Use the above public key to find out the xdc address
 					- Use the above xdc address to check against the master node list from step 1(For the received TC epoch)
 	*/
-	if timeoutCert == nil || timeoutCert.Signatures == nil {
+	if timeoutCert == nil || (timeoutCert.Signatures == nil && timeoutCert.Aggregated == nil) {
 		log.Warn("[verifyTC] TC or TC signatures is Nil")
 		return utils.ErrInvalidTC
 	}
@@ -116,6 +164,24 @@ func (x *XDPoS_v2) verifyTC(chain consensus.ChainReader, timeoutCert *types.Time
 	}
 
 	certThreshold := x.config.V2.Config(uint64(timeoutCert.Round)).CertThreshold
+	if x.config.V2.IsBLS(timeoutCert.GapNumber) {
+		signers, err := verifyAggregatedSignature(snap, snap.NextEpochMasterNodes, types.TimeoutSigHash(&types.TimeoutForSign{
+			Round:     timeoutCert.Round,
+			GapNumber: timeoutCert.GapNumber,
+		}), timeoutCert.Aggregated)
+		if err != nil {
+			log.Warn("[verifyTC] Fail to verify aggregated signature", "timeoutCert.Round", timeoutCert.Round, "timeoutCert.GapNumber", timeoutCert.GapNumber, "Error", err)
+			if _, ok := err.(*utils.ErrInvalidSignature); ok {
+				return &utils.ErrInvalidSignature{Err: fmt.Errorf("fail to verify TC aggregated signature, %s", err)}
+			}
+			return fmt.Errorf("fail to verify TC aggregated signature, %s", err)
+		}
+		if float64(len(signers)) < float64(epochInfo.MasternodesLen)*certThreshold {
+			log.Warn("[verifyTC] Not enough signers in TC", "timeoutCert.Round", timeoutCert.Round, "timeoutCert.GapNumber", timeoutCert.GapNumber, "signers", len(signers), "CertThreshold", float64(epochInfo.MasternodesLen)*certThreshold)
+			return utils.ErrInvalidTCSignatures
+		}
+		return nil
+	}
 	if float64(len(signatures)) < float64(epochInfo.MasternodesLen)*certThreshold {
 		log.Warn("[verifyTC] Invalid TC Signature is nil or empty", "timeoutCert.Round", timeoutCert.Round, "timeoutCert.GapNumber", timeoutCert.GapNumber, "Signatures len", len(timeoutCert.Signatures), "CertThreshold", float64(epochInfo.MasternodesLen)*certThreshold)
 		return utils.ErrInvalidTCSignatures
@@ -142,10 +208,10 @@ func (x *XDPoS_v2) verifyTC(chain consensus.ChainReader, timeoutCert *types.Time
 				if haveError == nil {
 					if err != nil {
 						log.Error("[verifyTC] Error while verfying TC message signatures", "timeoutCert.Round", timeoutCert.Round, "timeoutCert.GapNumber", timeoutCert.GapNumber, "Signatures len", len(signatures), "Error", err)
-						haveError = fmt.Errorf("error while verifying TC message signatures, %s", err)
+						haveError = &utils.ErrInvalidSignature{Err: fmt.Errorf("error while verifying TC message signatures, %s", err)}
 					} else {
 						log.Warn("[verifyTC] Signature not verified doing TC verification", "timeoutCert.Round", timeoutCert.Round, "timeoutCert.GapNumber", timeoutCert.GapNumber, "Signatures len", len(signatures))
-						haveError = fmt.Errorf("fail to verify TC due to signature mis-match")
+						haveError = &utils.ErrInvalidSignature{Err: fmt.Errorf("fail to verify TC due to signature mis-match")}
 					}
 				}
 				mutex.Unlock() // Unlock after modifying haveError
@@ -213,18 +279,25 @@ func (x *XDPoS_v2) sendTimeout(chain consensus.ChainReader) error {
 		log.Debug("[sendTimeout] non-epoch-switch block found its epoch block and calculated the gapNumber", "epochSwitchInfo.EpochSwitchBlockInfo.Number", epochSwitchInfo.EpochSwitchBlockInfo.Number.Uint64(), "gapNumber", gapNumber)
 	}
 
-	signedHash, err := x.signSignature(types.TimeoutSigHash(&types.TimeoutForSign{
+	timeoutHash := types.TimeoutSigHash(&types.TimeoutForSign{
 		Round:     x.currentRound,
 		GapNumber: gapNumber,
-	}))
+	})
+	signedHash, err := x.signSignature(timeoutHash)
 	if err != nil {
 		log.Error("[sendTimeout] signSignature when sending out TC", "Error", err, "round", x.currentRound, "gap", gapNumber)
 		return err
 	}
+	blsSignature, err := x.signBLSSignature(gapNumber, timeoutHash)
+	if err != nil {
+		log.Error("[sendTimeout] signBLSSignature when sending out TC", "Error", err, "round", x.currentRound, "gap", gapNumber)
+		return err
+	}
 	timeoutMsg := &types.Timeout{
-		Round:     x.currentRound,
-		Signature: signedHash,
-		GapNumber: gapNumber,
+		Round:        x.currentRound,
+		Signature:    signedHash,
+		GapNumber:    gapNumber,
+		BLSSignature: blsSignature,
 	}
 	log.Warn("[sendTimeout] Timeout message generated, ready to send!", "timeoutMsgRound", timeoutMsg.Round, "timeoutMsgGapNumber", timeoutMsg.GapNumber, "whosTurn", x.whosTurn)
 	err = x.timeoutHandler(chain, timeoutMsg)
@@ -18,7 +18,7 @@ import (
 func sigHash(header *types.Header) (hash common.Hash) {
 	hasher := sha3.NewKeccak256()
 
-	err := rlp.Encode(hasher, []interface{}{
+	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
 		header.Coinbase,
@@ -36,7 +36,11 @@ func sigHash(header *types.Header) (hash common.Hash) {
 		header.Nonce,
 		header.Validators,
 		header.Penalties,
-	})
+	}
+	if header.BaseFee != nil {
+		enc = append(enc, header.BaseFee)
+	}
+	err := rlp.Encode(hasher, enc)
 	if err != nil {
 		log.Debug("Fail to encode", err)
 	}
@@ -110,7 +114,7 @@ func (x *XDPoS_v2) verifyMsgSignature(signedHashToBeVerified common.Hash, signat
 	// Recover the public key and the Ethereum address
 	pubkey, err := crypto.Ecrecover(signedHashToBeVerified.Bytes(), signature)
 	if err != nil {
-		return false, signerAddress, fmt.Errorf("Error while verifying message: %v", err)
+		return false, signerAddress, &utils.ErrInvalidSignature{Err: fmt.Errorf("Error while verifying message: %v", err)}
 	}
 
 	copy(signerAddress[:], crypto.Keccak256(pubkey[1:])[12:])
@@ -145,6 +149,22 @@ func (x *XDPoS_v2) getExtraFields(header *types.Header) (*types.QuorumCert, type
 	return decodedExtraField.QuorumCert, decodedExtraField.Round, masternodes, nil
 }
 
+// Get the masternodes who signed the quorum certificate in the header extra, which certifies the parent block
+func (x *XDPoS_v2) GetQCSigners(chain consensus.ChainReader, header *types.Header) ([]common.Address, error) {
+	if header.Number.Cmp(x.config.V2.SwitchBlock) <= 0 {
+		return []common.Address{}, nil
+	}
+	var decodedExtraField types.ExtraFields_v2
+	err := utils.DecodeBytesExtraFields(header.Extra, &decodedExtraField)
+	if err != nil {
+		return nil, err
+	}
+	if decodedExtraField.QuorumCert == nil {
+		return []common.Address{}, nil
+	}
+	return x.getQCSigners(chain, decodedExtraField.QuorumCert)
+}
+
 func (x *XDPoS_v2) GetRoundNumber(header *types.Header) (types.Round, error) {
 	// If not v2 yet, return 0
 	if header.Number.Cmp(x.config.V2.SwitchBlock) <= 0 {
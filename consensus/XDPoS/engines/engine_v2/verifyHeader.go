@@ -31,6 +31,16 @@ func (x *XDPoS_v2) verifyHeader(chain consensus.ChainReader, header *types.Heade
 		return nil
 	}
 
+	// the configs voted in the epochs written to the canonical chain since the last verification, or in the
+	// epochs of the batch leading to the header which are not written yet
+	head := chain.CurrentHeader()
+	if len(parents) > 0 {
+		head = parents[len(parents)-1]
+	}
+	if err := x.syncEpochConfigs(chain, head, parents); err != nil {
+		return err
+	}
+
 	if header.Number == nil {
 		return utils.ErrUnknownBlock
 	}
@@ -99,6 +109,12 @@ func (x *XDPoS_v2) verifyHeader(chain consensus.ChainReader, header *types.Heade
 		return utils.ErrInvalidDifficulty
 	}
 
+	epochConfig, _, err := x.getEpochConfig(header)
+	if err != nil {
+		log.Warn("[verifyHeader] decode epoch config error", "err", err)
+		return utils.ErrInvalidV2Extra
+	}
+
 	var masterNodes []common.Address
 	isEpochSwitch, _, err := x.IsEpochSwitch(header) // Verify v2 block that is on the epoch switch
 	if err != nil {
@@ -113,21 +129,38 @@ func (x *XDPoS_v2) verifyHeader(chain consensus.ChainReader, header *types.Heade
 			return utils.ErrEmptyEpochSwitchValidators
 		}
 
-		localMasterNodes, _, err := x.calcMasternodes(chain, header.Number, header.ParentHash, round)
-		masterNodes = localMasterNodes
+		snap, err := x.getSnapshot(chain, number, false)
 		if err != nil {
-			log.Error("[verifyHeader] Fail to calculate master nodes list with penalty", "Number", header.Number, "Hash", header.Hash())
+			log.Error("[verifyHeader] Fail to get snapshot for epoch switch", "Number", header.Number, "Hash", header.Hash(), "error", err)
 			return err
 		}
+		if snap.HeaderOnly {
+			// synced by header only after the checkpoint, the voted config is certified by the QCs of the masternodes
+			if err := x.verifyMasternodesFromHeader(snap, header); err != nil {
+				log.Warn("[verifyHeader] Fail to verify masternodes with the snapshot from header", "Number", header.Number, "Hash", header.Hash())
+				return err
+			}
+			masterNodes = header.Validators
+		} else {
+			localMasterNodes, _, err := x.calcMasternodes(chain, header.Number, header.ParentHash, round)
+			masterNodes = localMasterNodes
+			if err != nil {
+				log.Error("[verifyHeader] Fail to calculate master nodes list with penalty", "Number", header.Number, "Hash", header.Hash())
+				return err
+			}
 
-		if !utils.CompareSignersLists(localMasterNodes, header.Validators) {
-			for i, addr := range localMasterNodes {
-				log.Warn("[verifyHeader] localMasterNodes", "i", i, "addr", addr.Hex())
+			if !utils.CompareSignersLists(localMasterNodes, header.Validators) {
+				for i, addr := range localMasterNodes {
+					log.Warn("[verifyHeader] localMasterNodes", "i", i, "addr", addr.Hex())
+				}
+				for i, addr := range header.Validators {
+					log.Warn("[verifyHeader] validatorsAddress", "i", i, "addr", addr.Hex())
+				}
+				return utils.ErrValidatorsNotLegit
 			}
-			for i, addr := range header.Validators {
-				log.Warn("[verifyHeader] validatorsAddress", "i", i, "addr", addr.Hex())
+			if err := x.verifyEpochConfig(chain, header, epochConfig); err != nil {
+				return err
 			}
-			return utils.ErrValidatorsNotLegit
 		}
 
 	} else {
@@ -135,10 +168,17 @@ func (x *XDPoS_v2) verifyHeader(chain consensus.ChainReader, header *types.Heade
 			log.Warn("[verifyHeader] Validators.CurrentEpoch shall not have values in non-epochSwitch block", "Hash", header.Hash(), "Number", header.Number, "header.Validators", header.Validators)
 			return utils.ErrInvalidFieldInNonEpochSwitch
 		}
+		if epochConfig != nil {
+			log.Warn("[verifyHeader] Epoch config shall not have values in non-epochSwitch block", "Hash", header.Hash(), "Number", header.Number, "config", epochConfig)
+			return utils.ErrInvalidFieldInNonEpochSwitch
+		}
 		masterNodes = x.GetMasternodes(chain, header)
 	}
 	// Verify v2 block that is gap plus one
-	if x.IsGapPlusOneBlock(header) {
+	if x.IsGapPlusOneBlock(header) && x.afterCheckpoint(number) && !x.hasSnapshot(header.ParentHash) {
+		// the gap block is synced by header only, the candidates are certified by the QCs of the following headers
+		log.Debug("[verifyHeader] Skip next validators check without snapshot after checkpoint", "Number", header.Number, "Hash", header.Hash())
+	} else if x.IsGapPlusOneBlock(header) {
 		validatorsAddress := header.NextValidators
 		// this header number == gap + 1, so should use parent hash to get snapshot
 		snapshot, err := x.getSnapshotByHash(header.ParentHash)
@@ -168,6 +208,10 @@ func (x *XDPoS_v2) verifyHeader(chain consensus.ChainReader, header *types.Heade
 	if err := misc.VerifyForkHashes(chain.Config(), header, false); err != nil {
 		return err
 	}
+	// Verify the base fee of the header, it is computed from the parent after the EIP1559 fork
+	if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		return err
+	}
 
 	verified, validatorAddress, err := x.verifyMsgSignature(sigHash(header), header.Validator, masterNodes)
 	if err != nil {
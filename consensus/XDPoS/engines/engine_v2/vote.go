@@ -33,20 +33,27 @@ func (x *XDPoS_v2) sendVote(chainReader consensus.ChainReader, blockInfo *types.
 	if epochSwitchNumber-epochSwitchNumber%x.config.Epoch < x.config.Gap {
 		gapNumber = 0
 	}
-	signedHash, err := x.signSignature(types.VoteSigHash(&types.VoteForSign{
+	voteHash := types.VoteSigHash(&types.VoteForSign{
 		ProposedBlockInfo: blockInfo,
 		GapNumber:         gapNumber,
-	}))
+	})
+	signedHash, err := x.signSignature(voteHash)
 	if err != nil {
 		log.Error("signSignature when sending out Vote", "BlockInfoHash", blockInfo.Hash, "Error", err)
 		return err
 	}
+	blsSignature, err := x.signBLSSignature(gapNumber, voteHash)
+	if err != nil {
+		log.Error("signBLSSignature when sending out Vote", "BlockInfoHash", blockInfo.Hash, "Error", err)
+		return err
+	}
 
 	x.highestVotedRound = x.currentRound
 	voteMsg := &types.Vote{
 		ProposedBlockInfo: blockInfo,
 		Signature:         signedHash,
 		GapNumber:         gapNumber,
+		BLSSignature:      blsSignature,
 	}
 
 	err = x.voteHandler(chainReader, voteMsg)
@@ -184,6 +191,31 @@ func (x *XDPoS_v2) onVotePoolThresholdReached(chain consensus.ChainReader, poole
 		Signatures:        validSignatures,
 		GapNumber:         currentVoteMsg.(*types.Vote).GapNumber,
 	}
+	if x.config.V2.IsBLS(quorumCert.GapNumber) {
+		// Aggregate the BLS signatures, indexed by the masternodes of the proposed block epoch as verifyQC does
+		proposedEpochInfo, err := x.getEpochSwitchInfo(chain, proposedBlockHeader, proposedBlockHeader.Hash())
+		if err != nil {
+			log.Error("[onVotePoolThresholdReached] Error when getting epoch switch Info of the proposed block", "error", err)
+			return err
+		}
+		blsSignatures := make(map[common.Address][]types.Signature)
+		for _, vote := range pooledVotes {
+			if vote.GetSigner() != emptySigner {
+				blsSignatures[vote.GetSigner()] = vote.(*types.Vote).BLSSignature
+			}
+		}
+		aggregated, numOfSigners, err := aggregateBLSSignatures(proposedEpochInfo.Masternodes, blsSignatures)
+		if err != nil {
+			log.Warn("[onVotePoolThresholdReached] Fail to aggregate BLS signatures", "error", err)
+			return nil
+		}
+		if float64(numOfSigners) < float64(epochInfo.MasternodesLen)*certThreshold {
+			log.Warn("[onVotePoolThresholdReached] Not enough BLS signatures to generate QC", "NumberOfSigners", numOfSigners, "NumberOfVotes", len(pooledVotes))
+			return nil
+		}
+		quorumCert.Signatures = nil
+		quorumCert.Aggregated = []*types.AggregatedSignature{aggregated}
+	}
 	err = x.processQC(chain, quorumCert)
 	if err != nil {
 		log.Error("Error while processing QC in the Vote handler after reaching pool threshold, ", err)
@@ -0,0 +1,399 @@
+// Package lightclient verifies the epoch proofs of a XDPoS 2.0 subnet with the masternodes of the
+// previous epoch only. Starting from a trusted epoch, relayers and mobile clients can follow the
+// masternodes of the subnet epoch by epoch, and check that a header is finalised by them.
+// After the BLS fork, the BLS public keys registered by the masternodes are followed along with them.
+package lightclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb/memorydb"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
+)
+
+var (
+	ErrInvalidExtra            = errors.New("invalid XDPoS v2 extra field")
+	ErrInvalidHeaderChain      = errors.New("headers are not chained")
+	ErrNotCommitted            = errors.New("header is not committed by three consecutive rounds")
+	ErrInvalidQuorumCert       = errors.New("quorum certificate does not certify the header")
+	ErrNotEnoughSignatures     = errors.New("not enough masternode signatures in quorum certificate")
+	ErrMissingBLSKey           = errors.New("BLS public key of the signer is not proven")
+	ErrInvalidBLSKeysProof     = errors.New("invalid BLS public keys proof")
+	ErrInvalidEpochSwitch      = errors.New("invalid epoch switch header")
+	ErrMasternodesNotLegit     = errors.New("masternodes are not selected from the committed candidates")
+	ErrInvalidGapPlusOneHeader = errors.New("invalid gap plus one header")
+)
+
+type Config struct {
+	Epoch          uint64     // Number of blocks per epoch
+	Gap            uint64     // Number of blocks before the epoch switch block to take the masternodes snapshot
+	V2             *params.V2 // Consensus v2 configs, a quorum certificate needs the cert threshold in use at its round
+	MaxMasternodes int        // Maximum number of masternodes selected from the candidates
+}
+
+// NewConfig takes the consensus parameters of the subnet chain config
+func NewConfig(config *params.XDPoSConfig) *Config {
+	return &Config{
+		Epoch:          config.Epoch,
+		Gap:            config.Gap,
+		V2:             config.V2,
+		MaxMasternodes: common.MaxMasternodes,
+	}
+}
+
+// Masternodes of an epoch with the BLS public keys they registered at its gap block, the keys are nil before the BLS fork
+type EpochMasternodes struct {
+	Masternodes []common.Address
+	BLSKeys     map[common.Address][]byte
+}
+
+type Verifier struct {
+	config *Config
+}
+
+func NewVerifier(config *Config) *Verifier {
+	return &Verifier{config: config}
+}
+
+// VerifyEpochProof checks the proof with the masternodes of the previous epoch and returns the masternodes of the epoch
+func (v *Verifier) VerifyEpochProof(proof *types.EpochProof, previous *EpochMasternodes) (*EpochMasternodes, error) {
+	if proof.EpochSwitch == nil || proof.NextValidators == nil || len(proof.EpochSwitch.Headers) == 0 || len(proof.NextValidators.Headers) == 0 {
+		return nil, ErrNotCommitted
+	}
+	number := proof.Epoch * v.config.Epoch
+	if number < v.config.Gap {
+		return nil, ErrInvalidEpochSwitch
+	}
+
+	// the candidates and penalties of the epoch are committed by the previous masternodes
+	gapPlusOneHeader := proof.NextValidators.Headers[0]
+	if gapPlusOneHeader.Number == nil || gapPlusOneHeader.Number.Uint64() != number-v.config.Gap+1 {
+		return nil, ErrInvalidGapPlusOneHeader
+	}
+	if err := v.VerifyCommit(proof.NextValidators, previous); err != nil {
+		return nil, err
+	}
+
+	epochSwitchHeader := proof.EpochSwitch.Headers[0]
+	if epochSwitchHeader.Number == nil || epochSwitchHeader.Number.Uint64() != number {
+		return nil, ErrInvalidEpochSwitch
+	}
+	if len(epochSwitchHeader.Validators) == 0 || !sameAddresses(epochSwitchHeader.Validators, proof.Masternodes) {
+		return nil, ErrInvalidEpochSwitch
+	}
+	// the masternodes are selected from the candidates of the gap plus one header on the chain of the epoch switch header
+	if err := verifyHeaderChain(gapPlusOneHeader, proof.Chain, epochSwitchHeader); err != nil {
+		return nil, err
+	}
+	candidates := common.RemoveItemFromArray(gapPlusOneHeader.NextValidators, gapPlusOneHeader.Penalties)
+	if err := v.verifySelection(epochSwitchHeader.Validators, candidates, proof.Epoch, gapPlusOneHeader.ParentHash); err != nil {
+		return nil, err
+	}
+
+	epochMasternodes := &EpochMasternodes{Masternodes: common.CopyAddresses(epochSwitchHeader.Validators)}
+	if proof.BLSKeys != nil {
+		blsKeys, err := v.verifyBLSKeys(proof.BLSKeys, gapPlusOneHeader, epochMasternodes.Masternodes)
+		if err != nil {
+			return nil, err
+		}
+		epochMasternodes.BLSKeys = blsKeys
+	}
+
+	// the epoch switch header is committed by the masternodes it records
+	if err := v.VerifyCommit(proof.EpochSwitch, epochMasternodes); err != nil {
+		return nil, err
+	}
+	return epochMasternodes, nil
+}
+
+// VerifyCommit checks the first header of the proof is committed by the masternodes of its epoch
+func (v *Verifier) VerifyCommit(commit *types.CommitProof, masternodes *EpochMasternodes) error {
+	headers := commit.Headers
+	if len(headers) < 3 || commit.QuorumCert == nil || masternodes == nil {
+		return ErrNotCommitted
+	}
+	if headers[0].Number == nil {
+		return ErrInvalidHeaderChain
+	}
+	epochSwitchNumber := v.epochSwitchNumber(headers[0].Number.Uint64())
+	rounds := make([]types.Round, len(headers))
+	quorumCerts := make([]*types.QuorumCert, len(headers))
+	for i, header := range headers {
+		if header.Number == nil || v.epochSwitchNumber(header.Number.Uint64()) != epochSwitchNumber {
+			return fmt.Errorf("%w: header %v is not in the epoch of block %v", ErrInvalidHeaderChain, header.Number, epochSwitchNumber)
+		}
+		if i > 0 && (header.ParentHash != headers[i-1].Hash() || header.Number.Uint64() != headers[i-1].Number.Uint64()+1) {
+			return ErrInvalidHeaderChain
+		}
+		extra, err := decodeExtraFields(header)
+		if err != nil {
+			return err
+		}
+		rounds[i], quorumCerts[i] = extra.Round, extra.QuorumCert
+	}
+
+	n := len(headers)
+	if rounds[n-3]+1 != rounds[n-2] || rounds[n-2]+1 != rounds[n-1] {
+		return ErrNotCommitted
+	}
+	// only the QCs of the three consecutive rounds are needed, the headers before are chained by the parent hash
+	gapNumber := v.gapNumber(epochSwitchNumber)
+	if err := v.verifyQuorumCert(quorumCerts[n-2], headers[n-3], rounds[n-3], gapNumber, masternodes); err != nil {
+		return err
+	}
+	if err := v.verifyQuorumCert(quorumCerts[n-1], headers[n-2], rounds[n-2], gapNumber, masternodes); err != nil {
+		return err
+	}
+	return v.verifyQuorumCert(commit.QuorumCert, headers[n-1], rounds[n-1], gapNumber, masternodes)
+}
+
+// Verify the QC certifies the header and is signed by enough masternodes, with the cert threshold in use at its round
+func (v *Verifier) verifyQuorumCert(quorumCert *types.QuorumCert, header *types.Header, round types.Round, gapNumber uint64, masternodes *EpochMasternodes) error {
+	if quorumCert == nil || quorumCert.ProposedBlockInfo == nil {
+		return ErrInvalidQuorumCert
+	}
+	blockInfo := quorumCert.ProposedBlockInfo
+	if blockInfo.Hash != header.Hash() || blockInfo.Round != round || blockInfo.Number == nil || blockInfo.Number.Cmp(header.Number) != 0 {
+		return ErrInvalidQuorumCert
+	}
+	if quorumCert.GapNumber != gapNumber {
+		return fmt.Errorf("%w: gap number %v, expect %v", ErrInvalidQuorumCert, quorumCert.GapNumber, gapNumber)
+	}
+
+	signedHash := types.VoteSigHash(&types.VoteForSign{
+		ProposedBlockInfo: blockInfo,
+		GapNumber:         quorumCert.GapNumber,
+	})
+	var signers map[common.Address]struct{}
+	var err error
+	if len(quorumCert.Aggregated) > 0 {
+		signers, err = verifyAggregatedSignature(quorumCert.Aggregated, signedHash, masternodes)
+	} else {
+		signers, err = recoverSigners(quorumCert.Signatures, signedHash, masternodes.Masternodes)
+	}
+	if err != nil {
+		return err
+	}
+	certThreshold := v.config.V2.Config(uint64(round)).CertThreshold
+	if float64(len(signers)) < float64(len(masternodes.Masternodes))*certThreshold {
+		return fmt.Errorf("%w: %v signers of %v masternodes", ErrNotEnoughSignatures, len(signers), len(masternodes.Masternodes))
+	}
+	return nil
+}
+
+// Recover the signers of the secp256k1 signatures, each one must be a masternode
+func recoverSigners(signatures []types.Signature, signedHash common.Hash, masternodes []common.Address) (map[common.Address]struct{}, error) {
+	signers := make(map[common.Address]struct{})
+	for _, signature := range signatures {
+		pubkey, err := crypto.Ecrecover(signedHash.Bytes(), signature)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuorumCert, err)
+		}
+		var signer common.Address
+		copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+		if !containsAddress(masternodes, signer) {
+			return nil, fmt.Errorf("%w: signer %v is not a masternode", ErrInvalidQuorumCert, signer.Hex())
+		}
+		signers[signer] = struct{}{}
+	}
+	return signers, nil
+}
+
+// Verify the aggregated BLS signature against the keys registered by its signers, the signer bitmap is indexed by the masternodes
+func verifyAggregatedSignature(aggregated []*types.AggregatedSignature, signedHash common.Hash, masternodes *EpochMasternodes) (map[common.Address]struct{}, error) {
+	if len(aggregated) != 1 || aggregated[0] == nil {
+		return nil, fmt.Errorf("%w: expect one aggregated signature, got %v", ErrInvalidQuorumCert, len(aggregated))
+	}
+	if len(aggregated[0].SignerBitmap) != (len(masternodes.Masternodes)+7)/8 {
+		return nil, fmt.Errorf("%w: signer bitmap length %v mismatch with %v masternodes", ErrInvalidQuorumCert, len(aggregated[0].SignerBitmap), len(masternodes.Masternodes))
+	}
+	for i := len(masternodes.Masternodes); i < len(aggregated[0].SignerBitmap)*8; i++ {
+		if aggregated[0].HasSigner(i) {
+			return nil, fmt.Errorf("%w: signer bitmap index %v out of %v masternodes", ErrInvalidQuorumCert, i, len(masternodes.Masternodes))
+		}
+	}
+	signers := make(map[common.Address]struct{})
+	publicKeys := []*bls.PublicKey{}
+	for i, masternode := range masternodes.Masternodes {
+		if !aggregated[0].HasSigner(i) {
+			continue
+		}
+		key, ok := masternodes.BLSKeys[masternode]
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrMissingBLSKey, masternode.Hex())
+		}
+		publicKey, err := bls.PublicKeyFromBytes(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMissingBLSKey, err)
+		}
+		signers[masternode] = struct{}{}
+		publicKeys = append(publicKeys, publicKey)
+	}
+	signature, err := bls.SignatureFromBytes(aggregated[0].Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidQuorumCert, err)
+	}
+	if len(publicKeys) == 0 || !bls.VerifyAggregate(publicKeys, signedHash.Bytes(), signature) {
+		return nil, fmt.Errorf("%w: aggregated BLS signature mismatch", ErrInvalidQuorumCert)
+	}
+	return signers, nil
+}
+
+// Verify the keys registered by the masternodes against the state root of the gap header, which is the parent of the
+// gap plus one header. The masternodes without a registered key are left out
+func (v *Verifier) verifyBLSKeys(proof *types.BLSKeysProof, gapPlusOneHeader *types.Header, masternodes []common.Address) (map[common.Address][]byte, error) {
+	if proof.GapHeader == nil || proof.GapHeader.Hash() != gapPlusOneHeader.ParentHash {
+		return nil, fmt.Errorf("%w: gap header is not the parent of the gap plus one header", ErrInvalidBLSKeysProof)
+	}
+	nodes := memorydb.New()
+	for _, node := range append(append([]hexutil.Bytes{}, proof.AccountProof...), proof.StorageProof...) {
+		nodes.Put(crypto.Keccak256(node), node)
+	}
+	registry := common.HexToAddress(common.BLSRegistrySMC)
+	value, err := trie.VerifyProof(proof.GapHeader.Root, crypto.Keccak256(registry.Bytes()), nodes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBLSKeysProof, err)
+	}
+	blsKeys := make(map[common.Address][]byte)
+	// no key is registered yet
+	if value == nil {
+		return blsKeys, nil
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(value, &account); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBLSKeysProof, err)
+	}
+	for _, masternode := range masternodes {
+		firstLoc, secondLoc := state.GetBLSPublicKeyLocs(masternode)
+		first, err := verifyStorage(account.Root, firstLoc, nodes)
+		if err != nil {
+			return nil, err
+		}
+		second, err := verifyStorage(account.Root, secondLoc, nodes)
+		if err != nil {
+			return nil, err
+		}
+		if key := state.DecodeBLSPublicKey(first, second); key != nil {
+			blsKeys[masternode] = key
+		}
+	}
+	return blsKeys, nil
+}
+
+// Get the value of the storage slot proven against the storage root, the slot is zero if it's not in the trie
+func verifyStorage(root common.Hash, loc common.Hash, nodes *memorydb.Database) (common.Hash, error) {
+	value, err := trie.VerifyProof(root, crypto.Keccak256(loc.Bytes()), nodes)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%w: %v", ErrInvalidBLSKeysProof, err)
+	}
+	if value == nil {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(value)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%w: %v", ErrInvalidBLSKeysProof, err)
+	}
+	return common.BytesToHash(content), nil
+}
+
+// Check the headers link the first header to the last one, each header is the parent of the next one
+func verifyHeaderChain(first *types.Header, headers []*types.Header, last *types.Header) error {
+	parent := first
+	for _, header := range append(append([]*types.Header{}, headers...), last) {
+		if header == nil || header.Number == nil || header.ParentHash != parent.Hash() || header.Number.Uint64() != parent.Number.Uint64()+1 {
+			return fmt.Errorf("%w: epoch switch header is not a descendant of the gap plus one header", ErrInvalidHeaderChain)
+		}
+		parent = header
+	}
+	return nil
+}
+
+// The masternodes are the first candidates ordered by the selection policy, same as the engine. The stake policy can't be
+// replayed without the validator state, so its masternodes are only checked to be distinct candidates and as many as selected
+func (v *Verifier) verifySelection(masternodes []common.Address, candidates []common.Address, epoch uint64, gapHash common.Hash) error {
+	seats := len(candidates)
+	if v.config.MaxMasternodes > 0 && seats > v.config.MaxMasternodes {
+		seats = v.config.MaxMasternodes
+	}
+	if len(masternodes) != seats {
+		return fmt.Errorf("%w: %v masternodes, expect %v", ErrMasternodesNotLegit, len(masternodes), seats)
+	}
+	switch v.config.V2.MasternodeSelection {
+	case params.MasternodeSelectionStake:
+		seen := make(map[common.Address]struct{}, len(masternodes))
+		for _, masternode := range masternodes {
+			if _, ok := seen[masternode]; ok || !containsAddress(candidates, masternode) {
+				return fmt.Errorf("%w: %v", ErrMasternodesNotLegit, masternode.Hex())
+			}
+			seen[masternode] = struct{}{}
+		}
+		return nil
+	case params.MasternodeSelectionRotation:
+		candidates = utils.OrderByRotation(candidates, seats, epoch)
+	case params.MasternodeSelectionShuffle:
+		candidates = utils.OrderByShuffle(candidates, gapHash)
+	}
+	// the default and unknown policies keep the order of the candidates
+	if !sameAddresses(masternodes, candidates[:seats]) {
+		return fmt.Errorf("%w: masternodes are not the selected candidates in order", ErrMasternodesNotLegit)
+	}
+	return nil
+}
+
+func (v *Verifier) epochSwitchNumber(number uint64) uint64 {
+	return number - number%v.config.Epoch
+}
+
+// Same as the engine, votes of an epoch are signed with the gap number before its epoch switch block
+func (v *Verifier) gapNumber(epochSwitchNumber uint64) uint64 {
+	if epochSwitchNumber < v.config.Gap {
+		return 0
+	}
+	return epochSwitchNumber - v.config.Gap
+}
+
+func decodeExtraFields(header *types.Header) (*types.ExtraFields_v2, error) {
+	// the first byte is the consensus version
+	if len(header.Extra) == 0 || header.Extra[0] != 2 {
+		return nil, ErrInvalidExtra
+	}
+	var extra types.ExtraFields_v2
+	if err := rlp.DecodeBytes(header.Extra[1:], &extra); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExtra, err)
+	}
+	if extra.QuorumCert == nil {
+		return nil, ErrInvalidExtra
+	}
+	return &extra, nil
+}
+
+func containsAddress(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
+
+func sameAddresses(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,135 @@
+package lightclient
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyAggregatedQuorumCert(t *testing.T) {
+	masternodes := []common.Address{{0x1}, {0x2}, {0x3}, {0x4}}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	keys := make([]*bls.SecretKey, len(masternodes))
+	for i, masternode := range masternodes {
+		key, err := bls.GenerateKey()
+		assert.Nil(t, err)
+		keys[i] = key
+		// the last masternode doesn't register its key
+		if i < len(masternodes)-1 {
+			state.SetBLSPublicKey(statedb, masternode, key.PublicKey().Bytes())
+		}
+	}
+	root, err := statedb.Commit(false)
+	assert.Nil(t, err)
+	accountProof, storageProof, err := state.GetBLSPublicKeysProof(statedb, masternodes)
+	assert.Nil(t, err)
+
+	proof := &types.BLSKeysProof{GapHeader: &types.Header{Number: big.NewInt(1350), Root: root}}
+	for _, node := range accountProof {
+		proof.AccountProof = append(proof.AccountProof, node)
+	}
+	for _, node := range storageProof {
+		proof.StorageProof = append(proof.StorageProof, node)
+	}
+	gapPlusOneHeader := &types.Header{Number: big.NewInt(1351), ParentHash: proof.GapHeader.Hash()}
+
+	config := &params.V2{AllConfigs: map[uint64]*params.V2Config{0: {CertThreshold: 0.667}}}
+	config.BuildConfigIndex()
+	verifier := NewVerifier(&Config{Epoch: 900, Gap: 450, V2: config})
+
+	blsKeys, err := verifier.verifyBLSKeys(proof, gapPlusOneHeader, masternodes)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(blsKeys))
+	for i, masternode := range masternodes[:3] {
+		assert.Equal(t, keys[i].PublicKey().Bytes(), blsKeys[masternode])
+	}
+
+	// the keys must be proven against the parent of the gap plus one header
+	_, err = verifier.verifyBLSKeys(proof, &types.Header{Number: big.NewInt(1351)}, masternodes)
+	assert.True(t, errors.Is(err, ErrInvalidBLSKeysProof))
+	forged := *proof
+	forged.StorageProof = []hexutil.Bytes{}
+	_, err = verifier.verifyBLSKeys(&forged, gapPlusOneHeader, masternodes)
+	assert.True(t, errors.Is(err, ErrInvalidBLSKeysProof))
+
+	// the first three masternodes sign the QC
+	header := &types.Header{Number: big.NewInt(1801)}
+	quorumCert := &types.QuorumCert{
+		ProposedBlockInfo: &types.BlockInfo{Hash: header.Hash(), Round: 10, Number: header.Number},
+		GapNumber:         1350,
+	}
+	signedHash := types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: quorumCert.ProposedBlockInfo, GapNumber: quorumCert.GapNumber})
+	aggregated := &types.AggregatedSignature{SignerBitmap: make([]byte, 1)}
+	var signatures []*bls.Signature
+	for i := range masternodes[:3] {
+		aggregated.SetSigner(i)
+		signatures = append(signatures, keys[i].Sign(signedHash.Bytes()))
+	}
+	aggregated.Signature = bls.AggregateSignatures(signatures).Bytes()
+	quorumCert.Aggregated = []*types.AggregatedSignature{aggregated}
+
+	epoch := &EpochMasternodes{Masternodes: masternodes, BLSKeys: blsKeys}
+	assert.Nil(t, verifier.verifyQuorumCert(quorumCert, header, 10, 1350, epoch))
+
+	// a signer without proven key can't be verified
+	err = verifier.verifyQuorumCert(quorumCert, header, 10, 1350, &EpochMasternodes{Masternodes: masternodes})
+	assert.True(t, errors.Is(err, ErrMissingBLSKey))
+
+	// claiming other signers fails the pairing check
+	forgedAggregated := &types.AggregatedSignature{SignerBitmap: common.CopyBytes(aggregated.SignerBitmap), Signature: aggregated.Signature}
+	forgedAggregated.SignerBitmap[0] &^= 1
+	forgedQuorumCert := *quorumCert
+	forgedQuorumCert.Aggregated = []*types.AggregatedSignature{forgedAggregated}
+	err = verifier.verifyQuorumCert(&forgedQuorumCert, header, 10, 1350, epoch)
+	assert.True(t, errors.Is(err, ErrInvalidQuorumCert))
+
+	// the cert threshold is the one in use at the round of the QC
+	config.AddConfig(&params.V2Config{SwitchRound: 9, CertThreshold: 1})
+	err = verifier.verifyQuorumCert(quorumCert, header, 10, 1350, epoch)
+	assert.True(t, errors.Is(err, ErrNotEnoughSignatures))
+}
+
+func TestVerifySelection(t *testing.T) {
+	candidates := []common.Address{{0x1}, {0x2}, {0x3}, {0x4}, {0x5}}
+	gapHash := common.Hash{0x1}
+	config := &params.V2{}
+	verifier := NewVerifier(&Config{Epoch: 900, Gap: 450, V2: config, MaxMasternodes: 3})
+
+	// the first candidates in snapshot order
+	assert.Nil(t, verifier.verifySelection([]common.Address{{0x1}, {0x2}, {0x3}}, candidates, 1, gapHash))
+	err := verifier.verifySelection([]common.Address{{0x2}, {0x1}, {0x3}}, candidates, 1, gapHash)
+	assert.True(t, errors.Is(err, ErrMasternodesNotLegit))
+
+	// the standbys of the previous epoch take the first seats
+	config.MasternodeSelection = params.MasternodeSelectionRotation
+	assert.Nil(t, verifier.verifySelection([]common.Address{{0x3}, {0x4}, {0x5}}, candidates, 1, gapHash))
+	err = verifier.verifySelection([]common.Address{{0x1}, {0x2}, {0x3}}, candidates, 1, gapHash)
+	assert.True(t, errors.Is(err, ErrMasternodesNotLegit))
+
+	// the candidates are shuffled with the gap block hash
+	config.MasternodeSelection = params.MasternodeSelectionShuffle
+	shuffled := utils.OrderByShuffle(append([]common.Address{}, candidates...), gapHash)
+	assert.Nil(t, verifier.verifySelection(shuffled[:3], candidates, 1, gapHash))
+	err = verifier.verifySelection([]common.Address{shuffled[1], shuffled[0], shuffled[2]}, candidates, 1, gapHash)
+	assert.True(t, errors.Is(err, ErrMasternodesNotLegit))
+	err = verifier.verifySelection(shuffled[2:5], candidates, 1, gapHash)
+	assert.True(t, errors.Is(err, ErrMasternodesNotLegit))
+
+	// the stakes are unknown, any distinct candidates are accepted
+	config.MasternodeSelection = params.MasternodeSelectionStake
+	assert.Nil(t, verifier.verifySelection([]common.Address{{0x5}, {0x1}, {0x3}}, candidates, 1, gapHash))
+	err = verifier.verifySelection([]common.Address{{0x5}, {0x5}, {0x3}}, candidates, 1, gapHash)
+	assert.True(t, errors.Is(err, ErrMasternodesNotLegit))
+	err = verifier.verifySelection([]common.Address{{0x5}, {0x6}, {0x3}}, candidates, 1, gapHash)
+	assert.True(t, errors.Is(err, ErrMasternodesNotLegit))
+}
@@ -50,6 +50,7 @@ var (
 	ErrValidatorsNotLegit          = errors.New("validators does not match what's stored in snapshot")
 	ErrPenaltiesNotLegit           = errors.New("penalties does not match")
 	ErrNextEpochValidatorsNotLegit = errors.New("next epoch validators does not match what's stored in snapshot")
+	ErrEpochConfigNotLegit         = errors.New("epoch config does not match what's stored in snapshot")
 
 	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
 	ErrInvalidMixDigest = errors.New("non-zero mix digest")
@@ -104,6 +105,20 @@ var (
 	ErrAlreadyMined = errors.New("Already mined")
 )
 
+// ErrInvalidSignature is a signature of a consensus message or certificate which doesn't verify. Unlike the messages
+// failing verification because the local chain is behind, the peer sending it is surely misbehaving
+type ErrInvalidSignature struct {
+	Err error
+}
+
+func (e *ErrInvalidSignature) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrInvalidSignature) Unwrap() error {
+	return e.Err
+}
+
 type ErrIncomingMessageRoundNotEqualCurrentRound struct {
 	Type          string
 	IncomingRound types.Round
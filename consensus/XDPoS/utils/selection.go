@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"sort"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+)
+
+// Sort candidates by stake in descending order, ties are broken by address so all nodes get the same order
+func OrderByStake(candidates []common.Address, stakes map[common.Address]*big.Int) []common.Address {
+	stakeOf := func(addr common.Address) *big.Int {
+		if stake, ok := stakes[addr]; ok && stake != nil {
+			return stake
+		}
+		return common.Big0
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if c := stakeOf(candidates[i]).Cmp(stakeOf(candidates[j])); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(candidates[i][:], candidates[j][:]) < 0
+	})
+	return candidates
+}
+
+// Rotate candidates so that the standby nodes of the previous epoch take the first seats of the current epoch
+func OrderByRotation(candidates []common.Address, seats int, epochNum uint64) []common.Address {
+	if len(candidates) <= seats {
+		return candidates
+	}
+	standbys := uint64(len(candidates) - seats)
+	offset := int(epochNum * standbys % uint64(len(candidates)))
+	rotated := make([]common.Address, 0, len(candidates))
+	rotated = append(rotated, candidates[offset:]...)
+	return append(rotated, candidates[:offset]...)
+}
+
+// Fisher-Yates shuffle of the candidates, seeded from the gap block hash
+func OrderByShuffle(candidates []common.Address, seed common.Hash) []common.Address {
+	index := make([]byte, 8)
+	for i := len(candidates) - 1; i > 0; i-- {
+		binary.BigEndian.PutUint64(index, uint64(i))
+		h := crypto.Keccak256(seed[:], index)
+		j := new(big.Int).Mod(new(big.Int).SetBytes(h), big.NewInt(int64(i+1))).Int64()
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	}
+	return candidates
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderByStake(t *testing.T) {
+	candidates := []common.Address{{0x1}, {0x2}, {0x3}, {0x4}}
+	stakes := map[common.Address]*big.Int{
+		{0x1}: big.NewInt(10),
+		{0x2}: big.NewInt(30),
+		{0x4}: big.NewInt(30),
+	}
+	ordered := OrderByStake(candidates, stakes)
+	assert.Equal(t, []common.Address{{0x2}, {0x4}, {0x1}, {0x3}}, ordered)
+}
+
+func TestOrderByRotation(t *testing.T) {
+	candidates := []common.Address{{0x1}, {0x2}, {0x3}, {0x4}, {0x5}}
+	// 3 seats, 2 standbys. Standbys of the previous epoch get the first seats
+	assert.Equal(t, []common.Address{{0x1}, {0x2}, {0x3}, {0x4}, {0x5}}, OrderByRotation(candidates, 3, 0))
+	assert.Equal(t, []common.Address{{0x3}, {0x4}, {0x5}, {0x1}, {0x2}}, OrderByRotation(candidates, 3, 1))
+	assert.Equal(t, []common.Address{{0x5}, {0x1}, {0x2}, {0x3}, {0x4}}, OrderByRotation(candidates, 3, 2))
+	// No standby, nothing to rotate
+	assert.Equal(t, candidates, OrderByRotation(candidates, 5, 7))
+}
+
+func TestOrderByShuffle(t *testing.T) {
+	candidates := []common.Address{{0x1}, {0x2}, {0x3}, {0x4}, {0x5}}
+	first := OrderByShuffle(append([]common.Address{}, candidates...), common.Hash{0x1})
+	second := OrderByShuffle(append([]common.Address{}, candidates...), common.Hash{0x1})
+	assert.Equal(t, first, second)
+	assert.ElementsMatch(t, candidates, first)
+}
@@ -11,12 +11,14 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/consensus/clique"
 	"github.com/XinFinOrg/XDC-Subnet/core/state"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/params"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
 type Masternode struct {
-	Address common.Address
-	Stake   *big.Int
+	Address      common.Address
+	Stake        *big.Int
+	BLSPublicKey []byte // Registered BLS public key, empty if not registered
 }
 
 type TradingService interface {
@@ -31,6 +33,8 @@ type TradingService interface {
 	IsSDKNode() bool
 	SyncDataToSDKNode(takerOrder *tradingstate.OrderItem, txHash common.Hash, txMatchTime time.Time, statedb *state.StateDB, trades []map[string]string, rejectedOrders []*tradingstate.OrderItem, dirtyOrderCount *uint64) error
 	RollbackReorgTxMatch(txhash common.Hash) error
+	ProcessExpiredOrders(header *types.Header, statedb *state.StateDB, tradingStateDB *tradingstate.TradingStateDB) ([]*tradingstate.OrderItem, error)
+	SyncExpiredOrdersToSDKNode(txHash common.Hash, txTime time.Time, expiredOrders []*tradingstate.OrderItem) error
 	GetTokenDecimal(chain consensus.ChainContext, statedb *state.StateDB, tokenAddr common.Address) (*big.Int, error)
 }
 
@@ -47,6 +51,8 @@ type LendingService interface {
 	SyncDataToSDKNode(chain consensus.ChainContext, state *state.StateDB, block *types.Block, takerOrderInTx *lendingstate.LendingItem, txHash common.Hash, txMatchTime time.Time, trades []*lendingstate.LendingTrade, rejectedOrders []*lendingstate.LendingItem, dirtyOrderCount *uint64) error
 	UpdateLiquidatedTrade(blockTime uint64, result lendingstate.FinalizedResult, trades map[common.Hash]*lendingstate.LendingTrade) error
 	RollbackLendingData(txhash common.Hash) error
+	ProcessExpiredOrders(header *types.Header, statedb *state.StateDB, tradingState *tradingstate.TradingStateDB, lendingState *lendingstate.LendingStateDB) ([]*lendingstate.LendingItem, error)
+	SyncExpiredItemsToSDKNode(txHash common.Hash, txTime time.Time, expiredItems []*lendingstate.LendingItem) error
 }
 
 type PublicApiSnapshot struct {
@@ -71,3 +77,9 @@ type PublicApiMissedRoundsMetadata struct {
 	EpochBlockNumber *big.Int
 	MissedRounds     []MissedRoundInfo
 }
+
+type PublicApiEpochConfig struct {
+	Active         *params.V2Config   `json:"active"`                   // Config in use for the blocks after the requested one
+	Pending        *types.EpochConfig `json:"pending"`                  // Config voted at the gap block, recorded in the next epoch switch block
+	NextEpochBlock uint64             `json:"nextEpochBlock,omitempty"` // Number of the epoch switch block recording the pending config
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package misc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+// config returns a chain config with EIP1559 activated at block 5.
+func config() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.EIP1559Block = big.NewInt(5)
+	return &config
+}
+
+// TestVerifyEip1559Header tests the base fee of the first EIP1559 block and of a block before the fork.
+func TestVerifyEip1559Header(t *testing.T) {
+	initial := new(big.Int).SetUint64(params.InitialBaseFee)
+
+	for i, tc := range []struct {
+		pNum     int64
+		pBaseFee *big.Int
+		num      int64
+		baseFee  *big.Int
+		ok       bool
+	}{
+		{3, nil, 4, nil, true},            // Before the fork
+		{3, nil, 4, initial, false},       // Base fee before the fork
+		{4, nil, 5, initial, true},        // First block of the fork
+		{4, nil, 5, nil, false},           // Missing base fee
+		{4, nil, 5, big.NewInt(1), false}, // Wrong initial base fee
+		{5, initial, 6, initial, true},    // Gas used on target
+	} {
+		parent := &types.Header{
+			Number:   big.NewInt(tc.pNum),
+			GasLimit: 10000000,
+			GasUsed:  5000000,
+			BaseFee:  tc.pBaseFee,
+		}
+		header := &types.Header{
+			Number:   big.NewInt(tc.num),
+			GasLimit: 10000000,
+			BaseFee:  tc.baseFee,
+		}
+		err := VerifyEip1559Header(config(), parent, header)
+		if tc.ok && err != nil {
+			t.Errorf("test %d: Expected valid header: %s", i, err)
+		}
+		if !tc.ok && err == nil {
+			t.Errorf("test %d: Expected invalid header", i)
+		}
+	}
+}
+
+// TestCalcBaseFee assumes all blocks are 1559-blocks
+func TestCalcBaseFee(t *testing.T) {
+	tests := []struct {
+		parentBaseFee   int64
+		parentGasLimit  uint64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{params.InitialBaseFee, 20000000, 10000000, params.InitialBaseFee}, // usage == target
+		{params.InitialBaseFee, 20000000, 9000000, params.InitialBaseFee},  // usage below target, floored at the initial base fee
+		{2 * params.InitialBaseFee, 20000000, 9000000, 493750000},          // usage below target
+		{2 * params.InitialBaseFee, 20000000, 0, 437500000},                // empty block
+		{params.InitialBaseFee, 20000000, 11000000, 253125000},             // usage above target
+		{params.InitialBaseFee, 20000000, 20000000, 281250000},             // full block
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(32),
+			GasLimit: test.parentGasLimit,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(test.parentBaseFee),
+		}
+		if have, want := CalcBaseFee(config(), parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d  want %d, ", i, have, want)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package engine_v2_tests
 
 import (
+	"encoding/json"
+	"errors"
 	"math/big"
 	"testing"
 
+	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/lightclient"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/params"
 	"github.com/XinFinOrg/XDC-Subnet/rpc"
@@ -97,3 +101,70 @@ func TestGetMissedRoundsInEpochByBlockNum(t *testing.T) {
 
 	assert.NotEqual(t, data.MissedRounds[0].Miner, data.MissedRounds[1].Miner)
 }
+
+func TestGetEpochProofAndVerify(t *testing.T) {
+	blockchain, _, _, _, _, _ := PrepareXDCTestBlockChainForV2Engine(t, 1805, params.TestXDPoSMockChainConfig, nil)
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	api := engine.APIs(blockchain)[0].Service.(*XDPoS.API)
+
+	proof, err := api.GetEpochProof(2)
+	assert.Nil(t, err)
+	assert.Equal(t, blockchain.GetBlockByNumber(1800).Hash(), proof.EpochSwitch.Headers[0].Hash())
+	assert.Equal(t, blockchain.GetBlockByNumber(1351).Hash(), proof.NextValidators.Headers[0].Hash())
+	assert.Equal(t, 3, len(proof.EpochSwitch.Headers))
+	// blocks 1352 to 1799 link the gap plus one header to the epoch switch header
+	assert.Equal(t, 448, len(proof.Chain))
+	assert.Equal(t, blockchain.GetBlockByNumber(1352).Hash(), proof.Chain[0].Hash())
+
+	// the proof is verified with the masternodes of the previous epoch only
+	previousMasternodes := &lightclient.EpochMasternodes{Masternodes: blockchain.GetBlockByNumber(900).Header().Validators}
+	verifier := lightclient.NewVerifier(lightclient.NewConfig(params.TestXDPoSMockChainConfig.XDPoS))
+	masternodes, err := verifier.VerifyEpochProof(proof, previousMasternodes)
+	assert.Nil(t, err)
+	assert.Equal(t, proof.Masternodes, masternodes.Masternodes)
+	// before the BLS fork, there is no key to follow
+	assert.Nil(t, proof.BLSKeys)
+	assert.Nil(t, masternodes.BLSKeys)
+
+	// proofs are relayed as JSON
+	encoded, err := json.Marshal(proof)
+	assert.Nil(t, err)
+	var decoded types.EpochProof
+	assert.Nil(t, json.Unmarshal(encoded, &decoded))
+	_, err = verifier.VerifyEpochProof(&decoded, previousMasternodes)
+	assert.Nil(t, err)
+
+	// candidates must be committed by the previous masternodes
+	_, err = verifier.VerifyEpochProof(proof, &lightclient.EpochMasternodes{Masternodes: []common.Address{acc1Addr, acc2Addr, acc3Addr}})
+	assert.True(t, errors.Is(err, lightclient.ErrInvalidQuorumCert))
+
+	// masternodes must be the ones recorded in the epoch switch header
+	forged := *proof
+	forged.Masternodes = []common.Address{acc1Addr}
+	_, err = verifier.VerifyEpochProof(&forged, previousMasternodes)
+	assert.Equal(t, lightclient.ErrInvalidEpochSwitch, err)
+
+	// the epoch switch header must descend from the gap plus one header
+	forged = *proof
+	forged.Chain = proof.Chain[1:]
+	_, err = verifier.VerifyEpochProof(&forged, previousMasternodes)
+	assert.True(t, errors.Is(err, lightclient.ErrInvalidHeaderChain))
+	forged.Chain = append([]*types.Header{}, proof.Chain...)
+	forged.Chain[100] = blockchain.GetBlockByNumber(1800).Header()
+	_, err = verifier.VerifyEpochProof(&forged, previousMasternodes)
+	assert.True(t, errors.Is(err, lightclient.ErrInvalidHeaderChain))
+
+	// the last QC must be signed by enough masternodes
+	commit := *proof.EpochSwitch
+	quorumCert := *commit.QuorumCert
+	quorumCert.Signatures = quorumCert.Signatures[:2]
+	commit.QuorumCert = &quorumCert
+	forged = *proof
+	forged.EpochSwitch = &commit
+	_, err = verifier.VerifyEpochProof(&forged, previousMasternodes)
+	assert.True(t, errors.Is(err, lightclient.ErrNotEnoughSignatures))
+
+	// the epoch is not committed yet
+	_, err = api.GetEpochProof(3)
+	assert.NotNil(t, err)
+}
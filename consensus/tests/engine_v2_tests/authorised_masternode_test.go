@@ -103,7 +103,7 @@ func TestIsYourTurnConsensusV2CrossConfig(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, isYourTurn)
 
-	adaptor.UpdateParams(currentBlockHeader) // it will be triggered automatically on the real code by other process
+	adaptor.UpdateParams(blockchain, currentBlockHeader) // it will be triggered automatically on the real code by other process
 
 	// after new mine period
 	secondMinePeriod := blockchain.Config().XDPoS.V2.CurrentConfig.MinePeriod
@@ -0,0 +1,124 @@
+package engine_v2_tests
+
+import (
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+	"github.com/XinFinOrg/XDC-Subnet/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// headersAfterCheckpoint is a chain synced from a checkpoint, the headers between the genesis and it are missing
+type headersAfterCheckpoint struct {
+	*core.BlockChain
+	checkpoint uint64
+}
+
+func (c *headersAfterCheckpoint) missing(number uint64) bool {
+	return number > 0 && number < c.checkpoint
+}
+
+func (c *headersAfterCheckpoint) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if c.missing(number) {
+		return nil
+	}
+	return c.BlockChain.GetHeader(hash, number)
+}
+
+func (c *headersAfterCheckpoint) GetHeaderByNumber(number uint64) *types.Header {
+	if c.missing(number) {
+		return nil
+	}
+	return c.BlockChain.GetHeaderByNumber(number)
+}
+
+func (c *headersAfterCheckpoint) GetHeaderByHash(hash common.Hash) *types.Header {
+	header := c.BlockChain.GetHeaderByHash(hash)
+	if header == nil || c.missing(header.Number.Uint64()) {
+		return nil
+	}
+	return header
+}
+
+func (c *headersAfterCheckpoint) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if c.missing(number) {
+		return nil
+	}
+	return c.BlockChain.GetBlock(hash, number)
+}
+
+func TestVerifyHeadersFromCheckpoint(t *testing.T) {
+	blockchain, _, _, _, _, _ := PrepareXDCTestBlockChainForV2Engine(t, 1805, params.TestXDPoSMockChainConfig, nil)
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	api := engine.APIs(blockchain)[0].Service.(*XDPoS.API)
+
+	// the checkpoint is the epoch switch block of the given block
+	number := rpc.BlockNumber(1000)
+	checkpoint, err := api.GetCheckpoint(&number)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(900), checkpoint.Number)
+	assert.Equal(t, blockchain.GetHeaderByNumber(900).Hash(), checkpoint.Hash)
+	assert.Equal(t, blockchain.GetTd(checkpoint.Hash, 900), checkpoint.TotalDifficulty)
+	assert.Equal(t, uint64(450), checkpoint.Snapshot.Number)
+
+	// a new node only has the headers from the checkpoint, and no snapshot built from the state
+	synced := XDPoS.NewFaker(rawdb.NewMemoryDatabase(), params.TestXDPoSMockChainConfig)
+	assert.Nil(t, synced.EngineV2.SetCheckpoint(checkpoint))
+	chain := &headersAfterCheckpoint{BlockChain: blockchain, checkpoint: checkpoint.Number}
+
+	for _, n := range []uint64{901, 1351, 1800, 1801} {
+		err = synced.VerifyHeader(chain, blockchain.GetHeaderByNumber(n), true)
+		assert.Nil(t, err, "block %v", n)
+	}
+
+	// the masternodes of the next epoch must be selected from the candidates of the gap plus one header
+	forged := types.CopyHeader(blockchain.GetHeaderByNumber(1800))
+	forged.Validators = forged.Validators[1:]
+	err = synced.VerifyHeader(chain, forged, true)
+	assert.Equal(t, utils.ErrValidatorsNotLegit, err)
+}
+
+func TestCheckpointAfterRestart(t *testing.T) {
+	blockchain, _, _, _, _, _ := PrepareXDCTestBlockChainForV2Engine(t, 1805, params.TestXDPoSMockChainConfig, nil)
+	engine := blockchain.Engine().(*XDPoS.XDPoS)
+	api := engine.APIs(blockchain)[0].Service.(*XDPoS.API)
+
+	number := rpc.BlockNumber(1000)
+	checkpoint, err := api.GetCheckpoint(&number)
+	assert.Nil(t, err)
+
+	db := rawdb.NewMemoryDatabase()
+	synced := XDPoS.NewFaker(db, params.TestXDPoSMockChainConfig)
+	assert.Nil(t, synced.EngineV2.SetCheckpoint(checkpoint))
+	chain := &headersAfterCheckpoint{BlockChain: blockchain, checkpoint: checkpoint.Number}
+	for _, n := range []uint64{901, 1351, 1800} {
+		assert.Nil(t, synced.VerifyHeader(chain, blockchain.GetHeaderByNumber(n), true), "block %v", n)
+	}
+	// the snapshot built from the gap plus one header is stored
+	gapHash := blockchain.GetHeaderByNumber(1350).Hash()
+	has, err := db.Has(append([]byte("XDPoS-V2-"), gapHash[:]...))
+	assert.Nil(t, err)
+	assert.True(t, has)
+
+	// the restarted node keeps syncing from the checkpoint without the checkpoint file
+	restarted := XDPoS.NewFaker(db, params.TestXDPoSMockChainConfig)
+	assert.NotNil(t, restarted.EngineV2.TrustedCheckpoint())
+	assert.Equal(t, checkpoint.Hash, restarted.EngineV2.TrustedCheckpoint().Hash)
+	assert.Equal(t, checkpoint.Snapshot.Hash, restarted.EngineV2.TrustedCheckpoint().Snapshot.Hash)
+	for _, n := range []uint64{1801, 1802} {
+		assert.Nil(t, restarted.VerifyHeader(chain, blockchain.GetHeaderByNumber(n), true), "block %v", n)
+	}
+
+	// the same checkpoint can be set again, but not another one
+	assert.Nil(t, restarted.EngineV2.SetCheckpoint(checkpoint))
+	number = rpc.BlockNumber(1800)
+	other, err := api.GetCheckpoint(&number)
+	assert.Nil(t, err)
+	assert.NotNil(t, restarted.EngineV2.SetCheckpoint(other))
+}
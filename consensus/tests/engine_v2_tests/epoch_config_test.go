@@ -0,0 +1,108 @@
+package engine_v2_tests
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+1. Vote a new config in the snapshot of gap block 1350
+2. The epoch switch block 1800 must record it in its extra
+3. It takes effect after the round of block 1800 once applied
+*/
+func TestEpochConfigRecordedAndApplied(t *testing.T) {
+	b, err := json.Marshal(params.TestXDPoSMockChainConfig)
+	assert.Nil(t, err)
+	var config params.ChainConfig
+	err = json.Unmarshal(b, &config)
+	assert.Nil(t, err)
+	// Enable verify
+	config.XDPoS.V2.SkipV2Validation = false
+	blockchain, _, currentBlock, _, _, _ := PrepareXDCTestBlockChainForV2Engine(t, int(config.XDPoS.Epoch)*2, &config, nil)
+	adaptor := blockchain.Engine().(*XDPoS.XDPoS)
+	x := adaptor.EngineV2
+
+	snap, err := x.GetSnapshot(blockchain, currentBlock.Header())
+	assert.Nil(t, err)
+	epochConfig := &types.EpochConfig{MinePeriod: 5, CertThreshold: 1000}
+	snap.NextEpochConfig = epochConfig
+
+	pending, err := x.GetEpochConfig(blockchain, blockchain.GetBlockByNumber(1799).Header())
+	assert.Nil(t, err)
+	assert.Equal(t, epochConfig, pending.Pending)
+	assert.Equal(t, uint64(1800), pending.NextEpochBlock)
+
+	header1800 := blockchain.GetBlockByNumber(1800).Header()
+	header1800.Validators = snap.NextEpochMasterNodes
+	round, err := x.GetRoundNumber(header1800)
+	assert.Nil(t, err)
+	previous := *config.XDPoS.V2.Config(uint64(round) + 1)
+
+	adaptor.EngineV2.SetNewRoundFaker(blockchain, round-1, false)
+
+	// config voted but not recorded
+	err = adaptor.VerifyHeader(blockchain, header1800, true)
+	assert.Equal(t, utils.ErrEpochConfigNotLegit, err)
+
+	var extra types.ExtraFields_v2
+	err = utils.DecodeBytesExtraFields(header1800.Extra, &extra)
+	assert.Nil(t, err)
+	extra.EpochConfig = []*types.EpochConfig{epochConfig}
+	header1800.Extra, err = extra.EncodeToBytes()
+	assert.Nil(t, err)
+	err = adaptor.VerifyHeader(blockchain, header1800, true)
+	// error ErrValidatorNotWithinMasternodes means the epoch config is verified and move to next verification process
+	assert.Equal(t, utils.ErrValidatorNotWithinMasternodes, err)
+
+	// non epoch switch block can't record a config
+	header1799 := blockchain.GetBlockByNumber(1799).Header()
+	extra = types.ExtraFields_v2{}
+	err = utils.DecodeBytesExtraFields(header1799.Extra, &extra)
+	assert.Nil(t, err)
+	extra.EpochConfig = []*types.EpochConfig{epochConfig}
+	header1799.Extra, err = extra.EncodeToBytes()
+	assert.Nil(t, err)
+	err = adaptor.VerifyHeader(blockchain, header1799, true)
+	assert.Equal(t, utils.ErrInvalidFieldInNonEpochSwitch, err)
+
+	adaptor.UpdateParams(blockchain, header1800)
+	applied := config.XDPoS.V2.Config(uint64(round) + 1)
+	assert.Equal(t, 5, applied.MinePeriod)
+	assert.Equal(t, 1.0, applied.CertThreshold)
+	assert.Equal(t, previous.TimeoutPeriod, applied.TimeoutPeriod)
+	assert.Equal(t, previous.TimeoutSyncThreshold, applied.TimeoutSyncThreshold)
+	assert.Equal(t, applied, config.XDPoS.V2.CurrentConfig)
+	// the epoch switch block itself still uses the previous config
+	assert.Equal(t, previous.MinePeriod, config.XDPoS.V2.Config(uint64(round)).MinePeriod)
+
+	active, err := x.GetEpochConfig(blockchain, header1800)
+	assert.Nil(t, err)
+	assert.Equal(t, applied, active.Active)
+	assert.Nil(t, active.Pending)
+
+	// the canonical block 1800 records no config, so the config of the replaced header is dropped
+	adaptor.UpdateParams(blockchain, blockchain.CurrentHeader())
+	assert.Equal(t, previous, *config.XDPoS.V2.Config(uint64(round)+1))
+	assert.Equal(t, previous.MinePeriod, config.XDPoS.V2.CurrentConfig.MinePeriod)
+
+	// a header not written to the canonical chain never registers its config
+	err = adaptor.VerifyHeader(blockchain, header1800, true)
+	assert.Equal(t, utils.ErrValidatorNotWithinMasternodes, err)
+	assert.Equal(t, previous, *config.XDPoS.V2.Config(uint64(round)+1))
+
+	// unless it leads the next headers of the same batch, which are verified with its config
+	header1801 := types.CopyHeader(header1800)
+	header1801.Number = big.NewInt(1801)
+	header1801.ParentHash = header1800.Hash()
+	_, results := adaptor.VerifyHeaders(blockchain, []*types.Header{header1800, header1801}, []bool{true, true})
+	<-results
+	<-results
+	assert.Equal(t, *applied, *config.XDPoS.V2.Config(uint64(round)+1))
+}
@@ -9,9 +9,11 @@ import (
 
 	"github.com/XinFinOrg/XDC-Subnet/accounts"
 	"github.com/XinFinOrg/XDC-Subnet/accounts/abi/bind/backends"
+	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
 	"github.com/XinFinOrg/XDC-Subnet/params"
 	"github.com/stretchr/testify/assert"
 )
@@ -389,6 +391,44 @@ func TestVoteEquivocationSameRound(t *testing.T) {
 	}
 }
 
+// Tests that the double votes of a slashing evidence are only accepted if they are
+// signed by a masternode of the epoch of the voted blocks.
+func TestVerifyVoteEquivocation(t *testing.T) {
+	var numOfForks = new(int)
+	*numOfForks = 1
+	blockchain, _, currentBlock, signer, signFn, currentForkBlock := PrepareXDCTestBlockChainForV2Engine(t, 901, params.TestXDPoSMockChainConfig, &ForkedBlockOptions{numOfForkedBlocks: numOfForks})
+	engineV2 := blockchain.Engine().(*XDPoS.XDPoS).EngineV2
+
+	vote := func(hash common.Hash, gapNumber uint64, sign func([]byte) ([]byte, error)) *types.Vote {
+		blockInfo := &types.BlockInfo{Hash: hash, Round: types.Round(905), Number: big.NewInt(901)}
+		signedHash, err := sign(types.VoteSigHash(&types.VoteForSign{ProposedBlockInfo: blockInfo, GapNumber: gapNumber}).Bytes())
+		assert.Nil(t, err)
+		return &types.Vote{ProposedBlockInfo: blockInfo, Signature: signedHash, GapNumber: gapNumber}
+	}
+	masternode := func(hash []byte) ([]byte, error) {
+		return signFn(accounts.Account{Address: signer}, hash)
+	}
+	outsiderKey, _ := crypto.GenerateKey()
+	outsider := func(hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, outsiderKey)
+	}
+
+	evidence := &types.SlashingEvidence{FirstVote: vote(currentBlock.Hash(), 450, masternode), SecondVote: vote(currentForkBlock.Hash(), 450, masternode)}
+	slashed, err := engineV2.VerifyVoteEquivocation(blockchain, evidence)
+	assert.Nil(t, err)
+	assert.Equal(t, signer, slashed)
+
+	// the gap number must be the one of the epoch of the voted blocks
+	evidence = &types.SlashingEvidence{FirstVote: vote(currentBlock.Hash(), 0, masternode), SecondVote: vote(currentForkBlock.Hash(), 0, masternode)}
+	_, err = engineV2.VerifyVoteEquivocation(blockchain, evidence)
+	assert.NotNil(t, err)
+
+	// the signer must be a masternode of the epoch
+	evidence = &types.SlashingEvidence{FirstVote: vote(currentBlock.Hash(), 450, outsider), SecondVote: vote(currentForkBlock.Hash(), 450, outsider)}
+	_, err = engineV2.VerifyVoteEquivocation(blockchain, evidence)
+	assert.NotNil(t, err)
+}
+
 func TestVoteEquivocationDifferentRound(t *testing.T) {
 	var numOfForks = new(int)
 	*numOfForks = 10
@@ -471,7 +471,7 @@ func PrepareXDCTestBlockChainForV2Engine(t *testing.T, numOfBlocks int, chainCon
 	if err != nil {
 		t.Fatal(err)
 	}
-	engine.EngineV2.UpdateParams(currentBlock.Header()) // it will be triggered automatically on the real code by other process
+	engine.EngineV2.UpdateParams(blockchain, currentBlock.Header()) // it will be triggered automatically on the real code by other process
 
 	return blockchain, backend, currentBlock, signer, signFn, currentForkBlock
 }
@@ -672,20 +672,21 @@ func createBlockFromHeader(bc *BlockChain, customHeader *types.Header, txs []*ty
 	}
 
 	header := types.Header{
-		ParentHash:  customHeader.ParentHash,
-		UncleHash:   types.EmptyUncleHash,
-		TxHash:      types.EmptyRootHash,
-		ReceiptHash: customHeader.ReceiptHash,
-		Root:        customHeader.Root,
-		Coinbase:    customHeader.Coinbase,
-		Difficulty:  difficulty,
-		Number:      customHeader.Number,
-		GasLimit:    1200000000,
-		Time:        big.NewInt(time.Now().Unix() - 1000000 + int64(customHeader.Number.Uint64()*10)),
-		Extra:       customHeader.Extra,
-		Validator:   customHeader.Validator,
-		Validators:  customHeader.Validators,
-		Penalties:   customHeader.Penalties,
+		ParentHash:     customHeader.ParentHash,
+		UncleHash:      types.EmptyUncleHash,
+		TxHash:         types.EmptyRootHash,
+		ReceiptHash:    customHeader.ReceiptHash,
+		Root:           customHeader.Root,
+		Coinbase:       customHeader.Coinbase,
+		Difficulty:     difficulty,
+		Number:         customHeader.Number,
+		GasLimit:       1200000000,
+		Time:           big.NewInt(time.Now().Unix() - 1000000 + int64(customHeader.Number.Uint64()*10)),
+		Extra:          customHeader.Extra,
+		Validator:      customHeader.Validator,
+		Validators:     customHeader.Validators,
+		Penalties:      customHeader.Penalties,
+		NextValidators: customHeader.NextValidators,
 	}
 	var block *types.Block
 	if len(txs) == 0 {
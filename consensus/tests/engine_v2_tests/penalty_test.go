@@ -47,3 +47,56 @@ func TestHookPenaltyV2TwoEpoch(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 124, len(penalty)) // 1 master node has signing tx cached, so it comes back. 125-1 candidates are penalties
 }
+
+func TestHookPenaltyV2Jail(t *testing.T) {
+	b, err := json.Marshal(params.TestXDPoSMockChainConfig)
+	assert.Nil(t, err)
+	configString := string(b)
+
+	var config params.ChainConfig
+	err = json.Unmarshal([]byte(configString), &config)
+	assert.Nil(t, err)
+	// set V2 switch to 0
+	config.XDPoS.V2.SwitchBlock.SetUint64(0)
+	config.XDPoS.Penalty = &params.PenaltyConfig{Block: big.NewInt(0), MinSignaturePercent: 50, JailEpochs: 2}
+	conf := &config
+	blockchain, _, _, signer, _ := PrepareXDCTestBlockChainWith128Candidates(t, int(config.XDPoS.Epoch+config.XDPoS.Gap)-2, conf)
+	adaptor := blockchain.Engine().(*XDPoS.XDPoS)
+	hooks.AttachConsensusV2Hooks(adaptor, blockchain, conf)
+
+	// acc1, acc2, acc3, voter and signer sign all the QCs, all other masternodes are jailed
+	qcSigners := map[common.Address]bool{acc1Addr: true, acc2Addr: true, acc3Addr: true, voterAddr: true, signer: true}
+	header449 := blockchain.GetHeaderByNumber(449)
+	masternodes := adaptor.GetMasternodes(blockchain, header449)
+	jailed, err := hooks.GetJailedMasternodes(adaptor, blockchain, header449, config.XDPoS.Penalty)
+	assert.Nil(t, err)
+	expected := 0
+	for _, addr := range masternodes {
+		if !qcSigners[addr] {
+			expected++
+		}
+	}
+	assert.NotZero(t, expected)
+	assert.Equal(t, expected, len(jailed))
+	for _, addr := range jailed {
+		assert.False(t, qcSigners[addr])
+	}
+
+	// jail lasts two epochs, the offenders of 0 - 899 are still jailed at 1348
+	header1348 := blockchain.GetHeaderByNumber(config.XDPoS.Epoch + config.XDPoS.Gap - 2)
+	jailed, err = hooks.GetJailedMasternodes(adaptor, blockchain, header1348, config.XDPoS.Penalty)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, len(jailed), expected)
+	for _, addr := range jailed {
+		assert.False(t, qcSigners[addr])
+	}
+
+	header450 := blockchain.GetHeaderByNumber(450)
+	penalty, err := adaptor.EngineV2.HookPenalty(blockchain, header450.Number, header450.ParentHash, adaptor.GetMasternodesFromCheckpointHeader(blockchain.GetHeaderByNumber(1)), config.XDPoS)
+	assert.Nil(t, err)
+	for _, addr := range masternodes {
+		if !qcSigners[addr] {
+			assert.Contains(t, penalty, addr)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package simulation
+
+import (
+	"sync"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+// chain is the header store of a simulated node, it implements consensus.ChainReader.
+// The canonical chain follows the highest header, as every v2 block has the difficulty of one.
+// It is read by the forensics goroutines of the engine, so it is protected by a lock.
+type chain struct {
+	config *params.ChainConfig
+
+	headers   map[common.Hash]*types.Header
+	canonical map[uint64]common.Hash
+	head      *types.Header
+	lock      sync.RWMutex
+}
+
+func newChain(config *params.ChainConfig, genesis *types.Header) *chain {
+	c := &chain{
+		config:    config,
+		headers:   make(map[common.Hash]*types.Header),
+		canonical: make(map[uint64]common.Hash),
+	}
+	c.headers[genesis.Hash()] = genesis
+	c.canonical[0] = genesis.Hash()
+	c.head = genesis
+	return c
+}
+
+// insert stores the header, whose parent must be known, and reorgs the canonical chain if it is higher than the head
+func (c *chain) insert(header *types.Header) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	hash := header.Hash()
+	c.headers[hash] = header
+	if header.Number.Cmp(c.head.Number) <= 0 {
+		return
+	}
+	for h := header; h != nil && c.canonical[h.Number.Uint64()] != h.Hash(); h = c.headers[h.ParentHash] {
+		c.canonical[h.Number.Uint64()] = h.Hash()
+	}
+	c.head = header
+}
+
+func (c *chain) has(hash common.Hash) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	_, ok := c.headers[hash]
+	return ok
+}
+
+func (c *chain) Config() *params.ChainConfig {
+	return c.config
+}
+
+func (c *chain) CurrentHeader() *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.head
+}
+
+func (c *chain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := c.GetHeaderByHash(hash)
+	if header == nil || header.Number.Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+func (c *chain) GetHeaderByNumber(number uint64) *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if number > c.head.Number.Uint64() {
+		return nil
+	}
+	hash, ok := c.canonical[number]
+	if !ok {
+		return nil
+	}
+	return c.headers[hash]
+}
+
+func (c *chain) GetHeaderByHash(hash common.Hash) *types.Header {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.headers[hash]
+}
+
+func (c *chain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	header := c.GetHeader(hash, number)
+	if header == nil {
+		return nil
+	}
+	return types.NewBlockWithHeader(header)
+}
@@ -0,0 +1,379 @@
+/*
+Package simulation runs the XDPoS v2 engines of a set of masternodes in process, on a simulated network
+and a virtual clock:
+  - Every node has its own engine, database and header store, the blocks carry no transactions
+  - Proposals, votes, timeouts and syncInfos are delivered with a latency, and can be dropped or cut by partitions
+  - The countdown timers of the engines and the message deliveries are driven by the same virtual clock, and the
+    randomness comes from a seeded source, so a scenario replays the same way for the same seed
+  - The blocks committed by every node are checked against each other for safety, liveness is checked by the
+    tests by running the network until a block number is committed
+*/
+package simulation
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/mclock"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+const (
+	genesisTime = 1600000000            // Unix time of the genesis block, the virtual clock starts from it
+	stepPeriod  = 10 * time.Millisecond // Virtual time the network advances between proposal and safety checks
+)
+
+// Config of a simulated network
+type Config struct {
+	Nodes int    // Number of masternodes
+	Epoch uint64 // Number of blocks per epoch
+	Gap   uint64 // Number of blocks before the epoch switch when the next masternodes are decided
+
+	MinePeriod           int     // Seconds between a block and its parent
+	TimeoutPeriod        int     // Seconds before a round times out
+	TimeoutSyncThreshold int     // Number of timeouts before a syncInfo is sent
+	CertThreshold        float64 // Ratio of masternodes to sign a QC or a TC
+
+	Latency  time.Duration // Minimum delay of a message
+	Jitter   time.Duration // Maximum random delay added to the latency
+	DropRate float64       // Probability of a message to be lost
+	Seed     int64         // Seed of the keys and the network randomness
+}
+
+// DefaultConfig is a network of four masternodes with short epochs, on a fast and reliable network
+func DefaultConfig() Config {
+	return Config{
+		Nodes:                4,
+		Epoch:                30,
+		Gap:                  15,
+		MinePeriod:           2,
+		TimeoutPeriod:        10,
+		TimeoutSyncThreshold: 3,
+		CertThreshold:        0.667,
+		Latency:              50 * time.Millisecond,
+		Jitter:               100 * time.Millisecond,
+		Seed:                 1,
+	}
+}
+
+// chainConfig creates the chain config of a node, each engine needs its own as the v2 config is updated by rounds
+func (c *Config) chainConfig() *params.ChainConfig {
+	v2Config := &params.V2Config{
+		SwitchRound:          0,
+		MinePeriod:           c.MinePeriod,
+		TimeoutPeriod:        c.TimeoutPeriod,
+		TimeoutSyncThreshold: c.TimeoutSyncThreshold,
+		CertThreshold:        c.CertThreshold,
+	}
+	return &params.ChainConfig{
+		ChainId:        big.NewInt(1337),
+		HomesteadBlock: big.NewInt(0),
+		EIP150Block:    big.NewInt(0),
+		EIP155Block:    big.NewInt(0),
+		EIP158Block:    big.NewInt(0),
+		ByzantiumBlock: big.NewInt(0),
+		XDPoS: &params.XDPoSConfig{
+			Period: uint64(c.MinePeriod),
+			Epoch:  c.Epoch,
+			Gap:    c.Gap,
+			V2: &params.V2{
+				SwitchBlock:   big.NewInt(0),
+				CurrentConfig: v2Config,
+				AllConfigs:    map[uint64]*params.V2Config{0: v2Config},
+			},
+		},
+	}
+}
+
+// Stats counts the messages of the network
+type Stats struct {
+	Proposed      int // Blocks proposed by the nodes
+	Sent          int // Messages sent to a peer
+	Dropped       int // Messages lost by drops, partitions or stopped nodes
+	InvalidBlocks int // Blocks failing the header verification of a node
+}
+
+// Network of simulated masternodes
+type Network struct {
+	config Config
+	clock  *mclock.Simulated
+	rand   *rand.Rand
+	nodes  []*Node
+	groups []int // Partition of each node, the messages between partitions are lost
+
+	committed  map[uint64]*types.Header // Blocks committed by any node
+	violations []string
+	stats      Stats
+}
+
+// NewNetwork creates the masternodes from a common genesis block, and starts their engines
+func NewNetwork(config Config) (*Network, error) {
+	network := &Network{
+		config:    config,
+		clock:     new(mclock.Simulated),
+		rand:      rand.New(rand.NewSource(config.Seed)),
+		groups:    make([]int, config.Nodes),
+		committed: make(map[uint64]*types.Header),
+	}
+	keys := make([]*ecdsa.PrivateKey, config.Nodes)
+	masternodes := make([]utils.Masternode, config.Nodes)
+	for i := range keys {
+		key, err := crypto.ToECDSA(crypto.Keccak256([]byte(fmt.Sprintf("simulation-%d-%d", config.Seed, i))))
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		masternodes[i] = utils.Masternode{Address: crypto.PubkeyToAddress(key.PublicKey)}
+	}
+	genesis := newGenesis(masternodes)
+	for i, key := range keys {
+		node, err := newNode(network, i, key, genesis, masternodes)
+		if err != nil {
+			network.Close()
+			return nil, err
+		}
+		network.nodes = append(network.nodes, node)
+	}
+	return network, nil
+}
+
+// newGenesis creates the switch block of consensus v2, its extra carries the first masternodes as in v1
+func newGenesis(masternodes []utils.Masternode) *types.Header {
+	extra := make([]byte, utils.ExtraVanity, utils.ExtraVanity+len(masternodes)*common.AddressLength+utils.ExtraSeal)
+	for _, masternode := range masternodes {
+		extra = append(extra, masternode.Address.Bytes()...)
+	}
+	extra = append(extra, make([]byte, utils.ExtraSeal)...)
+	return &types.Header{
+		Number:      big.NewInt(0),
+		Time:        big.NewInt(genesisTime),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    params.GenesisGasLimit,
+		Extra:       extra,
+		UncleHash:   types.EmptyUncleHash,
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Root:        types.EmptyRootHash,
+	}
+}
+
+// Close stops the countdown timers of the engines
+func (net *Network) Close() {
+	for _, node := range net.nodes {
+		node.close()
+	}
+}
+
+// Node returns the masternode of the given index
+func (net *Network) Node(index int) *Node {
+	return net.nodes[index]
+}
+
+// Now returns the virtual time in seconds, as used for the block timestamps
+func (net *Network) Now() uint64 {
+	return genesisTime + uint64(time.Duration(net.clock.Now())/time.Second)
+}
+
+// Run advances the virtual clock, delivering the messages, firing the timeouts and proposing the blocks on the way
+func (net *Network) Run(duration time.Duration) {
+	for elapsed := time.Duration(0); elapsed < duration; elapsed += stepPeriod {
+		net.step()
+	}
+}
+
+// RunUntil advances the virtual clock until the condition holds, it reports false if the timeout is reached first
+func (net *Network) RunUntil(condition func() bool, timeout time.Duration) bool {
+	for elapsed := time.Duration(0); elapsed < timeout; elapsed += stepPeriod {
+		if condition() {
+			return true
+		}
+		net.step()
+	}
+	return condition()
+}
+
+// RunUntilCommitted advances the virtual clock until every running node committed the given block number
+func (net *Network) RunUntilCommitted(number uint64, timeout time.Duration) bool {
+	return net.RunUntil(func() bool {
+		return net.CommittedNumber() >= number
+	}, timeout)
+}
+
+func (net *Network) step() {
+	net.clock.Run(stepPeriod)
+	for _, node := range net.nodes {
+		node.propose()
+	}
+	net.checkCommits()
+}
+
+// Stop cuts the node from the network as a crashed masternode, it keeps its state to be resumed by Start
+func (net *Network) Stop(index int) {
+	net.nodes[index].down = true
+}
+
+// Start reconnects a stopped node to the network
+func (net *Network) Start(index int) {
+	net.nodes[index].down = false
+}
+
+// Partition splits the network in the given groups of node indexes, the nodes left out are isolated
+func (net *Network) Partition(groups ...[]int) {
+	for i := range net.groups {
+		net.groups[i] = -i - 1
+	}
+	for g, group := range groups {
+		for _, index := range group {
+			net.groups[index] = g
+		}
+	}
+}
+
+// Heal removes the partitions
+func (net *Network) Heal() {
+	for i := range net.groups {
+		net.groups[i] = 0
+	}
+}
+
+// SetLatency changes the delay of the messages sent from now on
+func (net *Network) SetLatency(latency time.Duration, jitter time.Duration) {
+	net.config.Latency = latency
+	net.config.Jitter = jitter
+}
+
+// SetDropRate changes the probability of the messages sent from now on to be lost
+func (net *Network) SetDropRate(rate float64) {
+	net.config.DropRate = rate
+}
+
+// Stats returns the message counters of the network
+func (net *Network) Stats() Stats {
+	return net.stats
+}
+
+// Violations returns the safety violations found so far
+func (net *Network) Violations() []string {
+	return net.violations
+}
+
+// CommittedNumber returns the lowest number committed by the running nodes
+func (net *Network) CommittedNumber() uint64 {
+	lowest := ^uint64(0)
+	for _, node := range net.nodes {
+		if node.down {
+			continue
+		}
+		if number := node.CommittedNumber(); number < lowest {
+			lowest = number
+		}
+	}
+	return lowest
+}
+
+// CommittedHeader returns the header committed at the given number by any node
+func (net *Network) CommittedHeader(number uint64) *types.Header {
+	return net.committed[number]
+}
+
+// broadcast sends the message of the node to all the other nodes
+func (net *Network) broadcast(from *Node, msg interface{}) {
+	for _, node := range net.nodes {
+		if node != from {
+			net.send(from, node, msg)
+		}
+	}
+}
+
+// send delivers a copy of the message to the node after the latency, unless it is lost
+func (net *Network) send(from *Node, to *Node, msg interface{}) {
+	net.stats.Sent++
+	if from.down || to.down || net.groups[from.Index] != net.groups[to.Index] || net.rand.Float64() < net.config.DropRate {
+		net.stats.Dropped++
+		return
+	}
+	delay := net.config.Latency
+	if net.config.Jitter > 0 {
+		delay += time.Duration(net.rand.Int63n(int64(net.config.Jitter) + 1))
+	}
+	msg = copyMessage(msg)
+	net.clock.AfterFunc(delay, func() {
+		if to.down || net.groups[from.Index] != net.groups[to.Index] {
+			net.stats.Dropped++
+			return
+		}
+		to.deliver(from, msg)
+	})
+}
+
+// copyMessage gives each receiver its own message, as the engines record the recovered signer in it
+func copyMessage(msg interface{}) interface{} {
+	switch m := msg.(type) {
+	case *types.Vote:
+		vote := *m
+		vote.SetSigner(common.Address{})
+		return &vote
+	case *types.Timeout:
+		timeout := *m
+		timeout.SetSigner(common.Address{})
+		return &timeout
+	case *blockMsg:
+		return &blockMsg{header: types.CopyHeader(m.header), relay: m.relay}
+	}
+	return msg
+}
+
+// checkCommits records the blocks newly committed by every node, a node must only extend the blocks it committed
+// and no two nodes may commit different blocks at the same number
+func (net *Network) checkCommits() {
+	for _, node := range net.nodes {
+		info := node.engine.GetLatestCommittedBlockInfo()
+		if info == nil || (node.committed != nil && node.committed.Hash() == info.Hash) {
+			continue
+		}
+		header := node.chain.GetHeaderByHash(info.Hash)
+		if header == nil {
+			net.violate("node %d committed unknown block %d %x", node.Index, info.Number, info.Hash)
+			continue
+		}
+		var from uint64
+		if node.committed != nil {
+			from = node.committed.Number.Uint64()
+		}
+		h := header
+		for h != nil && h.Number.Uint64() > from {
+			net.commit(node, h)
+			h = node.chain.GetHeader(h.ParentHash, h.Number.Uint64()-1)
+		}
+		if node.committed != nil && (h == nil || h.Hash() != node.committed.Hash()) {
+			net.violate("node %d committed block %d %x not extending its committed block %d %x", node.Index, header.Number, header.Hash(), node.committed.Number, node.committed.Hash())
+		}
+		node.committed = header
+	}
+}
+
+func (net *Network) commit(node *Node, header *types.Header) {
+	number := header.Number.Uint64()
+	committed, ok := net.committed[number]
+	if !ok {
+		net.committed[number] = header
+		return
+	}
+	if committed.Hash() != header.Hash() {
+		net.violate("node %d committed block %d %x conflicting with %x", node.Index, number, header.Hash(), committed.Hash())
+	}
+}
+
+func (net *Network) violate(format string, args ...interface{}) {
+	violation := fmt.Sprintf(format, args...)
+	log.Error("[checkCommits] safety violation", "violation", violation)
+	net.violations = append(net.violations, violation)
+}
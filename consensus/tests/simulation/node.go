@@ -0,0 +1,272 @@
+package simulation
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/XinFinOrg/XDC-Subnet/accounts"
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/engines/engine_v2"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+)
+
+// blockMsg carries a header, proposals are relayed to the other nodes while the requested ancestors are not
+type blockMsg struct {
+	header *types.Header
+	relay  bool
+}
+
+// headerRequest asks a node for a missing ancestor
+type headerRequest struct {
+	hash common.Hash
+}
+
+// Node is a simulated masternode
+type Node struct {
+	Index   int
+	Address common.Address
+
+	network     *Network
+	engine      *engine_v2.XDPoS_v2
+	chain       *chain
+	masternodes []utils.Masternode // Candidates recorded in the snapshot at every gap block
+	down        bool
+	quit        chan struct{}
+
+	known         map[common.Hash]struct{}        // Consensus messages already handled
+	orphans       map[common.Hash][]*types.Header // Headers waiting for their parent, by parent hash
+	requested     map[common.Hash]struct{}        // Ancestors requested from a peer
+	proposedRound types.Round                     // Latest round the node tried to propose in
+	committed     *types.Header                   // Latest committed header checked by the network
+}
+
+func newNode(network *Network, index int, key *ecdsa.PrivateKey, genesis *types.Header, masternodes []utils.Masternode) (*Node, error) {
+	config := network.config.chainConfig()
+	minePeriodCh := make(chan int)
+	node := &Node{
+		Index:       index,
+		Address:     crypto.PubkeyToAddress(key.PublicKey),
+		network:     network,
+		engine:      engine_v2.New(config, rawdb.NewMemoryDatabase(), minePeriodCh),
+		chain:       newChain(config, types.CopyHeader(genesis)),
+		masternodes: masternodes,
+		quit:        make(chan struct{}),
+		known:       make(map[common.Hash]struct{}),
+		orphans:     make(map[common.Hash][]*types.Header),
+		requested:   make(map[common.Hash]struct{}),
+	}
+	// The mine period is given by the config, the miner is simulated by propose
+	go func() {
+		for {
+			select {
+			case <-minePeriodCh:
+			case <-node.quit:
+				return
+			}
+		}
+	}()
+	node.engine.SetClockFaker(network.clock)
+	node.engine.SetBroadcastFaker(node.broadcast)
+	node.engine.Authorize(node.Address, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	if err := node.engine.Initial(node.chain, node.chain.CurrentHeader()); err != nil {
+		close(node.quit)
+		return nil, err
+	}
+	return node, nil
+}
+
+func (n *Node) close() {
+	n.engine.SetBroadcastFaker(func(interface{}) {})
+	close(n.quit)
+}
+
+// Engine returns the consensus engine of the node
+func (n *Node) Engine() *engine_v2.XDPoS_v2 {
+	return n.engine
+}
+
+// CurrentHeader returns the head of the canonical chain of the node
+func (n *Node) CurrentHeader() *types.Header {
+	return n.chain.CurrentHeader()
+}
+
+// CommittedNumber returns the latest block number committed by the node
+func (n *Node) CommittedNumber() uint64 {
+	info := n.engine.GetLatestCommittedBlockInfo()
+	if info == nil {
+		return 0
+	}
+	return info.Number.Uint64()
+}
+
+// broadcast sends the votes, timeouts and syncInfos of the engine, it is called with the engine lock held
+func (n *Node) broadcast(msg interface{}) {
+	if hash, ok := messageHash(msg); ok {
+		n.known[hash] = struct{}{}
+	}
+	n.network.broadcast(n, msg)
+}
+
+func messageHash(msg interface{}) (common.Hash, bool) {
+	switch m := msg.(type) {
+	case *types.Vote:
+		return m.Hash(), true
+	case *types.Timeout:
+		return m.Hash(), true
+	case *types.SyncInfo:
+		return m.Hash(), true
+	}
+	return common.Hash{}, false
+}
+
+// propose mines a block on the highest QC once the mine period has passed, if the node is the leader of the round
+func (n *Node) propose() {
+	if n.down {
+		return
+	}
+	round := n.engine.GetCurrentRoundFaker()
+	if round <= n.proposedRound {
+		return
+	}
+	parent := n.engine.FindParentBlockToAssign(n.chain)
+	if parent == nil {
+		return
+	}
+	now := n.network.Now()
+	if now < parent.Time().Uint64()+uint64(n.network.config.MinePeriod) {
+		return
+	}
+	n.proposedRound = round
+
+	header := &types.Header{
+		ParentHash:  parent.Hash(),
+		Number:      new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:    parent.GasLimit(),
+		Coinbase:    n.Address,
+		Root:        parent.Root(),
+		UncleHash:   types.EmptyUncleHash,
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+	}
+	if err := n.engine.Prepare(n.chain, header); err != nil {
+		return
+	}
+	header.Time = new(big.Int).SetUint64(now)
+	block, err := n.engine.Seal(n.chain, types.NewBlockWithHeader(header), nil)
+	if err != nil {
+		log.Error("[propose] Fail to seal block", "node", n.Index, "number", header.Number, "err", err)
+		return
+	}
+	n.network.stats.Proposed++
+	n.importHeader(block.Header())
+	n.network.broadcast(n, &blockMsg{header: block.Header(), relay: true})
+}
+
+// deliver handles a message received from a peer, as the bft handler and the block fetcher do
+func (n *Node) deliver(from *Node, msg interface{}) {
+	switch m := msg.(type) {
+	case *blockMsg:
+		n.handleHeader(from, m.header, m.relay)
+
+	case *headerRequest:
+		if header := n.chain.GetHeaderByHash(m.hash); header != nil {
+			n.network.send(n, from, &blockMsg{header: header})
+		}
+
+	case *types.Vote:
+		if !n.learn(m.Hash()) {
+			return
+		}
+		if verified, err := n.engine.VerifyVoteMessage(n.chain, m); err != nil || !verified {
+			return
+		}
+		n.network.broadcast(n, m)
+		if err := n.engine.VoteHandler(n.chain, m); err != nil {
+			log.Debug("[deliver] Fail to handle vote", "node", n.Index, "err", err)
+		}
+
+	case *types.Timeout:
+		if !n.learn(m.Hash()) {
+			return
+		}
+		if verified, err := n.engine.VerifyTimeoutMessage(n.chain, m); err != nil || !verified {
+			return
+		}
+		n.network.broadcast(n, m)
+		if err := n.engine.TimeoutHandler(n.chain, m); err != nil {
+			log.Debug("[deliver] Fail to handle timeout", "node", n.Index, "err", err)
+		}
+
+	case *types.SyncInfo:
+		if !n.learn(m.Hash()) {
+			return
+		}
+		if verified, err := n.engine.VerifySyncInfoMessage(n.chain, m); err != nil || !verified {
+			return
+		}
+		n.network.broadcast(n, m)
+		if err := n.engine.SyncInfoHandler(n.chain, m); err != nil {
+			log.Debug("[deliver] Fail to handle syncInfo", "node", n.Index, "err", err)
+		}
+	}
+}
+
+// learn marks the message as known, it reports false if it was handled before
+func (n *Node) learn(hash common.Hash) bool {
+	if _, ok := n.known[hash]; ok {
+		return false
+	}
+	n.known[hash] = struct{}{}
+	return true
+}
+
+// handleHeader verifies and imports a header, the ones whose parent is missing wait for it to be fetched from the peer
+func (n *Node) handleHeader(from *Node, header *types.Header, relay bool) {
+	hash := header.Hash()
+	if n.chain.has(hash) {
+		return
+	}
+	if !n.chain.has(header.ParentHash) {
+		n.orphans[header.ParentHash] = append(n.orphans[header.ParentHash], header)
+		if _, ok := n.requested[header.ParentHash]; !ok {
+			n.requested[header.ParentHash] = struct{}{}
+			n.network.send(n, from, &headerRequest{hash: header.ParentHash})
+		}
+		return
+	}
+	if err := n.engine.VerifyHeader(n.chain, header, true); err != nil {
+		log.Warn("[handleHeader] Fail to verify header", "node", n.Index, "number", header.Number, "hash", hash, "err", err)
+		n.network.stats.InvalidBlocks++
+		return
+	}
+	n.importHeader(header)
+	if relay {
+		n.network.broadcast(n, &blockMsg{header: header, relay: true})
+	}
+	delete(n.requested, hash)
+	orphans := n.orphans[hash]
+	delete(n.orphans, hash)
+	for _, orphan := range orphans {
+		n.handleHeader(from, orphan, relay)
+	}
+}
+
+// importHeader writes the header to the chain and hands it to the engine, the gap blocks record the candidates as UpdateM1 does
+func (n *Node) importHeader(header *types.Header) {
+	n.chain.insert(header)
+	config := n.network.config
+	if header.Number.Uint64()%config.Epoch == config.Epoch-config.Gap {
+		if err := n.engine.UpdateMasternodes(n.chain, header, n.masternodes); err != nil {
+			log.Error("[importHeader] Fail to update masternodes", "node", n.Index, "number", header.Number, "err", err)
+		}
+	}
+	if err := n.engine.ProposedBlockHandler(n.chain, header); err != nil {
+		log.Debug("[importHeader] Fail to handle proposed block", "node", n.Index, "number", header.Number, "err", err)
+	}
+}
@@ -0,0 +1,142 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestNetwork(t *testing.T, config Config) *Network {
+	network, err := NewNetwork(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(network.Close)
+	return network
+}
+
+func round(t *testing.T, network *Network, header *types.Header) types.Round {
+	round, err := network.Node(0).Engine().GetRoundNumber(header)
+	assert.Nil(t, err)
+	return round
+}
+
+func TestCommitAcrossEpochs(t *testing.T) {
+	network := newTestNetwork(t, DefaultConfig())
+
+	assert.True(t, network.RunUntilCommitted(70, 5*time.Minute))
+	assert.Empty(t, network.Violations())
+	assert.Zero(t, network.Stats().InvalidBlocks)
+	for _, number := range []uint64{30, 60} {
+		header := network.CommittedHeader(number)
+		assert.Len(t, header.Validators, 4)
+	}
+	t.Log(network.Stats(), network.Now()-genesisTime)
+}
+
+func TestLeaderFailure(t *testing.T) {
+	network := newTestNetwork(t, DefaultConfig())
+	assert.True(t, network.RunUntilCommitted(5, time.Minute))
+
+	// the rounds led by the stopped node time out, the others still reach a QC
+	network.Stop(1)
+	committed := network.CommittedNumber()
+	assert.True(t, network.RunUntilCommitted(committed+10, 5*time.Minute))
+	for number := committed + 1; number <= committed+10; number++ {
+		assert.NotEqual(t, network.Node(1).Address, network.CommittedHeader(number).Coinbase)
+	}
+	t.Log(network.Stats(), network.Now()-genesisTime)
+
+	// the node catches up once restarted
+	network.Start(1)
+	committed = network.CommittedNumber()
+	assert.True(t, network.RunUntil(func() bool {
+		return network.Node(1).CommittedNumber() >= committed+5
+	}, 5*time.Minute))
+	assert.Empty(t, network.Violations())
+	t.Log(network.Stats(), network.Now()-genesisTime)
+}
+
+func TestNetworkPartition(t *testing.T) {
+	network := newTestNetwork(t, DefaultConfig())
+	assert.True(t, network.RunUntilCommitted(5, time.Minute))
+
+	// no side can form a QC
+	network.Partition([]int{0, 1}, []int{2, 3})
+	network.Run(30 * time.Second)
+	committed := network.CommittedNumber()
+	network.Run(2 * time.Minute)
+	assert.LessOrEqual(t, network.CommittedNumber(), committed+1)
+
+	network.Heal()
+	assert.True(t, network.RunUntilCommitted(committed+10, 10*time.Minute))
+	assert.Empty(t, network.Violations())
+	t.Log(network.Stats(), network.Now()-genesisTime)
+}
+
+func TestMinorityPartition(t *testing.T) {
+	network := newTestNetwork(t, DefaultConfig())
+	assert.True(t, network.RunUntilCommitted(5, time.Minute))
+
+	// the majority keeps committing without the isolated node, which catches up once healed
+	network.Partition([]int{0, 1, 2})
+	assert.True(t, network.RunUntil(func() bool {
+		return network.Node(0).CommittedNumber() >= 20
+	}, 5*time.Minute))
+	assert.Less(t, network.Node(3).CommittedNumber(), uint64(10))
+
+	network.Heal()
+	assert.True(t, network.RunUntilCommitted(25, 5*time.Minute))
+	assert.Empty(t, network.Violations())
+	t.Log(network.Stats(), network.Now()-genesisTime)
+}
+
+func TestEpochSwitchUnderTimeouts(t *testing.T) {
+	network := newTestNetwork(t, DefaultConfig())
+	assert.True(t, network.RunUntilCommitted(20, 2*time.Minute))
+
+	network.Stop(0)
+	assert.True(t, network.RunUntilCommitted(65, 10*time.Minute))
+	for _, number := range []uint64{30, 60} {
+		header := network.CommittedHeader(number)
+		assert.Len(t, header.Validators, 4)
+		assert.Greater(t, uint64(round(t, network, header)), number)
+	}
+	network.Start(0)
+	assert.True(t, network.RunUntilCommitted(70, 5*time.Minute))
+	assert.Empty(t, network.Violations())
+	assert.Zero(t, network.Stats().InvalidBlocks)
+	t.Log(network.Stats(), network.Now()-genesisTime)
+}
+
+func TestUnreliableNetwork(t *testing.T) {
+	config := DefaultConfig()
+	config.Jitter = 2 * time.Second
+	config.DropRate = 0.1
+	network := newTestNetwork(t, config)
+
+	assert.True(t, network.RunUntilCommitted(40, 20*time.Minute))
+	assert.Empty(t, network.Violations())
+	t.Log(network.Stats(), network.Now()-genesisTime)
+}
+
+func TestDeterministicReplay(t *testing.T) {
+	config := DefaultConfig()
+	config.Jitter = 2 * time.Second
+	config.DropRate = 0.1
+	replays := make([]*Network, 2)
+	for i := range replays {
+		replays[i] = newTestNetwork(t, config)
+		replays[i].Run(3 * time.Minute)
+	}
+	assert.Equal(t, replays[0].Stats(), replays[1].Stats())
+	assert.Equal(t, replays[0].CommittedNumber(), replays[1].CommittedNumber())
+	for number := uint64(1); number <= replays[0].CommittedNumber(); number++ {
+		first, second := replays[0].CommittedHeader(number), replays[1].CommittedHeader(number)
+		assert.Equal(t, first.Coinbase, second.Coinbase)
+		assert.Equal(t, first.Time, second.Time)
+		assert.Equal(t, round(t, replays[0], first), round(t, replays[1], second))
+	}
+}
@@ -117,6 +117,7 @@ func (v *BlockValidator) ValidateTradingOrder(statedb *state.StateDB, XDCxStated
 	}
 	log.Debug("verify matching transaction found a TxMatches Batch", "numTxMatches", len(txMatchBatch.Data))
 	tradingResult := map[common.Hash]tradingstate.MatchingResult{}
+	XDCxStatedb.Prepare(txMatchBatch.TxHash)
 	for _, txMatch := range txMatchBatch.Data {
 		// verify orderItem
 		order, err := txMatch.DecodeOrder()
@@ -157,6 +158,7 @@ func (v *BlockValidator) ValidateLendingOrder(statedb *state.StateDB, lendingSta
 	}
 	log.Debug("verify lendingItem ", "numItems", len(batch.Data))
 	lendingResult := map[common.Hash]lendingstate.MatchingResult{}
+	XDCxStatedb.Prepare(batch.TxHash)
 	for _, l := range batch.Data {
 		// verify lendingItem
 
@@ -255,3 +257,16 @@ func ExtractLendingFinalizedTradeTransactions(transactions types.Transactions) (
 	}
 	return lendingstate.FinalizedResult{}, nil
 }
+
+// ExtractTradingStateTransactionHash returns the hash of the transaction holding
+// the XDCx and lending state roots of the block, which is also the transaction
+// the SDK node records the orders expired by the block under.
+func ExtractTradingStateTransactionHash(transactions types.Transactions) common.Hash {
+	for _, tx := range transactions {
+		if tx.IsTradingStateTransaction() {
+			// each block has only one tx of this type
+			return tx.Hash()
+		}
+	}
+	return common.Hash{}
+}
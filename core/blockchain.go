@@ -41,6 +41,7 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
 	contractValidator "github.com/XinFinOrg/XDC-Subnet/contracts/validator/contract"
 	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/core/state/snapshot"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/core/vm"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
@@ -83,6 +84,12 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	PruneEpochs    uint64 // Number of finalized epochs of states kept by the background state pruning (0 = disabled)
+	PruneBloomSize uint64 // Size of the bloom filter of the state pruning in megabytes
+
+	SnapshotLimit int  // Memory allowance (MB) to use for caching snapshot entries in memory (0 = snapshot disabled)
+	SnapshotWait  bool // Wait for the snapshot construction on startup (testing)
 }
 type ResultProcessBlock struct {
 	logs         []*types.Log
@@ -135,6 +142,7 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache state.Database // State database to reuse between imports (contains state cache)
+	snaps      *snapshot.Tree // Flat state snapshot for fast state reads (nil = disabled)
 
 	bodyCache        *lru.Cache    // Cache for the most recent block bodies
 	bodyRLPCache     *lru.Cache    // Cache for the most recent block bodies in RLP encoded format
@@ -145,6 +153,7 @@ type BlockChain struct {
 	downloadingBlock *lru.Cache    // Cache for downloading blocks (avoid duplication from fetcher)
 	quit             chan struct{} // blockchain quit channel
 	running          int32         // running must be called atomically
+	pruning          int32         // pruning must be called atomically
 	// procInterrupt must be atomically called
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
@@ -167,6 +176,8 @@ type BlockChain struct {
 	resultLendingTrade  *lru.Cache
 	rejectedLendingItem *lru.Cache
 	finalizedTrade      *lru.Cache // include both trades which force update to closed/liquidated by the protocol
+	expiredOrders       *lru.Cache // orders cancelled by the protocol on expiry: key - trading state txHash
+	expiredLendingItems *lru.Cache // lending items cancelled by the protocol on expiry: key - trading state txHash
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -197,6 +208,8 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	resultLendingTrade, _ := lru.New(tradingstate.OrderCacheLimit)
 	rejectedLendingItem, _ := lru.New(tradingstate.OrderCacheLimit)
 	finalizedTrade, _ := lru.New(tradingstate.OrderCacheLimit)
+	expiredOrders, _ := lru.New(tradingstate.OrderCacheLimit)
+	expiredLendingItems, _ := lru.New(tradingstate.OrderCacheLimit)
 	bc := &BlockChain{
 		chainConfig:         chainConfig,
 		cacheConfig:         cacheConfig,
@@ -220,6 +233,8 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		resultLendingTrade:  resultLendingTrade,
 		rejectedLendingItem: rejectedLendingItem,
 		finalizedTrade:      finalizedTrade,
+		expiredOrders:       expiredOrders,
+		expiredLendingItems: expiredLendingItems,
 	}
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
@@ -249,8 +264,27 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	// Load any existing snapshot, regenerating it if loading failed
+	if cacheConfig.SnapshotLimit > 0 {
+		bc.snaps = snapshot.New(bc.db, bc.stateCache.TrieDB(), cacheConfig.SnapshotLimit, bc.CurrentBlock().Root(), !cacheConfig.SnapshotWait)
+	}
 	// Take ownership of this particular state
 	go bc.update()
+
+	// Move the finalized blocks into the ancient store, if the database has one
+	if _, err := bc.db.Ancients(); err == nil {
+		bc.wg.Add(1)
+		go bc.freeze()
+	}
+	// Prune the stale states in the background if requested
+	if cacheConfig.PruneEpochs > 0 {
+		if cacheConfig.Disabled {
+			log.Warn("[NewBlockChain] State pruning disabled on archive node")
+		} else {
+			bc.wg.Add(1)
+			go bc.prune()
+		}
+	}
 	return bc, nil
 }
 
@@ -301,7 +335,8 @@ func (bc *BlockChain) loadLastState() error {
 		repair = true
 	} else {
 		engine, ok := bc.Engine().(*XDPoS.XDPoS)
-		if ok {
+		// The XDCx services are not hooked into the engine by the offline commands
+		if ok && engine.GetXDCXService != nil && engine.GetLendingService != nil {
 			tradingService := engine.GetXDCXService()
 			lendingService := engine.GetLendingService()
 			if bc.Config().IsTIPXDCX(currentBlock.Number()) && bc.chainConfig.XDPoS != nil && currentBlock.NumberU64() > bc.chainConfig.XDPoS.Epoch && tradingService != nil && lendingService != nil {
@@ -424,6 +459,10 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	if currentBlock := bc.CurrentBlock(); currentBlock == nil {
 		bc.currentBlock.Store(bc.genesisBlock)
 	}
+	// The snapshot can't follow the rewind, regenerate it at the new head
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(bc.CurrentBlock().Root())
+	}
 	if currentFastBlock := bc.CurrentFastBlock(); currentFastBlock == nil {
 		bc.currentFastBlock.Store(bc.genesisBlock)
 	}
@@ -458,6 +497,38 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	return nil
 }
 
+// WriteCheckpointHeader writes a trusted header without its ancestors as the head
+// header, so a fast sync can import the headers after it.
+func (bc *BlockChain) WriteCheckpointHeader(header *types.Header, td *big.Int) error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	hash, number := header.Hash(), header.Number.Uint64()
+	if head := bc.hc.CurrentHeader(); head.Number.Uint64() >= number {
+		return fmt.Errorf("local head header #%d is not before checkpoint #%d", head.Number, number)
+	}
+	batch := bc.db.NewBatch()
+	if err := WriteTd(batch, hash, number, td); err != nil {
+		return err
+	}
+	if err := WriteHeader(batch, header); err != nil {
+		return err
+	}
+	if err := WriteCanonicalHash(batch, hash, number); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	bc.hc.SetCurrentHeader(header)
+
+	log.Info("Committed checkpoint header", "number", number, "hash", hash, "td", td)
+	return nil
+}
+
 // GasLimit returns the gas limit of the current HEAD block.
 func (bc *BlockChain) GasLimit() uint64 {
 	return bc.CurrentBlock().GasLimit()
@@ -496,6 +567,11 @@ func (bc *BlockChain) Validator() Validator {
 	return bc.validator
 }
 
+// GetVMConfig returns the block chain VM config.
+func (bc *BlockChain) GetVMConfig() *vm.Config {
+	return &bc.vmConfig
+}
+
 // Processor returns the current processor.
 func (bc *BlockChain) Processor() Processor {
 	bc.procmu.RLock()
@@ -510,7 +586,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
 }
 
 // OrderStateAt returns a new mutable state based on a particular point in time.
@@ -747,8 +823,7 @@ func (bc *BlockChain) HasBlock(hash common.Hash, number uint64) bool {
 	if bc.blockCache.Contains(hash) {
 		return true
 	}
-	ok, _ := bc.db.Has(blockBodyKey(hash, number))
-	return ok
+	return HasBody(bc.db, hash, number)
 }
 
 // HasFullState checks if state trie is fully present in the database or not.
@@ -895,7 +970,7 @@ func (bc *BlockChain) SaveData() {
 		triedb := bc.stateCache.TrieDB()
 		var tradingService utils.TradingService
 		var lendingService utils.LendingService
-		if bc.Config().IsTIPXDCX(bc.CurrentBlock().Number()) && bc.chainConfig.XDPoS != nil && bc.CurrentBlock().NumberU64() > bc.chainConfig.XDPoS.Epoch && engine != nil {
+		if bc.Config().IsTIPXDCX(bc.CurrentBlock().Number()) && bc.chainConfig.XDPoS != nil && bc.CurrentBlock().NumberU64() > bc.chainConfig.XDPoS.Epoch && engine != nil && engine.GetXDCXService != nil && engine.GetLendingService != nil {
 			tradingService = engine.GetXDCXService()
 			if tradingService != nil && tradingService.GetStateCache() != nil {
 				tradingTriedb = tradingService.GetStateCache().TrieDB()
@@ -966,6 +1041,19 @@ func (bc *BlockChain) Stop() {
 	close(bc.quit)
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 	bc.wg.Wait()
+
+	// Journal the snapshot diff layers and make sure the state of the disk
+	// layer is persisted, so the snapshot can be resumed after the restart
+	if bc.snaps != nil {
+		base, err := bc.snaps.Journal(bc.CurrentBlock().Root())
+		if err != nil {
+			log.Error("[Stop] Failed to journal state snapshot", "err", err)
+		} else if !bc.cacheConfig.Disabled {
+			if err := bc.stateCache.TrieDB().Commit(base, false); err != nil {
+				log.Error("[Stop] Failed to commit snapshot base state trie", "err", err)
+			}
+		}
+	}
 	bc.SaveData()
 	log.Info("Blockchain manager stopped")
 }
@@ -1215,6 +1303,12 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return NonStatTy, err
 	}
+	// Keep at most triesInMemory snapshot diff layers, flattening the rest
+	if bc.snaps != nil {
+		if err := bc.snaps.Cap(root, triesInMemory); err != nil {
+			log.Warn("[WriteBlockWithState] Failed to cap state snapshot", "root", root, "err", err)
+		}
+	}
 	tradingRoot := common.Hash{}
 	if tradingState != nil {
 		tradingRoot, err = tradingState.Commit()
@@ -1576,7 +1670,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		} else {
 			parent = chain[i-1]
 		}
-		statedb, err := state.New(parent.Root(), bc.stateCache)
+		statedb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1644,6 +1738,22 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 							return i, events, coalescedLogs, err
 						}
 					}
+					// cancel expired orders
+					var (
+						expiredOrders       []*tradingstate.OrderItem
+						expiredLendingItems []*lendingstate.LendingItem
+					)
+					if bc.chainConfig.IsXDCxOrderExpiry(block.Number()) {
+						if expiredOrders, err = tradingService.ProcessExpiredOrders(block.Header(), statedb, tradingState); err != nil {
+							return i, events, coalescedLogs, fmt.Errorf("failed to ProcessExpiredOrders. Err: %v ", err)
+						}
+						if expiredLendingItems, err = lendingService.ProcessExpiredOrders(block.Header(), statedb, tradingState, lendingState); err != nil {
+							return i, events, coalescedLogs, fmt.Errorf("failed to ProcessExpiredOrders. Err: %v ", err)
+						}
+					}
+					if isSDKNode {
+						bc.AddExpiredOrders(ExtractTradingStateTransactionHash(block.Transactions()), expiredOrders, expiredLendingItems)
+					}
 					// liquidate / finalize open lendingTrades
 					if block.Number().Uint64()%bc.chainConfig.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
 						finalizedTrades := map[common.Hash]*lendingstate.LendingTrade{}
@@ -1720,6 +1830,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			if bc.chainConfig.IsTIPXDCX(block.Number()) && bc.chainConfig.XDPoS != nil && block.NumberU64() > bc.chainConfig.XDPoS.Epoch {
 				bc.logExchangeData(block)
 				bc.logLendingData(block)
+				bc.logExpiredOrders(block)
 			}
 		case SideStatTy:
 			log.Debug("Inserted forked block from downloader", "number", block.Number(), "hash", block.Hash(), "diff", block.Difficulty(), "elapsed",
@@ -1854,7 +1965,7 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 	// Create a new statedb using the parent block and report an
 	// error if it fails.
 	var parent = bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
-	statedb, err := state.New(parent.Root(), bc.stateCache)
+	statedb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 	if err != nil {
 		return nil, err
 	}
@@ -1924,6 +2035,22 @@ func (bc *BlockChain) getResultBlock(block *types.Block, verifiedM2 bool) (*Resu
 						return nil, err
 					}
 				}
+				// cancel expired orders
+				var (
+					expiredOrders       []*tradingstate.OrderItem
+					expiredLendingItems []*lendingstate.LendingItem
+				)
+				if bc.chainConfig.IsXDCxOrderExpiry(block.Number()) {
+					if expiredOrders, err = tradingService.ProcessExpiredOrders(block.Header(), statedb, tradingState); err != nil {
+						return nil, fmt.Errorf("failed to ProcessExpiredOrders. Err: %v ", err)
+					}
+					if expiredLendingItems, err = lendingService.ProcessExpiredOrders(block.Header(), statedb, tradingState, lendingState); err != nil {
+						return nil, fmt.Errorf("failed to ProcessExpiredOrders. Err: %v ", err)
+					}
+				}
+				if isSDKNode {
+					bc.AddExpiredOrders(ExtractTradingStateTransactionHash(block.Transactions()), expiredOrders, expiredLendingItems)
+				}
 				// liquidate / finalize open lendingTrades
 				if block.Number().Uint64()%bc.chainConfig.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
 					finalizedTrades := map[common.Hash]*lendingstate.LendingTrade{}
@@ -2051,6 +2178,7 @@ func (bc *BlockChain) insertBlock(block *types.Block) ([]interface{}, []*types.L
 		if bc.chainConfig.IsTIPXDCX(block.Number()) && bc.chainConfig.XDPoS != nil && block.NumberU64() > bc.chainConfig.XDPoS.Epoch {
 			bc.logExchangeData(block)
 			bc.logLendingData(block)
+			bc.logExpiredOrders(block)
 		}
 	case SideStatTy:
 		log.Debug("Inserted forked block from fetcher", "number", block.Number(), "hash", block.Hash(), "diff", block.Difficulty(), "elapsed",
@@ -2566,7 +2694,11 @@ func (bc *BlockChain) UpdateM1() error {
 		}
 		// TODO: smart contract shouldn't return "0x0000000000000000000000000000000000000000"
 		if !candidate.IsZero() {
-			ms = append(ms, utils.Masternode{Address: candidate, Stake: v})
+			m := utils.Masternode{Address: candidate, Stake: v}
+			if stateDB != nil && bc.Config().XDPoS.V2 != nil && bc.Config().XDPoS.V2.BLSBlock != nil {
+				m.BLSPublicKey = state.GetBLSPublicKey(stateDB, candidate)
+			}
+			ms = append(ms, m)
 		}
 	}
 	if len(ms) == 0 {
@@ -2675,13 +2807,13 @@ func (bc *BlockChain) reorgTxMatches(deletedTxs types.Transactions, newChain typ
 		log.Debug("reorgTxMatches takes", "time", common.PrettyDuration(time.Since(start)))
 	}()
 	for _, deletedTx := range deletedTxs {
-		if deletedTx.IsTradingTransaction() {
+		if deletedTx.IsTradingTransaction() || deletedTx.IsTradingStateTransaction() {
 			log.Debug("Rollback reorg txMatch", "txhash", deletedTx.Hash())
 			if err := XDCXService.RollbackReorgTxMatch(deletedTx.Hash()); err != nil {
 				log.Crit("Reorg trading failed", "err", err, "hash", deletedTx.Hash())
 			}
 		}
-		if lendingService != nil && (deletedTx.IsLendingTransaction() || deletedTx.IsLendingFinalizedTradeTransaction()) {
+		if lendingService != nil && (deletedTx.IsLendingTransaction() || deletedTx.IsLendingFinalizedTradeTransaction() || deletedTx.IsTradingStateTransaction()) {
 			log.Debug("Rollback reorg lendingItem", "txhash", deletedTx.Hash())
 			if err := lendingService.RollbackLendingData(deletedTx.Hash()); err != nil {
 				log.Crit("Reorg lending failed", "err", err, "hash", deletedTx.Hash())
@@ -2693,6 +2825,7 @@ func (bc *BlockChain) reorgTxMatches(deletedTxs types.Transactions, newChain typ
 	for i := len(newChain) - 1; i >= 0; i-- {
 		bc.logExchangeData(newChain[i])
 		bc.logLendingData(newChain[i])
+		bc.logExpiredOrders(newChain[i])
 	}
 }
 
@@ -2769,6 +2902,38 @@ func (bc *BlockChain) logLendingData(block *types.Block) {
 	}
 }
 
+// logExpiredOrders marks the orders and lending items expired by the block as
+// cancelled in the SDK node database.
+func (bc *BlockChain) logExpiredOrders(block *types.Block) {
+	engine, ok := bc.Engine().(*XDPoS.XDPoS)
+	if !ok || engine == nil {
+		return
+	}
+	XDCXService := engine.GetXDCXService()
+	if XDCXService == nil || !XDCXService.IsSDKNode() {
+		return
+	}
+	txHash := ExtractTradingStateTransactionHash(block.Transactions())
+	if txHash == (common.Hash{}) {
+		return
+	}
+	txTime := time.Unix(block.Header().Time.Int64(), 0).UTC()
+	if cached, ok := bc.expiredOrders.Get(txHash); ok && cached != nil {
+		if err := XDCXService.SyncExpiredOrdersToSDKNode(txHash, txTime, cached.([]*tradingstate.OrderItem)); err != nil {
+			log.Crit("failed to SyncExpiredOrdersToSDKNode ", "blockNumber", block.Number(), "err", err)
+		}
+	}
+	lendingService := engine.GetLendingService()
+	if lendingService == nil {
+		return
+	}
+	if cached, ok := bc.expiredLendingItems.Get(txHash); ok && cached != nil {
+		if err := lendingService.SyncExpiredItemsToSDKNode(txHash, txTime, cached.([]*lendingstate.LendingItem)); err != nil {
+			log.Crit("lending: failed to SyncExpiredItemsToSDKNode ", "blockNumber", block.Number(), "err", err)
+		}
+	}
+}
+
 func (bc *BlockChain) AddMatchingResult(txHash common.Hash, matchingResults map[common.Hash]tradingstate.MatchingResult) {
 	for hash, result := range matchingResults {
 		cacheKey := crypto.Keccak256Hash(txHash.Bytes(), hash.Bytes())
@@ -2787,3 +2952,12 @@ func (bc *BlockChain) AddLendingResult(txHash common.Hash, lendingResults map[co
 func (bc *BlockChain) AddFinalizedTrades(txHash common.Hash, trades map[common.Hash]*lendingstate.LendingTrade) {
 	bc.finalizedTrade.Add(txHash, trades)
 }
+
+func (bc *BlockChain) AddExpiredOrders(txHash common.Hash, orders []*tradingstate.OrderItem, lendingItems []*lendingstate.LendingItem) {
+	if len(orders) > 0 {
+		bc.expiredOrders.Add(txHash, orders)
+	}
+	if len(lendingItems) > 0 {
+		bc.expiredLendingItems.Add(txHash, lendingItems)
+	}
+}
@@ -17,6 +17,8 @@
 package core
 
 import (
+	"bytes"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -1412,3 +1414,171 @@ func TestAreTwoBlocksSamePath(t *testing.T) {
 	})
 
 }
+
+// Tests that the state snapshot follows the imported blocks and survives a
+// restart of the chain through its journal.
+func TestSnapshotFollowsChain(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+		config  = &CacheConfig{
+			TrieNodeLimit: 256 * 1024 * 1024,
+			TrieTimeLimit: 5 * time.Minute,
+			SnapshotLimit: 16,
+			SnapshotWait:  true,
+		}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 5, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i + 1)}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(tx)
+	})
+	chain, err := NewBlockChain(db, config, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range blocks {
+		snap := chain.snaps.Snapshot(block.Root())
+		if snap == nil {
+			t.Fatalf("block %d: snapshot missing", block.NumberU64())
+		}
+		statedb, _ := chain.StateAt(block.Root())
+		acc, err := snap.Account(crypto.Keccak256Hash(address.Bytes()))
+		if err != nil {
+			t.Fatalf("block %d: failed to read snapshot: %v", block.NumberU64(), err)
+		}
+		if acc.Nonce != statedb.GetNonce(address) || acc.Balance.Cmp(statedb.GetBalance(address)) != 0 {
+			t.Errorf("block %d: account mismatch: have %d/%v, want %d/%v", block.NumberU64(), acc.Nonce, acc.Balance, statedb.GetNonce(address), statedb.GetBalance(address))
+		}
+	}
+	chain.Stop()
+
+	// Restart the chain and ensure the snapshot was loaded from the journal
+	chain, err = NewBlockChain(db, config, gspec.Config, ethash.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to recreate chain: %v", err)
+	}
+	defer chain.Stop()
+
+	head := chain.CurrentBlock()
+	if head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head.Hash(), blocks[len(blocks)-1].Hash())
+	}
+	if chain.snaps.Snapshot(blocks[0].Root()) == nil {
+		t.Fatalf("journalled snapshot layer missing after restart")
+	}
+}
+
+// Tests that processing the blocks with parallel transaction execution yields
+// the same state and receipts as the sequential execution, with transactions
+// conflicting on balances, nonces, storage and created accounts.
+func TestParallelTxProcessing(t *testing.T) {
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		funds   = big.NewInt(1000000000000000)
+		counter = common.Address{0xcc}
+		keys    = make([]*ecdsa.PrivateKey, 4)
+		addrs   = make([]common.Address, len(keys))
+		alloc   = GenesisAlloc{
+			// Increments slot 0 and logs the new value
+			counter: {Balance: new(big.Int), Code: common.FromHex("6000546001018060005560006000a000")},
+		}
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = GenesisAccount{Balance: funds}
+	}
+	gspec := &Genesis{Config: params.TestChainConfig, Alloc: alloc}
+	genesis := gspec.MustCommit(gendb)
+	signer := types.NewEIP155Signer(gspec.Config.ChainId)
+
+	blocks, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 4, func(i int, block *BlockGen) {
+		nonces := make([]uint64, len(addrs))
+		for j, addr := range addrs {
+			nonces[j] = block.TxNonce(addr)
+		}
+		// Interleave the senders, so that only some of the transactions conflict
+		for kind := 0; kind < 4; kind++ {
+			for j, key := range keys {
+				var tx *types.Transaction
+				switch nonce := nonces[j] + uint64(kind); kind {
+				case 0:
+					// Independent transfer to a fresh account
+					tx = types.NewTransaction(nonce, common.Address{byte(i), byte(j), 1}, big.NewInt(1000), params.TxGas, big.NewInt(1), nil)
+				case 1:
+					// Transfer to the next sender, conflicting with its transactions
+					tx = types.NewTransaction(nonce, addrs[(j+1)%len(addrs)], big.NewInt(1000), params.TxGas, big.NewInt(1), nil)
+				case 2:
+					// Storage update conflicting with every other call of the contract
+					tx = types.NewTransaction(nonce, counter, new(big.Int), 100000, big.NewInt(1), nil)
+				case 3:
+					// Contract creation storing a value
+					tx = types.NewContractCreation(nonce, new(big.Int), 100000, big.NewInt(1), common.FromHex("600160005500"))
+				}
+				signed, err := types.SignTx(tx, signer, key)
+				if err != nil {
+					t.Fatalf("failed to sign transaction: %v", err)
+				}
+				block.AddTx(signed)
+			}
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{ParallelTxs: 4})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Process every block on its parent state and compare the receipts
+	for i, block := range blocks {
+		statedb, err := chain.StateAt(chain.GetBlockByHash(block.ParentHash()).Root())
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", i, err)
+		}
+		have, _, usedGas, err := chain.Processor().Process(block, statedb, nil, vm.Config{ParallelTxs: 4}, nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to process: %v", i, err)
+		}
+		if usedGas != block.GasUsed() {
+			t.Errorf("block %d: gas used mismatch: have %d, want %d", i, usedGas, block.GasUsed())
+		}
+		if root := statedb.IntermediateRoot(true); root != block.Root() {
+			t.Errorf("block %d: state root mismatch: have %x, want %x", i, root, block.Root())
+		}
+		want := receipts[i]
+		if len(have) != len(want) {
+			t.Fatalf("block %d: receipt count mismatch: have %d, want %d", i, len(have), len(want))
+		}
+		for j := range want {
+			if have[j].Status != want[j].Status || have[j].CumulativeGasUsed != want[j].CumulativeGasUsed || have[j].ContractAddress != want[j].ContractAddress {
+				t.Errorf("block %d, receipt %d: mismatch: have %+v, want %+v", i, j, have[j], want[j])
+			}
+			if len(have[j].Logs) != len(want[j].Logs) {
+				t.Fatalf("block %d, receipt %d: log count mismatch: have %d, want %d", i, j, len(have[j].Logs), len(want[j].Logs))
+			}
+			for k, log := range want[j].Logs {
+				if l := have[j].Logs[k]; l.Index != log.Index || l.TxIndex != log.TxIndex || l.TxHash != log.TxHash || !bytes.Equal(l.Data, log.Data) {
+					t.Errorf("block %d, receipt %d: log %d mismatch: have %+v, want %+v", i, j, k, l, log)
+				}
+			}
+		}
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to insert: %v", i, err)
+		}
+	}
+}
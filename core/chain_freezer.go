@@ -0,0 +1,226 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+)
+
+const (
+	// freezerRecheckInterval is the frequency to check the committed block of the
+	// consensus engine for new blocks to be frozen into the ancient store.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting them from the key-value store.
+	freezerBatchLimit = 30000
+
+	// freezerRecentEpochs is the number of epochs below the committed block kept
+	// in the key-value store, the engine and XDCx keep reading the headers of the
+	// last epochs for the masternodes and the snapshots.
+	freezerRecentEpochs = 2
+)
+
+// FreezeLimit returns the number of blocks that can be held by the ancient store,
+// all of them are finalized by the consensus engine. It is zero if the engine
+// doesn't finalize blocks.
+func (bc *BlockChain) FreezeLimit() uint64 {
+	number, ok := bc.finalizedNumber()
+	if !ok {
+		return 0
+	}
+	recent := freezerRecentEpochs * bc.chainConfig.XDPoS.Epoch
+	if number+1 <= recent {
+		return 0
+	}
+	return number + 1 - recent
+}
+
+// finalizedNumber returns the number of the latest block committed by the
+// consensus engine, false if the engine doesn't finalize blocks.
+func (bc *BlockChain) finalizedNumber() (uint64, bool) {
+	engine, ok := bc.Engine().(*XDPoS.XDPoS)
+	if !ok || engine.EngineV2 == nil {
+		return 0, false
+	}
+	if committed := engine.EngineV2.GetLatestCommittedBlockInfo(); committed != nil {
+		// The engine only commits blocks it has seen, make sure it's one of our chain
+		number := committed.Number.Uint64()
+		if GetCanonicalHash(bc.db, number) == committed.Hash {
+			return number, true
+		}
+		log.Warn("[finalizedNumber] Committed block is not canonical", "number", number, "hash", committed.Hash)
+	}
+	// The engine commits nothing until it processes a new QC, which never happens
+	// offline or right after a restart, so read the committed block from the chain
+	return committedNumber(bc.db, bc.CurrentHeader())
+}
+
+// committedNumber returns the number of the latest block committed by the
+// 3-chain rule, following the QC in the head header back to three blocks of
+// consecutive rounds. Nothing is committed as long as the headers can't be
+// decoded as v2 ones or no such blocks are found.
+func committedNumber(db DatabaseReader, head *types.Header) (uint64, bool) {
+	if head == nil {
+		return 0, false
+	}
+	var extra types.ExtraFields_v2
+	if err := utils.DecodeBytesExtraFields(head.Extra, &extra); err != nil || extra.QuorumCert == nil {
+		return 0, false
+	}
+	proposed := extra.QuorumCert.ProposedBlockInfo
+	header := GetHeader(db, proposed.Hash, proposed.Number.Uint64())
+	for header != nil {
+		round := proposed.Round
+		parent := GetHeader(db, header.ParentHash, header.Number.Uint64()-1)
+		if parent == nil {
+			break
+		}
+		if err := utils.DecodeBytesExtraFields(parent.Extra, &extra); err != nil || extra.QuorumCert == nil {
+			break
+		}
+		// The QC of a block certifies its parent, the grandparent of the block is
+		// committed once the three of them have consecutive rounds
+		grandparent := extra.QuorumCert.ProposedBlockInfo
+		if extra.Round+1 == round && grandparent.Round+1 == extra.Round {
+			return grandparent.Number.Uint64(), true
+		}
+		proposed = &types.BlockInfo{Hash: parent.Hash(), Round: extra.Round, Number: parent.Number}
+		header = parent
+	}
+	return 0, false
+}
+
+// FreezeAncients moves the finalized blocks of the canonical chain from the
+// key-value store into the ancient store, at most freezerBatchLimit blocks up
+// to the given limit. It returns the number of blocks in the ancient store.
+func FreezeAncients(db ethdb.Database, limit uint64) (uint64, error) {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	if limit <= frozen {
+		return frozen, nil
+	}
+	if limit-frozen > freezerBatchLimit {
+		limit = frozen + freezerBatchLimit
+	}
+	var (
+		start    = time.Now()
+		first    = frozen
+		ancients = make([]common.Hash, 0, limit-frozen)
+	)
+	for frozen < limit {
+		// Retrieves all the components of the canonical block
+		hash := GetCanonicalHash(db, frozen)
+		if hash == (common.Hash{}) {
+			err = fmt.Errorf("canonical hash missing, can't freeze block %d", frozen)
+			break
+		}
+		header := GetHeaderRLP(db, hash, frozen)
+		if len(header) == 0 {
+			err = fmt.Errorf("header missing, can't freeze block %d [%x…]", frozen, hash[:4])
+			break
+		}
+		body := GetBodyRLP(db, hash, frozen)
+		if len(body) == 0 {
+			err = fmt.Errorf("body missing, can't freeze block %d [%x…]", frozen, hash[:4])
+			break
+		}
+		receipts := GetBlockReceiptsRLP(db, hash, frozen)
+		if len(receipts) == 0 {
+			err = fmt.Errorf("receipts missing, can't freeze block %d [%x…]", frozen, hash[:4])
+			break
+		}
+		td := GetTdRLP(db, hash, frozen)
+		if len(td) == 0 {
+			err = fmt.Errorf("total difficulty missing, can't freeze block %d [%x…]", frozen, hash[:4])
+			break
+		}
+		log.Trace("Deep froze ancient block", "number", frozen, "hash", hash)
+		if err = db.AppendAncient(frozen, hash[:], header, body, receipts, td); err != nil {
+			break
+		}
+		ancients = append(ancients, hash)
+		frozen++
+	}
+	if len(ancients) == 0 {
+		return frozen, err
+	}
+	// Batch of blocks have been frozen, flush them before wiping from leveldb
+	if err := db.Sync(); err != nil {
+		log.Crit("Failed to flush frozen tables", "err", err)
+	}
+	batch := db.NewBatch()
+	for i, hash := range ancients {
+		number := first + uint64(i)
+		// Always keep the genesis block in the key-value store
+		if number == 0 {
+			continue
+		}
+		deleteBlockWithoutNumber(batch, hash, number)
+		DeleteCanonicalHash(batch, number)
+
+		// Wipe out the side chains too, they can't become canonical anymore
+		for _, side := range getAllHashes(db, number) {
+			if side != hash {
+				DeleteBlock(batch, side, number)
+			}
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete frozen blocks", "err", err)
+	}
+	log.Info("Deep froze chain segment", "blocks", frozen-first, "elapsed", common.PrettyDuration(time.Since(start)), "number", frozen-1, "hash", ancients[len(ancients)-1])
+	return frozen, err
+}
+
+// getAllHashes retrieves the hashes of all the headers of the key-value store
+// assigned to the number, canonical or not.
+func getAllHashes(db ethdb.Iteratee, number uint64) []common.Hash {
+	prefix := append(headerPrefix, encodeBlockNumber(number)...)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var hashes []common.Hash
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+common.HashLength {
+			hashes = append(hashes, common.BytesToHash(key[len(key)-common.HashLength:]))
+		}
+	}
+	return hashes
+}
+
+// freeze periodically moves the blocks finalized by the consensus engine into
+// the ancient store. It's deliberately broken off from the block import to not
+// delay the block propagation.
+func (bc *BlockChain) freeze() {
+	defer bc.wg.Done()
+
+	timer := time.NewTimer(freezerRecheckInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-bc.quit:
+			return
+		}
+		first, _ := bc.db.Ancients()
+		frozen, err := FreezeAncients(bc.db, bc.FreezeLimit())
+		if err != nil {
+			log.Warn("[freeze] Fail to freeze ancient blocks", "frozen", frozen, "err", err)
+		}
+		// Keep going while there are batches to freeze, avoid tiny writes otherwise
+		if err == nil && frozen-first == freezerBatchLimit {
+			timer.Reset(0)
+		} else {
+			timer.Reset(freezerRecheckInterval)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb/memorydb"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+// writeTestChain writes a canonical chain of the given length and a side block
+// at every height into the database.
+func writeTestChain(t *testing.T, db ethdb.Database, length int) (canon []*types.Block, side []*types.Block) {
+	parent := common.Hash{}
+	for i := 0; i < length; i++ {
+		tx := types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+		block := types.NewBlock(&types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Extra: []byte("canonical")}, []*types.Transaction{tx}, nil, nil)
+		fork := types.NewBlock(&types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Extra: []byte("side")}, nil, nil, nil)
+		for _, b := range []*types.Block{block, fork} {
+			if err := WriteBlock(db, b); err != nil {
+				t.Fatalf("Failed to write block %d: %v", i, err)
+			}
+			if err := WriteTd(db, b.Hash(), b.NumberU64(), big.NewInt(int64(i+1))); err != nil {
+				t.Fatalf("Failed to write td %d: %v", i, err)
+			}
+		}
+		receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: params.TxGas, Logs: []*types.Log{}}}
+		if err := WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts); err != nil {
+			t.Fatalf("Failed to write receipts %d: %v", i, err)
+		}
+		if err := WriteBlockReceipts(db, fork.Hash(), fork.NumberU64(), nil); err != nil {
+			t.Fatalf("Failed to write receipts %d: %v", i, err)
+		}
+		if err := WriteCanonicalHash(db, block.Hash(), block.NumberU64()); err != nil {
+			t.Fatalf("Failed to write canonical hash %d: %v", i, err)
+		}
+		canon, side = append(canon, block), append(side, fork)
+		parent = block.Hash()
+	}
+	return canon, side
+}
+
+// Tests that the finalized blocks are moved into the ancient store and can still
+// be retrieved, while the side chains are wiped out.
+func TestFreezeAncients(t *testing.T) {
+	kv := memorydb.New()
+	db, err := rawdb.NewDatabaseWithFreezer(kv, t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("Failed to create database with freezer: %v", err)
+	}
+	defer db.Close()
+
+	canon, side := writeTestChain(t, db, 10)
+
+	frozen, err := FreezeAncients(db, 6)
+	if err != nil {
+		t.Fatalf("Failed to freeze blocks: %v", err)
+	}
+	if frozen != 6 {
+		t.Fatalf("Frozen blocks mismatch: have %d, want %d", frozen, 6)
+	}
+	for i, block := range canon {
+		hash, number := block.Hash(), block.NumberU64()
+		// The genesis and the blocks above the limit are kept in the key-value store
+		kept := i == 0 || i >= 6
+		if ok, _ := kv.Has(headerKey(hash, number)); ok != kept {
+			t.Errorf("Block %d: header in key-value store %v, want %v", i, ok, kept)
+		}
+		if have := GetCanonicalHash(db, number); have != hash {
+			t.Errorf("Block %d: canonical hash mismatch: have %x, want %x", i, have, hash)
+		}
+		if have := GetBlockNumber(db, hash); have != number {
+			t.Errorf("Block %d: number mismatch: have %d, want %d", i, have, number)
+		}
+		if entry := GetBlock(db, hash, number); entry == nil || entry.Hash() != hash {
+			t.Errorf("Block %d: retrieved block mismatch: have %v, want %v", i, entry, block)
+		} else if entry.Transactions()[0].Hash() != block.Transactions()[0].Hash() {
+			t.Errorf("Block %d: retrieved transaction mismatch", i)
+		}
+		if receipts := GetBlockReceipts(db, hash, number); len(receipts) != 1 {
+			t.Errorf("Block %d: receipts mismatch: have %d, want %d", i, len(receipts), 1)
+		}
+		if td := GetTd(db, hash, number); td == nil || td.Int64() != int64(i+1) {
+			t.Errorf("Block %d: td mismatch: have %v, want %d", i, td, i+1)
+		}
+		if !HasHeader(db, hash, number) || !HasBody(db, hash, number) {
+			t.Errorf("Block %d: block reported missing", i)
+		}
+	}
+	for i, block := range side {
+		// The side blocks of the frozen heights are gone, the other ones are kept
+		kept := i == 0 || i >= 6
+		if entry := GetBlock(db, block.Hash(), block.NumberU64()); (entry != nil) != kept {
+			t.Errorf("Side block %d: present %v, want %v", i, entry != nil, kept)
+		}
+	}
+	// A block of the frozen height which is not canonical can't be read from the ancient store
+	if HasHeader(db, side[3].Hash(), 3) {
+		t.Errorf("Side block reported present in the ancient store")
+	}
+	// Freezing again is a noop, freezing further continues from the ancient head
+	if frozen, err := FreezeAncients(db, 6); err != nil || frozen != 6 {
+		t.Fatalf("Refreeze mismatch: have %d, %v, want %d", frozen, err, 6)
+	}
+	if frozen, err := FreezeAncients(db, 20); err == nil || frozen != 10 {
+		t.Fatalf("Freeze beyond the chain mismatch: have %d, %v, want %d and an error", frozen, err, 10)
+	}
+}
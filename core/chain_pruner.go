@@ -0,0 +1,223 @@
+package core
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDC-Subnet/XDCxlending/lendingstate"
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
+	"github.com/XinFinOrg/XDC-Subnet/core/state/pruner"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
+)
+
+const (
+	// DefaultStatePruneEpochs is the default number of finalized epochs whose
+	// states are kept by the state pruning.
+	DefaultStatePruneEpochs = 4
+
+	// pruneRecheckInterval is the frequency to check whether enough epochs were
+	// finalized since the last background state pruning.
+	pruneRecheckInterval = 10 * time.Minute
+)
+
+var (
+	errPruneArchive = errors.New("archive node states can't be pruned")
+	errPruneRunning = errors.New("state pruning already running")
+	errPruneNoFinal = errors.New("no finalized block to prune the states before")
+)
+
+// PruneState deletes the trie nodes and contract codes which are only reachable
+// from the states older than the given number of finalized epochs, for both the
+// main state and the XDCx trading and lending states. The chain keeps importing
+// blocks meanwhile, the pruning is aborted if the chain stops.
+func (bc *BlockChain) PruneState(epochs uint64, bloomSize uint64) error {
+	if bc.cacheConfig.Disabled {
+		return errPruneArchive
+	}
+	if !atomic.CompareAndSwapInt32(&bc.pruning, 0, 1) {
+		return errPruneRunning
+	}
+	defer atomic.StoreInt32(&bc.pruning, 0)
+
+	finalized, ok := bc.finalizedNumber()
+	if !ok {
+		return errPruneNoFinal
+	}
+	// The XDCx states are pruned along the main state if the services are running
+	var (
+		engine         = bc.Engine().(*XDPoS.XDPoS)
+		tradingService = engine.GetXDCXService()
+		lendingService = engine.GetLendingService()
+		triedbs        = []*trie.Database{bc.stateCache.TrieDB()}
+		dbs            = []ethdb.KeyValueStore{bc.db}
+	)
+	if tradingService == nil || tradingService.GetStateCache() == nil || lendingService == nil || lendingService.GetStateCache() == nil {
+		tradingService, lendingService = nil, nil
+	} else {
+		triedbs = append(triedbs, tradingService.GetStateCache().TrieDB(), lendingService.GetStateCache().TrieDB())
+		for _, triedb := range triedbs[1:] {
+			db, ok := triedb.DiskDB().(ethdb.KeyValueStore)
+			if !ok {
+				return errors.New("unsupported XDCx database")
+			}
+			// The trading and lending states share the same database
+			if db != dbs[len(dbs)-1] {
+				dbs = append(dbs, db)
+			}
+		}
+	}
+	p, err := pruner.New(bloomSize, dbs...)
+	if err != nil {
+		return err
+	}
+	// Mark the nodes flushed from now on, the ones flushed before are reachable
+	// from the roots of the blocks already imported
+	bc.mu.Lock()
+	for _, triedb := range triedbs {
+		triedb.SetFlushCallback(p.Mark)
+	}
+	bc.mu.Unlock()
+
+	defer func() {
+		for _, triedb := range triedbs {
+			triedb.SetFlushCallback(nil)
+		}
+	}()
+	tries := bc.pruneTries(finalized, epochs, tradingService, lendingService)
+
+	log.Info("Pruning stale states", "finalized", finalized, "epochs", epochs)
+	return p.Prune(tries, bc.quit)
+}
+
+// pruneTries returns the versions of the state tries kept by the state pruning:
+// the genesis state, the most recent complete state the chain can be rewound to
+// before the kept blocks, and the states of all the kept blocks. The blocks are
+// kept from the given number of epochs before the finalized block, and at least
+// the ones whose states may still be in memory. The side chains are only kept
+// above the finalized block.
+func (bc *BlockChain) pruneTries(finalized, epochs uint64, tradingService utils.TradingService, lendingService utils.LendingService) []pruner.Tries {
+	var (
+		head  = bc.CurrentBlock().NumberU64()
+		first uint64
+	)
+	if keep := epochs * bc.chainConfig.XDPoS.Epoch; finalized > keep {
+		first = finalized - keep
+	}
+	if head > triesInMemory && head-triesInMemory < first {
+		first = head - triesInMemory
+	} else if head <= triesInMemory {
+		first = 0
+	}
+	tries := []pruner.Tries{{Database: bc.stateCache.TrieDB(), Resolve: pruner.ResolveAccount}}
+	if tradingService != nil {
+		tries = append(tries,
+			pruner.Tries{Database: tradingService.GetStateCache().TrieDB(), Resolve: tradingstate.ResolvePruneLeaf},
+			pruner.Tries{Database: lendingService.GetStateCache().TrieDB(), Resolve: lendingstate.ResolvePruneLeaf},
+		)
+	}
+	// states retrieves the roots of the states of a block in the order of the
+	// tries, empty if not available, and whether all of them are available
+	states := func(block *types.Block) ([]common.Hash, bool) {
+		roots := []common.Hash{block.Root()}
+		if tradingService != nil && bc.Config().IsTIPXDCX(block.Number()) && block.NumberU64() > bc.chainConfig.XDPoS.Epoch {
+			author, err := bc.Engine().Author(block.Header())
+			if err != nil {
+				return nil, false
+			}
+			tradingRoot, err := tradingService.GetTradingStateRoot(block, author)
+			if err != nil {
+				return nil, false
+			}
+			lendingRoot, err := lendingService.GetLendingStateRoot(block, author)
+			if err != nil {
+				return nil, false
+			}
+			roots = append(roots, tradingRoot, lendingRoot)
+		}
+		complete := true
+		for i, root := range roots {
+			if !hasTrieNode(tries[i].Database, root) {
+				roots[i], complete = common.Hash{}, false
+			}
+		}
+		return roots, complete
+	}
+	keep := func(block *types.Block) {
+		roots, _ := states(block)
+		for i, root := range roots {
+			if root != (common.Hash{}) {
+				tries[i].Roots = append(tries[i].Roots, root)
+			}
+		}
+	}
+	keep(bc.genesisBlock)
+
+	// Keep the state the chain is rewound to at startup if the later ones are missing
+	for number := first; number > 0; number-- {
+		if block := bc.GetBlockByNumber(number); block != nil {
+			if _, complete := states(block); complete {
+				keep(block)
+				break
+			}
+		}
+	}
+	for number := first + 1; number <= head; number++ {
+		hashes := []common.Hash{GetCanonicalHash(bc.db, number)}
+		if number > finalized {
+			for _, hash := range getAllHashes(bc.db, number) {
+				if hash != hashes[0] {
+					hashes = append(hashes, hash)
+				}
+			}
+		}
+		for _, hash := range hashes {
+			if block := bc.GetBlock(hash, number); block != nil {
+				keep(block)
+			}
+		}
+	}
+	return tries
+}
+
+// hasTrieNode reports whether the node of a trie is available, in memory or on
+// disk. The empty trie is always available.
+func hasTrieNode(triedb *trie.Database, hash common.Hash) bool {
+	if hash == (common.Hash{}) || hash == types.EmptyRootHash {
+		return true
+	}
+	_, err := triedb.Node(hash)
+	return err == nil
+}
+
+// prune periodically prunes the stale states once enough epochs were finalized
+// since the last pruning.
+func (bc *BlockChain) prune() {
+	defer bc.wg.Done()
+
+	last, _ := bc.finalizedNumber()
+	timer := time.NewTimer(pruneRecheckInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-bc.quit:
+			return
+		}
+		finalized, ok := bc.finalizedNumber()
+		if ok && finalized >= last+bc.cacheConfig.PruneEpochs*bc.chainConfig.XDPoS.Epoch {
+			if err := bc.PruneState(bc.cacheConfig.PruneEpochs, bc.cacheConfig.PruneBloomSize); err == nil {
+				last = finalized
+			} else if err != pruner.ErrAborted {
+				log.Warn("[prune] Fail to prune stale states", "finalized", finalized, "err", err)
+			}
+		}
+		timer.Reset(pruneRecheckInterval)
+	}
+}
@@ -32,6 +32,7 @@ func NewEVMContext(msg Message, header *types.Header, chain consensus.ChainConte
 	var (
 		beneficiary common.Address
 		random      common.Hash
+		baseFee     *big.Int
 	)
 	if author == nil {
 		beneficiary, _ = chain.Engine().Author(header) // Ignore error, we're past header validation
@@ -40,6 +41,9 @@ func NewEVMContext(msg Message, header *types.Header, chain consensus.ChainConte
 	}
 	// since xdpos chain do not use difficulty and mixdigest, we use hash of the block number as random
 	random = crypto.Keccak256Hash(header.Number.Bytes())
+	if header.BaseFee != nil {
+		baseFee = new(big.Int).Set(header.BaseFee)
+	}
 	return vm.Context{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
@@ -52,6 +56,7 @@ func NewEVMContext(msg Message, header *types.Header, chain consensus.ChainConte
 		GasLimit:    header.GasLimit,
 		GasPrice:    new(big.Int).Set(msg.GasPrice()),
 		Random:      &random,
+		BaseFee:     baseFee,
 	}
 }
 
@@ -32,6 +32,7 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 		Number         math.HexOrDecimal64                         `json:"number"`
 		GasUsed        math.HexOrDecimal64                         `json:"gasUsed"`
 		ParentHash     common.Hash                                 `json:"parentHash"`
+		BaseFee        *math.HexOrDecimal256                       `json:"baseFeePerGas"`
 	}
 	var enc Genesis
 	enc.Config = g.Config
@@ -53,6 +54,7 @@ func (g Genesis) MarshalJSON() ([]byte, error) {
 	enc.Number = math.HexOrDecimal64(g.Number)
 	enc.GasUsed = math.HexOrDecimal64(g.GasUsed)
 	enc.ParentHash = g.ParentHash
+	enc.BaseFee = (*math.HexOrDecimal256)(g.BaseFee)
 	return json.Marshal(&enc)
 }
 
@@ -73,6 +75,7 @@ func (g *Genesis) UnmarshalJSON(input []byte) error {
 		Number         *math.HexOrDecimal64                        `json:"number"`
 		GasUsed        *math.HexOrDecimal64                        `json:"gasUsed"`
 		ParentHash     *common.Hash                                `json:"parentHash"`
+		BaseFee        *math.HexOrDecimal256                       `json:"baseFeePerGas"`
 	}
 	var dec Genesis
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -126,5 +129,8 @@ func (g *Genesis) UnmarshalJSON(input []byte) error {
 	if dec.ParentHash != nil {
 		g.ParentHash = *dec.ParentHash
 	}
+	if dec.BaseFee != nil {
+		g.BaseFee = (*big.Int)(dec.BaseFee)
+	}
 	return nil
 }
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"fmt"
 	"math/big"
 	"reflect"
 	"testing"
@@ -52,8 +53,10 @@ func TestSetupGenesis(t *testing.T) {
 			},
 		}
 		oldcustomg = customg
+		badcustomg = customg
 	)
 	oldcustomg.Config = &params.ChainConfig{HomesteadBlock: big.NewInt(2)}
+	badcustomg.Config = &params.ChainConfig{HomesteadBlock: big.NewInt(3), XDPoS: &params.XDPoSConfig{BaseFeeDestination: "foundaton"}}
 	tests := []struct {
 		name       string
 		fn         func(ethdb.Database) (*params.ChainConfig, common.Hash, error)
@@ -114,6 +117,14 @@ func TestSetupGenesis(t *testing.T) {
 			wantHash:   customghash,
 			wantConfig: customg.Config,
 		},
+		{
+			name: "unknown base fee destination",
+			fn: func(db ethdb.Database) (*params.ChainConfig, common.Hash, error) {
+				return SetupGenesisBlock(db, &badcustomg)
+			},
+			wantErr:    fmt.Errorf("unknown XDPoS base fee destination %q", "foundaton"),
+			wantConfig: badcustomg.Config,
+		},
 		{
 			name: "incompatible config in DB",
 			fn: func(db ethdb.Database) (*params.ChainConfig, common.Hash, error) {
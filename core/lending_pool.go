@@ -50,6 +50,8 @@ var (
 	ErrInvalidCancelledLending   = errors.New("invalid cancel lending id")
 	ErrInvalidLendingTradeID     = errors.New("invalid lending trade ID")
 	ErrInvalidLendingCollateral  = errors.New("invalid collateral")
+	ErrExpiredLending            = errors.New("lending already expired")
+	ErrInvalidLendingExpiry      = errors.New("lending expiry not supported")
 )
 
 var (
@@ -430,6 +432,13 @@ func (pool *LendingPool) validateNewLending(cloneStateDb *state.StateDB, cloneLe
 	if lendingType != LendingTypeLimit && lendingType != LendingTypeMarket {
 		return ErrInvalidLendingType
 	}
+	// the pool validates the lending items for the next block
+	next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+	if expiresAt := tx.ExpiresAt(); expiresAt > 0 && !pool.chainconfig.IsXDCxOrderExpiry(next) {
+		return ErrInvalidLendingExpiry
+	} else if expiresAt > 0 && expiresAt <= uint64(time.Now().Unix()) {
+		return ErrExpiredLending
+	}
 	if tx.Side() == lendingstate.Borrowing {
 		if tx.CollateralToken().IsZero() || tx.CollateralToken() == tx.LendingToken() {
 			return ErrInvalidLendingCollateral
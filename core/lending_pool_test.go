@@ -154,7 +154,7 @@ func testSendLending(key string, nonce uint64, lendToken, collateralToken common
 		msg.Hash = msg.computeHash()
 	}
 
-	tx := types.NewLendingTransaction(msg.AccountNonce, msg.Quantity, msg.Interest, msg.Term, msg.RelayerAddress, msg.UserAddress, msg.LendingToken, msg.CollateralToken, msg.AutoTopUp, msg.Status, msg.Side, msg.Type, msg.Hash, lendingId, tradeId, msg.ExtraData)
+	tx := types.NewLendingTransaction(msg.AccountNonce, msg.Quantity, msg.Interest, msg.Term, msg.RelayerAddress, msg.UserAddress, msg.LendingToken, msg.CollateralToken, msg.AutoTopUp, msg.Status, msg.Side, msg.Type, msg.Hash, lendingId, tradeId, msg.ExtraData, 0)
 	signedTx, err := types.LendingSignTx(tx, types.LendingTxSigner{}, privateKey)
 	if err != nil {
 		log.Print(err)
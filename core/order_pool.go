@@ -49,6 +49,13 @@ var (
 	ErrInvalidOrderPrice       = errors.New("invalid order price")
 	ErrInvalidOrderHash        = errors.New("invalid order hash")
 	ErrInvalidCancelledOrder   = errors.New("invalid cancel orderid")
+	ErrInvalidOrderTimeInForce = errors.New("invalid order time in force")
+	ErrInvalidPostOnlyOrder    = errors.New("invalid post-only order")
+	ErrPostOnlyOrderWouldTake  = errors.New("post-only order would take liquidity")
+	ErrInvalidOrderTrigger     = errors.New("invalid order trigger price")
+	ErrExpiredOrder            = errors.New("order already expired")
+	ErrInvalidOrderExpiry      = errors.New("order expiry not supported")
+	ErrInvalidOrderSelfTrade   = errors.New("invalid order self-trade prevention mode")
 )
 
 var (
@@ -443,10 +450,15 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 	cloneXDCXStateDb := pool.currentOrderState.Copy()
 
 	if !tx.IsCancelledOrder() {
+		// the pool validates the orders for the next block, the forked fields are
+		// rejected until then
+		next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
 		if quantity == nil || quantity.Cmp(big.NewInt(0)) <= 0 {
 			return ErrInvalidOrderQuantity
 		}
-		if orderType != OrderTypeMarket {
+		// stop and take-profit orders are matched as market or limit orders once triggered
+		activatedType := tradingstate.ActivatedOrderType(orderType)
+		if activatedType != OrderTypeMarket {
 			if price == nil || price.Cmp(big.NewInt(0)) <= 0 {
 				return ErrInvalidOrderPrice
 			}
@@ -455,9 +467,54 @@ func (pool *OrderPool) validateOrder(tx *types.OrderTransaction) error {
 		if orderSide != OrderSideAsk && orderSide != OrderSideBid {
 			return ErrInvalidOrderSide
 		}
-		if orderType != OrderTypeLimit && orderType != OrderTypeMarket {
+		if _, ok := tradingstate.MatchingOrderType[orderType]; !ok {
 			return ErrInvalidOrderType
 		}
+		if tx.IsTriggerTypeOrder() && !pool.chainconfig.IsXDCxTriggerOrders(next) {
+			return ErrInvalidOrderType
+		}
+		if triggerPrice := tx.TriggerPrice(); tx.IsTriggerTypeOrder() != (triggerPrice != nil && triggerPrice.Sign() > 0) {
+			return ErrInvalidOrderTrigger
+		}
+		if _, ok := tradingstate.MatchingSelfTradePrevention[tx.SelfTrade()]; !ok {
+			return ErrInvalidOrderSelfTrade
+		}
+		if tx.SelfTrade() != "" && !pool.chainconfig.IsXDCxSelfTrade(next) {
+			return ErrInvalidOrderSelfTrade
+		}
+		if expiresAt := tx.ExpiresAt(); expiresAt > 0 && !pool.chainconfig.IsXDCxOrderExpiry(next) {
+			return ErrInvalidOrderExpiry
+		} else if expiresAt > 0 && expiresAt <= uint64(time.Now().Unix()) {
+			return ErrExpiredOrder
+		}
+		if !pool.chainconfig.IsXDCxOrderFlags(next) {
+			if tx.TimeInForce() != "" {
+				return ErrInvalidOrderTimeInForce
+			}
+			if tx.PostOnly() {
+				return ErrInvalidPostOnlyOrder
+			}
+		}
+		if _, ok := tradingstate.MatchingTimeInForce[tx.TimeInForce()]; !ok {
+			return ErrInvalidOrderTimeInForce
+		}
+		// market orders never rest on the book
+		if activatedType == OrderTypeMarket && tx.TimeInForce() == types.OrderTimeInForceGtc {
+			return ErrInvalidOrderTimeInForce
+		}
+		if tx.PostOnly() {
+			if orderType != OrderTypeLimit || (tx.TimeInForce() != "" && tx.TimeInForce() != types.OrderTimeInForceGtc) {
+				return ErrInvalidPostOnlyOrder
+			}
+			orderBook := tradingstate.GetTradingOrderBookHash(tx.BaseToken(), tx.QuoteToken())
+			if orderSide == OrderSideBid {
+				if bestAsk, _ := cloneXDCXStateDb.GetBestAskPrice(orderBook); bestAsk.Sign() > 0 && price.Cmp(bestAsk) >= 0 {
+					return ErrPostOnlyOrderWouldTake
+				}
+			} else if bestBid, _ := cloneXDCXStateDb.GetBestBidPrice(orderBook); bestBid.Sign() > 0 && price.Cmp(bestBid) <= 0 {
+				return ErrPostOnlyOrderWouldTake
+			}
+		}
 		if err := tradingstate.VerifyPair(cloneStateDb, tx.ExchangeAddress(), tx.BaseToken(), tx.QuoteToken()); err != nil {
 			return err
 		}
@@ -0,0 +1,320 @@
+// Package pruner removes the state trie nodes and contract codes that are not
+// reachable anymore from the recent states, replacing the offline cmd/gc tool.
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
+)
+
+const (
+	// DefaultBloomSize is the default size of the bloom filter of the live nodes,
+	// in megabytes.
+	DefaultBloomSize = 256
+
+	// sweepBatchSize is the number of stale candidates collected while iterating
+	// the database before they are checked against the bloom and deleted.
+	sweepBatchSize = 10000
+
+	// abortCheckInterval is the number of trie nodes marked between two checks
+	// of the abort channel.
+	abortCheckInterval = 10000
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+
+	// errPruned is returned if a pruner is run twice.
+	errPruned = errors.New("state already pruned")
+
+	// ErrAborted is returned if the pruning was aborted.
+	ErrAborted = errors.New("state pruning aborted")
+)
+
+// Subtrie is a trie referenced from the leaf of another trie, like the storage
+// trie of an account.
+type Subtrie struct {
+	Root    common.Hash  // Root of the subtrie, empty if the leaf has none
+	Resolve LeafResolver // Resolver of the leaves of the subtrie, nil if they reference nothing
+}
+
+// LeafResolver decodes the leaf of a trie and returns the subtries and the blobs
+// (contract codes) it references. The subtries are returned at fixed positions,
+// with an empty root if the leaf doesn't have one, so that the subtries of two
+// versions of a leaf can be compared.
+type LeafResolver func(leaf []byte) (tries []Subtrie, blobs []common.Hash)
+
+// Tries are the versions of a kind of state trie whose nodes are kept.
+type Tries struct {
+	Database *trie.Database // Database to resolve the nodes from, including the ones only in memory
+	Roots    []common.Hash  // Roots of the tries to keep, from the oldest to the most recent
+	Resolve  LeafResolver   // Resolver of the leaves of the tries
+}
+
+// ResolveAccount is the leaf resolver of the main state trie, it references the
+// storage trie and the code of the account.
+func ResolveAccount(leaf []byte) ([]Subtrie, []common.Hash) {
+	var account state.Account
+	if err := rlp.DecodeBytes(leaf, &account); err != nil {
+		return nil, nil
+	}
+	var blobs []common.Hash
+	if code := common.BytesToHash(account.CodeHash); code != emptyCode {
+		blobs = append(blobs, code)
+	}
+	return []Subtrie{{Root: account.Root}}, blobs
+}
+
+// Pruner deletes from the databases all the trie nodes and contract codes which
+// are not reachable from a given set of state roots. The live entries are marked
+// into a bloom filter, then the databases are swept.
+//
+// The pruning can run while the chain keeps importing blocks, as long as the
+// nodes flushed from the creation of the pruner are marked too, see Mark. A
+// pruner is meant to be used once.
+type Pruner struct {
+	dbs []ethdb.KeyValueStore // Databases holding the pruned tries
+
+	bloom *stateBloom // Filter of the live entries, nil once the pruning is done
+	lock  sync.Mutex  // Lock protecting the bloom, held while deleting a batch
+}
+
+// New creates a pruner of the tries held by the given databases, with a bloom
+// filter of the given size in megabytes.
+func New(bloomSize uint64, dbs ...ethdb.KeyValueStore) (*Pruner, error) {
+	if bloomSize == 0 {
+		bloomSize = DefaultBloomSize
+	}
+	bloom, err := newStateBloomWithSize(bloomSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Pruner{
+		dbs:   dbs,
+		bloom: bloom,
+	}, nil
+}
+
+// Mark records a trie node or a blob as live until the pruning is done, it's a
+// noop afterwards. It's meant to be the flush callback of the trie databases
+// writing into the pruned databases, so that the nodes of the blocks imported
+// while pruning are not deleted.
+func (p *Pruner) Mark(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom != nil {
+		p.bloom.Put(hash)
+	}
+}
+
+// Prune keeps the trie nodes and codes reachable from the given tries and deletes
+// all the other ones from the databases. It can be aborted by closing the abort
+// channel, the databases are left consistent in any case.
+func (p *Pruner) Prune(tries []Tries, abort <-chan struct{}) error {
+	defer func() {
+		p.lock.Lock()
+		p.bloom = nil
+		p.lock.Unlock()
+	}()
+	if p.bloom == nil {
+		return errPruned
+	}
+	// Mark all the entries to keep, any error leaves the databases untouched
+	start := time.Now()
+	m := &marker{pruner: p, abort: abort}
+	for _, t := range tries {
+		var prev common.Hash
+		for _, root := range t.Roots {
+			if err := m.markTrie(t.Database, root, prev, t.Resolve); err != nil {
+				return err
+			}
+			prev = root
+		}
+	}
+	log.Info("Marked live state entries", "nodes", m.marked, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Sweep the databases from the stale entries
+	for _, db := range p.dbs {
+		if err := p.sweep(db, abort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sweep deletes all the trie nodes and codes of the database which are not marked
+// as live in the bloom filter.
+func (p *Pruner) sweep(db ethdb.KeyValueStore, abort <-chan struct{}) error {
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		deleted int
+		size    common.StorageSize
+		keys    [][]byte
+		sizes   []common.StorageSize
+	)
+	flush := func() error {
+		n, s, err := p.deleteStale(db, keys, sizes)
+		deleted, size = deleted+n, size+s
+		keys, sizes = keys[:0], sizes[:0]
+		return err
+	}
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		// Trie nodes and codes are keyed by their hash, skip anything else
+		key, value := it.Key(), it.Value()
+		if len(key) != common.HashLength || !bytes.Equal(crypto.Keccak256(value), key) {
+			continue
+		}
+		keys = append(keys, common.CopyBytes(key))
+		sizes = append(sizes, common.StorageSize(len(key)+len(value)))
+		if len(keys) < sweepBatchSize {
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		select {
+		case <-abort:
+			return ErrAborted
+		default:
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "nodes", deleted, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	log.Info("Pruned state data", "nodes", deleted, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// deleteStale deletes the given entries which are not marked as live. The check
+// and the deletion are atomic with regard to Mark, an entry flushed again by the
+// chain is either kept or written back after its deletion.
+func (p *Pruner) deleteStale(db ethdb.KeyValueStore, keys [][]byte, sizes []common.StorageSize) (int, common.StorageSize, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var (
+		batch   = db.NewBatch()
+		deleted int
+		size    common.StorageSize
+	)
+	for i, key := range keys {
+		if p.bloom.Contain(key) {
+			continue
+		}
+		if err := batch.Delete(key); err != nil {
+			return deleted, size, err
+		}
+		deleted, size = deleted+1, size+sizes[i]
+	}
+	return deleted, size, batch.Write()
+}
+
+// marker walks the tries to keep, marking their nodes in the bloom of the pruner.
+type marker struct {
+	pruner *Pruner
+	abort  <-chan struct{}
+	marked uint64
+}
+
+// mark records a live entry.
+func (m *marker) mark(hash common.Hash) error {
+	m.pruner.Mark(hash)
+	if m.marked++; m.marked%abortCheckInterval == 0 {
+		select {
+		case <-m.abort:
+			return ErrAborted
+		default:
+		}
+	}
+	return nil
+}
+
+// markTrie marks all the nodes of a trie and of its subtries. If the previous
+// version of the trie, which is already marked, is given only the nodes that
+// differ from it are walked.
+func (m *marker) markTrie(db *trie.Database, root common.Hash, prev common.Hash, resolve LeafResolver) error {
+	if root == prev || root == (common.Hash{}) || root == emptyRoot {
+		return nil
+	}
+	t, err := trie.New(root, db)
+	if err != nil {
+		return err
+	}
+	var (
+		it  = t.NodeIterator(nil)
+		old *trie.Trie
+	)
+	if prev != (common.Hash{}) && prev != emptyRoot {
+		if old, err = trie.New(prev, db); err != nil {
+			return err
+		}
+		it, _ = trie.NewDifferenceIterator(old.NodeIterator(nil), it)
+	}
+	for it.Next(true) {
+		// Embedded nodes don't have a hash, they are stored in their parent
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			if err := m.mark(hash); err != nil {
+				return err
+			}
+		}
+		if !it.Leaf() || resolve == nil {
+			continue
+		}
+		tries, blobs := resolve(it.LeafBlob())
+		for _, blob := range blobs {
+			if err := m.mark(blob); err != nil {
+				return err
+			}
+		}
+		if len(tries) == 0 {
+			continue
+		}
+		// Walk the subtries only where they changed since the previous version
+		var prevTries []Subtrie
+		if old != nil {
+			enc, err := old.TryGet(it.LeafKey())
+			if err != nil {
+				return err
+			}
+			if len(enc) > 0 {
+				prevTries, _ = resolve(enc)
+			}
+		}
+		for i, sub := range tries {
+			var prevRoot common.Hash
+			if i < len(prevTries) {
+				prevRoot = prevTries[i].Root
+			}
+			if err := m.markTrie(db, sub.Root, prevRoot, sub.Resolve); err != nil {
+				return err
+			}
+		}
+	}
+	return it.Error()
+}
@@ -0,0 +1,132 @@
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+)
+
+// commitState applies the given changes on top of a state and writes the result
+// to disk, returning the new root.
+func commitState(t *testing.T, sdb state.Database, root common.Hash, change func(*state.StateDB)) common.Hash {
+	statedb, err := state.New(root, sdb)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	change(statedb)
+	if root, err = statedb.Commit(false); err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return root
+}
+
+// trieNodes returns the hashes of all the nodes of the state trie, storage tries
+// included, reachable from a root.
+func trieNodes(t *testing.T, sdb state.Database, root common.Hash) map[common.Hash]struct{} {
+	nodes := make(map[common.Hash]struct{})
+	it := state.NewNodeIterator(mustState(t, sdb, root))
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			nodes[it.Hash] = struct{}{}
+		}
+	}
+	if it.Error != nil {
+		t.Fatalf("failed to iterate state %x: %v", root, it.Error)
+	}
+	return nodes
+}
+
+func mustState(t *testing.T, sdb state.Database, root common.Hash) *state.StateDB {
+	statedb, err := state.New(root, sdb)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	return statedb
+}
+
+func TestPruneStaleStates(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		sdb   = state.NewDatabase(db)
+		addrs = []common.Address{{0x01}, {0x02}, {0x03}}
+	)
+	old := commitState(t, sdb, common.Hash{}, func(s *state.StateDB) {
+		for i, addr := range addrs {
+			s.SetBalance(addr, big.NewInt(int64(i+1)))
+			s.SetState(addr, common.Hash{0x01}, common.Hash{byte(i + 1)})
+		}
+		s.SetCode(addrs[0], []byte{0x60, 0x00})
+	})
+	recent := commitState(t, sdb, old, func(s *state.StateDB) {
+		s.SetBalance(addrs[1], big.NewInt(100))
+		s.SetState(addrs[2], common.Hash{0x01}, common.Hash{0xff})
+	})
+	latest := commitState(t, sdb, recent, func(s *state.StateDB) {
+		s.SetNonce(addrs[2], 1)
+	})
+	stale := trieNodes(t, sdb, old)
+	for _, root := range []common.Hash{recent, latest} {
+		for hash := range trieNodes(t, sdb, root) {
+			delete(stale, hash)
+		}
+	}
+	if len(stale) == 0 {
+		t.Fatalf("no stale node to prune")
+	}
+	p, err := New(1, db)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	tries := []Tries{{Database: sdb.TrieDB(), Roots: []common.Hash{recent, latest}, Resolve: ResolveAccount}}
+	if err := p.Prune(tries, nil); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	for hash := range stale {
+		if ok, _ := db.Has(hash[:]); ok {
+			t.Errorf("stale node %x not pruned", hash)
+		}
+	}
+	// The kept states must be complete on disk, check them through a fresh cache
+	fresh := state.NewDatabase(db)
+	for _, root := range []common.Hash{recent, latest} {
+		trieNodes(t, fresh, root)
+	}
+	if balance := mustState(t, fresh, latest).GetBalance(addrs[1]); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 100", balance)
+	}
+	if code := mustState(t, fresh, latest).GetCode(addrs[0]); len(code) != 2 {
+		t.Errorf("code mismatch: have %x", code)
+	}
+	if err := p.Prune(tries, nil); err != errPruned {
+		t.Errorf("second prune error mismatch: have %v, want %v", err, errPruned)
+	}
+}
+
+func TestPruneKeepsMarkedNodes(t *testing.T) {
+	var (
+		db  = rawdb.NewMemoryDatabase()
+		sdb = state.NewDatabase(db)
+	)
+	root := commitState(t, sdb, common.Hash{}, func(s *state.StateDB) {
+		s.SetBalance(common.Address{0x01}, big.NewInt(1))
+	})
+	p, err := New(1, db)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	// Nodes flushed by the chain while pruning are kept even if not reachable
+	// from the kept roots
+	p.Mark(root)
+	if err := p.Prune(nil, nil); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if ok, _ := db.Has(root[:]); !ok {
+		t.Errorf("marked node %x pruned", root)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb/memorydb"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
+)
+
+// Tests that a snapshot generated from the state tries holds exactly the
+// accounts and storage slots of the tries.
+func TestGeneration(t *testing.T) {
+	var (
+		diskdb = memorydb.New()
+		triedb = trie.NewDatabase(diskdb)
+	)
+	// Create a storage trie shared by a few accounts
+	stTrie, _ := trie.NewSecure(common.Hash{}, triedb)
+	for i := byte(1); i <= 3; i++ {
+		val, _ := rlp.EncodeToBytes([]byte{i})
+		stTrie.Update(common.BytesToHash([]byte{i}).Bytes(), val)
+	}
+	stRoot, _ := stTrie.Commit(nil)
+
+	// Create the account trie, half of the accounts with storage
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb)
+	accounts := make(map[common.Address][]byte)
+	for i := byte(1); i <= 10; i++ {
+		acc := struct {
+			Nonce    uint64
+			Balance  *big.Int
+			Root     common.Hash
+			CodeHash []byte
+		}{uint64(i), big.NewInt(int64(i)), emptyRoot, emptyCode.Bytes()}
+		if i%2 == 0 {
+			acc.Root = stRoot
+		}
+		val, _ := rlp.EncodeToBytes(acc)
+		addr := common.BytesToAddress([]byte{i})
+		accTrie.Update(addr.Bytes(), val)
+		accounts[addr] = SlimAccountRLP(acc.Nonce, acc.Balance, acc.Root, acc.CodeHash)
+	}
+	root, _ := accTrie.Commit(nil)
+	triedb.Commit(root, false)
+
+	snap := generateSnapshot(diskdb, triedb, 16, root, nil)
+	select {
+	case <-snap.genPending:
+		// Snapshot generation succeeded
+	case <-time.After(3 * time.Second):
+		t.Fatalf("snapshot generation timed out")
+	}
+	for addr, want := range accounts {
+		hash := crypto.Keccak256Hash(addr.Bytes())
+		blob, err := snap.AccountRLP(hash)
+		if err != nil {
+			t.Fatalf("account %x: failed to read snapshot: %v", addr, err)
+		}
+		if !bytes.Equal(blob, want) {
+			t.Errorf("account %x: blob mismatch: have %x, want %x", addr, blob, want)
+		}
+		for i := byte(1); i <= 3; i++ {
+			val, err := snap.Storage(hash, crypto.Keccak256Hash(common.BytesToHash([]byte{i}).Bytes()))
+			if err != nil {
+				t.Fatalf("account %x: failed to read slot %d: %v", addr, i, err)
+			}
+			if addr[common.AddressLength-1]%2 == 1 {
+				if val != nil {
+					t.Errorf("account %x: unexpected slot %d: %x", addr, i, val)
+				}
+				continue
+			}
+			if want, _ := rlp.EncodeToBytes([]byte{i}); !bytes.Equal(val, want) {
+				t.Errorf("account %x: slot %d mismatch: have %x, want %x", addr, i, val, want)
+			}
+		}
+	}
+	// Stop the generator, it waits for an abort after finishing
+	stop := make(chan *generatorStats)
+	snap.genAbort <- stop
+	if stats := <-stop; stats != nil {
+		t.Errorf("finished generator returned stats: %v", stats)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb"
+)
+
+// Iterator is an iterator to step over all the accounts or the specific
+// storage in a snapshot which may or may not be composed of multiple layers.
+type Iterator interface {
+	// Next steps the iterator forward one element, returning false if exhausted,
+	// or an error if iteration failed for some reason (e.g. root being iterated
+	// becomes stale and garbage collected).
+	Next() bool
+
+	// Error returns any failure that occurred during iteration, which might have
+	// caused a premature iteration exit (e.g. snapshot stack becoming stale).
+	Error() error
+
+	// Hash returns the hash of the account or storage slot the iterator is
+	// currently at.
+	Hash() common.Hash
+
+	// Release releases associated resources. Release should always succeed and
+	// can be called multiple times without causing error.
+	Release()
+}
+
+// AccountIterator is an iterator to step over all the accounts in a snapshot,
+// which may or may not be composed of multiple layers.
+type AccountIterator interface {
+	Iterator
+
+	// Account returns the RLP encoded slim account the iterator is currently at.
+	// An error will be returned if the iterator becomes invalid
+	Account() []byte
+}
+
+// StorageIterator is an iterator to step over the specific storage in a snapshot,
+// which may or may not be composed of multiple layers.
+type StorageIterator interface {
+	Iterator
+
+	// Slot returns the storage slot the iterator is currently at. An error will
+	// be returned if the iterator becomes invalid
+	Slot() []byte
+}
+
+// layerIterator steps over the entries of a single snapshot layer, in ascending
+// hash order. Empty values mark the entries deleted in the layer.
+type layerIterator interface {
+	next() bool
+	hash() common.Hash
+	value() ([]byte, error)
+	release()
+}
+
+// diffIterator steps over the sorted entries of a diff layer. The values are
+// resolved lazily, failing if the layer becomes stale.
+type diffIterator struct {
+	layer *diffLayer
+	keys  []common.Hash
+	curr  common.Hash
+
+	resolve func(layer *diffLayer, hash common.Hash) []byte
+}
+
+func (it *diffIterator) next() bool {
+	if len(it.keys) == 0 {
+		return false
+	}
+	it.curr, it.keys = it.keys[0], it.keys[1:]
+	return true
+}
+
+func (it *diffIterator) hash() common.Hash {
+	return it.curr
+}
+
+func (it *diffIterator) value() ([]byte, error) {
+	it.layer.lock.RLock()
+	defer it.layer.lock.RUnlock()
+
+	if it.layer.Stale() {
+		return nil, ErrSnapshotStale
+	}
+	return it.resolve(it.layer, it.curr), nil
+}
+
+func (it *diffIterator) release() {
+	it.keys = nil
+}
+
+// diskIterator steps over the entries of the disk layer with a given key prefix.
+type diskIterator struct {
+	layer  *diskLayer
+	it     ethdb.Iterator
+	prefix int // Length of the key prefix before the iterated hash
+	keylen int // Length of the iterated keys, the other ones sharing the prefix are skipped
+}
+
+func (it *diskIterator) next() bool {
+	for it.it.Next() {
+		if len(it.it.Key()) == it.keylen {
+			return true
+		}
+	}
+	return false
+}
+
+func (it *diskIterator) hash() common.Hash {
+	return common.BytesToHash(it.it.Key()[it.prefix:])
+}
+
+func (it *diskIterator) value() ([]byte, error) {
+	if it.layer.Stale() {
+		return nil, ErrSnapshotStale
+	}
+	return it.it.Value(), nil
+}
+
+func (it *diskIterator) release() {
+	it.it.Release()
+}
+
+// mergedIterator merges the iterators of a stack of layers, the entry of a hash
+// being the one of the top-most layer that contains it. The deleted entries are
+// skipped.
+type mergedIterator struct {
+	layers []layerIterator // Iterators of the layers, from the top-most one
+	alive  []bool          // Whether the iterator of each layer is positioned on an entry
+	curr   common.Hash
+	value  []byte
+	fail   error
+}
+
+// newMergedIterator creates a merged iterator, positioning all the layer
+// iterators on their first entry.
+func newMergedIterator(layers []layerIterator) *mergedIterator {
+	it := &mergedIterator{
+		layers: layers,
+		alive:  make([]bool, len(layers)),
+	}
+	for i, layer := range layers {
+		it.alive[i] = layer.next()
+	}
+	return it
+}
+
+// Next steps the iterator forward to the next live entry.
+func (it *mergedIterator) Next() bool {
+	for it.fail == nil {
+		// Find the smallest hash, preferring the top-most layer
+		top := -1
+		for i, layer := range it.layers {
+			if !it.alive[i] {
+				continue
+			}
+			if hash := layer.hash(); top == -1 || bytes.Compare(hash[:], it.curr[:]) < 0 {
+				top, it.curr = i, hash
+			}
+		}
+		if top == -1 {
+			return false
+		}
+		if it.value, it.fail = it.layers[top].value(); it.fail != nil {
+			return false
+		}
+		// Step all the layers holding the same entry past it
+		for i, layer := range it.layers {
+			if it.alive[i] && layer.hash() == it.curr {
+				it.alive[i] = layer.next()
+			}
+		}
+		if len(it.value) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *mergedIterator) Error() error {
+	return it.fail
+}
+
+// Hash returns the hash of the entry the iterator is currently at.
+func (it *mergedIterator) Hash() common.Hash {
+	return it.curr
+}
+
+// Account returns the account the iterator is currently at.
+func (it *mergedIterator) Account() []byte {
+	return it.value
+}
+
+// Slot returns the storage slot the iterator is currently at.
+func (it *mergedIterator) Slot() []byte {
+	return it.value
+}
+
+// Release releases the iterators of all the layers.
+func (it *mergedIterator) Release() {
+	for _, layer := range it.layers {
+		layer.release()
+	}
+	it.layers = nil
+}
+
+// newAccountIterator creates an iterator over the accounts of a stack of layers,
+// from the top-most one down to the disk layer.
+func newAccountIterator(layers []snapshot, seek common.Hash) (AccountIterator, error) {
+	var its []layerIterator
+	for _, layer := range layers {
+		switch layer := layer.(type) {
+		case *diffLayer:
+			its = append(its, &diffIterator{
+				layer: layer,
+				keys:  layer.accountList(seek),
+				resolve: func(layer *diffLayer, hash common.Hash) []byte {
+					return layer.accountData[hash]
+				},
+			})
+		case *diskLayer:
+			if layer.generating() {
+				return nil, ErrNotCoveredYet
+			}
+			its = append(its, &diskIterator{
+				layer:  layer,
+				it:     layer.diskdb.NewIterator(rawdb.SnapshotAccountPrefix, seek[:]),
+				prefix: len(rawdb.SnapshotAccountPrefix),
+				keylen: len(rawdb.SnapshotAccountPrefix) + common.HashLength,
+			})
+		default:
+			panic(fmt.Sprintf("unknown data layer: %T", layer))
+		}
+	}
+	return newMergedIterator(its), nil
+}
+
+// newStorageIterator creates an iterator over the storage of an account in a
+// stack of layers, from the top-most one down to the disk layer. The layers
+// below the one that destructed the account are ignored.
+func newStorageIterator(layers []snapshot, account common.Hash, seek common.Hash) (StorageIterator, error) {
+	var its []layerIterator
+	for _, layer := range layers {
+		switch layer := layer.(type) {
+		case *diffLayer:
+			keys, destructed := layer.storageList(account, seek)
+			its = append(its, &diffIterator{
+				layer: layer,
+				keys:  keys,
+				resolve: func(layer *diffLayer, hash common.Hash) []byte {
+					return layer.storageData[account][hash]
+				},
+			})
+			if destructed {
+				return newMergedIterator(its), nil
+			}
+		case *diskLayer:
+			if layer.generating() {
+				return nil, ErrNotCoveredYet
+			}
+			prefix := append(append([]byte{}, rawdb.SnapshotStoragePrefix...), account[:]...)
+			its = append(its, &diskIterator{
+				layer:  layer,
+				it:     layer.diskdb.NewIterator(prefix, seek[:]),
+				prefix: len(prefix),
+				keylen: len(prefix) + common.HashLength,
+			})
+		default:
+			panic(fmt.Sprintf("unknown data layer: %T", layer))
+		}
+	}
+	return newMergedIterator(its), nil
+}
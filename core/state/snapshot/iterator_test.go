@@ -0,0 +1,134 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+)
+
+// Tests that the account iterator merges the layers in hash order, the values
+// of the top-most layer winning and the deleted accounts being skipped.
+func TestAccountIteratorMerge(t *testing.T) {
+	var (
+		acc1 = common.HexToHash("0x01")
+		acc2 = common.HexToHash("0x02")
+		acc3 = common.HexToHash("0x03")
+		acc4 = common.HexToHash("0x04")
+		blob = [][]byte{randomAccount(), randomAccount(), randomAccount()}
+	)
+	snaps := newTestTree(common.HexToHash("0xa1"), map[common.Hash][]byte{acc1: blob[0], acc2: blob[0], acc4: blob[0]})
+
+	if err := snaps.Update(common.HexToHash("0xa2"), common.HexToHash("0xa1"),
+		map[common.Hash]struct{}{acc2: {}},
+		map[common.Hash][]byte{acc1: blob[1], acc3: blob[1]}, nil); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := snaps.Update(common.HexToHash("0xa3"), common.HexToHash("0xa2"), nil,
+		map[common.Hash][]byte{acc3: blob[2]}, nil); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	it, err := snaps.AccountIterator(common.HexToHash("0xa3"), common.Hash{})
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Release()
+
+	want := []struct {
+		hash common.Hash
+		blob []byte
+	}{{acc1, blob[1]}, {acc3, blob[2]}, {acc4, blob[0]}}
+	for i := 0; it.Next(); i++ {
+		if i >= len(want) {
+			t.Fatalf("unexpected account %x", it.Hash())
+		}
+		if it.Hash() != want[i].hash || !bytes.Equal(it.Account(), want[i].blob) {
+			t.Errorf("item %d: have %x (%x), want %x (%x)", i, it.Hash(), it.Account(), want[i].hash, want[i].blob)
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	// Seeking skips the accounts before the seek position
+	seekIt, err := snaps.AccountIterator(common.HexToHash("0xa3"), acc2)
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer seekIt.Release()
+
+	if !seekIt.Next() || seekIt.Hash() != acc3 {
+		t.Errorf("seeked iterator position mismatch: have %x, want %x", seekIt.Hash(), acc3)
+	}
+}
+
+// Tests that the storage iterator ignores the slots below the layer in which
+// the account was destructed.
+func TestStorageIteratorDestruct(t *testing.T) {
+	var (
+		acc   = common.HexToHash("0x01")
+		slot1 = common.HexToHash("0x01")
+		slot2 = common.HexToHash("0x02")
+		slot3 = common.HexToHash("0x03")
+	)
+	snaps := newTestTree(common.HexToHash("0xa1"), map[common.Hash][]byte{acc: randomAccount()})
+	rawdb.WriteStorageSnapshot(snaps.diskdb, acc, slot1, []byte{0x01})
+	rawdb.WriteStorageSnapshot(snaps.diskdb, acc, slot2, []byte{0x01})
+
+	if err := snaps.Update(common.HexToHash("0xa2"), common.HexToHash("0xa1"), nil,
+		map[common.Hash][]byte{acc: randomAccount()},
+		map[common.Hash]map[common.Hash][]byte{acc: {slot1: nil, slot3: []byte{0x02}}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	tests := []struct {
+		root  common.Hash
+		slots []common.Hash
+	}{
+		{common.HexToHash("0xa1"), []common.Hash{slot1, slot2}},
+		{common.HexToHash("0xa2"), []common.Hash{slot2, slot3}},
+		{common.HexToHash("0xa3"), []common.Hash{slot3}},
+	}
+	// Recreate the account in a new layer, wiping the older slots
+	if err := snaps.Update(common.HexToHash("0xa3"), common.HexToHash("0xa2"),
+		map[common.Hash]struct{}{acc: {}},
+		map[common.Hash][]byte{acc: randomAccount()},
+		map[common.Hash]map[common.Hash][]byte{acc: {slot3: []byte{0x03}}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	for i, tt := range tests {
+		it, err := snaps.StorageIterator(tt.root, acc, common.Hash{})
+		if err != nil {
+			t.Fatalf("test %d: failed to create iterator: %v", i, err)
+		}
+		var slots []common.Hash
+		for it.Next() {
+			slots = append(slots, it.Hash())
+		}
+		it.Release()
+
+		if len(slots) != len(tt.slots) {
+			t.Fatalf("test %d: slot count mismatch: have %d, want %d", i, len(slots), len(tt.slots))
+		}
+		for j := range slots {
+			if slots[j] != tt.slots[j] {
+				t.Errorf("test %d, slot %d: have %x, want %x", i, j, slots[j], tt.slots[j])
+			}
+		}
+	}
+}
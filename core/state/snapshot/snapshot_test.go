@@ -0,0 +1,241 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb/memorydb"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+)
+
+// randomHash generates a random blob of data and returns it as a hash.
+func randomHash() common.Hash {
+	var hash common.Hash
+	if n, err := rand.Read(hash[:]); n != common.HashLength || err != nil {
+		panic(err)
+	}
+	return hash
+}
+
+// randomAccount generates a random account and returns it RLP encoded.
+func randomAccount() []byte {
+	return SlimAccountRLP(rand.Uint64(), big.NewInt(rand.Int63()), randomHash(), randomHash().Bytes())
+}
+
+// newTestTree creates a snapshot tree with a single, fully generated disk layer
+// holding the given accounts.
+func newTestTree(root common.Hash, accounts map[common.Hash][]byte) *Tree {
+	diskdb := memorydb.New()
+	rawdb.WriteSnapshotRoot(diskdb, root)
+	for hash, blob := range accounts {
+		rawdb.WriteAccountSnapshot(diskdb, hash, blob)
+	}
+	base := &diskLayer{
+		diskdb: diskdb,
+		root:   root,
+		cache:  fastcache.New(1024 * 500),
+	}
+	return &Tree{
+		diskdb: diskdb,
+		layers: map[common.Hash]snapshot{base.root: base},
+	}
+}
+
+// Tests that the account and storage reads resolve through the diff layers down
+// to the disk one, with deletions and destructs shadowing the lower layers.
+func TestDiffLayerReads(t *testing.T) {
+	var (
+		acc1, acc2, acc3 = randomHash(), randomHash(), randomHash()
+		slot             = randomHash()
+		blob1, blob2     = randomAccount(), randomAccount()
+	)
+	snaps := newTestTree(common.HexToHash("0x01"), map[common.Hash][]byte{acc1: blob1, acc3: blob1})
+	rawdb.WriteStorageSnapshot(snaps.diskdb, acc3, slot, []byte{0x01})
+
+	// Layer 2 overrides acc1 and destructs acc3, layer 3 recreates acc3 empty
+	if err := snaps.Update(common.HexToHash("0x02"), common.HexToHash("0x01"),
+		map[common.Hash]struct{}{acc3: {}},
+		map[common.Hash][]byte{acc1: blob2, acc2: blob1},
+		map[common.Hash]map[common.Hash][]byte{acc2: {slot: []byte{0x02}}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := snaps.Update(common.HexToHash("0x03"), common.HexToHash("0x02"), nil,
+		map[common.Hash][]byte{acc3: blob2},
+		map[common.Hash]map[common.Hash][]byte{acc2: {slot: nil}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := snaps.Update(common.HexToHash("0x03"), common.HexToHash("0x03"), nil, nil, nil); err != errSnapshotCycle {
+		t.Fatalf("self referencing update: have %v, want %v", err, errSnapshotCycle)
+	}
+	tests := []struct {
+		root    common.Hash
+		account common.Hash
+		blob    []byte
+		slot    []byte
+	}{
+		{common.HexToHash("0x01"), acc1, blob1, nil},
+		{common.HexToHash("0x01"), acc3, blob1, []byte{0x01}},
+		{common.HexToHash("0x02"), acc1, blob2, nil},
+		{common.HexToHash("0x02"), acc2, blob1, []byte{0x02}},
+		{common.HexToHash("0x02"), acc3, nil, nil},
+		{common.HexToHash("0x03"), acc1, blob2, nil},
+		{common.HexToHash("0x03"), acc2, blob1, nil},
+		{common.HexToHash("0x03"), acc3, blob2, nil},
+	}
+	for i, tt := range tests {
+		snap := snaps.Snapshot(tt.root)
+		blob, err := snap.AccountRLP(tt.account)
+		if err != nil {
+			t.Fatalf("test %d: failed to read account: %v", i, err)
+		}
+		if !bytes.Equal(blob, tt.blob) {
+			t.Errorf("test %d: account mismatch: have %x, want %x", i, blob, tt.blob)
+		}
+		val, err := snap.Storage(tt.account, slot)
+		if err != nil {
+			t.Fatalf("test %d: failed to read storage: %v", i, err)
+		}
+		if !bytes.Equal(val, tt.slot) {
+			t.Errorf("test %d: storage mismatch: have %x, want %x", i, val, tt.slot)
+		}
+	}
+}
+
+// Tests that capping the tree flattens the bottom layers into the disk one,
+// marking the flattened layers stale and keeping the reads of the head intact.
+func TestTreeCap(t *testing.T) {
+	var (
+		accounts = make(map[common.Hash][]byte)
+		snaps    = newTestTree(common.HexToHash("0x00"), nil)
+	)
+	for i := 1; i <= 10; i++ {
+		hash, blob := randomHash(), randomAccount()
+		accounts[hash] = blob
+
+		if err := snaps.Update(common.BigToHash(big.NewInt(int64(i))), common.BigToHash(big.NewInt(int64(i-1))), nil, map[common.Hash][]byte{hash: blob}, nil); err != nil {
+			t.Fatalf("layer %d: failed to update snapshot tree: %v", i, err)
+		}
+	}
+	head := common.BigToHash(big.NewInt(10))
+	if n := len(snaps.layers); n != 11 {
+		t.Fatalf("pre-cap layer count mismatch: have %d, want %d", n, 11)
+	}
+	stale := snaps.Snapshot(common.BigToHash(big.NewInt(1))).(snapshot)
+
+	// Force persisting everything but the top layer into the disk layer
+	if err := snaps.Cap(head, 0); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if n := len(snaps.layers); n != 1 {
+		t.Fatalf("post-cap layer count mismatch: have %d, want %d", n, 1)
+	}
+	if !stale.Stale() {
+		t.Errorf("flattened layer not marked stale")
+	}
+	if _, err := stale.AccountRLP(randomHash()); err != ErrSnapshotStale {
+		t.Errorf("stale layer read error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if root := rawdb.ReadSnapshotRoot(snaps.diskdb); root != head {
+		t.Errorf("persisted snapshot root mismatch: have %x, want %x", root, head)
+	}
+	for hash, blob := range accounts {
+		if have := rawdb.ReadAccountSnapshot(snaps.diskdb, hash); !bytes.Equal(have, blob) {
+			t.Errorf("account %x: persisted blob mismatch: have %x, want %x", hash, have, blob)
+		}
+		have, err := snaps.Snapshot(head).AccountRLP(hash)
+		if err != nil || !bytes.Equal(have, blob) {
+			t.Errorf("account %x: head read mismatch: have %x (%v), want %x", hash, have, err, blob)
+		}
+	}
+}
+
+// Tests that a tree journalled on shutdown can be reloaded with all of its diff
+// layers intact.
+func TestJournalRoundTrip(t *testing.T) {
+	var (
+		acc1, acc2 = randomHash(), randomHash()
+		slot       = randomHash()
+		snaps      = newTestTree(common.HexToHash("0x01"), map[common.Hash][]byte{acc1: randomAccount()})
+	)
+	if err := snaps.Update(common.HexToHash("0x02"), common.HexToHash("0x01"),
+		map[common.Hash]struct{}{acc1: {}},
+		map[common.Hash][]byte{acc2: randomAccount()},
+		map[common.Hash]map[common.Hash][]byte{acc2: {slot: []byte{0x01}}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := snaps.Update(common.HexToHash("0x03"), common.HexToHash("0x02"), nil, nil,
+		map[common.Hash]map[common.Hash][]byte{acc2: {slot: nil}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	base, err := snaps.Journal(common.HexToHash("0x03"))
+	if err != nil {
+		t.Fatalf("failed to journal snapshot tree: %v", err)
+	}
+	if base != common.HexToHash("0x01") {
+		t.Fatalf("journal base mismatch: have %x, want %x", base, common.HexToHash("0x01"))
+	}
+	head, err := loadSnapshot(snaps.diskdb, nil, 1, common.HexToHash("0x03"))
+	if err != nil {
+		t.Fatalf("failed to load journalled snapshot: %v", err)
+	}
+	for layer, orig := head, snaps.Snapshot(common.HexToHash("0x03")).(snapshot); orig != nil; layer, orig = layer.Parent(), orig.Parent() {
+		if layer == nil || layer.Root() != orig.Root() {
+			t.Fatalf("layer root mismatch: have %v, want %x", layer, orig.Root())
+		}
+		for _, hash := range []common.Hash{acc1, acc2} {
+			want, _ := orig.AccountRLP(hash)
+			if have, _ := layer.AccountRLP(hash); !bytes.Equal(have, want) {
+				t.Errorf("layer %x, account %x: blob mismatch: have %x, want %x", orig.Root(), hash, have, want)
+			}
+			want, _ = orig.Storage(hash, slot)
+			if have, _ := layer.Storage(hash, slot); !bytes.Equal(have, want) {
+				t.Errorf("layer %x, account %x: slot mismatch: have %x, want %x", orig.Root(), hash, have, want)
+			}
+		}
+	}
+	// A journal for a different head must be rejected
+	if _, err := loadSnapshot(snaps.diskdb, nil, 1, common.HexToHash("0x04")); err == nil {
+		t.Fatalf("mismatching head loaded")
+	}
+}
+
+// Tests that the slim account format round trips into the consensus one.
+func TestSlimAccount(t *testing.T) {
+	blob := SlimAccountRLP(1, big.NewInt(2), emptyRoot, emptyCode[:])
+
+	var slim Account
+	if err := rlp.DecodeBytes(blob, &slim); err != nil {
+		t.Fatalf("failed to decode slim account: %v", err)
+	}
+	if len(slim.Root) != 0 || len(slim.CodeHash) != 0 {
+		t.Errorf("empty root and code not omitted: root %x, code %x", slim.Root, slim.CodeHash)
+	}
+	full, err := FullAccount(blob)
+	if err != nil {
+		t.Fatalf("failed to expand slim account: %v", err)
+	}
+	if !bytes.Equal(full.Root, emptyRoot[:]) || !bytes.Equal(full.CodeHash, emptyCode[:]) {
+		t.Errorf("full account mismatch: root %x, code %x", full.Root, full.CodeHash)
+	}
+}
@@ -0,0 +1,220 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+)
+
+// accessTracker records the accounts and storage slots accessed by the
+// transactions applied to a state, in order to execute transactions
+// optimistically on copies of a state and merge the results back.
+//
+// On the state the copies are made from, only the writes are recorded along
+// with the number of transactions finalised at the time of the write. On a
+// speculative copy, the reads are recorded as well, and the balance credits of
+// the fee recipients are deferred until the copy is merged, as they commute
+// with the ones of the other transactions.
+type accessTracker struct {
+	version int // Number of transactions finalised on the tracked state
+	base    int // Version of the state a speculative copy was made from
+
+	speculative   bool
+	accountReads  map[common.Address]struct{}
+	storageReads  map[common.Address]map[common.Hash]struct{}
+	accountWrites map[common.Address]int
+	storageWrites map[common.Address]map[common.Hash]int
+	storageAny    map[common.Address]int // Last write of any storage slot of an account
+	resets        map[common.Address]int // Accounts created, reset or destructed
+
+	feeRecipients map[common.Address]struct{}
+	credits       map[common.Address]*big.Int
+	invalid       bool // Set if a fee recipient was accessed other than by a credit
+}
+
+func newAccessTracker() *accessTracker {
+	return &accessTracker{
+		accountReads:  make(map[common.Address]struct{}),
+		storageReads:  make(map[common.Address]map[common.Hash]struct{}),
+		accountWrites: make(map[common.Address]int),
+		storageWrites: make(map[common.Address]map[common.Hash]int),
+		storageAny:    make(map[common.Address]int),
+		resets:        make(map[common.Address]int),
+		credits:       make(map[common.Address]*big.Int),
+	}
+}
+
+func (t *accessTracker) readAccount(addr common.Address) {
+	if !t.speculative {
+		return
+	}
+	if _, ok := t.feeRecipients[addr]; ok {
+		t.invalid = true
+	}
+	t.accountReads[addr] = struct{}{}
+}
+
+func (t *accessTracker) readStorage(addr common.Address, key common.Hash) {
+	if !t.speculative {
+		return
+	}
+	if t.storageReads[addr] == nil {
+		t.storageReads[addr] = make(map[common.Hash]struct{})
+	}
+	t.storageReads[addr][key] = struct{}{}
+}
+
+func (t *accessTracker) writeAccount(addr common.Address) {
+	t.accountWrites[addr] = t.version + 1
+}
+
+func (t *accessTracker) writeStorage(addr common.Address, key common.Hash) {
+	if t.storageWrites[addr] == nil {
+		t.storageWrites[addr] = make(map[common.Hash]int)
+	}
+	t.storageWrites[addr][key] = t.version + 1
+	t.storageAny[addr] = t.version + 1
+}
+
+func (t *accessTracker) reset(addr common.Address) {
+	t.accountWrites[addr] = t.version + 1
+	t.storageAny[addr] = t.version + 1
+	t.resets[addr] = t.version + 1
+}
+
+// credit defers a balance credit of a fee recipient, reporting false if the
+// account isn't a fee recipient.
+func (t *accessTracker) credit(addr common.Address, amount *big.Int) bool {
+	if !t.speculative {
+		return false
+	}
+	if _, ok := t.feeRecipients[addr]; !ok {
+		return false
+	}
+	if t.credits[addr] == nil {
+		t.credits[addr] = new(big.Int)
+	}
+	t.credits[addr].Add(t.credits[addr], amount)
+	return true
+}
+
+// SpeculativeCopy creates a copy of the state to execute a single transaction
+// on, recording the state it accesses so that it can be merged back with Merge
+// if the state it read wasn't modified in the meantime. The balance credits of
+// the fee recipients are deferred until the merge, any other access to those
+// accounts preventing the merge.
+//
+// Merging requires the writes to be tracked on the state, see TrackWrites.
+func (self *StateDB) SpeculativeCopy(feeRecipients []common.Address) *StateDB {
+	state := self.Copy()
+	tracker := newAccessTracker()
+	tracker.speculative = true
+	tracker.feeRecipients = make(map[common.Address]struct{}, len(feeRecipients))
+	for _, addr := range feeRecipients {
+		tracker.feeRecipients[addr] = struct{}{}
+	}
+	self.lock.Lock()
+	if self.tracker != nil {
+		tracker.base = self.tracker.version
+	}
+	self.lock.Unlock()
+	state.tracker = tracker
+	return state
+}
+
+// TrackWrites starts or stops recording the writes of the transactions applied
+// to the state, which is needed to check the speculative copies for conflicts.
+func (self *StateDB) TrackWrites(enable bool) {
+	if !enable {
+		self.tracker = nil
+	} else if self.tracker == nil {
+		self.tracker = newAccessTracker()
+	}
+}
+
+// Conflicts reports whether a speculative copy of the state can't be merged,
+// because it read some state modified since the copy was made, or because the
+// state doesn't track its writes.
+func (self *StateDB) Conflicts(spec *StateDB) bool {
+	written, accessed := self.tracker, spec.tracker
+	if written == nil || accessed == nil || !accessed.speculative || accessed.invalid || spec.dbErr != nil {
+		return true
+	}
+	base := accessed.base
+	for addr := range accessed.accountReads {
+		if written.accountWrites[addr] > base {
+			return true
+		}
+	}
+	for addr, keys := range accessed.storageReads {
+		if written.resets[addr] > base {
+			return true
+		}
+		for key := range keys {
+			if written.storageWrites[addr][key] > base {
+				return true
+			}
+		}
+	}
+	for addr := range accessed.resets {
+		if written.storageAny[addr] > base || written.accountWrites[addr] > base {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge applies the changes of the transaction executed on a speculative copy
+// of the state, which must not conflict with the state, and finalises it,
+// deleting the touched empty objects if deleteEmptyObjects is set. The logs of
+// the transaction are re-indexed as the current transaction of the state.
+func (self *StateDB) Merge(spec *StateDB, deleteEmptyObjects bool) {
+	accessed := spec.tracker
+
+	// Destructed and (re)created accounts are taken over with their storage
+	for addr := range accessed.resets {
+		if obj := spec.stateObjects[addr]; obj != nil {
+			self.stateObjects[addr] = obj.deepCopy(self, self.MarkStateObjectDirty)
+			self.stateObjectsDirty[addr] = struct{}{}
+			if self.tracker != nil {
+				self.tracker.reset(addr)
+			}
+		}
+	}
+	for addr := range accessed.accountWrites {
+		if _, ok := accessed.resets[addr]; ok {
+			continue
+		}
+		obj := spec.stateObjects[addr]
+		if obj == nil {
+			continue
+		}
+		self.SetBalance(addr, obj.data.Balance)
+		self.SetNonce(addr, obj.data.Nonce)
+		if obj.dirtyCode {
+			self.SetCode(addr, obj.code)
+		}
+	}
+	for addr, keys := range accessed.storageWrites {
+		if _, ok := accessed.resets[addr]; ok {
+			continue
+		}
+		obj := spec.stateObjects[addr]
+		if obj == nil {
+			continue
+		}
+		for key := range keys {
+			self.SetState(addr, key, obj.cachedStorage[key])
+		}
+	}
+	for addr, amount := range accessed.credits {
+		self.AddBalance(addr, amount)
+	}
+	for _, log := range spec.logs[spec.thash] {
+		self.AddLog(log)
+	}
+	for hash, preimage := range spec.preimages {
+		self.AddPreimage(hash, preimage)
+	}
+	self.Finalise(deleteEmptyObjects)
+}
@@ -79,6 +79,7 @@ type stateObject struct {
 
 	cachedStorage Storage // Storage entry cache to avoid duplicate reads
 	dirtyStorage  Storage // Storage entries that need to be flushed to disk
+	fakeStorage   Storage // Fake storage replacing the real one, constructed by the caller for debugging
 
 	// Cache flags.
 	// When an object is marked suicided it will be delete from the trie
@@ -87,7 +88,17 @@ type stateObject struct {
 	suicided  bool
 	touched   bool
 	deleted   bool
+	created   bool                      // true if the object was created (or reset) in this state
 	onDirty   func(addr common.Address) // Callback method to mark a state object newly dirty
+
+	// Snapshot tracking.
+	// fromSnap marks objects loaded from the flat state snapshot, in which case
+	// storage reads are served from the snapshot as long as the storage root is
+	// still the one the snapshot holds. snapStorage collects the flushed storage
+	// writes, keyed by slot hash, to be pushed into the snapshot on commit.
+	fromSnap    bool
+	snapRoot    common.Hash
+	snapStorage map[common.Hash][]byte
 }
 
 // empty returns whether the account is considered empty.
@@ -169,34 +180,59 @@ func (c *stateObject) getTrie(db Database) Trie {
 }
 
 func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
-	value := common.Hash{}
+	// If the fake storage is set, only lookup the state here
+	if self.fakeStorage != nil {
+		return self.fakeStorage[key]
+	}
 	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
+	value, err := self.readStorage(db, key)
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
 	}
-	if len(enc) > 0 {
-		_, content, _, err := rlp.Split(enc)
-		if err != nil {
-			self.setError(err)
-		}
-		value.SetBytes(content)
-	}
 	return value
 }
 
 func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
+	// If the fake storage is set, only lookup the state here
+	if self.fakeStorage != nil {
+		return self.fakeStorage[key]
+	}
 	value, exists := self.cachedStorage[key]
 	if exists {
 		return value
 	}
 	// Load from DB in case it is missing.
-	enc, err := self.getTrie(db).TryGet(key[:])
+	value, err := self.readStorage(db, key)
 	if err != nil {
 		self.setError(err)
 		return common.Hash{}
 	}
+	if (value != common.Hash{}) {
+		self.cachedStorage[key] = value
+	}
+	return value
+}
+
+// readStorage retrieves a storage slot as stored in the last flushed storage
+// trie, serving it from the state snapshot if the trie wasn't modified since
+// the object was loaded from there.
+func (self *stateObject) readStorage(db Database, key common.Hash) (common.Hash, error) {
+	var (
+		enc     []byte
+		err     error
+		useSnap = self.db.snap != nil && self.fromSnap && self.data.Root == self.snapRoot
+	)
+	if useSnap {
+		enc, err = self.db.snap.Storage(self.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	// Fall back to the trie if the snapshot can't serve the slot
+	if !useSnap || err != nil {
+		if enc, err = self.getTrie(db).TryGet(key[:]); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	var value common.Hash
 	if len(enc) > 0 {
 		_, content, _, err := rlp.Split(enc)
 		if err != nil {
@@ -204,14 +240,16 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		}
 		value.SetBytes(content)
 	}
-	if (value != common.Hash{}) {
-		self.cachedStorage[key] = value
-	}
-	return value
+	return value, nil
 }
 
 // SetState updates a value in account storage.
 func (self *stateObject) SetState(db Database, key, value common.Hash) {
+	// If the fake storage is set, put the temporary state update here
+	if self.fakeStorage != nil {
+		self.fakeStorage[key] = value
+		return
+	}
 	self.db.journal = append(self.db.journal, storageChange{
 		account:  &self.address,
 		key:      key,
@@ -220,6 +258,20 @@ func (self *stateObject) SetState(db Database, key, value common.Hash) {
 	self.setState(key, value)
 }
 
+// SetStorage replaces the entire storage with the given one. From then on, the
+// original storage is ignored and the state is only looked up in the fake one.
+//
+// Note this should only be used for debugging, the fake storage is never
+// journalled nor committed to the database.
+func (self *stateObject) SetStorage(storage map[common.Hash]common.Hash) {
+	if self.fakeStorage == nil {
+		self.fakeStorage = make(Storage)
+	}
+	for key, value := range storage {
+		self.fakeStorage[key] = value
+	}
+}
+
 func (self *stateObject) setState(key, value common.Hash) {
 	self.cachedStorage[key] = value
 	self.dirtyStorage[key] = value
@@ -233,15 +285,24 @@ func (self *stateObject) setState(key, value common.Hash) {
 // updateTrie writes cached storage modifications into the object's storage trie.
 func (self *stateObject) updateTrie(db Database) Trie {
 	tr := self.getTrie(db)
+	if self.db.snap != nil && self.snapStorage == nil && len(self.dirtyStorage) > 0 {
+		self.snapStorage = make(map[common.Hash][]byte)
+	}
 	for key, value := range self.dirtyStorage {
 		delete(self.dirtyStorage, key)
 		if (value == common.Hash{}) {
 			self.setError(tr.TryDelete(key[:]))
+			if self.snapStorage != nil {
+				self.snapStorage[crypto.Keccak256Hash(key[:])] = nil
+			}
 			continue
 		}
 		// Encoding []byte cannot fail, ok to ignore the error.
 		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
 		self.setError(tr.TryUpdate(key[:], v))
+		if self.snapStorage != nil {
+			self.snapStorage[crypto.Keccak256Hash(key[:])] = v
+		}
 	}
 	return tr
 }
@@ -314,9 +375,21 @@ func (self *stateObject) deepCopy(db *StateDB, onDirty func(addr common.Address)
 	stateObject.code = self.code
 	stateObject.dirtyStorage = self.dirtyStorage.Copy()
 	stateObject.cachedStorage = self.dirtyStorage.Copy()
+	if self.fakeStorage != nil {
+		stateObject.fakeStorage = self.fakeStorage.Copy()
+	}
 	stateObject.suicided = self.suicided
 	stateObject.dirtyCode = self.dirtyCode
 	stateObject.deleted = self.deleted
+	stateObject.created = self.created
+	stateObject.fromSnap = self.fromSnap
+	stateObject.snapRoot = self.snapRoot
+	if self.snapStorage != nil {
+		stateObject.snapStorage = make(map[common.Hash][]byte, len(self.snapStorage))
+		for hash, value := range self.snapStorage {
+			stateObject.snapStorage[hash] = value
+		}
+	}
 	return stateObject
 }
 
@@ -18,12 +18,14 @@
 package state
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 	"sort"
 	"sync"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/state/snapshot"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
 	"github.com/XinFinOrg/XDC-Subnet/log"
@@ -36,6 +38,18 @@ type revision struct {
 	journalIndex int
 }
 
+// proofList collects the trie nodes of a Merkle proof in order.
+type proofList [][]byte
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, value)
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
 var (
 	// emptyState is the known hash of an empty state trie entry.
 	emptyState = crypto.Keccak256Hash(nil)
@@ -53,6 +67,11 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	// Flat state snapshot serving the reads of the state the trie was opened
+	// at, nil if not maintained for that state.
+	snaps *snapshot.Tree
+	snap  snapshot.Snapshot
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -74,12 +93,19 @@ type StateDB struct {
 
 	preimages map[common.Hash][]byte
 
+	// Per-transaction access list
+	accessList *accessList
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        journal
 	validRevisions []revision
 	nextRevisionId int
 
+	// Accesses of the state recorded for the speculative execution of the
+	// transactions, nil if not tracked.
+	tracker *accessTracker
+
 	lock sync.Mutex
 }
 
@@ -101,6 +127,9 @@ func (self *StateDB) SubRefund(gas uint64) {
 }
 
 func (self *StateDB) GetCommittedState(addr common.Address, hash common.Hash) common.Hash {
+	if self.tracker != nil {
+		self.tracker.readStorage(addr, hash)
+	}
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetCommittedState(self.db, hash)
@@ -110,18 +139,31 @@ func (self *StateDB) GetCommittedState(addr common.Address, hash common.Hash) co
 
 // Create a new state from a given trie.
 func New(root common.Hash, db Database) (*StateDB, error) {
+	return NewWithSnapshot(root, db, nil)
+}
+
+// NewWithSnapshot creates a new state from a given trie, serving the account
+// and storage reads from the flat state snapshot if one is available for the
+// root. The snapshot is kept up to date with the changes on commit.
+func NewWithSnapshot(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error) {
 	tr, err := db.OpenTrie(root)
 	if err != nil {
 		return nil, err
 	}
-	return &StateDB{
+	sdb := &StateDB{
 		db:                db,
 		trie:              tr,
+		snaps:             snaps,
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
-	}, nil
+		accessList:        newAccessList(),
+	}
+	if sdb.snaps != nil {
+		sdb.snap = sdb.snaps.Snapshot(root)
+	}
+	return sdb, nil
 }
 
 // setError remembers the first non-nil error it is called with.
@@ -143,6 +185,10 @@ func (self *StateDB) Reset(root common.Hash) error {
 		return err
 	}
 	self.trie = tr
+	self.snap = nil
+	if self.snaps != nil {
+		self.snap = self.snaps.Snapshot(root)
+	}
 	self.stateObjects = make(map[common.Address]*stateObject)
 	self.stateObjectsDirty = make(map[common.Address]struct{})
 	self.thash = common.Hash{}
@@ -151,6 +197,7 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.logs = make(map[common.Hash][]*types.Log)
 	self.logSize = 0
 	self.preimages = make(map[common.Hash][]byte)
+	self.accessList = newAccessList()
 	self.clearJournalAndRefund()
 	return nil
 }
@@ -293,6 +340,9 @@ func (self *StateDB) GetAccountInfo(addr common.Address) *AccountInfo {
 }
 
 func (self *StateDB) GetState(addr common.Address, bhash common.Hash) common.Hash {
+	if self.tracker != nil {
+		self.tracker.readStorage(addr, bhash)
+	}
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
 		return stateObject.GetState(self.db, bhash)
@@ -316,6 +366,25 @@ func (self *StateDB) StorageTrie(addr common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// GetProof returns the Merkle proof of an account in the account trie.
+func (self *StateDB) GetProof(addr common.Address) ([][]byte, error) {
+	var proof proofList
+	err := self.trie.Prove(crypto.Keccak256(addr.Bytes()), 0, &proof)
+	return proof, err
+}
+
+// GetStorageProof returns the Merkle proof of a storage slot in the storage
+// trie of an account.
+func (self *StateDB) GetStorageProof(addr common.Address, key common.Hash) ([][]byte, error) {
+	var proof proofList
+	tr := self.StorageTrie(addr)
+	if tr == nil {
+		return proof, errors.New("storage trie for requested address does not exist")
+	}
+	err := tr.Prove(crypto.Keccak256(key.Bytes()), 0, &proof)
+	return proof, err
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -330,8 +399,14 @@ func (self *StateDB) HasSuicided(addr common.Address) bool {
 
 // AddBalance adds amount to the account associated with addr.
 func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
+	if self.tracker != nil && self.tracker.credit(addr, amount) {
+		return
+	}
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if self.tracker != nil && (amount.Sign() != 0 || stateObject.empty()) {
+			self.tracker.writeAccount(addr)
+		}
 		stateObject.AddBalance(amount)
 	}
 }
@@ -340,6 +415,9 @@ func (self *StateDB) AddBalance(addr common.Address, amount *big.Int) {
 func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if self.tracker != nil && amount.Sign() != 0 {
+			self.tracker.writeAccount(addr)
+		}
 		stateObject.SubBalance(amount)
 	}
 }
@@ -347,6 +425,9 @@ func (self *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 func (self *StateDB) SetBalance(addr common.Address, amount *big.Int) {
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if self.tracker != nil {
+			self.tracker.writeAccount(addr)
+		}
 		stateObject.SetBalance(amount)
 	}
 }
@@ -354,6 +435,9 @@ func (self *StateDB) SetBalance(addr common.Address, amount *big.Int) {
 func (self *StateDB) SetNonce(addr common.Address, nonce uint64) {
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if self.tracker != nil {
+			self.tracker.writeAccount(addr)
+		}
 		stateObject.SetNonce(nonce)
 	}
 }
@@ -361,6 +445,9 @@ func (self *StateDB) SetNonce(addr common.Address, nonce uint64) {
 func (self *StateDB) SetCode(addr common.Address, code []byte) {
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if self.tracker != nil {
+			self.tracker.writeAccount(addr)
+		}
 		stateObject.SetCode(crypto.Keccak256Hash(code), code)
 	}
 }
@@ -368,10 +455,25 @@ func (self *StateDB) SetCode(addr common.Address, code []byte) {
 func (self *StateDB) SetState(addr common.Address, key, value common.Hash) {
 	stateObject := self.GetOrNewStateObject(addr)
 	if stateObject != nil {
+		if self.tracker != nil {
+			self.tracker.writeStorage(addr, key)
+		}
 		stateObject.SetState(self.db, key, value)
 	}
 }
 
+// SetStorage replaces the entire storage of the account with the given one.
+// This should only be used for debugging, e.g. to override the state of a call.
+func (self *StateDB) SetStorage(addr common.Address, storage map[common.Hash]common.Hash) {
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		if self.tracker != nil {
+			self.tracker.reset(addr)
+		}
+		stateObject.SetStorage(storage)
+	}
+}
+
 // Suicide marks the given account as suicided.
 // This clears the account balance.
 //
@@ -382,6 +484,9 @@ func (self *StateDB) Suicide(addr common.Address) bool {
 	if stateObject == nil {
 		return false
 	}
+	if self.tracker != nil {
+		self.tracker.reset(addr)
+	}
 	self.journal = append(self.journal, suicideChange{
 		account:     &addr,
 		prev:        stateObject.suicided,
@@ -418,12 +523,18 @@ func (self *StateDB) deleteStateObject(stateObject *stateObject) {
 func (self *StateDB) DeleteAddress(addr common.Address) {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil && !stateObject.deleted {
+		if self.tracker != nil {
+			self.tracker.reset(addr)
+		}
 		self.deleteStateObject(stateObject)
 	}
 }
 
 // Retrieve a state object given my the address. Returns nil if not found.
 func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObject) {
+	if self.tracker != nil {
+		self.tracker.readAccount(addr)
+	}
 	// Prefer 'live' objects.
 	if obj := self.stateObjects[addr]; obj != nil {
 		if obj.deleted {
@@ -432,6 +543,31 @@ func (self *StateDB) getStateObject(addr common.Address) (stateObject *stateObje
 		return obj
 	}
 
+	// Load the object from the snapshot if it covers the account.
+	if self.snap != nil {
+		acc, err := self.snap.Account(crypto.Keccak256Hash(addr[:]))
+		if err == nil {
+			if acc == nil {
+				return nil
+			}
+			data := Account{
+				Nonce:    acc.Nonce,
+				Balance:  acc.Balance,
+				Root:     common.BytesToHash(acc.Root),
+				CodeHash: acc.CodeHash,
+			}
+			if len(acc.Root) == 0 {
+				data.Root = types.EmptyRootHash
+			}
+			if len(acc.CodeHash) == 0 {
+				data.CodeHash = emptyCodeHash
+			}
+			obj := newObject(self, addr, data, self.MarkStateObjectDirty)
+			obj.fromSnap, obj.snapRoot = true, data.Root
+			self.setStateObject(obj)
+			return obj
+		}
+	}
 	// Load the object from the database.
 	enc, err := self.trie.TryGet(addr[:])
 	if len(enc) == 0 {
@@ -474,6 +610,10 @@ func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObjec
 	prev = self.getStateObject(addr)
 	newobj = newObject(self, addr, Account{}, self.MarkStateObjectDirty)
 	newobj.setNonce(0) // sets the object to dirty
+	newobj.created = true
+	if self.tracker != nil {
+		self.tracker.reset(addr)
+	}
 	if prev == nil {
 		self.journal = append(self.journal, createObjectChange{account: &addr})
 	} else {
@@ -532,12 +672,15 @@ func (self *StateDB) Copy() *StateDB {
 	state := &StateDB{
 		db:                self.db,
 		trie:              self.db.CopyTrie(self.trie),
+		snaps:             self.snaps,
+		snap:              self.snap,
 		stateObjects:      make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty: make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
 		refund:            self.refund,
 		logs:              make(map[common.Hash][]*types.Log, len(self.logs)),
 		logSize:           self.logSize,
 		preimages:         make(map[common.Hash][]byte),
+		accessList:        self.accessList.Copy(),
 	}
 	// Copy the dirty states, logs, and preimages
 	for addr := range self.stateObjectsDirty {
@@ -602,6 +745,9 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 	}
 	// Invalidate journal because reverting across transactions is not allowed.
 	s.clearJournalAndRefund()
+	if s.tracker != nil {
+		s.tracker.version++
+	}
 }
 
 // IntermediateRoot computes the current root hash of the state trie.
@@ -618,6 +764,70 @@ func (self *StateDB) Prepare(thash, bhash common.Hash, ti int) {
 	self.thash = thash
 	self.bhash = bhash
 	self.txIndex = ti
+	self.accessList = newAccessList()
+}
+
+// PrepareAccessList handles the preparatory steps for executing a state transition
+// with regards to EIP-2929 and EIP-2930:
+//
+// - Add sender to access list (2929)
+// - Add destination to access list (2929)
+// - Add precompiles to access list (2929)
+// - Add the contents of the optional tx access list (2930)
+//
+// The access list of the previous message is dropped, so it only has to be called
+// once the fork is active.
+func (self *StateDB) PrepareAccessList(sender common.Address, dst *common.Address, precompiles []common.Address, list types.AccessList) {
+	self.accessList = newAccessList()
+	self.AddAddressToAccessList(sender)
+	if dst != nil {
+		self.AddAddressToAccessList(*dst)
+		// If it's a create-tx, the destination will be added inside evm.create
+	}
+	for _, addr := range precompiles {
+		self.AddAddressToAccessList(addr)
+	}
+	for _, el := range list {
+		self.AddAddressToAccessList(el.Address)
+		for _, key := range el.StorageKeys {
+			self.AddSlotToAccessList(el.Address, key)
+		}
+	}
+}
+
+// AddAddressToAccessList adds the given address to the access list
+func (self *StateDB) AddAddressToAccessList(addr common.Address) {
+	if self.accessList.AddAddress(addr) {
+		self.journal = append(self.journal, accessListAddAccountChange{&addr})
+	}
+}
+
+// AddSlotToAccessList adds the given (address, slot)-tuple to the access list
+func (self *StateDB) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	addrMod, slotMod := self.accessList.AddSlot(addr, slot)
+	if addrMod {
+		// In practice, this should not happen, since there is no way to enter the
+		// scope of 'address' without having the 'address' become already added
+		// to the access list (via call-variant, create, etc).
+		// Better safe than sorry, though
+		self.journal = append(self.journal, accessListAddAccountChange{&addr})
+	}
+	if slotMod {
+		self.journal = append(self.journal, accessListAddSlotChange{
+			address: &addr,
+			slot:    &slot,
+		})
+	}
+}
+
+// AddressInAccessList returns true if the given address is in the access list.
+func (self *StateDB) AddressInAccessList(addr common.Address) bool {
+	return self.accessList.ContainsAddress(addr)
+}
+
+// SlotInAccessList returns true if the given (address, slot)-tuple is in the access list.
+func (self *StateDB) SlotInAccessList(addr common.Address, slot common.Hash) (addressPresent bool, slotPresent bool) {
+	return self.accessList.Contains(addr, slot)
 }
 
 // DeleteSuicides flags the suicided objects for deletion so that it
@@ -651,6 +861,17 @@ func (s *StateDB) clearJournalAndRefund() {
 func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error) {
 	defer s.clearJournalAndRefund()
 
+	// Collect the changes to push into the snapshot, if one is maintained.
+	var (
+		destructs map[common.Hash]struct{}
+		accounts  map[common.Hash][]byte
+		storage   map[common.Hash]map[common.Hash][]byte
+	)
+	if s.snap != nil {
+		destructs = make(map[common.Hash]struct{})
+		accounts = make(map[common.Hash][]byte)
+		storage = make(map[common.Hash]map[common.Hash][]byte)
+	}
 	// Commit objects to the trie.
 	for addr, stateObject := range s.stateObjects {
 		_, isDirty := s.stateObjectsDirty[addr]
@@ -659,6 +880,9 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 			// If the object has been removed, don't bother syncing it
 			// and just mark it for deletion in the trie.
 			s.deleteStateObject(stateObject)
+			if s.snap != nil {
+				destructs[stateObject.addrHash] = struct{}{}
+			}
 		case isDirty:
 			// Write any contract code associated with the state object
 			if stateObject.code != nil && stateObject.dirtyCode {
@@ -671,6 +895,22 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 			}
 			// Update the object in the main account trie.
 			s.updateStateObject(stateObject)
+
+			if s.snap != nil {
+				if stateObject.created {
+					destructs[stateObject.addrHash] = struct{}{}
+				}
+				accounts[stateObject.addrHash] = snapshot.SlimAccountRLP(stateObject.data.Nonce, stateObject.data.Balance, stateObject.data.Root, stateObject.data.CodeHash)
+				if len(stateObject.snapStorage) > 0 {
+					storage[stateObject.addrHash] = stateObject.snapStorage
+				}
+			}
+			stateObject.created = false
+			stateObject.snapStorage = nil
+
+		case stateObject.deleted && s.snap != nil:
+			// Removed from the trie earlier on, make sure the snapshot follows
+			destructs[stateObject.addrHash] = struct{}{}
 		}
 		delete(s.stateObjectsDirty, addr)
 	}
@@ -689,6 +929,19 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		}
 		return nil
 	})
+	if err != nil {
+		return root, err
+	}
+	// Push the changes into a new snapshot layer on top of the one of the
+	// parent state. The snapshot is only used for the reads of the parent.
+	if s.snap != nil {
+		if parent := s.snap.Root(); parent != root {
+			if err := s.snaps.Update(root, parent, destructs, accounts, storage); err != nil {
+				log.Warn("[Commit] Failed to update state snapshot", "from", parent, "to", root, "err", err)
+			}
+		}
+		s.snap = nil
+	}
 	return root, err
 }
 
@@ -32,7 +32,12 @@ import (
 	check "gopkg.in/check.v1"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/state/snapshot"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/ethdb/memorydb"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -427,3 +432,405 @@ func (s *StateSuite) TestTouchDelete(c *check.C) {
 		c.Fatal("expected no dirty state object")
 	}
 }
+
+func TestStateDBAccessList(t *testing.T) {
+	// Some helpers
+	addr := func(a string) common.Address {
+		return common.HexToAddress(a)
+	}
+	slot := func(a string) common.Hash {
+		return common.HexToHash(a)
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+	state.accessList = newAccessList()
+
+	// revert undoes the journal entries down to the given length
+	revert := func(length int) {
+		for i := len(state.journal) - 1; i >= length; i-- {
+			state.journal[i].undo(state)
+		}
+		state.journal = state.journal[:length]
+	}
+	verifyAddrs := func(astrings ...string) {
+		t.Helper()
+		// convert to common.Address form
+		var addresses []common.Address
+		var addressMap = make(map[common.Address]struct{})
+		for _, astring := range astrings {
+			address := addr(astring)
+			addresses = append(addresses, address)
+			addressMap[address] = struct{}{}
+		}
+		// Check that the given addresses are in the access list
+		for _, address := range addresses {
+			if !state.AddressInAccessList(address) {
+				t.Fatalf("expected %x to be in access list", address)
+			}
+		}
+		// Check that only the expected addresses are present in the acesslist
+		for address := range state.accessList.addresses {
+			if _, exist := addressMap[address]; !exist {
+				t.Fatalf("extra address %x in access list", address)
+			}
+		}
+	}
+	verifySlots := func(addrString string, slotStrings ...string) {
+		if !state.AddressInAccessList(addr(addrString)) {
+			t.Fatalf("scope missing address/slots %v", addrString)
+		}
+		var address = addr(addrString)
+		// convert to common.Hash form
+		var slots []common.Hash
+		var slotMap = make(map[common.Hash]struct{})
+		for _, slotString := range slotStrings {
+			s := slot(slotString)
+			slots = append(slots, s)
+			slotMap[s] = struct{}{}
+		}
+		// Check that the expected items are in the access list
+		for i, s := range slots {
+			if _, slotPresent := state.SlotInAccessList(address, s); !slotPresent {
+				t.Fatalf("input %d: scope missing slot %v (address %v)", i, s, addrString)
+			}
+		}
+		// Check that no extra elements are in the access list
+		index := state.accessList.addresses[address]
+		if index >= 0 {
+			stateSlots := state.accessList.slots[index]
+			for s := range stateSlots {
+				if _, slotPresent := slotMap[s]; !slotPresent {
+					t.Fatalf("scope has extra slot %v (address %v)", s, addrString)
+				}
+			}
+		}
+	}
+
+	state.AddAddressToAccessList(addr("aa"))          // 1
+	state.AddSlotToAccessList(addr("bb"), slot("01")) // 2,3
+	state.AddSlotToAccessList(addr("bb"), slot("02")) // 4
+	verifyAddrs("aa", "bb")
+	verifySlots("bb", "01", "02")
+
+	// Make a copy
+	stateCopy1 := state.Copy()
+	if exp, got := 4, len(state.journal); exp != got {
+		t.Fatalf("journal length mismatch: have %d, want %d", got, exp)
+	}
+
+	// same again, should cause no journal entries
+	state.AddSlotToAccessList(addr("bb"), slot("01"))
+	state.AddSlotToAccessList(addr("bb"), slot("02"))
+	state.AddAddressToAccessList(addr("aa"))
+	if exp, got := 4, len(state.journal); exp != got {
+		t.Fatalf("journal length mismatch: have %d, want %d", got, exp)
+	}
+	// some new ones
+	state.AddSlotToAccessList(addr("bb"), slot("03")) // 5
+	state.AddSlotToAccessList(addr("aa"), slot("01")) // 6
+	state.AddSlotToAccessList(addr("cc"), slot("01")) // 7,8
+	state.AddAddressToAccessList(addr("cc"))
+	if exp, got := 8, len(state.journal); exp != got {
+		t.Fatalf("journal length mismatch: have %d, want %d", got, exp)
+	}
+
+	verifyAddrs("aa", "bb", "cc")
+	verifySlots("aa", "01")
+	verifySlots("bb", "01", "02", "03")
+	verifySlots("cc", "01")
+
+	// now start rolling back changes
+	revert(7)
+	if _, ok := state.SlotInAccessList(addr("cc"), slot("01")); ok {
+		t.Fatalf("slot present, expected missing")
+	}
+	verifyAddrs("aa", "bb", "cc")
+	verifySlots("aa", "01")
+	verifySlots("bb", "01", "02", "03")
+
+	revert(6)
+	if state.AddressInAccessList(addr("cc")) {
+		t.Fatalf("addr present, expected missing")
+	}
+	verifyAddrs("aa", "bb")
+	verifySlots("aa", "01")
+	verifySlots("bb", "01", "02", "03")
+
+	revert(4)
+	if _, ok := state.SlotInAccessList(addr("bb"), slot("03")); ok {
+		t.Fatalf("slot present, expected missing")
+	}
+	if _, ok := state.SlotInAccessList(addr("aa"), slot("01")); ok {
+		t.Fatalf("slot present, expected missing")
+	}
+	verifyAddrs("aa", "bb")
+	verifySlots("bb", "01", "02")
+
+	revert(1)
+	if state.AddressInAccessList(addr("bb")) {
+		t.Fatalf("addr present, expected missing")
+	}
+	verifyAddrs("aa")
+
+	revert(0)
+	if state.AddressInAccessList(addr("aa")) {
+		t.Fatalf("addr present, expected missing")
+	}
+	if got, exp := len(state.accessList.addresses), 0; got != exp {
+		t.Fatalf("expected empty, got %d", got)
+	}
+	if got, exp := len(state.accessList.slots), 0; got != exp {
+		t.Fatalf("expected empty, got %d", got)
+	}
+	// Check the copy
+	// Make a copy
+	state = stateCopy1
+	verifyAddrs("aa", "bb")
+	verifySlots("bb", "01", "02")
+	if got, exp := len(state.accessList.addresses), 2; got != exp {
+		t.Fatalf("expected empty, got %d", got)
+	}
+	if got, exp := len(state.accessList.slots), 1; got != exp {
+		t.Fatalf("expected empty, got %d", got)
+	}
+}
+
+// Tests that the state reads served from the flat snapshot match the trie ones,
+// and that committing a state pushes its changes into a new snapshot layer.
+func TestStateSnapshot(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		sdb   = NewDatabase(db)
+		state = func(root common.Hash, snaps *snapshot.Tree) *StateDB {
+			s, err := NewWithSnapshot(root, sdb, snaps)
+			if err != nil {
+				t.Fatalf("failed to open state %x: %v", root, err)
+			}
+			return s
+		}
+		key = func(i byte) common.Hash { return common.BytesToHash([]byte{i, i}) }
+	)
+	// Create a base state with a few contracts and generate its snapshot
+	base := state(common.Hash{}, nil)
+	for i := byte(1); i <= 8; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		base.SetNonce(addr, uint64(i))
+		base.AddBalance(addr, big.NewInt(int64(i)))
+		if i%2 == 0 {
+			base.SetCode(addr, []byte{i})
+			base.SetState(addr, key(i), common.BytesToHash([]byte{i}))
+			base.SetState(addr, key(i+1), common.BytesToHash([]byte{i + 1}))
+		}
+	}
+	root, _ := base.Commit(false)
+	sdb.TrieDB().Commit(root, false)
+
+	snaps := snapshot.New(db, sdb.TrieDB(), 16, root, false)
+	if snaps.Snapshot(root) == nil {
+		t.Fatalf("snapshot of the base state missing")
+	}
+	// Modify the state through the snapshot: update, destruct and recreate
+	// accounts, both with and without storage
+	modified := state(root, snaps)
+	if have := modified.GetState(common.BytesToAddress([]byte{2}), key(2)); have != common.BytesToHash([]byte{2}) {
+		t.Fatalf("snapshot storage read mismatch: have %x, want %x", have, common.BytesToHash([]byte{2}))
+	}
+	modified.SetState(common.BytesToAddress([]byte{2}), key(2), common.Hash{})
+	modified.SetState(common.BytesToAddress([]byte{2}), key(7), common.BytesToHash([]byte{7}))
+	modified.Suicide(common.BytesToAddress([]byte{4}))
+	modified.CreateAccount(common.BytesToAddress([]byte{6}))
+	modified.SetState(common.BytesToAddress([]byte{6}), key(1), common.BytesToHash([]byte{1}))
+	modified.AddBalance(common.BytesToAddress([]byte{9}), big.NewInt(9))
+	modified.Finalise(false)
+	modified.SetNonce(common.BytesToAddress([]byte{1}), 100)
+
+	next, err := modified.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if snaps.Snapshot(next) == nil {
+		t.Fatalf("snapshot of the committed state missing")
+	}
+	// Compare the reads through the snapshot with the trie ones for both states
+	for _, root := range []common.Hash{root, next} {
+		snapState, trieState := state(root, snaps), state(root, nil)
+		for i := byte(1); i <= 10; i++ {
+			addr := common.BytesToAddress([]byte{i})
+			if have, want := snapState.Exist(addr), trieState.Exist(addr); have != want {
+				t.Errorf("root %x, account %x: existence mismatch: have %v, want %v", root, addr, have, want)
+			}
+			if have, want := snapState.GetNonce(addr), trieState.GetNonce(addr); have != want {
+				t.Errorf("root %x, account %x: nonce mismatch: have %d, want %d", root, addr, have, want)
+			}
+			if have, want := snapState.GetBalance(addr), trieState.GetBalance(addr); have.Cmp(want) != 0 {
+				t.Errorf("root %x, account %x: balance mismatch: have %v, want %v", root, addr, have, want)
+			}
+			if have, want := snapState.GetCodeHash(addr), trieState.GetCodeHash(addr); have != want {
+				t.Errorf("root %x, account %x: code hash mismatch: have %x, want %x", root, addr, have, want)
+			}
+			if have, want := snapState.GetStorageRoot(addr), trieState.GetStorageRoot(addr); have != want {
+				t.Errorf("root %x, account %x: storage root mismatch: have %x, want %x", root, addr, have, want)
+			}
+			for j := byte(1); j <= 10; j++ {
+				if have, want := snapState.GetCommittedState(addr, key(j)), trieState.GetCommittedState(addr, key(j)); have != want {
+					t.Errorf("root %x, account %x, slot %x: value mismatch: have %x, want %x", root, addr, key(j), have, want)
+				}
+			}
+		}
+	}
+}
+
+func TestGetProof(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	sdb := NewDatabase(db)
+	state, _ := New(common.Hash{}, sdb)
+	addr := common.BytesToAddress([]byte{1})
+	slot := common.BytesToHash([]byte{1, 1})
+	state.AddBalance(addr, big.NewInt(42))
+	state.SetNonce(addr, 7)
+	state.SetState(addr, slot, common.BytesToHash([]byte{0xff}))
+	state.AddBalance(common.BytesToAddress([]byte{2}), big.NewInt(1))
+	root, _ := state.Commit(false)
+	state, _ = New(root, sdb)
+
+	verify := func(root common.Hash, key []byte, proof [][]byte) []byte {
+		proofDb := memorydb.New()
+		for _, node := range proof {
+			proofDb.Put(crypto.Keccak256(node), node)
+		}
+		value, err := trie.VerifyProof(root, crypto.Keccak256(key), proofDb)
+		if err != nil {
+			t.Fatalf("invalid proof for key %x: %v", key, err)
+		}
+		return value
+	}
+	proof, err := state.GetProof(addr)
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(verify(root, addr.Bytes(), proof), &account); err != nil {
+		t.Fatalf("failed to decode account: %v", err)
+	}
+	if account.Nonce != 7 || account.Balance.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("wrong account proven: nonce %d balance %v", account.Nonce, account.Balance)
+	}
+	proof, err = state.GetStorageProof(addr, slot)
+	if err != nil {
+		t.Fatalf("GetStorageProof failed: %v", err)
+	}
+	if value := verify(account.Root, slot.Bytes(), proof); !bytes.Equal(value, []byte{0x81, 0xff}) {
+		t.Errorf("wrong storage value proven: %x", value)
+	}
+	proof, err = state.GetProof(common.BytesToAddress([]byte{3}))
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	if value := verify(root, common.BytesToAddress([]byte{3}).Bytes(), proof); value != nil {
+		t.Errorf("missing account proven with value %x", value)
+	}
+	if _, err := state.GetStorageProof(common.BytesToAddress([]byte{3}), slot); err == nil {
+		t.Errorf("expected error for the storage of a missing account")
+	}
+}
+
+func TestSpeculativeMerge(t *testing.T) {
+	var (
+		a, b, c = common.Address{0xa}, common.Address{0xb}, common.Address{0xc}
+		fee     = common.Address{0xf}
+		slot    = common.Hash{0x1}
+	)
+	newState := func() *StateDB {
+		state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+		for _, addr := range []common.Address{a, b, c, fee} {
+			state.AddBalance(addr, big.NewInt(100))
+		}
+		state.Finalise(true)
+		return state
+	}
+	// The transfer and the storage write don't conflict with the main state
+	state := newState()
+	state.TrackWrites(true)
+	spec := state.SpeculativeCopy([]common.Address{fee})
+	spec.SubBalance(a, big.NewInt(10))
+	spec.AddBalance(b, big.NewInt(10))
+	spec.SetState(c, slot, common.Hash{0x2})
+	spec.AddBalance(fee, big.NewInt(1))
+	spec.Finalise(true)
+
+	// A copy reading the balance of b conflicts once the transfer is merged
+	reader := state.SpeculativeCopy([]common.Address{fee})
+	reader.SetNonce(c, reader.GetBalance(b).Uint64())
+	reader.AddBalance(fee, big.NewInt(2))
+	reader.Finalise(true)
+
+	// A copy reading the fee recipient can't be merged at all
+	feeReader := state.SpeculativeCopy([]common.Address{fee})
+	feeReader.GetBalance(fee)
+	feeReader.Finalise(true)
+
+	if state.Conflicts(spec) {
+		t.Fatal("transfer conflicts with unmodified state")
+	}
+	if !state.Conflicts(feeReader) {
+		t.Error("fee recipient read doesn't conflict")
+	}
+	state.Merge(spec, true)
+	if !state.Conflicts(reader) {
+		t.Error("stale balance read doesn't conflict")
+	}
+	// Credits of the fee recipient don't conflict with each other
+	credit := state.SpeculativeCopy([]common.Address{fee})
+	credit.SetState(a, slot, common.Hash{0x3})
+	credit.AddBalance(fee, big.NewInt(2))
+	credit.Finalise(true)
+
+	other := state.SpeculativeCopy([]common.Address{fee})
+	other.AddBalance(fee, big.NewInt(3))
+	other.Finalise(true)
+	state.Merge(other, true)
+
+	if state.Conflicts(credit) {
+		t.Fatal("fee credits conflict")
+	}
+	state.Merge(credit, true)
+
+	// The merged state must match the sequential execution
+	want := newState()
+	want.SubBalance(a, big.NewInt(10))
+	want.AddBalance(b, big.NewInt(10))
+	want.SetState(c, slot, common.Hash{0x2})
+	want.AddBalance(fee, big.NewInt(1))
+	want.Finalise(true)
+	want.AddBalance(fee, big.NewInt(3))
+	want.Finalise(true)
+	want.SetState(a, slot, common.Hash{0x3})
+	want.AddBalance(fee, big.NewInt(2))
+	want.Finalise(true)
+
+	if have, want := state.IntermediateRoot(true), want.IntermediateRoot(true); have != want {
+		t.Errorf("state root mismatch: have %x, want %x", have, want)
+	}
+	if have := state.GetBalance(fee); have.Cmp(big.NewInt(106)) != 0 {
+		t.Errorf("fee recipient balance mismatch: have %v, want 106", have)
+	}
+}
+
+// Tests that merging a speculative copy only deletes the empty accounts it
+// touched when asked to.
+func TestSpeculativeMergeEmptyAccounts(t *testing.T) {
+	for _, deleteEmpty := range []bool{false, true} {
+		state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+		state.TrackWrites(true)
+		spec := state.SpeculativeCopy(nil)
+		spec.AddBalance(common.Address{0xa}, big.NewInt(1))
+		spec.SubBalance(common.Address{0xa}, big.NewInt(1))
+		spec.Finalise(deleteEmpty)
+
+		state.Merge(spec, deleteEmpty)
+		if exist := state.Exist(common.Address{0xa}); exist == deleteEmpty {
+			t.Errorf("delete empty %v: account existence mismatch: have %v, want %v", deleteEmpty, exist, !deleteEmpty)
+		}
+	}
+}
@@ -85,6 +85,7 @@ var (
 		"candidateWithdrawDelay": 12,
 		"voterWithdrawDelay":     13,
 		"grandMasters":           14,
+		"candidateCount":         15,
 	}
 )
 
@@ -167,4 +168,186 @@ func GetGrandMasters(statedb *StateDB) []common.Address {
 		rets = append(rets, common.HexToAddress(ret.Hex()))
 	}
 	return rets
-}
\ No newline at end of file
+}
+
+func SetCandidateCap(statedb *StateDB, candidate common.Address, cap *big.Int) {
+	slot := slotValidatorMapping["validatorsState"]
+	// validatorsState[_candidate].cap;
+	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
+	locCandidateCap := locValidatorsState.Add(locValidatorsState, new(big.Int).SetUint64(uint64(1)))
+	statedb.SetState(common.HexToAddress(common.MasternodeVotingSMC), common.BigToHash(locCandidateCap), common.BigToHash(cap))
+}
+
+func SetVoterCap(statedb *StateDB, candidate, voter common.Address, cap *big.Int) {
+	slot := slotValidatorMapping["validatorsState"]
+	// validatorsState[_candidate].voters[_voter];
+	locValidatorsState := GetLocMappingAtKey(candidate.Hash(), slot)
+	locCandidateVoters := locValidatorsState.Add(locValidatorsState, new(big.Int).SetUint64(uint64(2)))
+	retByte := crypto.Keccak256(voter.Hash().Bytes(), common.BigToHash(locCandidateVoters).Bytes())
+	statedb.SetState(common.HexToAddress(common.MasternodeVotingSMC), common.BytesToHash(retByte), common.BigToHash(cap))
+}
+
+// RemoveCandidate clears the candidate from the candidates array of the validator contract and
+// unsets its isCandidate flag as resign does, so it can't be voted for anymore. The array length
+// is kept, as GetCandidates already skips empty entries.
+func RemoveCandidate(statedb *StateDB, candidate common.Address) {
+	validatorAddr := common.HexToAddress(common.MasternodeVotingSMC)
+	slot := slotValidatorMapping["candidates"]
+	slotHash := common.BigToHash(new(big.Int).SetUint64(slot))
+	arrLength := statedb.GetState(validatorAddr, slotHash)
+	for i := uint64(0); i < arrLength.Big().Uint64(); i++ {
+		key := GetLocDynamicArrAtElement(slotHash, i, 1)
+		ret := statedb.GetState(validatorAddr, key)
+		if common.HexToAddress(ret.Hex()) == candidate {
+			statedb.SetState(validatorAddr, key, common.Hash{})
+		}
+	}
+	// validatorsState[_candidate].isCandidate is packed right after the owner in the first slot
+	locValidatorsState := common.BigToHash(GetLocMappingAtKey(candidate.Hash(), slotValidatorMapping["validatorsState"]))
+	ownerSlot := statedb.GetState(validatorAddr, locValidatorsState)
+	if ownerSlot[common.HashLength-common.AddressLength-1] == 0 {
+		return
+	}
+	ownerSlot[common.HashLength-common.AddressLength-1] = 0
+	statedb.SetState(validatorAddr, locValidatorsState, ownerSlot)
+	countSlot := common.BigToHash(new(big.Int).SetUint64(slotValidatorMapping["candidateCount"]))
+	if count := statedb.GetState(validatorAddr, countSlot).Big(); count.Sign() > 0 {
+		statedb.SetState(validatorAddr, countSlot, common.BigToHash(count.Sub(count, common.Big1)))
+	}
+}
+
+// Storage layout of the slashing special address, it is not a real contract
+var (
+	slotSlashingMapping = map[string]uint64{
+		"jailedUntil":      0,
+		"appliedEvidences": 1,
+	}
+)
+
+// GetJailedUntil returns the block number until which the candidate is jailed, 0 if never jailed
+func GetJailedUntil(statedb *StateDB, candidate common.Address) uint64 {
+	slot := slotSlashingMapping["jailedUntil"]
+	loc := GetLocMappingAtKey(candidate.Hash(), slot)
+	ret := statedb.GetState(common.HexToAddress(common.SlashingSMC), common.BigToHash(loc))
+	return ret.Big().Uint64()
+}
+
+func SetJailedUntil(statedb *StateDB, candidate common.Address, number uint64) {
+	slot := slotSlashingMapping["jailedUntil"]
+	loc := GetLocMappingAtKey(candidate.Hash(), slot)
+	statedb.SetState(common.HexToAddress(common.SlashingSMC), common.BigToHash(loc), common.BigToHash(new(big.Int).SetUint64(number)))
+}
+
+// IsEvidenceApplied reports whether a slashing evidence has already been applied, so it can't be used twice
+func IsEvidenceApplied(statedb *StateDB, evidence common.Hash) bool {
+	slot := slotSlashingMapping["appliedEvidences"]
+	loc := GetLocMappingAtKey(evidence, slot)
+	ret := statedb.GetState(common.HexToAddress(common.SlashingSMC), common.BigToHash(loc))
+	return !ret.IsZero()
+}
+
+func MarkEvidenceApplied(statedb *StateDB, evidence common.Hash) {
+	slot := slotSlashingMapping["appliedEvidences"]
+	loc := GetLocMappingAtKey(evidence, slot)
+	statedb.SetState(common.HexToAddress(common.SlashingSMC), common.BigToHash(loc), common.BigToHash(common.Big1))
+}
+
+// Storage layout of the BLS registry special address, it is not a real contract
+var (
+	slotBLSRegistryMapping = map[string]uint64{
+		"publicKeys": 0,
+	}
+)
+
+// GetBLSPublicKeyLocs returns the two slots of the key registered by the candidate in the BLS registry
+func GetBLSPublicKeyLocs(candidate common.Address) (common.Hash, common.Hash) {
+	slot := slotBLSRegistryMapping["publicKeys"]
+	loc := GetLocMappingAtKey(candidate.Hash(), slot)
+	return common.BigToHash(loc), common.BigToHash(new(big.Int).Add(loc, common.Big1))
+}
+
+// DecodeBLSPublicKey returns the key kept in its two slots, nil if not registered.
+// The 48 bytes key takes two slots, the second one keeps the last 16 bytes left aligned
+func DecodeBLSPublicKey(first, second common.Hash) []byte {
+	if first.IsZero() && second.IsZero() {
+		return nil
+	}
+	return append(first.Bytes(), second[:16]...)
+}
+
+// GetBLSPublicKey returns the compressed BLS public key registered by the candidate, nil if not registered.
+func GetBLSPublicKey(statedb *StateDB, candidate common.Address) []byte {
+	firstLoc, secondLoc := GetBLSPublicKeyLocs(candidate)
+	first := statedb.GetState(common.HexToAddress(common.BLSRegistrySMC), firstLoc)
+	second := statedb.GetState(common.HexToAddress(common.BLSRegistrySMC), secondLoc)
+	return DecodeBLSPublicKey(first, second)
+}
+
+func SetBLSPublicKey(statedb *StateDB, candidate common.Address, key []byte) {
+	firstLoc, secondLoc := GetBLSPublicKeyLocs(candidate)
+	var first, second common.Hash
+	copy(first[:], key)
+	if len(key) > common.HashLength {
+		copy(second[:], key[common.HashLength:])
+	}
+	statedb.SetState(common.HexToAddress(common.BLSRegistrySMC), firstLoc, first)
+	statedb.SetState(common.HexToAddress(common.BLSRegistrySMC), secondLoc, second)
+}
+
+// GetBLSPublicKeysProof returns the Merkle proof of the BLS registry account and the nodes of the Merkle proofs
+// of the key slots of all the candidates, so light clients can check the keys against the state root
+func GetBLSPublicKeysProof(statedb *StateDB, candidates []common.Address) ([][]byte, [][]byte, error) {
+	registry := common.HexToAddress(common.BLSRegistrySMC)
+	accountProof, err := statedb.GetProof(registry)
+	if err != nil {
+		return nil, nil, err
+	}
+	storageProof := [][]byte{}
+	seen := make(map[common.Hash]struct{})
+	for _, candidate := range candidates {
+		firstLoc, secondLoc := GetBLSPublicKeyLocs(candidate)
+		for _, loc := range []common.Hash{firstLoc, secondLoc} {
+			proof, err := statedb.GetStorageProof(registry, loc)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, node := range proof {
+				hash := crypto.Keccak256Hash(node)
+				if _, ok := seen[hash]; !ok {
+					seen[hash] = struct{}{}
+					storageProof = append(storageProof, node)
+				}
+			}
+		}
+	}
+	return accountProof, storageProof, nil
+}
+
+// Storage layout of the consensus config contract, which must be deployed at genesis with these slots first
+var (
+	slotConsensusConfigMapping = map[string]uint64{
+		"minePeriod":           0,
+		"timeoutPeriod":        1,
+		"timeoutSyncThreshold": 2,
+		"certThreshold":        3,
+	}
+)
+
+// GetEpochConfig returns the consensus config voted in the config contract, nil if nothing is set
+func GetEpochConfig(statedb *StateDB) *types.EpochConfig {
+	get := func(name string) uint64 {
+		slotHash := common.BigToHash(new(big.Int).SetUint64(slotConsensusConfigMapping[name]))
+		ret := statedb.GetState(common.HexToAddress(common.ConsensusConfigSMC), slotHash)
+		return ret.Big().Uint64()
+	}
+	config := &types.EpochConfig{
+		MinePeriod:           get("minePeriod"),
+		TimeoutPeriod:        get("timeoutPeriod"),
+		TimeoutSyncThreshold: get("timeoutSyncThreshold"),
+		CertThreshold:        get("certThreshold"),
+	}
+	if config.IsEmpty() {
+		return nil
+	}
+	return config
+}
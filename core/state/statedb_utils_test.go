@@ -0,0 +1,124 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+)
+
+func TestSlashingState(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	candidate := common.HexToAddress("0x0000000000000000000000000000000000000abc")
+
+	if jailed := GetJailedUntil(state, candidate); jailed != 0 {
+		t.Fatalf("jailedUntil mismatch: have %d, want 0", jailed)
+	}
+	SetJailedUntil(state, candidate, 1800)
+	if jailed := GetJailedUntil(state, candidate); jailed != 1800 {
+		t.Fatalf("jailedUntil mismatch: have %d, want 1800", jailed)
+	}
+
+	evidence := crypto.Keccak256Hash([]byte("evidence"))
+	if IsEvidenceApplied(state, evidence) {
+		t.Fatalf("evidence should not be applied yet")
+	}
+	MarkEvidenceApplied(state, evidence)
+	if !IsEvidenceApplied(state, evidence) {
+		t.Fatalf("evidence should be applied")
+	}
+
+	SetCandidateCap(state, candidate, big.NewInt(1000))
+	if cap := GetCandidateCap(state, candidate); cap.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("candidate cap mismatch: have %v, want 1000", cap)
+	}
+}
+
+func TestRemoveCandidate(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	validator := common.HexToAddress(common.MasternodeVotingSMC)
+	slotHash := common.BigToHash(new(big.Int).SetUint64(slotValidatorMapping["candidates"]))
+	candidates := []common.Address{
+		common.HexToAddress("0x0000000000000000000000000000000000000001"),
+		common.HexToAddress("0x0000000000000000000000000000000000000002"),
+		common.HexToAddress("0x0000000000000000000000000000000000000003"),
+	}
+	state.SetState(validator, slotHash, common.BigToHash(big.NewInt(int64(len(candidates)))))
+	for i, c := range candidates {
+		state.SetState(validator, GetLocDynamicArrAtElement(slotHash, uint64(i), 1), c.Hash())
+	}
+
+	// validatorsState[_candidate] packs the owner with the isCandidate flag
+	owner := common.HexToAddress("0x0000000000000000000000000000000000000abc")
+	locState := common.BigToHash(GetLocMappingAtKey(candidates[1].Hash(), slotValidatorMapping["validatorsState"]))
+	ownerSlot := owner.Hash()
+	ownerSlot[common.HashLength-common.AddressLength-1] = 1
+	state.SetState(validator, locState, ownerSlot)
+	countSlot := common.BigToHash(new(big.Int).SetUint64(slotValidatorMapping["candidateCount"]))
+	state.SetState(validator, countSlot, common.BigToHash(big.NewInt(int64(len(candidates)))))
+
+	RemoveCandidate(state, candidates[1])
+	got := GetCandidates(state)
+	if len(got) != 2 || got[0] != candidates[0] || got[1] != candidates[2] {
+		t.Fatalf("candidates mismatch after removal: have %v", got)
+	}
+	if flag := state.GetState(validator, locState)[common.HashLength-common.AddressLength-1]; flag != 0 {
+		t.Errorf("isCandidate not cleared after removal")
+	}
+	if have := GetCandidateOwner(state, candidates[1]); have != owner {
+		t.Errorf("owner mismatch after removal: have %x, want %x", have, owner)
+	}
+	if count := state.GetState(validator, countSlot).Big(); count.Uint64() != 2 {
+		t.Errorf("candidate count mismatch after removal: have %v, want 2", count)
+	}
+	// Removing it again leaves the count untouched
+	RemoveCandidate(state, candidates[1])
+	if count := state.GetState(validator, countSlot).Big(); count.Uint64() != 2 {
+		t.Errorf("candidate count mismatch after second removal: have %v, want 2", count)
+	}
+}
+
+func TestSetVoterCap(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	candidate := common.HexToAddress("0x0000000000000000000000000000000000000abc")
+	voter := common.HexToAddress("0x0000000000000000000000000000000000000def")
+
+	SetVoterCap(state, candidate, voter, big.NewInt(500))
+	if cap := GetVoterCap(state, candidate, voter); cap.Cmp(big.NewInt(500)) != 0 {
+		t.Fatalf("voter cap mismatch: have %v, want 500", cap)
+	}
+}
+
+func TestBLSPublicKeyState(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	candidate := common.HexToAddress("0x0000000000000000000000000000000000000abc")
+
+	if key := GetBLSPublicKey(state, candidate); key != nil {
+		t.Fatalf("BLS public key should not be registered, have %x", key)
+	}
+	key := make([]byte, 48)
+	for i := range key {
+		key[i] = byte(i + 1)
+	}
+	SetBLSPublicKey(state, candidate, key)
+	if got := GetBLSPublicKey(state, candidate); !bytes.Equal(got, key) {
+		t.Fatalf("BLS public key mismatch: have %x, want %x", got, key)
+	}
+}
+
+func TestGetEpochConfig(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()))
+	if config := GetEpochConfig(state); config != nil {
+		t.Fatalf("epoch config should not be set, have %v", config)
+	}
+	contract := common.HexToAddress(common.ConsensusConfigSMC)
+	state.SetState(contract, common.BigToHash(new(big.Int).SetUint64(slotConsensusConfigMapping["minePeriod"])), common.BigToHash(big.NewInt(3)))
+	state.SetState(contract, common.BigToHash(new(big.Int).SetUint64(slotConsensusConfigMapping["certThreshold"])), common.BigToHash(big.NewInt(750)))
+	config := GetEpochConfig(state)
+	if config == nil || config.MinePeriod != 3 || config.CertThreshold != 750 || config.TimeoutPeriod != 0 || config.TimeoutSyncThreshold != 0 {
+		t.Fatalf("epoch config mismatch: have %v", config)
+	}
+}
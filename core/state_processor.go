@@ -17,6 +17,7 @@
 package core
 
 import (
+	"errors"
 	"fmt"
 
 	"math/big"
@@ -29,11 +30,13 @@ import (
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/misc"
 	"github.com/XinFinOrg/XDC-Subnet/core/state"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/core/vm"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
 	"github.com/XinFinOrg/XDC-Subnet/params"
 )
 
@@ -86,6 +89,10 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, tra
 	InitSignerInTransactions(p.config, header, block.Transactions())
 	balanceUpdated := map[common.Address]*big.Int{}
 	totalFeeUsed := big.NewInt(0)
+	speculator := NewTxSpeculator(p.config, p.bc, nil, statedb, tradingState, header, cfg, cfg.ParallelTxs)
+	if speculator != nil {
+		defer speculator.Close()
+	}
 	for i, tx := range block.Transactions() {
 		// check black-list txs after hf
 		if (block.Number().Uint64() >= common.BlackListHFNumber) && !common.IsTestnet {
@@ -113,7 +120,18 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, tra
 			}
 		}
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
-		receipt, gas, err, tokenFeeUsed := ApplyTransaction(p.config, balanceFee, p.bc, nil, gp, statedb, tradingState, header, tx, usedGas, cfg)
+		var (
+			receipt      *types.Receipt
+			gas          uint64
+			err          error
+			tokenFeeUsed bool
+		)
+		if speculator != nil {
+			speculator.SpeculateBatch(block.Transactions(), i, balanceFee)
+			receipt, gas, err, tokenFeeUsed = speculator.Apply(balanceFee, gp, tx, usedGas)
+		} else {
+			receipt, gas, err, tokenFeeUsed = ApplyTransaction(p.config, balanceFee, p.bc, nil, gp, statedb, tradingState, header, tx, usedGas, cfg)
+		}
 		if err != nil {
 			return nil, nil, 0, err
 		}
@@ -155,6 +173,10 @@ func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, stated
 	InitSignerInTransactions(p.config, header, block.Transactions())
 	balanceUpdated := map[common.Address]*big.Int{}
 	totalFeeUsed := big.NewInt(0)
+	speculator := NewTxSpeculator(p.config, p.bc, nil, statedb, tradingState, header, cfg, cfg.ParallelTxs)
+	if speculator != nil {
+		defer speculator.Close()
+	}
 
 	if cBlock.stop {
 		return nil, nil, 0, ErrStopPreparingBlock
@@ -188,7 +210,18 @@ func (p *StateProcessor) ProcessBlockNoValidator(cBlock *CalculatedBlock, stated
 			}
 		}
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
-		receipt, gas, err, tokenFeeUsed := ApplyTransaction(p.config, balanceFee, p.bc, nil, gp, statedb, tradingState, header, tx, usedGas, cfg)
+		var (
+			receipt      *types.Receipt
+			gas          uint64
+			err          error
+			tokenFeeUsed bool
+		)
+		if speculator != nil {
+			speculator.SpeculateBatch(block.Transactions(), i, balanceFee)
+			receipt, gas, err, tokenFeeUsed = speculator.Apply(balanceFee, gp, tx, usedGas)
+		} else {
+			receipt, gas, err, tokenFeeUsed = ApplyTransaction(p.config, balanceFee, p.bc, nil, gp, statedb, tradingState, header, tx, usedGas, cfg)
+		}
 		if err != nil {
 			return nil, nil, 0, err
 		}
@@ -218,18 +251,24 @@ func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*
 	if tx.To() != nil && tx.To().String() == common.BlockSigners && config.IsTIPSigning(header.Number) {
 		return ApplySignTransaction(config, statedb, header, tx, usedGas)
 	}
+	if tx.IsSlashingTransaction() && config.XDPoS != nil && config.XDPoS.V2.IsSlashing(header.Number) {
+		return ApplySlashingTransaction(config, bc, statedb, header, tx, usedGas)
+	}
+	if tx.IsBLSRegistrationTransaction() && config.XDPoS != nil && config.XDPoS.V2 != nil && config.XDPoS.V2.BLSBlock != nil {
+		return ApplyBLSRegistrationTransaction(config, statedb, header, tx, usedGas)
+	}
 	if tx.To() != nil && tx.To().String() == common.TradingStateAddr && config.IsTIPXDCX(header.Number) {
-		return ApplyEmptyTransaction(config, statedb, header, tx, usedGas)
+		return ApplyEmptyTransaction(config, statedb, XDCxState, header, tx, usedGas)
 	}
 	if tx.To() != nil && tx.To().String() == common.XDCXLendingAddress && config.IsTIPXDCX(header.Number) {
-		return ApplyEmptyTransaction(config, statedb, header, tx, usedGas)
+		return ApplyEmptyTransaction(config, statedb, XDCxState, header, tx, usedGas)
 	}
 	if tx.IsTradingTransaction() && config.IsTIPXDCX(header.Number) {
-		return ApplyEmptyTransaction(config, statedb, header, tx, usedGas)
+		return ApplyEmptyTransaction(config, statedb, XDCxState, header, tx, usedGas)
 	}
 
 	if tx.IsLendingFinalizedTradeTransaction() && config.IsTIPXDCX(header.Number) {
-		return ApplyEmptyTransaction(config, statedb, header, tx, usedGas)
+		return ApplyEmptyTransaction(config, statedb, XDCxState, header, tx, usedGas)
 	}
 
 	var balanceFee *big.Int
@@ -238,7 +277,7 @@ func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*
 			balanceFee = value
 		}
 	}
-	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), balanceFee, header.Number)
+	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number), balanceFee, header.Number, header.BaseFee)
 	if err != nil {
 		return nil, 0, err, false
 	}
@@ -420,6 +459,7 @@ func ApplyTransaction(config *params.ChainConfig, tokensFee map[common.Address]*
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing wether the root touch-delete accounts.
 	receipt := types.NewReceipt(root, failed, *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = gas
 	// if the transaction created a contract, store the creation address in the receipt.
@@ -457,6 +497,7 @@ func ApplySignTransaction(config *params.ChainConfig, statedb *state.StateDB, he
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing wether the root touch-delete accounts.
 	receipt := types.NewReceipt(root, false, *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = 0
 	// if the transaction created a contract, store the creation address in the receipt.
@@ -470,7 +511,151 @@ func ApplySignTransaction(config *params.ChainConfig, statedb *state.StateDB, he
 	return receipt, 0, nil, false
 }
 
-func ApplyEmptyTransaction(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64) (*types.Receipt, uint64, error, bool) {
+// ApplySlashingTransaction verifies the vote equivocation evidence carried in the tx data and applies the
+// configured penalty to the validator contract state. Slashing txs don't pay gas, so an invalid evidence
+// invalidates the tx, the miner drops it and a block carrying it is rejected.
+func ApplySlashingTransaction(config *params.ChainConfig, bc *BlockChain, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64) (*types.Receipt, uint64, error, bool) {
+	signer, evidence, err := VerifySlashingEvidence(config, bc, statedb, tx.Data())
+	if err != nil {
+		return nil, 0, err, false
+	}
+	return applyConsensusTransaction(config, statedb, header, tx, usedGas, common.HexToAddress(common.SlashingSMC), func(from common.Address) error {
+		return applySlashingEvidence(config, statedb, header, signer, evidence)
+	})
+}
+
+// ApplyBLSRegistrationTransaction registers the BLS public key of the sender, the tx data is the compressed
+// public key followed by its proof of possession. An invalid registration fails the receipt only.
+func ApplyBLSRegistrationTransaction(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64) (*types.Receipt, uint64, error, bool) {
+	return applyConsensusTransaction(config, statedb, header, tx, usedGas, common.HexToAddress(common.BLSRegistrySMC), func(from common.Address) error {
+		data := tx.Data()
+		if len(data) != bls.PublicKeyLength+bls.SignatureLength {
+			return fmt.Errorf("invalid BLS registration data length %v", len(data))
+		}
+		publicKey, err := bls.PublicKeyFromBytes(data[:bls.PublicKeyLength])
+		if err != nil {
+			return err
+		}
+		proof, err := bls.SignatureFromBytes(data[bls.PublicKeyLength:])
+		if err != nil {
+			return err
+		}
+		if !publicKey.VerifyPossession(proof) {
+			return errors.New("invalid BLS proof of possession")
+		}
+		state.SetBLSPublicKey(statedb, from, publicKey.Bytes())
+		return nil
+	})
+}
+
+// applyConsensusTransaction applies a zero gas special tx handled by the node itself, it checks and increases the
+// sender nonce, then applies the tx with apply. The receipt is marked as failed when apply returns an error.
+func applyConsensusTransaction(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, contract common.Address, apply func(from common.Address) error) (*types.Receipt, uint64, error, bool) {
+	from, err := types.Sender(types.MakeSigner(config, header.Number), tx)
+	if err != nil {
+		return nil, 0, err, false
+	}
+	nonce := statedb.GetNonce(from)
+	if nonce < tx.Nonce() {
+		return nil, 0, ErrNonceTooHigh, false
+	} else if nonce > tx.Nonce() {
+		return nil, 0, ErrNonceTooLow, false
+	}
+	statedb.SetNonce(from, nonce+1)
+
+	failed := false
+	if err := apply(from); err != nil {
+		log.Warn("[applyConsensusTransaction] Reject special transaction", "tx", tx.Hash().Hex(), "to", contract.Hex(), "from", from.Hex(), "err", err)
+		failed = true
+	}
+	// Update the state with pending changes
+	var root []byte
+	if config.IsByzantium(header.Number) {
+		statedb.Finalise(true)
+	} else {
+		root = statedb.IntermediateRoot(config.IsEIP158(header.Number)).Bytes()
+	}
+	receipt := types.NewReceipt(root, failed, *usedGas)
+	receipt.Type = tx.Type()
+	receipt.TxHash = tx.Hash()
+	receipt.GasUsed = 0
+	// Set the receipt logs and create a bloom for filtering
+	log := &types.Log{}
+	log.Address = contract
+	log.BlockNumber = header.Number.Uint64()
+	statedb.AddLog(log)
+	receipt.Logs = statedb.GetLogs(tx.Hash())
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt, 0, nil, false
+}
+
+// VerifySlashingEvidence decodes and verifies the vote equivocation evidence of a slashing tx against the
+// given state, and returns the masternode to be slashed.
+func VerifySlashingEvidence(config *params.ChainConfig, bc *BlockChain, statedb *state.StateDB, data []byte) (common.Address, *types.SlashingEvidence, error) {
+	evidence, err := types.DecodeSlashingEvidence(data)
+	if err != nil {
+		return common.Address{}, nil, fmt.Errorf("%w: %v", types.ErrInvalidSlashingEvidence, err)
+	}
+	if bc == nil {
+		return common.Address{}, nil, errors.New("no chain to verify slashing evidence")
+	}
+	engine, ok := bc.Engine().(*XDPoS.XDPoS)
+	if !ok {
+		return common.Address{}, nil, errors.New("slashing is only supported by XDPoS engine")
+	}
+	signer, err := engine.VerifyVoteEquivocation(bc, evidence)
+	if err != nil {
+		return common.Address{}, nil, fmt.Errorf("%w: %v", types.ErrInvalidSlashingEvidence, err)
+	}
+	// A masternode can only be slashed once per round, whichever pair of votes is submitted
+	if state.IsEvidenceApplied(statedb, slashingEvidenceId(signer, evidence)) {
+		return common.Address{}, nil, fmt.Errorf("%w: evidence of masternode %v at round %v already applied", types.ErrInvalidSlashingEvidence, signer.Hex(), evidence.FirstVote.ProposedBlockInfo.Round)
+	}
+	return signer, evidence, nil
+}
+
+func slashingEvidenceId(signer common.Address, evidence *types.SlashingEvidence) common.Hash {
+	return crypto.Keccak256Hash(signer.Bytes(), new(big.Int).SetUint64(uint64(evidence.FirstVote.ProposedBlockInfo.Round)).Bytes())
+}
+
+func applySlashingEvidence(config *params.ChainConfig, statedb *state.StateDB, header *types.Header, signer common.Address, evidence *types.SlashingEvidence) error {
+	slashing := config.XDPoS.V2.Slashing
+	switch slashing.Policy {
+	case params.SlashingPolicyBurn:
+		// Only the owner's own stake is burnt, the voters keep withdrawing what they staked
+		owner := state.GetCandidateOwner(statedb, signer)
+		ownerCap := state.GetVoterCap(statedb, signer, owner)
+		burnt := new(big.Int).Div(new(big.Int).Mul(ownerCap, new(big.Int).SetUint64(slashing.BurnPercent)), big.NewInt(100))
+		if burnt.Cmp(ownerCap) > 0 {
+			burnt = ownerCap
+		}
+		candidateCap := state.GetCandidateCap(statedb, signer)
+		if burnt.Cmp(candidateCap) > 0 {
+			return fmt.Errorf("candidate cap %v is less than burnt stake %v", candidateCap, burnt)
+		}
+		validatorAddr := common.HexToAddress(common.MasternodeVotingSMC)
+		if statedb.GetBalance(validatorAddr).Cmp(burnt) < 0 {
+			return fmt.Errorf("validator contract balance is less than burnt stake %v", burnt)
+		}
+		state.SetVoterCap(statedb, signer, owner, new(big.Int).Sub(ownerCap, burnt))
+		state.SetCandidateCap(statedb, signer, new(big.Int).Sub(candidateCap, burnt))
+		statedb.SubBalance(validatorAddr, burnt)
+	case params.SlashingPolicyJail:
+		jailedUntil := header.Number.Uint64() + slashing.JailEpochs*config.XDPoS.Epoch
+		if state.GetJailedUntil(statedb, signer) < jailedUntil {
+			state.SetJailedUntil(statedb, signer, jailedUntil)
+		}
+	case params.SlashingPolicyRemove:
+		state.RemoveCandidate(statedb, signer)
+	default:
+		return fmt.Errorf("unknown slashing policy %v", slashing.Policy)
+	}
+	state.MarkEvidenceApplied(statedb, slashingEvidenceId(signer, evidence))
+	log.Info("[applySlashingEvidence] Masternode slashed", "signer", signer.Hex(), "round", evidence.FirstVote.ProposedBlockInfo.Round, "policy", slashing.Policy)
+	return nil
+}
+
+func ApplyEmptyTransaction(config *params.ChainConfig, statedb *state.StateDB, XDCxState *tradingstate.TradingStateDB, header *types.Header, tx *types.Transaction, usedGas *uint64) (*types.Receipt, uint64, error, bool) {
 	// Update the state with pending changes
 	var root []byte
 	if config.IsByzantium(header.Number) {
@@ -481,6 +666,7 @@ func ApplyEmptyTransaction(config *params.ChainConfig, statedb *state.StateDB, h
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing wether the root touch-delete accounts.
 	receipt := types.NewReceipt(root, false, *usedGas)
+	receipt.Type = tx.Type()
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = 0
 	// if the transaction created a contract, store the creation address in the receipt.
@@ -489,6 +675,14 @@ func ApplyEmptyTransaction(config *params.ChainConfig, statedb *state.StateDB, h
 	log.Address = *tx.To()
 	log.BlockNumber = header.Number.Uint64()
 	statedb.AddLog(log)
+	// Append the logs of the XDCx and lending matches carried by the transaction
+	if XDCxState != nil && config.IsXDCxLogs(header.Number) {
+		for _, l := range XDCxState.GetLogs(tx.Hash()) {
+			cpy := *l
+			cpy.BlockNumber = header.Number.Uint64()
+			statedb.AddLog(&cpy)
+		}
+	}
 	receipt.Logs = statedb.GetLogs(tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 	return receipt, 0, nil, false
@@ -18,10 +18,13 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	cmath "github.com/XinFinOrg/XDC-Subnet/common/math"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/core/vm"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 	"github.com/XinFinOrg/XDC-Subnet/params"
@@ -69,17 +72,20 @@ type Message interface {
 	To() *common.Address
 
 	GasPrice() *big.Int
+	GasFeeCap() *big.Int
+	GasTipCap() *big.Int
 	Gas() uint64
 	Value() *big.Int
 
 	Nonce() uint64
 	CheckNonce() bool
 	Data() []byte
+	AccessList() types.AccessList
 	BalanceTokenFee() *big.Int
 }
 
 // IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error) {
+func IntrinsicGas(data []byte, accessList types.AccessList, contractCreation, homestead bool) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if contractCreation && homestead {
@@ -108,6 +114,10 @@ func IntrinsicGas(data []byte, contractCreation, homestead bool) (uint64, error)
 		}
 		gas += z * params.TxDataZeroGas
 	}
+	if accessList != nil {
+		gas += uint64(len(accessList)) * params.TxAccessListAddressGas
+		gas += uint64(accessList.StorageKeys()) * params.TxAccessListStorageKeyGas
+	}
 	return gas, nil
 }
 
@@ -180,7 +190,12 @@ func (st *StateTransition) buyGas() error {
 	)
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
 	if balanceTokenFee == nil {
-		if state.GetBalance(from.Address()).Cmp(mgval) < 0 {
+		// The sender must afford the fee cap of a dynamic fee transaction, while only the effective price is charged
+		balanceCheck := mgval
+		if st.msg.GasFeeCap() != nil && st.evm.ChainConfig().IsEIP1559(st.evm.BlockNumber) {
+			balanceCheck = new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.msg.GasFeeCap())
+		}
+		if state.GetBalance(from.Address()).Cmp(balanceCheck) < 0 {
 			return errInsufficientBalanceForGas
 		}
 	} else if balanceTokenFee.Cmp(mgval) < 0 {
@@ -211,6 +226,31 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	// Make sure that transaction gasFeeCap is greater than the baseFee (post EIP1559),
+	// the fee of the transactions paid by a TRC21 token is not affected by the base fee
+	if st.evm.ChainConfig().IsEIP1559(st.evm.BlockNumber) && st.balanceTokenFee() == nil {
+		// Skip the checks if gas fields are zero and baseFee was explicitly disabled (eth_call)
+		if !st.evm.Config().NoBaseFee || msg.GasFeeCap().BitLen() > 0 || msg.GasTipCap().BitLen() > 0 {
+			if l := msg.GasFeeCap().BitLen(); l > 256 {
+				return fmt.Errorf("%w: address %v, maxFeePerGas bit length: %d", ErrFeeCapVeryHigh,
+					msg.From().Hex(), l)
+			}
+			if l := msg.GasTipCap().BitLen(); l > 256 {
+				return fmt.Errorf("%w: address %v, maxPriorityFeePerGas bit length: %d", ErrTipVeryHigh,
+					msg.From().Hex(), l)
+			}
+			if msg.GasFeeCap().Cmp(msg.GasTipCap()) < 0 {
+				return fmt.Errorf("%w: address %v, maxPriorityFeePerGas: %s, maxFeePerGas: %s", ErrTipAboveFeeCap,
+					msg.From().Hex(), msg.GasTipCap(), msg.GasFeeCap())
+			}
+			// This will panic if baseFee is nil, but basefee presence is verified
+			// as part of header validation.
+			if msg.GasFeeCap().Cmp(st.evm.BaseFee) < 0 {
+				return fmt.Errorf("%w: address %v, maxFeePerGas: %s baseFee: %s", ErrFeeCapTooLow,
+					msg.From().Hex(), msg.GasFeeCap(), st.evm.BaseFee)
+			}
+		}
+	}
 	return st.buyGas()
 }
 
@@ -228,7 +268,7 @@ func (st *StateTransition) TransitionDb(owner common.Address) (ret []byte, usedG
 	contractCreation := msg.To() == nil
 
 	// Pay intrinsic gas
-	gas, err := IntrinsicGas(st.data, contractCreation, homestead)
+	gas, err := IntrinsicGas(st.data, msg.AccessList(), contractCreation, homestead)
 	if err != nil {
 		return nil, 0, false, err, nil
 	}
@@ -236,6 +276,11 @@ func (st *StateTransition) TransitionDb(owner common.Address) (ret []byte, usedG
 		return nil, 0, false, err, nil
 	}
 
+	// Warm up the sender, the destination, the precompiles and the access list of the message
+	if rules := st.evm.ChainConfig().Rules(st.evm.BlockNumber); rules.IsEIP2930 {
+		st.state.PrepareAccessList(msg.From(), msg.To(), vm.ActivePrecompiles(rules), msg.AccessList())
+	}
+
 	var (
 		evm = st.evm
 		// vm errors do not effect consensus and are therefor
@@ -269,8 +314,17 @@ func (st *StateTransition) TransitionDb(owner common.Address) (ret []byte, usedG
 	st.refundGas()
 
 	if st.evm.BlockNumber.Cmp(common.TIPTRC21Fee) > 0 {
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice)
+		// After EIP1559 the base fee goes to the destination chosen by the subnet, the owner keeps the tip
+		if st.evm.BaseFee != nil && st.balanceTokenFee() == nil {
+			baseFee := new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), cmath.BigMin(st.evm.BaseFee, st.gasPrice))
+			fee.Sub(fee, baseFee)
+			if recipient, ok := st.evm.ChainConfig().XDPoS.BaseFeeRecipient(); ok {
+				st.state.AddBalance(recipient, baseFee)
+			}
+		}
 		if (owner != common.Address{}) {
-			st.state.AddBalance(owner, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
+			st.state.AddBalance(owner, fee)
 		}
 	} else {
 		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(new(big.Int).SetUint64(st.gasUsed()), st.gasPrice))
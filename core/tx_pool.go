@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/misc"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/core/state"
@@ -88,6 +89,10 @@ var (
 	ErrDuplicateSpecialTransaction = errors.New("duplicate a special transaction")
 
 	ErrMinDeploySMC = errors.New("smart contract creation cost is under allowance")
+
+	// ErrTxTypeNotSupported is returned if a transaction is not supported in the
+	// current network configuration.
+	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
 )
 
 var (
@@ -232,7 +237,11 @@ type TxPool struct {
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead        bool
+	eip2930          bool // Fork indicator whether typed transactions with access lists are accepted
+	eip1559          bool // Fork indicator whether dynamic fee transactions are accepted
+	slashing         bool // Fork indicator whether slashing txs are applied
 	IsSigner         func(address common.Address) bool
+	VerifySlashing   func(statedb *state.StateDB, data []byte) error // Verifies the evidence of the gasless slashing txs, nil if slashing is disabled
 	trc21FeeCapacity map[common.Address]*big.Int
 }
 
@@ -247,7 +256,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		config:           config,
 		chainconfig:      chainconfig,
 		chain:            chain,
-		signer:           types.NewEIP155Signer(chainconfig.ChainId),
+		signer:           types.LatestSigner(chainconfig),
 		pending:          make(map[common.Address]*txList),
 		queue:            make(map[common.Address]*txList),
 		beats:            make(map[common.Address]time.Time),
@@ -435,6 +444,14 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.trc21FeeCapacity = state.GetTRC21FeeCapacityFromStateWithCache(newHead.Root, statedb)
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	pool.eip2930 = pool.chainconfig.IsEIP2930(next)
+	pool.eip1559 = pool.chainconfig.IsEIP1559(next)
+	pool.slashing = pool.chainconfig.XDPoS != nil && pool.chainconfig.XDPoS.V2.IsSlashing(next)
+	// Sort the transactions by the tip they pay on top of the base fee of the next block
+	if pool.eip1559 {
+		pool.priced.SetBaseFee(misc.CalcBaseFee(pool.chainconfig, newHead))
+	}
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -587,6 +604,14 @@ func (pool *TxPool) GetSender(tx *types.Transaction) (common.Address, error) {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
+	// Accept only legacy transactions until EIP-2930 activates
+	if !pool.eip2930 && tx.Type() != types.LegacyTxType {
+		return ErrTxTypeNotSupported
+	}
+	// Reject dynamic fee transactions until EIP-1559 activates
+	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
+		return ErrTxTypeNotSupported
+	}
 	// check if sender is in black list
 	if tx.From() != nil && common.Blacklist[*tx.From()] {
 		return fmt.Errorf("Reject transaction with sender in black-list: %v", tx.From().Hex())
@@ -610,14 +635,25 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentMaxGas < tx.Gas() {
 		return ErrGasLimit
 	}
+	// Sanity check for extremely large numbers
+	if tx.GasFeeCap().BitLen() > 256 {
+		return ErrFeeCapVeryHigh
+	}
+	if tx.GasTipCap().BitLen() > 256 {
+		return ErrTipVeryHigh
+	}
+	// Ensure gasFeeCap is greater than or equal to gasTipCap.
+	if tx.GasFeeCapIntCmp(tx.GasTipCap()) < 0 {
+		return ErrTipAboveFeeCap
+	}
 	// Make sure the transaction is signed properly
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
 		return ErrInvalidSender
 	}
-	// Drop non-local transactions under our own minimal accepted gas price
+	// Drop non-local transactions under our own minimal accepted gas price or tip
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
-	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
+	if !local && tx.GasTipCapIntCmp(pool.gasPrice) < 0 {
 		if !tx.IsSpecialTransaction() || (pool.IsSigner != nil && !pool.IsSigner(from)) {
 			return ErrUnderpriced
 		}
@@ -654,7 +690,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 
 	if tx.To() == nil || (tx.To() != nil && !tx.IsSpecialTransaction()) {
-		intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
+		intrGas, err := IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, pool.homestead)
 		if err != nil {
 			return err
 		}
@@ -683,6 +719,13 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		}
 	*/
 
+	// slashing txs don't pay gas, only accept the ones carrying a valid evidence
+	if tx.IsSlashingTransaction() && pool.slashing && pool.VerifySlashing != nil {
+		if err := pool.VerifySlashing(pool.currentState, tx.Data()); err != nil {
+			return err
+		}
+	}
+
 	// validate minFee slot for XDCZ
 	if tx.IsXDCZApplyTransaction() {
 		copyState := pool.currentState.Copy()
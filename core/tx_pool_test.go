@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"fmt"
 	"math/big"
@@ -278,6 +279,41 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that the gasless slashing transactions are only accepted with a valid evidence.
+func TestSlashingTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(0xffffffffffffff))
+	pool.slashing = true
+
+	evidence := []byte("evidence")
+	pool.VerifySlashing = func(statedb *state.StateDB, data []byte) error {
+		if !bytes.Equal(data, evidence) {
+			return types.ErrInvalidSlashingEvidence
+		}
+		return nil
+	}
+	slashing := func(nonce uint64, data []byte) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.HexToAddress(common.SlashingSMC), big.NewInt(0), 100000, common.GetMinGasPrice(big.NewInt(1)), data), types.HomesteadSigner{}, key)
+		return tx
+	}
+	if err := pool.AddRemote(slashing(0, []byte("forged"))); err != types.ErrInvalidSlashingEvidence {
+		t.Error("expected", types.ErrInvalidSlashingEvidence, "got", err)
+	}
+	if err := pool.AddRemote(slashing(0, evidence)); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+	// before the slashing block, the evidence isn't verified as the tx is a plain transfer
+	pool.slashing = false
+	if err := pool.AddRemote(slashing(1, []byte("forged"))); err != nil {
+		t.Error("expected", nil, "got", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
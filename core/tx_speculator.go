@@ -0,0 +1,204 @@
+package core
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/XinFinOrg/XDC-Subnet/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+// speculationBatch is the number of transactions speculated per worker at once
+// when processing a block.
+const speculationBatch = 4
+
+// speculation is the result of a transaction executed on a speculative copy of
+// the state.
+type speculation struct {
+	statedb *state.StateDB
+	receipt *types.Receipt
+	gas     uint64
+	err     error
+}
+
+// TxSpeculator executes transactions optimistically in parallel, each on a
+// speculative copy of the state, and merges their results into the state in
+// order as long as the state they read wasn't modified by the transactions
+// applied in the meantime. The conflicting transactions are executed again on
+// the state, so the results always match the sequential execution.
+type TxSpeculator struct {
+	config       *params.ChainConfig
+	bc           *BlockChain
+	author       *common.Address
+	statedb      *state.StateDB
+	tradingState *tradingstate.TradingStateDB
+	header       *types.Header
+	cfg          vm.Config
+	workers      int
+
+	feeRecipients []common.Address
+	results       map[common.Hash]*speculation
+}
+
+// NewTxSpeculator creates a speculator applying the transactions of the given
+// header to statedb with the given number of workers. It returns nil if the
+// transactions can't be executed in parallel, in which case they should be
+// applied with ApplyTransaction. The speculator must be closed once done.
+func NewTxSpeculator(config *params.ChainConfig, bc *BlockChain, author *common.Address, statedb *state.StateDB, tradingState *tradingstate.TradingStateDB, header *types.Header, cfg vm.Config, workers int) *TxSpeculator {
+	// The receipts of the pre-Byzantium blocks hold the intermediate roots
+	if workers < 2 || cfg.Debug || !config.IsByzantium(header.Number) {
+		return nil
+	}
+	var beneficiary common.Address
+	if author == nil {
+		beneficiary, _ = bc.Engine().Author(header)
+	} else {
+		beneficiary = *author
+	}
+	// The fee recipients credited by every transaction, see TransitionDb
+	var feeRecipients []common.Address
+	if header.Number.Cmp(common.TIPTRC21Fee) > 0 {
+		if owner := statedb.GetOwner(beneficiary); owner != (common.Address{}) {
+			feeRecipients = append(feeRecipients, owner)
+		}
+		if recipient, ok := config.XDPoS.BaseFeeRecipient(); ok && header.BaseFee != nil {
+			feeRecipients = append(feeRecipients, recipient)
+		}
+	} else {
+		feeRecipients = append(feeRecipients, beneficiary)
+	}
+	statedb.TrackWrites(true)
+	return &TxSpeculator{
+		config:        config,
+		bc:            bc,
+		author:        author,
+		statedb:       statedb,
+		tradingState:  tradingState,
+		header:        header,
+		cfg:           cfg,
+		workers:       workers,
+		feeRecipients: feeRecipients,
+		results:       make(map[common.Hash]*speculation),
+	}
+}
+
+// Close stops tracking the writes of the state.
+func (s *TxSpeculator) Close() {
+	s.statedb.TrackWrites(false)
+}
+
+// Speculated reports whether a speculative result is pending for the transaction.
+func (s *TxSpeculator) Speculated(tx *types.Transaction) bool {
+	_, ok := s.results[tx.Hash()]
+	return ok
+}
+
+// speculative reports whether the transaction can be executed on a copy of the
+// state. The special transactions, which don't go through the EVM, and the
+// ones paying their fee in TRC21 tokens, whose balance is tracked outside of
+// the state, are left to the sequential execution.
+func (s *TxSpeculator) speculative(tx *types.Transaction, tokensFee map[common.Address]*big.Int) bool {
+	to := tx.To()
+	if to == nil {
+		return true
+	}
+	if _, ok := tokensFee[*to]; ok {
+		return false
+	}
+	return !tx.IsSpecialTransaction() && !tx.IsSlashingTransaction() && !tx.IsBLSRegistrationTransaction() &&
+		!tx.IsTradingTransaction() && !tx.IsLendingTransaction() && !tx.IsLendingFinalizedTradeTransaction() &&
+		!tx.IsXDCZApplyTransaction() && !tx.IsXDCXApplyTransaction() && to.String() != common.TradingStateAddr
+}
+
+// Speculate executes the given transactions in parallel on speculative copies
+// of the current state. The transactions already speculated are skipped.
+func (s *TxSpeculator) Speculate(txs types.Transactions, tokensFee map[common.Address]*big.Int) {
+	var pending types.Transactions
+	for _, tx := range txs {
+		if _, ok := s.results[tx.Hash()]; !ok && s.speculative(tx, tokensFee) {
+			pending = append(pending, tx)
+		}
+	}
+	// A single transaction is faster to apply right away
+	if len(pending) < 2 {
+		return
+	}
+	var (
+		results = make([]*speculation, len(pending))
+		next    = int32(-1)
+		wg      sync.WaitGroup
+	)
+	workers := s.workers
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				j := int(atomic.AddInt32(&next, 1))
+				if j >= len(pending) {
+					return
+				}
+				results[j] = s.execute(pending[j], tokensFee)
+			}
+		}()
+	}
+	wg.Wait()
+	for i, tx := range pending {
+		s.results[tx.Hash()] = results[i]
+	}
+}
+
+// SpeculateBatch speculates the next batch of the transactions of a block if
+// the i-th one starts a batch.
+func (s *TxSpeculator) SpeculateBatch(txs types.Transactions, i int, tokensFee map[common.Address]*big.Int) {
+	size := s.workers * speculationBatch
+	if i%size != 0 {
+		return
+	}
+	end := i + size
+	if end > len(txs) {
+		end = len(txs)
+	}
+	s.Speculate(txs[i:end], tokensFee)
+}
+
+func (s *TxSpeculator) execute(tx *types.Transaction, tokensFee map[common.Address]*big.Int) *speculation {
+	var (
+		statedb = s.statedb.SpeculativeCopy(s.feeRecipients)
+		gp      = new(GasPool).AddGas(tx.Gas())
+		usedGas uint64
+	)
+	statedb.Prepare(tx.Hash(), common.Hash{}, 0)
+	receipt, gas, err, _ := ApplyTransaction(s.config, tokensFee, s.bc, s.author, gp, statedb, s.tradingState, s.header, tx, &usedGas, s.cfg)
+	return &speculation{statedb: statedb, receipt: receipt, gas: gas, err: err}
+}
+
+// Apply applies the transaction to the state like ApplyTransaction, merging its
+// speculative result if it's still valid and executing it again otherwise.
+func (s *TxSpeculator) Apply(tokensFee map[common.Address]*big.Int, gp *GasPool, tx *types.Transaction, usedGas *uint64) (*types.Receipt, uint64, error, bool) {
+	spec := s.results[tx.Hash()]
+	delete(s.results, tx.Hash())
+	if spec == nil || spec.err != nil || gp.Gas() < tx.Gas() || s.statedb.Conflicts(spec.statedb) {
+		return ApplyTransaction(s.config, tokensFee, s.bc, s.author, gp, s.statedb, s.tradingState, s.header, tx, usedGas, s.cfg)
+	}
+	// speculation only runs on Byzantium blocks, whose transactions are finalised
+	// deleting the empty objects whatever the EIP158 rule
+	s.statedb.Merge(spec.statedb, true)
+	if err := gp.SubGas(spec.gas); err != nil {
+		return nil, 0, err, false
+	}
+	*usedGas += spec.gas
+
+	receipt := spec.receipt
+	receipt.CumulativeGasUsed = *usedGas
+	receipt.Logs = s.statedb.GetLogs(tx.Hash())
+	return receipt, spec.gas, nil, false
+}
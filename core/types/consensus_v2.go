@@ -5,6 +5,7 @@ import (
 	"math/big"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
 	"github.com/XinFinOrg/XDC-Subnet/rlp"
 )
 
@@ -25,6 +26,22 @@ type Vote struct {
 	ProposedBlockInfo *BlockInfo     `json:"proposedBlockInfo"`
 	Signature         Signature      `json:"signature"`
 	GapNumber         uint64         `json:"gapNumber"`
+	BLSSignature      []Signature    `json:"blsSignature,omitempty" rlp:"tail"` // BLS signature of the vote after the BLS fork, at most one
+}
+
+type voteRLP Vote
+
+// DecodeRLP keeps the BLS signature nil for votes without it, as before the BLS fork
+func (v *Vote) DecodeRLP(s *rlp.Stream) error {
+	var dec voteRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*v = Vote(dec)
+	if len(v.BLSSignature) == 0 {
+		v.BLSSignature = nil
+	}
+	return nil
 }
 
 func (v *Vote) Hash() common.Hash {
@@ -47,9 +64,25 @@ func (v *Vote) SetSigner(signer common.Address) {
 // Timeout message in XDPoS 2.0
 type Timeout struct {
 	signer    common.Address
-	Round     Round
-	Signature Signature
-	GapNumber uint64
+	Round        Round
+	Signature    Signature
+	GapNumber    uint64
+	BLSSignature []Signature `rlp:"tail"` // BLS signature of the timeout after the BLS fork, at most one
+}
+
+type timeoutRLP Timeout
+
+// DecodeRLP keeps the BLS signature nil for timeouts without it, as before the BLS fork
+func (t *Timeout) DecodeRLP(s *rlp.Stream) error {
+	var dec timeoutRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*t = Timeout(dec)
+	if len(t.BLSSignature) == 0 {
+		t.BLSSignature = nil
+	}
+	return nil
 }
 
 func (t *Timeout) Hash() common.Hash {
@@ -84,6 +117,8 @@ type QuorumCert struct {
 	ProposedBlockInfo *BlockInfo `json:"proposedBlockInfo"`
 	Signatures        []Signature `json:"signatures"`
 	GapNumber         uint64 `json:"gapNumber"`
+	// BLS aggregated signature replacing Signatures after the BLS fork, at most one
+	Aggregated []*AggregatedSignature `json:"aggregated,omitempty" rlp:"tail"`
 }
 
 // Timeout Certificate struct in XDPoS 2.0
@@ -91,6 +126,67 @@ type TimeoutCert struct {
 	Round      Round
 	Signatures []Signature
 	GapNumber  uint64
+	// BLS aggregated signature replacing Signatures after the BLS fork, at most one
+	Aggregated []*AggregatedSignature `rlp:"tail"`
+}
+
+type quorumCertRLP QuorumCert
+
+// DecodeRLP keeps the aggregated signature nil for QCs without it, as before the BLS fork
+func (qc *QuorumCert) DecodeRLP(s *rlp.Stream) error {
+	var dec quorumCertRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*qc = QuorumCert(dec)
+	if len(qc.Aggregated) == 0 {
+		qc.Aggregated = nil
+	}
+	return nil
+}
+
+type timeoutCertRLP TimeoutCert
+
+// DecodeRLP keeps the aggregated signature nil for TCs without it, as before the BLS fork
+func (tc *TimeoutCert) DecodeRLP(s *rlp.Stream) error {
+	var dec timeoutCertRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*tc = TimeoutCert(dec)
+	if len(tc.Aggregated) == 0 {
+		tc.Aggregated = nil
+	}
+	return nil
+}
+
+// BLS aggregated signature of a QC or TC, the i-th bit of SignerBitmap is set if the i-th masternode signed
+type AggregatedSignature struct {
+	SignerBitmap []byte    `json:"signerBitmap"`
+	Signature    Signature `json:"signature"`
+}
+
+// Number of signers in the bitmap
+func (a *AggregatedSignature) SignerCount() int {
+	count := 0
+	for _, b := range a.SignerBitmap {
+		for ; b != 0; b &= b - 1 {
+			count++
+		}
+	}
+	return count
+}
+
+// Whether the i-th masternode is a signer
+func (a *AggregatedSignature) HasSigner(i int) bool {
+	return i/8 < len(a.SignerBitmap) && a.SignerBitmap[i/8]&(1<<uint(i%8)) != 0
+}
+
+func (a *AggregatedSignature) SetSigner(i int) {
+	for i/8 >= len(a.SignerBitmap) {
+		a.SignerBitmap = append(a.SignerBitmap, 0)
+	}
+	a.SignerBitmap[i/8] |= 1 << uint(i%8)
 }
 
 // The parsed extra fields in block header in XDPoS 2.0 (excluding the version byte)
@@ -98,6 +194,36 @@ type TimeoutCert struct {
 type ExtraFields_v2 struct {
 	Round      Round
 	QuorumCert *QuorumCert
+	// Consensus config voted on chain, only in epoch switch blocks, at most one
+	EpochConfig []*EpochConfig `rlp:"tail"`
+}
+
+type extraFieldsRLP ExtraFields_v2
+
+// DecodeRLP keeps the epoch config nil for extra fields without it
+func (e *ExtraFields_v2) DecodeRLP(s *rlp.Stream) error {
+	var dec extraFieldsRLP
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	*e = ExtraFields_v2(dec)
+	if len(e.EpochConfig) == 0 {
+		e.EpochConfig = nil
+	}
+	return nil
+}
+
+// Consensus parameters read from the config contract at the gap block and recorded in the next epoch switch header.
+// They take effect after the epoch switch round, a zero field keeps the value in use.
+type EpochConfig struct {
+	MinePeriod           uint64 `json:"minePeriod"`
+	TimeoutPeriod        uint64 `json:"timeoutPeriod"`
+	TimeoutSyncThreshold uint64 `json:"timeoutSyncThreshold"`
+	CertThreshold        uint64 `json:"certThreshold"` // Per mille of the masternodes, i.e 667 for 0.667
+}
+
+func (c *EpochConfig) IsEmpty() bool {
+	return c.MinePeriod == 0 && c.TimeoutPeriod == 0 && c.TimeoutSyncThreshold == 0 && c.CertThreshold == 0
 }
 
 // Encode XDPoS 2.0 extra fields into bytes
@@ -110,6 +236,31 @@ func (e *ExtraFields_v2) EncodeToBytes() ([]byte, error) {
 	return append(versionByte, bytes...), nil
 }
 
+// Headers committed by the three consecutive rounds rule. The last three headers have consecutive rounds,
+// the QCs of the first two are in the extra of their children and QuorumCert certifies the last one.
+type CommitProof struct {
+	Headers    []*Header   `json:"headers"`
+	QuorumCert *QuorumCert `json:"quorumCert"`
+}
+
+// Proof that the masternodes of an epoch are finalised, verifiable with the masternodes of the previous epoch only
+type EpochProof struct {
+	Epoch          uint64           `json:"epoch"`
+	Masternodes    []common.Address `json:"masternodes"`
+	EpochSwitch    *CommitProof     `json:"epochSwitch"`       // Starts with the epoch switch header recording the masternodes
+	NextValidators *CommitProof     `json:"nextValidators"`    // Starts with the gap plus one header of the previous epoch recording the candidates
+	Chain          []*Header        `json:"chain"`             // Headers between the gap plus one header and the epoch switch header, linking them by the parent hash
+	BLSKeys        *BLSKeysProof    `json:"blsKeys,omitempty"` // Keys verifying the aggregated QCs of the epoch, only after the BLS fork
+}
+
+// Proof of the BLS public keys registered by the masternodes of an epoch, against the state root of its gap header
+// which is the parent of the gap plus one header
+type BLSKeysProof struct {
+	GapHeader    *Header         `json:"gapHeader"`
+	AccountProof []hexutil.Bytes `json:"accountProof"` // Trie nodes of the BLS registry account
+	StorageProof []hexutil.Bytes `json:"storageProof"` // Trie nodes of the key slots of all the masternodes
+}
+
 type EpochSwitchInfo struct {
 	Penalties                  []common.Address
 	Standbynodes               []common.Address
@@ -112,3 +112,59 @@ func TestPoolKeyFormat(t *testing.T) {
 	assert.Equal(t, "4", voteKey[2])
 	assert.Equal(t, common.Hash{1}.String(), voteKey[3])
 }
+
+func TestAggregatedQuorumCertEncodeDecode(t *testing.T) {
+	aggregated := &AggregatedSignature{SignerBitmap: make([]byte, 2)}
+	aggregated.SetSigner(0)
+	aggregated.SetSigner(9)
+	aggregated.Signature = []byte{1, 2, 3}
+	assert.Equal(t, 2, aggregated.SignerCount())
+	assert.True(t, aggregated.HasSigner(9))
+	assert.False(t, aggregated.HasSigner(1))
+	assert.False(t, aggregated.HasSigner(16))
+
+	quorumCert := &QuorumCert{
+		ProposedBlockInfo: &BlockInfo{Hash: common.Hash{1}, Round: 10, Number: big.NewInt(900)},
+		GapNumber:         450,
+		Aggregated:        []*AggregatedSignature{aggregated},
+	}
+	encoded, err := rlp.EncodeToBytes(quorumCert)
+	assert.Nil(t, err)
+	var decoded QuorumCert
+	assert.Nil(t, rlp.DecodeBytes(encoded, &decoded))
+	assert.Empty(t, decoded.Signatures)
+	assert.Equal(t, quorumCert.Aggregated, decoded.Aggregated)
+
+	// QC before the BLS fork keeps the same encoding
+	quorumCert.Aggregated = nil
+	quorumCert.Signatures = []Signature{{1}, {2}}
+	encoded, err = rlp.EncodeToBytes(quorumCert)
+	assert.Nil(t, err)
+	decoded = QuorumCert{}
+	assert.Nil(t, rlp.DecodeBytes(encoded, &decoded))
+	assert.Nil(t, decoded.Aggregated)
+	assert.Equal(t, quorumCert.Signatures, decoded.Signatures)
+}
+
+func TestEpochConfigExtraFieldsEncodeDecode(t *testing.T) {
+	extraFields := ExtraFields_v2{
+		Round:       Round(900),
+		QuorumCert:  &QuorumCert{ProposedBlockInfo: &BlockInfo{Hash: common.Hash{1}, Round: 899, Number: big.NewInt(899)}, GapNumber: 450},
+		EpochConfig: []*EpochConfig{{MinePeriod: 3, CertThreshold: 750}},
+	}
+	encoded, err := extraFields.EncodeToBytes()
+	assert.Nil(t, err)
+	var decoded ExtraFields_v2
+	assert.Nil(t, DecodeBytesExtraFields(encoded, &decoded))
+	assert.Equal(t, extraFields.EpochConfig, decoded.EpochConfig)
+
+	// extra fields without config keep the same encoding
+	extraFields.EpochConfig = nil
+	encoded, err = extraFields.EncodeToBytes()
+	assert.Nil(t, err)
+	decoded = ExtraFields_v2{}
+	assert.Nil(t, DecodeBytesExtraFields(encoded, &decoded))
+	assert.Nil(t, decoded.EpochConfig)
+	assert.False(t, (&EpochConfig{CertThreshold: 667}).IsEmpty())
+	assert.True(t, (&EpochConfig{}).IsEmpty())
+}
@@ -1,6 +1,15 @@
 package types
 
-import "github.com/XinFinOrg/XDC-Subnet/common"
+import (
+	"errors"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+)
+
+// ErrInvalidSlashingEvidence is returned if a slashing transaction carries an
+// evidence that can't be verified or has already been applied.
+var ErrInvalidSlashingEvidence = errors.New("invalid slashing evidence")
 
 type ForensicsInfo struct {
 	HashPath        []string   `json:"hashPath"`
@@ -31,3 +40,20 @@ type ForensicProof struct {
 type ForensicsEvent struct {
 	ForensicsProof *ForensicProof
 }
+
+// Vote equivocation evidence submitted in a slashing transaction, the tx data is the RLP encoding of it
+type SlashingEvidence struct {
+	FirstVote  *Vote
+	SecondVote *Vote
+}
+
+func DecodeSlashingEvidence(data []byte) (*SlashingEvidence, error) {
+	evidence := new(SlashingEvidence)
+	if err := rlp.DecodeBytes(data, evidence); err != nil {
+		return nil, err
+	}
+	if evidence.FirstVote == nil || evidence.SecondVote == nil || evidence.FirstVote.ProposedBlockInfo == nil || evidence.SecondVote.ProposedBlockInfo == nil {
+		return nil, ErrInvalidSlashingEvidence
+	}
+	return evidence, nil
+}
@@ -35,6 +35,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 		Validators     []common.Address `json:"validators"       gencodec:"required"`
 		NextValidators []common.Address `json:"nextValidators"   gencodec:"required"`
 		Penalties      []common.Address `json:"penalties"        gencodec:"required"`
+		BaseFee        *hexutil.Big     `json:"baseFeePerGas" rlp:"optional"`
 		Hash           common.Hash      `json:"hash"`
 	}
 	var enc Header
@@ -57,6 +58,7 @@ func (h Header) MarshalJSON() ([]byte, error) {
 	enc.Validators = h.Validators
 	enc.NextValidators = h.NextValidators
 	enc.Penalties = h.Penalties
+	enc.BaseFee = (*hexutil.Big)(h.BaseFee)
 	enc.Hash = h.Hash()
 	return json.Marshal(&enc)
 }
@@ -83,6 +85,7 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		Validators     []common.Address `json:"validators"       gencodec:"required"`
 		NextValidators []common.Address `json:"nextValidators"   gencodec:"required"`
 		Penalties      []common.Address `json:"penalties"        gencodec:"required"`
+		BaseFee        *hexutil.Big     `json:"baseFeePerGas" rlp:"optional"`
 	}
 	var dec Header
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -164,5 +167,8 @@ func (h *Header) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'penalties' for Header")
 	}
 	h.Penalties = dec.Penalties
+	if dec.BaseFee != nil {
+		h.BaseFee = (*big.Int)(dec.BaseFee)
+	}
 	return nil
 }
@@ -135,6 +135,11 @@ func (lendingsign LendingTxSigner) LendingCreateHash(tx *LendingTransaction) com
 		}
 		sha.Write(common.BigToHash(big.NewInt(autoTopUp)).Bytes())
 	}
+	// The expiry is only hashed when set, keeping the hashes of the lending
+	// orders signed without it
+	if tx.ExpiresAt() > 0 {
+		sha.Write(common.BigToHash(new(big.Int).SetUint64(tx.ExpiresAt())).Bytes())
+	}
 	return common.BytesToHash(sha.Sum(nil))
 }
 
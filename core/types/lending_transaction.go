@@ -81,6 +81,9 @@ type lendingtxdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash common.Hash `json:"hash"`
+
+	// Unix time after which a resting lending order is cancelled by the chain
+	ExpiresAt uint64 `json:"expiresAt,omitempty" rlp:"optional"`
 }
 
 // IsCreatedLending check if tx is cancelled transaction
@@ -177,6 +180,9 @@ func (tx *LendingTransaction) Signature() (V, R, S *big.Int) { return tx.data.V,
 // LendingHash return hash of lending transaction
 func (tx *LendingTransaction) LendingHash() common.Hash { return tx.data.Hash }
 
+// ExpiresAt return the expiry time of lending transaction, 0 if it never expires
+func (tx *LendingTransaction) ExpiresAt() uint64 { return tx.data.ExpiresAt }
+
 // LendingId return lending id
 func (tx *LendingTransaction) LendingId() uint64 { return tx.data.LendingId }
 
@@ -258,11 +264,11 @@ func (tx *LendingTransaction) Size() common.StorageSize {
 }
 
 // NewLendingTransaction init lending from value
-func NewLendingTransaction(nonce uint64, quantity *big.Int, interest, duration uint64, relayerAddress, userAddress, lendingToken, collateralToken common.Address, autoTopUp bool, status, side, typeLending string, hash common.Hash, id, tradeId uint64, extraData string) *LendingTransaction {
-	return newLendingTransaction(nonce, quantity, interest, duration, relayerAddress, userAddress, lendingToken, collateralToken, autoTopUp, status, side, typeLending, hash, id, tradeId, extraData)
+func NewLendingTransaction(nonce uint64, quantity *big.Int, interest, duration uint64, relayerAddress, userAddress, lendingToken, collateralToken common.Address, autoTopUp bool, status, side, typeLending string, hash common.Hash, id, tradeId uint64, extraData string, expiresAt uint64) *LendingTransaction {
+	return newLendingTransaction(nonce, quantity, interest, duration, relayerAddress, userAddress, lendingToken, collateralToken, autoTopUp, status, side, typeLending, hash, id, tradeId, extraData, expiresAt)
 }
 
-func newLendingTransaction(nonce uint64, quantity *big.Int, interest, duration uint64, relayerAddress, userAddress, lendingToken, collateralToken common.Address, autoTopUp bool, status, side, typeLending string, hash common.Hash, id, tradeId uint64, extraData string) *LendingTransaction {
+func newLendingTransaction(nonce uint64, quantity *big.Int, interest, duration uint64, relayerAddress, userAddress, lendingToken, collateralToken common.Address, autoTopUp bool, status, side, typeLending string, hash common.Hash, id, tradeId uint64, extraData string, expiresAt uint64) *LendingTransaction {
 	d := lendingtxdata{
 		AccountNonce:    nonce,
 		Quantity:        new(big.Int),
@@ -280,6 +286,7 @@ func newLendingTransaction(nonce uint64, quantity *big.Int, interest, duration u
 		LendingId:       id,
 		LendingTradeId:  tradeId,
 		ExtraData:       extraData,
+		ExpiresAt:       expiresAt,
 		V:               new(big.Int),
 		R:               new(big.Int),
 		S:               new(big.Int),
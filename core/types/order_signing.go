@@ -114,7 +114,7 @@ func (ordersign OrderTxSigner) OrderCreateHash(tx *OrderTransaction) common.Hash
 	sha.Write(tx.BaseToken().Bytes())
 	sha.Write(tx.QuoteToken().Bytes())
 	sha.Write(common.BigToHash(tx.Quantity()).Bytes())
-	if tx.IsLoTypeOrder() {
+	if tx.IsLoTypeOrder() || tx.Type() == OrderTypeStopLo || tx.Type() == OrderTypeTakeProfitLo {
 		if tx.Price() != nil {
 			sha.Write(common.BigToHash(tx.Price()).Bytes())
 		}
@@ -123,6 +123,23 @@ func (ordersign OrderTxSigner) OrderCreateHash(tx *OrderTransaction) common.Hash
 	sha.Write([]byte(tx.Status()))
 	sha.Write([]byte(tx.Type()))
 	sha.Write(common.BigToHash(big.NewInt(int64(tx.Nonce()))).Bytes())
+	// The execution flags are only hashed when set, keeping the hashes of
+	// the orders signed without them
+	if tx.TimeInForce() != "" {
+		sha.Write([]byte(tx.TimeInForce()))
+	}
+	if tx.PostOnly() {
+		sha.Write(common.BigToHash(big.NewInt(1)).Bytes())
+	}
+	if tx.TriggerPrice() != nil {
+		sha.Write(common.BigToHash(tx.TriggerPrice()).Bytes())
+	}
+	if tx.ExpiresAt() > 0 {
+		sha.Write(common.BigToHash(new(big.Int).SetUint64(tx.ExpiresAt())).Bytes())
+	}
+	if tx.SelfTrade() != "" {
+		sha.Write([]byte(tx.SelfTrade()))
+	}
 	return common.BytesToHash(sha.Sum(nil))
 }
 
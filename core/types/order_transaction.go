@@ -42,6 +42,17 @@ const (
 	OrderStatusCancelled     = "CANCELLED"
 	OrderTypeMo              = "MO"
 	OrderTypeLo              = "LO"
+	OrderTypeStopMo          = "SMO"
+	OrderTypeStopLo          = "SLO"
+	OrderTypeTakeProfitMo    = "TPMO"
+	OrderTypeTakeProfitLo    = "TPLO"
+	OrderTimeInForceGtc      = "GTC"
+	OrderTimeInForceIoc      = "IOC"
+	OrderTimeInForceFok      = "FOK"
+	OrderSelfTradeCn         = "CN"
+	OrderSelfTradeCo         = "CO"
+	OrderSelfTradeCb         = "CB"
+	OrderSelfTradeDc         = "DC"
 )
 
 // OrderTransaction order transaction
@@ -72,6 +83,19 @@ type ordertxdata struct {
 
 	// This is only used when marshaling to JSON.
 	Hash common.Hash `json:"hash"`
+
+	// Execution flags, left out of the encoding of the orders not setting them
+	TimeInForce string `json:"timeInForce,omitempty" rlp:"optional"`
+	PostOnly    bool   `json:"postOnly,omitempty" rlp:"optional"`
+
+	// Price activating a stop or take-profit order
+	TriggerPrice *big.Int `json:"triggerPrice,omitempty" rlp:"optional"`
+
+	// Unix time after which a resting order is cancelled by the chain
+	ExpiresAt uint64 `json:"expiresAt,omitempty" rlp:"optional"`
+
+	// Handling of the matches against the orders of the same user
+	SelfTrade string `json:"selfTradePrevention,omitempty" rlp:"optional"`
 }
 
 // IsCancelledOrder check if tx is cancelled transaction
@@ -89,6 +113,15 @@ func (tx *OrderTransaction) IsLoTypeOrder() bool {
 	return tx.Type() == OrderTypeLo
 }
 
+// IsTriggerTypeOrder check if tx type is a stop or take-profit order
+func (tx *OrderTransaction) IsTriggerTypeOrder() bool {
+	switch tx.Type() {
+	case OrderTypeStopMo, OrderTypeStopLo, OrderTypeTakeProfitMo, OrderTypeTakeProfitLo:
+		return true
+	}
+	return false
+}
+
 // EncodeRLP implements rlp.Encoder
 func (tx *OrderTransaction) EncodeRLP(w io.Writer) error {
 	return rlp.Encode(w, &tx.data)
@@ -119,6 +152,11 @@ func (tx *OrderTransaction) Type() string                    { return tx.data.Ty
 func (tx *OrderTransaction) Signature() (V, R, S *big.Int)   { return tx.data.V, tx.data.R, tx.data.S }
 func (tx *OrderTransaction) OrderHash() common.Hash          { return tx.data.Hash }
 func (tx *OrderTransaction) OrderID() uint64                 { return tx.data.OrderID }
+func (tx *OrderTransaction) TimeInForce() string             { return tx.data.TimeInForce }
+func (tx *OrderTransaction) PostOnly() bool                  { return tx.data.PostOnly }
+func (tx *OrderTransaction) TriggerPrice() *big.Int          { return tx.data.TriggerPrice }
+func (tx *OrderTransaction) ExpiresAt() uint64               { return tx.data.ExpiresAt }
+func (tx *OrderTransaction) SelfTrade() string               { return tx.data.SelfTrade }
 func (tx *OrderTransaction) EncodedSide() *big.Int {
 	if tx.Side() == "BUY" {
 		return big.NewInt(0)
@@ -198,11 +236,11 @@ func (tx *OrderTransaction) Size() common.StorageSize {
 }
 
 // NewOrderTransaction init order from value
-func NewOrderTransaction(nonce uint64, quantity, price *big.Int, ex, ua, b, q common.Address, status, side, t string, hash common.Hash, id uint64) *OrderTransaction {
-	return newOrderTransaction(nonce, quantity, price, ex, ua, b, q, status, side, t, hash, id)
+func NewOrderTransaction(nonce uint64, quantity, price *big.Int, ex, ua, b, q common.Address, status, side, t string, hash common.Hash, id uint64, timeInForce string, postOnly bool, triggerPrice *big.Int, expiresAt uint64, selfTrade string) *OrderTransaction {
+	return newOrderTransaction(nonce, quantity, price, ex, ua, b, q, status, side, t, hash, id, timeInForce, postOnly, triggerPrice, expiresAt, selfTrade)
 }
 
-func newOrderTransaction(nonce uint64, quantity, price *big.Int, ex, ua, b, q common.Address, status, side, t string, hash common.Hash, id uint64) *OrderTransaction {
+func newOrderTransaction(nonce uint64, quantity, price *big.Int, ex, ua, b, q common.Address, status, side, t string, hash common.Hash, id uint64, timeInForce string, postOnly bool, triggerPrice *big.Int, expiresAt uint64, selfTrade string) *OrderTransaction {
 	d := ordertxdata{
 		AccountNonce:    nonce,
 		Quantity:        new(big.Int),
@@ -216,6 +254,10 @@ func newOrderTransaction(nonce uint64, quantity, price *big.Int, ex, ua, b, q co
 		Type:            t,
 		Hash:            hash,
 		OrderID:         id,
+		TimeInForce:     timeInForce,
+		PostOnly:        postOnly,
+		ExpiresAt:       expiresAt,
+		SelfTrade:       selfTrade,
 		V:               new(big.Int),
 		R:               new(big.Int),
 		S:               new(big.Int),
@@ -226,6 +268,9 @@ func newOrderTransaction(nonce uint64, quantity, price *big.Int, ex, ua, b, q co
 	if price != nil {
 		d.Price.Set(price)
 	}
+	if triggerPrice != nil && triggerPrice.Sign() > 0 {
+		d.TriggerPrice = new(big.Int).Set(triggerPrice)
+	}
 
 	return &OrderTransaction{data: d}
 }
@@ -1,12 +1,14 @@
 package types
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"math/big"
 	"testing"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/crypto"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
 )
 
 func TestNewOrderTransactionByNonce(t *testing.T) {
@@ -21,7 +23,7 @@ func TestNewOrderTransactionByNonce(t *testing.T) {
 		addr := crypto.PubkeyToAddress(key.PublicKey)
 		for i := 0; i < 1; i++ {
 			//tx, _ := SignTx(NewTransaction(uint64(start+i), common.Address{}, big.NewInt(100), 100, big.NewInt(int64(start+i)), nil), signer, key)
-			orderTx := NewOrderTransaction(uint64(start+i), big.NewInt(1), big.NewInt(2), common.Address{}, common.Address{}, common.Address{}, common.Address{}, "new", "BID", "test", common.Hash{}, 1001)
+			orderTx := NewOrderTransaction(uint64(start+i), big.NewInt(1), big.NewInt(2), common.Address{}, common.Address{}, common.Address{}, common.Address{}, "new", "BID", "test", common.Hash{}, 1001, "", false, nil, 0, "")
 
 			groups[addr] = append(groups[addr], orderTx)
 		}
@@ -29,3 +31,80 @@ func TestNewOrderTransactionByNonce(t *testing.T) {
 	tx := NewOrderTransactionByNonce(OrderTxSigner{}, groups)
 	t.Log(tx)
 }
+
+// Tests that the execution flags are left out of the encoding and of the hash
+// signed by the orders not setting them, and round-trip when set.
+func TestOrderTransactionFlags(t *testing.T) {
+	plain := NewOrderTransaction(1, big.NewInt(1), big.NewInt(2), common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}, OrderStatusNew, "BUY", OrderTypeLo, common.Hash{}, 0, "", false, nil, 0, "")
+	flagged := NewOrderTransaction(1, big.NewInt(1), big.NewInt(2), common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}, OrderStatusNew, "BUY", OrderTypeLo, common.Hash{}, 0, OrderTimeInForceGtc, true, nil, 0, OrderSelfTradeCo)
+
+	// The encoding of a plain order must match the one without the flag fields
+	legacy := []interface{}{
+		plain.Nonce(), plain.Quantity(), plain.Price(), plain.ExchangeAddress(), plain.UserAddress(), plain.BaseToken(), plain.QuoteToken(),
+		plain.Status(), plain.Side(), plain.Type(), plain.OrderID(), new(big.Int), new(big.Int), new(big.Int), common.Hash{},
+	}
+	have, _ := rlp.EncodeToBytes(plain)
+	want, _ := rlp.EncodeToBytes(legacy)
+	if !bytes.Equal(have, want) {
+		t.Errorf("plain order encoding mismatch: have %x, want %x", have, want)
+	}
+	enc, err := rlp.EncodeToBytes(flagged)
+	if err != nil {
+		t.Fatalf("failed to encode order: %v", err)
+	}
+	var decoded OrderTransaction
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("failed to decode order: %v", err)
+	}
+	if decoded.TimeInForce() != OrderTimeInForceGtc || !decoded.PostOnly() || decoded.SelfTrade() != OrderSelfTradeCo {
+		t.Errorf("flags mismatch: have %q/%v/%q, want %q/%v/%q", decoded.TimeInForce(), decoded.PostOnly(), decoded.SelfTrade(), OrderTimeInForceGtc, true, OrderSelfTradeCo)
+	}
+	signer := OrderTxSigner{}
+	if signer.Hash(plain) == signer.Hash(flagged) {
+		t.Error("flags not covered by the signed hash")
+	}
+}
+
+// Tests that the trigger price of the stop and take-profit orders round-trips
+// and is covered by the signed hash.
+func TestOrderTransactionTriggerPrice(t *testing.T) {
+	stop := NewOrderTransaction(1, big.NewInt(1), big.NewInt(2), common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}, OrderStatusNew, "SELL", OrderTypeStopLo, common.Hash{}, 0, "", false, big.NewInt(3), 0, "")
+	other := NewOrderTransaction(1, big.NewInt(1), big.NewInt(2), common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}, OrderStatusNew, "SELL", OrderTypeStopLo, common.Hash{}, 0, "", false, big.NewInt(4), 0, "")
+	if !stop.IsTriggerTypeOrder() {
+		t.Error("stop-limit order not reported as trigger order")
+	}
+	enc, err := rlp.EncodeToBytes(stop)
+	if err != nil {
+		t.Fatalf("failed to encode order: %v", err)
+	}
+	var decoded OrderTransaction
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("failed to decode order: %v", err)
+	}
+	if decoded.TriggerPrice() == nil || decoded.TriggerPrice().Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("trigger price mismatch: have %v, want %v", decoded.TriggerPrice(), 3)
+	}
+	signer := OrderTxSigner{}
+	if signer.Hash(stop) == signer.Hash(other) {
+		t.Error("trigger price not covered by the signed hash")
+	}
+}
+func TestOrderTransactionExpiresAt(t *testing.T) {
+	gtc := NewOrderTransaction(1, big.NewInt(1), big.NewInt(2), common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}, OrderStatusNew, "SELL", "LO", common.Hash{}, 0, "", false, nil, 0, "")
+	gtt := NewOrderTransaction(1, big.NewInt(1), big.NewInt(2), common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}, OrderStatusNew, "SELL", "LO", common.Hash{}, 0, "", false, nil, 1700000000, "")
+	signer := OrderTxSigner{}
+	if signer.Hash(gtc) == signer.Hash(gtt) {
+		t.Error("expiry not covered by the signed hash")
+	}
+	enc, err := rlp.EncodeToBytes(gtt)
+	if err != nil {
+		t.Fatalf("failed to encode order: %v", err)
+	}
+	var decoded OrderTransaction
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("failed to decode order: %v", err)
+	}
+	if decoded.ExpiresAt() != 1700000000 {
+		t.Errorf("expiry mismatch: have %d, want %d", decoded.ExpiresAt(), 1700000000)
+	}
+}
@@ -136,3 +136,69 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestEIP2930Signing(t *testing.T) {
+	key, addr := defaultTestKey()
+
+	signer := NewEIP2930Signer(big.NewInt(18))
+	tx, err := SignNewTx(key, signer, &AccessListTx{
+		ChainID:    big.NewInt(18),
+		To:         &common.Address{},
+		Gas:        21000,
+		GasPrice:   new(big.Int),
+		AccessList: AccessList{{Address: addr, StorageKeys: []common.Hash{{1}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != addr {
+		t.Errorf("expected from and address to be equal. Got %x want %x", from, addr)
+	}
+	// Typed transactions are rejected by the signers before the fork
+	if _, err := Sender(NewEIP155Signer(big.NewInt(18)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("expected error %v, got %v", ErrTxTypeNotSupported, err)
+	}
+	// The access list signer still accepts legacy transactions
+	legacy, err := SignTx(NewTransaction(0, addr, new(big.Int), 0, new(big.Int), nil), NewEIP155Signer(big.NewInt(18)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(signer, legacy); err != nil || from != addr {
+		t.Errorf("legacy sender mismatch, got %x, err %v", from, err)
+	}
+}
+
+func TestEIP1559Signing(t *testing.T) {
+	key, addr := defaultTestKey()
+
+	signer := NewEIP1559Signer(big.NewInt(18))
+	tx, err := SignNewTx(key, signer, &DynamicFeeTx{
+		ChainID:   big.NewInt(18),
+		To:        &common.Address{},
+		Gas:       21000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := Sender(signer, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != addr {
+		t.Errorf("expected from and address to be equal. Got %x want %x", from, addr)
+	}
+	// Dynamic fee transactions are rejected by the signers before the fork
+	if _, err := Sender(NewEIP2930Signer(big.NewInt(18)), tx); err != ErrTxTypeNotSupported {
+		t.Errorf("expected error %v, got %v", ErrTxTypeNotSupported, err)
+	}
+	// The signer is bound to its chain
+	if _, err := Sender(NewEIP1559Signer(big.NewInt(19)), tx); err != ErrInvalidChainId {
+		t.Errorf("expected error %v, got %v", ErrInvalidChainId, err)
+	}
+}
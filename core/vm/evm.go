@@ -56,11 +56,17 @@ func run(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, err
 			precompiles = PrecompiledContractsHomestead
 		}
 		if p := precompiles[*contract.CodeAddr]; p != nil {
+			// The price contracts are instantiated per call, as the EVMs
+			// of a block may run in parallel
 			switch p.(type) {
 			case *XDCxEpochPrice:
-				p.(*XDCxEpochPrice).SetTradingState(evm.tradingStateDB)
+				price := &XDCxEpochPrice{}
+				price.SetTradingState(evm.tradingStateDB)
+				p = price
 			case *XDCxLastPrice:
-				p.(*XDCxLastPrice).SetTradingState(evm.tradingStateDB)
+				price := &XDCxLastPrice{}
+				price.SetTradingState(evm.tradingStateDB)
+				p = price
 			}
 			return RunPrecompiledContract(p, input, contract)
 		}
@@ -108,6 +114,7 @@ type Context struct {
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
 	Random      *common.Hash   // Provides information for PREVRANDAO
+	BaseFee     *big.Int       // Provides information for BASEFEE, nil before the EIP1559 fork
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -384,6 +391,11 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	}
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
+	// We add this to the access list _before_ taking a snapshot. Even if the creation fails,
+	// the access-list change should not be rolled back
+	if evm.chainRules.IsEIP2930 {
+		evm.StateDB.AddAddressToAccessList(address)
+	}
 
 	// Ensure there's no existing contract already at the designated address
 	contractHash := evm.StateDB.GetCodeHash(address)
@@ -462,3 +474,6 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
+
+// Config returns the configuration of the interpreters
+func (evm *EVM) Config() Config { return evm.vmConfig }
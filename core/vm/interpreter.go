@@ -29,6 +29,8 @@ type Config struct {
 	Debug                   bool   // Enables debugging
 	Tracer                  Tracer // Opcode logger
 	EnablePreimageRecording bool   // Enables recording of SHA3/keccak preimages
+	NoBaseFee               bool   // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
+	ParallelTxs             int    // Number of goroutines executing the transactions of a block in parallel (0 = sequential)
 
 	JumpTable *JumpTable // EVM instruction table, automatically populated if unset
 
@@ -116,6 +118,9 @@ func NewEVMInterpreter(evm *EVM, cfg Config) *EVMInterpreter {
 		default:
 			cfg.JumpTable = &frontierInstructionSet
 		}
+		if evm.chainRules.IsEIP2930 {
+			cfg.JumpTable = eip2929InstructionSet(cfg.JumpTable)
+		}
 		var extraEips []int
 		for _, eip := range cfg.ExtraEips {
 			copy := *cfg.JumpTable
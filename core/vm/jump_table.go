@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"sync"
+
 	"github.com/XinFinOrg/XDC-Subnet/params"
 )
 
@@ -56,9 +58,38 @@ var (
 	shanghaiInstructionSet         = newShanghaiInstructionSet()
 )
 
+// eip2929InstructionSets are the instruction sets of the forks with the gas cost increases
+// of EIP-2929, which the access lists of EIP-2930 rely on. They are built on first use,
+// as only the chains scheduling the EIP-2930 fork need them.
+var (
+	eip2929InstructionSets     = make(map[*JumpTable]*JumpTable)
+	eip2929InstructionSetsLock sync.Mutex
+)
+
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// eip2929InstructionSet returns the instruction set of the fork with EIP-2929 enabled.
+func eip2929InstructionSet(base *JumpTable) *JumpTable {
+	eip2929InstructionSetsLock.Lock()
+	defer eip2929InstructionSetsLock.Unlock()
+
+	if instructionSet, ok := eip2929InstructionSets[base]; ok {
+		return instructionSet
+	}
+	// enabling an EIP rewrites the operations in place, so they are copied first
+	var instructionSet JumpTable
+	for i, op := range base {
+		if op != nil {
+			cpy := *op
+			instructionSet[i] = &cpy
+		}
+	}
+	enable2929(&instructionSet)
+	eip2929InstructionSets[base] = &instructionSet
+	return &instructionSet
+}
+
 func newShanghaiInstructionSet() JumpTable {
 	instructionSet := newMergeInstructionSet()
 	enable3855(&instructionSet) // PUSH0 instruction
@@ -0,0 +1,205 @@
+// Package bls implements BLS signatures on the BLS12-381 curve, with public keys
+// in G1 and signatures in G2. Signatures of the same message can be aggregated into
+// one and verified with a single pairing check against the aggregated public key.
+// Public keys must come with a proof of possession to prevent rogue key attacks.
+package bls
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+
+	bls12381 "github.com/kilic/bls12-381"
+)
+
+const (
+	SecretKeyLength = 32 // Length of a serialized secret key
+	PublicKeyLength = 48 // Length of a compressed G1 point
+	SignatureLength = 96 // Length of a compressed G2 point
+)
+
+var (
+	// Domain separation tags of the proof of possession scheme
+	signatureDomain  = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+	possessionDomain = []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+	curveOrder = bls12381.NewG1().Q()
+
+	ErrInvalidSecretKey = errors.New("invalid BLS secret key")
+	ErrInvalidPublicKey = errors.New("invalid BLS public key")
+	ErrInvalidSignature = errors.New("invalid BLS signature")
+)
+
+type SecretKey struct {
+	key *bls12381.Fr
+}
+
+type PublicKey struct {
+	point *bls12381.PointG1
+}
+
+type Signature struct {
+	point *bls12381.PointG2
+}
+
+// GenerateKey creates a new random secret key
+func GenerateKey() (*SecretKey, error) {
+	key, err := bls12381.NewFr().Rand(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsZero() {
+		return GenerateKey()
+	}
+	return &SecretKey{key: key}, nil
+}
+
+// SecretKeyFromBytes decodes a big endian secret key, which must be a non zero scalar of the curve order
+func SecretKeyFromBytes(b []byte) (*SecretKey, error) {
+	if len(b) != SecretKeyLength {
+		return nil, ErrInvalidSecretKey
+	}
+	n := new(big.Int).SetBytes(b)
+	if n.Sign() == 0 || n.Cmp(curveOrder) >= 0 {
+		return nil, ErrInvalidSecretKey
+	}
+	return &SecretKey{key: bls12381.NewFr().FromBytes(b)}, nil
+}
+
+// LoadSecretKey loads a hex encoded secret key from the given file
+func LoadSecretKey(file string) (*SecretKey, error) {
+	buf := make([]byte, SecretKeyLength*2)
+	fd, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	if _, err := io.ReadFull(fd, buf); err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(string(buf))
+	if err != nil {
+		return nil, err
+	}
+	return SecretKeyFromBytes(key)
+}
+
+// SaveSecretKey saves the secret key hex encoded to the given file with restrictive permissions
+func SaveSecretKey(file string, key *SecretKey) error {
+	return os.WriteFile(file, []byte(hex.EncodeToString(key.Bytes())), 0600)
+}
+
+func (sk *SecretKey) Bytes() []byte {
+	return sk.key.ToBytes()
+}
+
+func (sk *SecretKey) PublicKey() *PublicKey {
+	g1 := bls12381.NewG1()
+	return &PublicKey{point: g1.MulScalar(g1.New(), g1.One(), sk.key)}
+}
+
+// Sign signs the message, it's usually a 32 bytes hash of the consensus message
+func (sk *SecretKey) Sign(msg []byte) *Signature {
+	return sk.sign(msg, signatureDomain)
+}
+
+// ProvePossession signs the own public key, to be registered along with it
+func (sk *SecretKey) ProvePossession() *Signature {
+	return sk.sign(sk.PublicKey().Bytes(), possessionDomain)
+}
+
+func (sk *SecretKey) sign(msg []byte, domain []byte) *Signature {
+	g2 := bls12381.NewG2()
+	point, err := g2.HashToCurve(msg, domain)
+	if err != nil {
+		// hashing to curve only fails with a domain longer than 255 bytes
+		panic(err)
+	}
+	return &Signature{point: g2.MulScalar(point, point, sk.key)}
+}
+
+// PublicKeyFromBytes decodes a compressed public key and checks it is a valid non infinity point of G1
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeyLength {
+		return nil, ErrInvalidPublicKey
+	}
+	g1 := bls12381.NewG1()
+	point, err := g1.FromCompressed(b)
+	if err != nil || g1.IsZero(point) || !g1.InCorrectSubgroup(point) {
+		return nil, ErrInvalidPublicKey
+	}
+	return &PublicKey{point: point}, nil
+}
+
+func (pk *PublicKey) Bytes() []byte {
+	return bls12381.NewG1().ToCompressed(pk.point)
+}
+
+// VerifyPossession checks the proof of possession of the public key
+func (pk *PublicKey) VerifyPossession(proof *Signature) bool {
+	return verify(pk, pk.Bytes(), proof, possessionDomain)
+}
+
+// SignatureFromBytes decodes a compressed signature and checks it is a valid point of G2
+func SignatureFromBytes(b []byte) (*Signature, error) {
+	if len(b) != SignatureLength {
+		return nil, ErrInvalidSignature
+	}
+	g2 := bls12381.NewG2()
+	point, err := g2.FromCompressed(b)
+	if err != nil || !g2.InCorrectSubgroup(point) {
+		return nil, ErrInvalidSignature
+	}
+	return &Signature{point: point}, nil
+}
+
+func (s *Signature) Bytes() []byte {
+	return bls12381.NewG2().ToCompressed(s.point)
+}
+
+// Verify checks the signature of the message against a single public key
+func Verify(pk *PublicKey, msg []byte, sig *Signature) bool {
+	return verify(pk, msg, sig, signatureDomain)
+}
+
+// VerifyAggregate checks an aggregated signature of the same message signed by all the public keys
+func VerifyAggregate(pks []*PublicKey, msg []byte, sig *Signature) bool {
+	if len(pks) == 0 {
+		return false
+	}
+	return verify(AggregatePublicKeys(pks), msg, sig, signatureDomain)
+}
+
+func AggregateSignatures(sigs []*Signature) *Signature {
+	g2 := bls12381.NewG2()
+	aggregated := g2.Zero()
+	for _, sig := range sigs {
+		g2.Add(aggregated, aggregated, sig.point)
+	}
+	return &Signature{point: aggregated}
+}
+
+func AggregatePublicKeys(pks []*PublicKey) *PublicKey {
+	g1 := bls12381.NewG1()
+	aggregated := g1.Zero()
+	for _, pk := range pks {
+		g1.Add(aggregated, aggregated, pk.point)
+	}
+	return &PublicKey{point: aggregated}
+}
+
+// e(pk, H(msg)) == e(g1, sig)
+func verify(pk *PublicKey, msg []byte, sig *Signature, domain []byte) bool {
+	g2 := bls12381.NewG2()
+	point, err := g2.HashToCurve(msg, domain)
+	if err != nil {
+		return false
+	}
+	engine := bls12381.NewEngine()
+	engine.AddPair(pk.point, point)
+	engine.AddPairInv(bls12381.NewG1().One(), sig.point)
+	return engine.Check()
+}
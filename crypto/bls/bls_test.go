@@ -0,0 +1,119 @@
+package bls
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := crypto.Keccak256([]byte("vote"))
+	sig := sk.Sign(msg)
+	if !Verify(sk.PublicKey(), msg, sig) {
+		t.Fatal("signature not verified")
+	}
+	if Verify(sk.PublicKey(), crypto.Keccak256([]byte("timeout")), sig) {
+		t.Fatal("signature verified for another message")
+	}
+	// the proof of possession is not a valid message signature, and vice versa
+	if Verify(sk.PublicKey(), sk.PublicKey().Bytes(), sk.ProvePossession()) {
+		t.Fatal("proof of possession verified as signature")
+	}
+	if !sk.PublicKey().VerifyPossession(sk.ProvePossession()) {
+		t.Fatal("proof of possession not verified")
+	}
+	if sk.PublicKey().VerifyPossession(sk.Sign(sk.PublicKey().Bytes())) {
+		t.Fatal("signature verified as proof of possession")
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	msg := crypto.Keccak256([]byte("vote"))
+	var (
+		pks  []*PublicKey
+		sigs []*Signature
+	)
+	for i := 0; i < 4; i++ {
+		sk, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pks = append(pks, sk.PublicKey())
+		sigs = append(sigs, sk.Sign(msg))
+	}
+	aggregated := AggregateSignatures(sigs)
+	if !VerifyAggregate(pks, msg, aggregated) {
+		t.Fatal("aggregated signature not verified")
+	}
+	if VerifyAggregate(pks[:3], msg, aggregated) {
+		t.Fatal("aggregated signature verified with missing public key")
+	}
+	if VerifyAggregate(nil, msg, aggregated) {
+		t.Fatal("aggregated signature verified without public keys")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedSk, err := SecretKeyFromBytes(sk.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decodedSk.PublicKey().Bytes(), sk.PublicKey().Bytes()) {
+		t.Fatal("secret key mismatch after decoding")
+	}
+	pk, err := PublicKeyFromBytes(sk.PublicKey().Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := SignatureFromBytes(sk.Sign([]byte("msg")).Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(pk, []byte("msg"), sig) {
+		t.Fatal("decoded signature not verified")
+	}
+	if _, err := SecretKeyFromBytes(make([]byte, SecretKeyLength)); err != ErrInvalidSecretKey {
+		t.Fatalf("zero secret key error mismatch: have %v, want %v", err, ErrInvalidSecretKey)
+	}
+	if _, err := PublicKeyFromBytes(make([]byte, PublicKeyLength)); err != ErrInvalidPublicKey {
+		t.Fatalf("invalid public key error mismatch: have %v, want %v", err, ErrInvalidPublicKey)
+	}
+	if _, err := SignatureFromBytes(sk.PublicKey().Bytes()); err != ErrInvalidSignature {
+		t.Fatalf("invalid signature error mismatch: have %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestLoadSaveSecretKey(t *testing.T) {
+	sk, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := os.MkdirTemp("", "blskey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "key")
+	if err := SaveSecretKey(file, sk); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSecretKey(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Bytes(), sk.Bytes()) {
+		t.Fatal("loaded secret key mismatch")
+	}
+}
@@ -19,6 +19,7 @@ package eth
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -28,14 +29,17 @@ import (
 	"time"
 
 	"github.com/XinFinOrg/XDC-Subnet/XDCx/tradingstate"
+	"github.com/XinFinOrg/XDC-Subnet/XDCxlending/lendingstate"
 
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
 	"github.com/XinFinOrg/XDC-Subnet/core"
 	"github.com/XinFinOrg/XDC-Subnet/core/state"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/core/vm"
 	"github.com/XinFinOrg/XDC-Subnet/eth/tracers"
+	"github.com/XinFinOrg/XDC-Subnet/eth/tracers/native"
 	"github.com/XinFinOrg/XDC-Subnet/internal/ethapi"
 	"github.com/XinFinOrg/XDC-Subnet/log"
 	"github.com/XinFinOrg/XDC-Subnet/rlp"
@@ -57,9 +61,18 @@ const (
 // TraceConfig holds extra parameters to trace functions.
 type TraceConfig struct {
 	*vm.LogConfig
-	Tracer  *string
-	Timeout *string
-	Reexec  *uint64
+	Tracer       *string
+	TracerConfig json.RawMessage // Options of the native tracers, e.g. {"onlyTopCall": true}
+	Timeout      *string
+	Reexec       *uint64
+}
+
+// TraceCallConfig is the config for traceCall API. It holds one more field to
+// override the state for tracing.
+type TraceCallConfig struct {
+	TraceConfig
+	StateOverrides *ethapi.StateOverride
+	BlockOverrides *ethapi.BlockOverrides
 }
 
 // txTraceResult is the result of a single transaction trace.
@@ -208,7 +221,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 							balacne = value
 						}
 					}
-					msg, _ := tx.AsMessage(signer, balacne, task.block.Number())
+					msg, _ := tx.AsMessage(signer, balacne, task.block.Number(), task.block.BaseFee())
 					vmctx := core.NewEVMContext(msg, task.block.Header(), api.eth.blockchain, nil)
 
 					res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
@@ -448,7 +461,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 						balacne = value
 					}
 				}
-				msg, _ := txs[task.index].AsMessage(signer, balacne, block.Number())
+				msg, _ := txs[task.index].AsMessage(signer, balacne, block.Number(), block.BaseFee())
 				vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
 
 				res, err := api.traceTx(ctx, msg, vmctx, task.statedb, config)
@@ -477,7 +490,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 			}
 		}
 		// Generate the next state snapshot fast without tracing
-		msg, _ := tx.AsMessage(signer, balacne, block.Number())
+		msg, _ := tx.AsMessage(signer, balacne, block.Number(), block.BaseFee())
 		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
 
 		vmenv := vm.NewEVM(vmctx, statedb, XDCxState, api.config, vm.Config{})
@@ -590,6 +603,10 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
 	}
+	// Orders, lending, expirations and liquidations are settled outside of the EVM
+	if tx.IsTradingTransaction() || tx.IsLendingTransaction() || tx.IsLendingFinalizedTradeTransaction() || tx.IsTradingStateTransaction() {
+		return api.traceXDCxTransaction(tx, blockHash, reexec)
+	}
 	msg, vmctx, statedb, err := api.computeTxEnv(blockHash, int(index), reexec)
 	if err != nil {
 		return nil, err
@@ -598,11 +615,164 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// traceXDCxTransaction replays the order matching of the block containing the
+// given trading, lending or liquidation transaction, returning the frames of
+// the matches, balance changes and rejected orders done on its behalf. The
+// orders expired by the block are reported under its trading state transaction.
+func (api *PrivateDebugAPI) traceXDCxTransaction(tx *types.Transaction, blockHash common.Hash, reexec uint64) ([]*tradingstate.TraceFrame, error) {
+	block := api.eth.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	tracer := tradingstate.NewFrameTracer()
+
+	engine, ok := api.eth.Engine().(*XDPoS.XDPoS)
+	if !ok || api.eth.XDCX == nil || api.eth.Lending == nil {
+		return nil, errors.New("XDCx is not enabled")
+	}
+	if !api.config.IsTIPXDCX(block.Number()) || block.NumberU64() <= api.config.XDPoS.Epoch {
+		return tracer.Frames(), nil
+	}
+	if isEpochSwitch, _, err := engine.IsEpochSwitch(block.Header()); err != nil || isEpochSwitch {
+		return tracer.Frames(), err
+	}
+	// Matching is done on top of the parent state, before the transactions
+	statedb, _, err := api.computeStateDB(parent, reexec)
+	if err != nil {
+		return nil, err
+	}
+	author, err := engine.Author(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	parentAuthor, _ := engine.Author(parent.Header())
+	tradingState, err := api.eth.XDCX.GetTradingState(parent, parentAuthor)
+	if err != nil {
+		return nil, err
+	}
+	lendingState, err := api.eth.Lending.GetLendingState(parent, parentAuthor)
+	if err != nil {
+		return nil, err
+	}
+	header := block.Header()
+
+	// Apply the batches in block order, only tracing the requested one
+	txMatchBatches, err := core.ExtractTradingTransactions(block.Transactions())
+	if err != nil {
+		return nil, err
+	}
+	for _, txMatchBatch := range txMatchBatches {
+		if txMatchBatch.TxHash == tx.Hash() {
+			tradingState.SetTracer(tracer)
+		}
+		for _, txMatch := range txMatchBatch.Data {
+			order, err := txMatch.DecodeOrder()
+			if err != nil {
+				continue
+			}
+			orderBook := tradingstate.GetTradingOrderBookHash(order.BaseToken, order.QuoteToken)
+			if _, _, err := api.eth.XDCX.ApplyOrder(header, author, api.eth.blockchain, statedb, tradingState, orderBook, order); err != nil {
+				return nil, err
+			}
+		}
+		if txMatchBatch.TxHash == tx.Hash() {
+			return tracer.Frames(), nil
+		}
+	}
+	batches, err := core.ExtractLendingTransactions(block.Transactions())
+	if err != nil {
+		return nil, err
+	}
+	for _, batch := range batches {
+		if batch.TxHash == tx.Hash() {
+			tradingState.SetTracer(tracer)
+		}
+		for _, item := range batch.Data {
+			lendingBook := lendingstate.GetLendingOrderBookHash(item.LendingToken, item.Term)
+			if _, _, err := api.eth.Lending.ApplyOrder(header, author, api.eth.blockchain, statedb, lendingState, tradingState, lendingBook, item); err != nil {
+				return nil, err
+			}
+		}
+		if batch.TxHash == tx.Hash() {
+			return tracer.Frames(), nil
+		}
+	}
+	if tx.IsTradingStateTransaction() {
+		tradingState.SetTracer(tracer)
+	}
+	if api.config.IsXDCxOrderExpiry(header.Number) {
+		if _, err := api.eth.XDCX.ProcessExpiredOrders(header, statedb, tradingState); err != nil {
+			return nil, err
+		}
+		if _, err := api.eth.Lending.ProcessExpiredOrders(header, statedb, tradingState, lendingState); err != nil {
+			return nil, err
+		}
+	}
+	if tx.IsTradingStateTransaction() {
+		return tracer.Frames(), nil
+	}
+	if tx.IsLendingFinalizedTradeTransaction() && block.NumberU64()%api.config.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
+		tradingState.SetTracer(tracer)
+		if _, _, _, _, _, err := api.eth.Lending.ProcessLiquidationData(header, api.eth.blockchain, statedb, tradingState, lendingState); err != nil {
+			return nil, err
+		}
+	}
+	return tracer.Frames(), nil
+}
+
+// TraceCall lets you trace a given eth_call. It collects the structured logs
+// created during the execution of EVM if the given transaction was added on
+// top of the provided block and returns them as a JSON object. The state and
+// the block context can be overridden for the call.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	// Fetch the block that we want to trace on top of
+	var block *types.Block
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		block = api.eth.blockchain.GetBlockByHash(hash)
+	} else if number, ok := blockNrOrHash.Number(); ok {
+		switch number {
+		case rpc.PendingBlockNumber, rpc.LatestBlockNumber:
+			block = api.eth.blockchain.CurrentBlock()
+		default:
+			block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %v not found", blockNrOrHash)
+	}
+	reexec := defaultTraceReexec
+	if config != nil && config.Reexec != nil {
+		reexec = *config.Reexec
+	}
+	statedb, _, err := api.computeStateDB(block, reexec)
+	if err != nil {
+		return nil, err
+	}
+	var traceConfig *TraceConfig
+	if config != nil {
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, err
+		}
+		traceConfig = &config.TraceConfig
+	}
+	// Execute the trace with the block gas limit as the default allowance
+	msg := args.ToMessage(block.GasLimit(), block.Number())
+	vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+	if config != nil {
+		config.BlockOverrides.Apply(&vmctx)
+	}
+	return api.traceTx(ctx, msg, vmctx, statedb, traceConfig)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
-	// Assemble the structured logger or the JavaScript tracer
+	// Assemble the structured logger, the native or the JavaScript tracer
 	var (
 		tracer vm.Tracer
 		err    error
@@ -616,16 +786,23 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
+		// Prefer the native tracer by the given name, falling back to constructing
+		// the JavaScript tracer to execute with
+		switch tracer, err = native.New(*config.Tracer, config.TracerConfig); err {
+		case nil:
+		case native.ErrUnknownTracer:
+			if tracer, err = tracers.New(*config.Tracer); err != nil {
+				return nil, err
+			}
+		default:
 			return nil, err
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-		go func() {
+		go func(stopper interface{ Stop(error) }) {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
-		}()
+			stopper.Stop(errors.New("execution timeout"))
+		}(tracer.(interface{ Stop(error) }))
 		defer cancel()
 
 	case config == nil:
@@ -635,13 +812,20 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
 	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(vmctx, statedb, nil, api.config, vm.Config{Debug: true, Tracer: tracer})
+	vmenv := vm.NewEVM(vmctx, statedb, nil, api.config, vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
 
+	// Native tracers have access to the state right before and after the transaction
+	if tracer, ok := tracer.(native.Tracer); ok {
+		tracer.CaptureTxStart(statedb, message.From(), message.To())
+	}
 	owner := common.Address{}
 	ret, gas, failed, err, _ := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()), owner)
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
+	if tracer, ok := tracer.(native.Tracer); ok {
+		tracer.CaptureTxEnd(statedb)
+	}
 	// Depending on the tracer type, format and return the output
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
@@ -652,6 +836,9 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
 		}, nil
 
+	case native.Tracer:
+		return tracer.GetResult()
+
 	case *tracers.Tracer:
 		return tracer.GetResult()
 
@@ -695,7 +882,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 					balanceFee = value
 				}
 			}
-			msg, err := tx.AsMessage(types.MakeSigner(api.config, block.Header().Number), balanceFee, block.Number())
+			msg, err := tx.AsMessage(types.MakeSigner(api.config, block.Header().Number), balanceFee, block.Number(), block.BaseFee())
 			if err != nil {
 				return nil, vm.Context{}, nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
 			}
@@ -36,15 +36,18 @@ import (
 	"github.com/XinFinOrg/XDC-Subnet/common"
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/engines/engine_v2"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/ethash"
 	"github.com/XinFinOrg/XDC-Subnet/contracts"
 	"github.com/XinFinOrg/XDC-Subnet/core"
 	"github.com/XinFinOrg/XDC-Subnet/core/bloombits"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
 
 	"github.com/XinFinOrg/XDC-Subnet/XDCx"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+	"github.com/XinFinOrg/XDC-Subnet/crypto/bls"
 	"github.com/XinFinOrg/XDC-Subnet/eth/downloader"
 	"github.com/XinFinOrg/XDC-Subnet/eth/gasprice"
 	"github.com/XinFinOrg/XDC-Subnet/ethdb"
@@ -119,7 +122,7 @@ func New(ctx *node.ServiceContext, config *Config, XDCXServ *XDCx.XDCX, lendingS
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
-	chainDb, err := CreateDB(ctx, config, "chaindata")
+	chainDb, err := ctx.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer)
 	if err != nil {
 		return nil, err
 	}
@@ -161,8 +164,16 @@ func New(ctx *node.ServiceContext, config *Config, XDCXServ *XDCx.XDCX, lendingS
 		core.WriteBlockChainVersion(chainDb, core.BlockChainVersion)
 	}
 	var (
-		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording, ParallelTxs: config.ParallelTxs}
+		cacheConfig = &core.CacheConfig{
+			Disabled:       config.NoPruning,
+			TrieNodeLimit:  config.TrieCache,
+			TrieTimeLimit:  config.TrieTimeout,
+			PruneEpochs:    config.StatePruneEpochs,
+			PruneBloomSize: config.StatePruneBloomSize,
+			SnapshotLimit:  config.SnapshotCache,
+		}
+		checkpointSync *engine_v2.Checkpoint
 	)
 	if eth.chainConfig.XDPoS != nil {
 		c := eth.engine.(*XDPoS.XDPoS)
@@ -172,6 +183,17 @@ func New(ctx *node.ServiceContext, config *Config, XDCXServ *XDCx.XDCX, lendingS
 		c.GetLendingService = func() utils.LendingService {
 			return eth.Lending
 		}
+		if config.CheckpointFile != "" {
+			checkpoint, err := engine_v2.LoadCheckpoint(config.CheckpointFile)
+			if err != nil {
+				return nil, fmt.Errorf("invalid checkpoint file %s: %v", config.CheckpointFile, err)
+			}
+			if err := c.EngineV2.SetCheckpoint(checkpoint); err != nil {
+				return nil, err
+			}
+		}
+		// the engine keeps the checkpoint once set, so the sync goes on from it after a restart
+		checkpointSync = c.EngineV2.TrustedCheckpoint()
 	}
 	eth.blockchain, err = core.NewBlockChainEx(chainDb, XDCXServ.GetLevelDB(), cacheConfig, eth.chainConfig, eth.engine, vmConfig)
 	if err != nil {
@@ -213,6 +235,14 @@ func New(ctx *node.ServiceContext, config *Config, XDCXServ *XDCx.XDCX, lendingS
 	if eth.protocolManager, err = NewProtocolManagerEx(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.orderPool, eth.lendingPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	if checkpointSync != nil {
+		eth.protocolManager.downloader.SetCheckpoint(&downloader.Checkpoint{
+			Number: checkpointSync.Number,
+			Hash:   checkpointSync.Hash,
+			Td:     checkpointSync.TotalDifficulty,
+			Epoch:  eth.chainConfig.XDPoS.Epoch,
+		})
+	}
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine, ctx.GetConfig().AnnounceTxs)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
 
@@ -302,6 +332,12 @@ func New(ctx *node.ServiceContext, config *Config, XDCXServ *XDCx.XDCX, lendingS
 			}
 			return c.IsAuthorisedAddress(eth.blockchain, header, address)
 		}
+		if chainConfig.XDPoS.V2 != nil && chainConfig.XDPoS.V2.Slashing != nil {
+			eth.txPool.VerifySlashing = func(statedb *state.StateDB, data []byte) error {
+				_, _, err := core.VerifySlashingEvidence(chainConfig, eth.blockchain, statedb, data)
+				return err
+			}
+		}
 
 	}
 	return eth, nil
@@ -514,6 +550,14 @@ func (s *Ethereum) StartStaking(local bool) error {
 			return fmt.Errorf("signer missing: %v", err)
 		}
 		XDPoS.Authorize(eb, wallet.SignHash)
+		if s.config.BLSKeyFile != "" {
+			blsKey, err := bls.LoadSecretKey(s.config.BLSKeyFile)
+			if err != nil {
+				log.Error("Cannot load BLS key", "file", s.config.BLSKeyFile, "err", err)
+				return fmt.Errorf("BLS key missing: %v", err)
+			}
+			XDPoS.AuthorizeBLS(blsKey)
+		}
 	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
@@ -1,12 +1,15 @@
 package bft
 
 import (
+	"errors"
+
 	"github.com/XinFinOrg/XDC-Subnet/consensus"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS"
 	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/utils"
 	"github.com/XinFinOrg/XDC-Subnet/core"
 	"github.com/XinFinOrg/XDC-Subnet/core/types"
 	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/XinFinOrg/XDC-Subnet/metrics"
 )
 
 const maxBlockDist = 7 // Maximum allowed backward distance from the chain head, 7 is just a magic number indicate very close block
@@ -19,6 +22,9 @@ type broadcastSyncInfoFn func(*types.SyncInfo)
 // chainHeightFn is a callback type to retrieve the current chain height.
 type chainHeightFn func() uint64
 
+// peerDropFn is a callback type for dropping a peer detected as malicious.
+type peerDropFn func(id string)
+
 type Bfter struct {
 	epoch uint64
 
@@ -28,6 +34,12 @@ type Bfter struct {
 	consensus        ConsensusFns
 	broadcast        BroadcastFns
 	chainHeight      chainHeightFn // Retrieves the current chain's height
+	dropPeer         peerDropFn    // Drops a peer for misbehaving
+
+	limiter   *peerLimiter // Rate limits and scores the peers sending consensus messages
+	votes     *roundFilter // Votes received in the recent rounds
+	timeouts  *roundFilter // Timeouts received in the recent rounds
+	syncInfos *roundFilter // SyncInfos received in the recent rounds
 }
 
 type ConsensusFns struct {
@@ -39,6 +51,8 @@ type ConsensusFns struct {
 
 	verifySyncInfo  func(consensus.ChainReader, *types.SyncInfo) (bool, error)
 	syncInfoHandler func(consensus.ChainReader, *types.SyncInfo) error
+
+	latestCommitted func() *types.BlockInfo
 }
 
 type BroadcastFns struct {
@@ -47,11 +61,17 @@ type BroadcastFns struct {
 	SyncInfo broadcastSyncInfoFn
 }
 
-func New(broadcasts BroadcastFns, blockChainReader *core.BlockChain, chainHeight chainHeightFn) *Bfter {
+func New(broadcasts BroadcastFns, blockChainReader *core.BlockChain, chainHeight chainHeightFn, dropPeer peerDropFn) *Bfter {
 	return &Bfter{
 		broadcast:        broadcasts,
 		blockChainReader: blockChainReader,
 		chainHeight:      chainHeight,
+		dropPeer:         dropPeer,
+
+		limiter:   newPeerLimiter(peerMessageRate, peerMessageBurst),
+		votes:     newRoundFilter(),
+		timeouts:  newRoundFilter(),
+		syncInfos: newRoundFilter(),
 
 		quit:        make(chan struct{}),
 		broadcastCh: make(chan interface{}),
@@ -74,15 +94,84 @@ func (b *Bfter) SetConsensusFuns(engine consensus.Engine) {
 		voteHandler:     e.EngineV2.VoteHandler,
 		timeoutHandler:  e.EngineV2.TimeoutHandler,
 		syncInfoHandler: e.EngineV2.SyncInfoHandler,
+
+		latestCommitted: e.EngineV2.GetLatestCommittedBlockInfo,
+	}
+}
+
+// UnregisterPeer forgets the rate limit and the score of a disconnected peer.
+func (b *Bfter) UnregisterPeer(peer string) {
+	b.limiter.remove(peer)
+}
+
+// allow checks the peer is within its rate limit, the message is dropped otherwise.
+func (b *Bfter) allow(peer string, dropMeter metrics.Meter) bool {
+	if b.limiter.allow(peer) {
+		return true
+	}
+	log.Debug("Discarded consensus message, peer over rate limit", "peer", peer)
+	dropMeter.Mark(1)
+	b.penalise(peer, rateLimitPenalty)
+	return false
+}
+
+// penalise lowers the score of the peer and drops it once the score is too low.
+func (b *Bfter) penalise(peer string, penalty int) {
+	if !b.limiter.penalise(peer, penalty) {
+		return
+	}
+	log.Warn("Dropping peer sending bad consensus messages", "peer", peer)
+	peerDropMeter.Mark(1)
+	b.limiter.remove(peer)
+	if b.dropPeer != nil {
+		b.dropPeer(peer)
+	}
+}
+
+// penaliseInvalid lowers the score of the peer for a message failing verification by its signatures only,
+// other failures may come from the local chain not synced yet.
+func (b *Bfter) penaliseInvalid(peer string, err error) {
+	var invalid *utils.ErrInvalidSignature
+	if errors.As(err, &invalid) {
+		b.penalise(peer, invalidMessagePenalty)
+	}
+}
+
+// penaliseStale lowers the score of the peer for a message too far away, only if it's more than dist blocks
+// behind the latest committed block. The chain head of a syncing node is behind, so it can't tell a message is
+// too far ahead or behind.
+func (b *Bfter) penaliseStale(peer string, number int64, dist int64) {
+	if b.consensus.latestCommitted == nil {
+		return
+	}
+	committed := b.consensus.latestCommitted()
+	if committed == nil || committed.Number == nil {
+		return
+	}
+	if number < committed.Number.Int64()-dist {
+		b.penalise(peer, staleMessagePenalty)
 	}
 }
 
 func (b *Bfter) Vote(peer string, vote *types.Vote) error {
 	log.Trace("Receive Vote", "hash", vote.Hash().Hex(), "voted block hash", vote.ProposedBlockInfo.Hash.Hex(), "number", vote.ProposedBlockInfo.Number, "round", vote.ProposedBlockInfo.Round)
+	voteInMeter.Mark(1)
+
+	if !b.allow(peer, voteDropRateMeter) {
+		return nil
+	}
 
 	voteBlockNum := vote.ProposedBlockInfo.Number.Int64()
 	if dist := voteBlockNum - int64(b.chainHeight()); dist < -maxBlockDist || dist > maxBlockDist {
 		log.Debug("Discarded propagated vote, too far away", "peer", peer, "number", voteBlockNum, "hash", vote.ProposedBlockInfo.Hash, "distance", dist)
+		voteDropStaleMeter.Mark(1)
+		b.penaliseStale(peer, voteBlockNum, maxBlockDist)
+		return nil
+	}
+
+	if !b.votes.add(vote.ProposedBlockInfo.Round, vote.Hash()) {
+		log.Trace("Discarded propagated vote, known in round", "peer", peer, "hash", vote.Hash(), "round", vote.ProposedBlockInfo.Round)
+		voteDropKnownMeter.Mark(1)
 		return nil
 	}
 
@@ -90,8 +179,11 @@ func (b *Bfter) Vote(peer string, vote *types.Vote) error {
 
 	if err != nil {
 		log.Error("Verify BFT Vote", "error", err)
+		voteDropInvalidMeter.Mark(1)
+		b.penaliseInvalid(peer, err)
 		return err
 	}
+	b.limiter.reward(peer)
 
 	b.broadcastCh <- vote
 
@@ -111,20 +203,36 @@ func (b *Bfter) Vote(peer string, vote *types.Vote) error {
 }
 func (b *Bfter) Timeout(peer string, timeout *types.Timeout) error {
 	log.Debug("Receive Timeout", "timeout", timeout)
+	timeoutInMeter.Mark(1)
+
+	if !b.allow(peer, timeoutDropRateMeter) {
+		return nil
+	}
 
 	gapNum := timeout.GapNumber
 
 	// dist times 3, ex: timeout message's gap number is based on block and find out it's epoch switch number, then mod 900 then minus 450
 	if dist := int64(gapNum) - int64(b.chainHeight()); dist < -int64(b.epoch)*3 || dist > int64(b.epoch)*3 {
 		log.Debug("Discarded propagated timeout, too far away", "peer", peer, "gapNumber", gapNum, "hash", timeout.Hash, "distance", dist)
+		timeoutDropStaleMeter.Mark(1)
+		b.penaliseStale(peer, int64(gapNum), int64(b.epoch)*3)
+		return nil
+	}
+
+	if !b.timeouts.add(timeout.Round, timeout.Hash()) {
+		log.Trace("Discarded propagated timeout, known in round", "peer", peer, "hash", timeout.Hash(), "round", timeout.Round)
+		timeoutDropKnownMeter.Mark(1)
 		return nil
 	}
 
 	verified, err := b.consensus.verifyTimeout(b.blockChainReader, timeout)
 	if err != nil {
 		log.Error("Verify BFT Timeout", "timeoutRound", timeout.Round, "timeoutGapNum", gapNum, "error", err)
+		timeoutDropInvalidMeter.Mark(1)
+		b.penaliseInvalid(peer, err)
 		return err
 	}
+	b.limiter.reward(peer)
 
 	b.broadcastCh <- timeout
 	if verified {
@@ -143,18 +251,34 @@ func (b *Bfter) Timeout(peer string, timeout *types.Timeout) error {
 }
 func (b *Bfter) SyncInfo(peer string, syncInfo *types.SyncInfo) error {
 	log.Debug("Receive SyncInfo", "syncInfo", syncInfo)
+	syncInfoInMeter.Mark(1)
+
+	if !b.allow(peer, syncInfoDropRateMeter) {
+		return nil
+	}
 
 	qcBlockNum := syncInfo.HighestQuorumCert.ProposedBlockInfo.Number.Int64()
 	if dist := qcBlockNum - int64(b.chainHeight()); dist < -maxBlockDist || dist > maxBlockDist {
 		log.Debug("Discarded propagated syncInfo, too far away", "peer", peer, "blockNum", qcBlockNum, "hash", syncInfo.Hash, "distance", dist)
+		syncInfoDropStaleMeter.Mark(1)
+		b.penaliseStale(peer, qcBlockNum, maxBlockDist)
+		return nil
+	}
+
+	if !b.syncInfos.add(syncInfo.HighestQuorumCert.ProposedBlockInfo.Round, syncInfo.Hash()) {
+		log.Trace("Discarded propagated syncInfo, known in round", "peer", peer, "hash", syncInfo.Hash(), "round", syncInfo.HighestQuorumCert.ProposedBlockInfo.Round)
+		syncInfoDropKnownMeter.Mark(1)
 		return nil
 	}
 
 	verified, err := b.consensus.verifySyncInfo(b.blockChainReader, syncInfo)
 	if err != nil {
 		log.Error("Verify BFT SyncInfo", "error", err)
+		syncInfoDropInvalidMeter.Mark(1)
+		b.penaliseInvalid(peer, err)
 		return err
 	}
+	b.limiter.reward(peer)
 
 	b.broadcastCh <- syncInfo
 	// Process only if verified and qualified
@@ -48,7 +48,7 @@ func newTester() *bfterTester {
 	}
 
 	tester := &bfterTester{}
-	tester.bfter = New(broadcasts, blockChain, chainHeight)
+	tester.bfter = New(broadcasts, blockChain, chainHeight, func(string) {})
 	tester.bfter.InitEpochNumber()
 	tester.bfter.SetConsensusFuns(testConsensus)
 	tester.bfter.broadcastCh = make(chan interface{})
@@ -393,3 +393,131 @@ func TestTooFarSyncInfo(t *testing.T) {
 		t.Fatalf("count mismatch: have %v on verify, have %v on handler, %v on broadcast, want %v", verifyCounter, handlerCounter, broadcastCounter, targetSyncInfo)
 	}
 }
+
+// Tests that the messages of a peer over its rate limit are dropped before verification
+func TestRateLimitedVotes(t *testing.T) {
+	tester := newTester()
+	verifyCounter := uint32(0)
+	burst := 5
+
+	tester.bfter.limiter = newPeerLimiter(0, float64(burst))
+	tester.bfter.consensus.verifyVote = func(chain consensus.ChainReader, vote *types.Vote) (bool, error) {
+		atomic.AddUint32(&verifyCounter, 1)
+		return false, nil
+	}
+	tester.bfter.broadcast.Vote = func(*types.Vote) {}
+
+	votes := makeVotes(2 * burst)
+	for _, vote := range votes {
+		if err := tester.bfter.Vote(peerID, &vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// other peers have their own bucket
+	if err := tester.bfter.Vote("def", &types.Vote{ProposedBlockInfo: &types.BlockInfo{Number: big.NewInt(1350)}, GapNumber: 450}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, uint32(burst+1), atomic.LoadUint32(&verifyCounter))
+	assert.Equal(t, -burst*rateLimitPenalty, tester.bfter.limiter.score(peerID))
+}
+
+// Tests that a message of a round already received from another peer is not verified twice
+func TestKnownVotesInRound(t *testing.T) {
+	tester := newTester()
+	verifyCounter := uint32(0)
+
+	tester.bfter.consensus.verifyVote = func(chain consensus.ChainReader, vote *types.Vote) (bool, error) {
+		atomic.AddUint32(&verifyCounter, 1)
+		return false, nil
+	}
+	tester.bfter.broadcast.Vote = func(*types.Vote) {}
+
+	votes := makeVotes(2)
+	for _, peer := range []string{peerID, "def"} {
+		for _, vote := range votes {
+			if err := tester.bfter.Vote(peer, &vote); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	assert.Equal(t, uint32(2), atomic.LoadUint32(&verifyCounter))
+}
+
+// Tests that a peer keeping sending invalid messages is dropped, and valid messages restore its score
+func TestDropPeerSendingInvalidTimeouts(t *testing.T) {
+	tester := newTester()
+	var dropped []string
+
+	invalid := true
+	tester.bfter.dropPeer = func(id string) { dropped = append(dropped, id) }
+	tester.bfter.consensus.verifyTimeout = func(consensus.ChainReader, *types.Timeout) (bool, error) {
+		if invalid {
+			return false, &utils.ErrInvalidSignature{Err: fmt.Errorf("This is invalid timeout")}
+		}
+		return false, nil
+	}
+	tester.bfter.broadcast.Timeout = func(*types.Timeout) {}
+
+	round := types.Round(1)
+	send := func(n int) {
+		for i := 0; i < n; i++ {
+			tester.bfter.Timeout(peerID, &types.Timeout{Round: round, GapNumber: 450})
+			round++
+		}
+	}
+	limit := -dropPeerScore / invalidMessagePenalty
+
+	send(limit - 1)
+	assert.Empty(t, dropped)
+
+	invalid = false
+	send(invalidMessagePenalty)
+	assert.Equal(t, -(limit-2)*invalidMessagePenalty, tester.bfter.limiter.score(peerID))
+
+	invalid = true
+	send(1)
+	assert.Empty(t, dropped)
+	send(1)
+	assert.Equal(t, []string{peerID}, dropped)
+	assert.Equal(t, 0, tester.bfter.limiter.score(peerID))
+}
+
+// Tests that only the messages proven invalid are penalised, not the ones a lagging node can't verify
+func TestPenaliseProvenInvalidMessagesOnly(t *testing.T) {
+	tester := newTester()
+	var committed *types.BlockInfo
+	tester.bfter.consensus.latestCommitted = func() *types.BlockInfo { return committed }
+	tester.bfter.consensus.verifyVote = func(consensus.ChainReader, *types.Vote) (bool, error) {
+		return false, fmt.Errorf("gap block not found")
+	}
+	tester.bfter.broadcast.Vote = func(*types.Vote) {}
+
+	vote := func(number int64, signature byte) *types.Vote {
+		return &types.Vote{
+			ProposedBlockInfo: &types.BlockInfo{Number: big.NewInt(number), Round: types.Round(signature)},
+			Signature:         []byte{signature},
+			GapNumber:         450,
+		}
+	}
+
+	// the local chain is behind, the messages ahead of it or failing verification are dropped silently
+	tester.bfter.Vote(peerID, vote(1400, 1))
+	tester.bfter.Vote(peerID, vote(1300, 2))
+	tester.bfter.Vote(peerID, vote(1351, 3))
+	assert.Equal(t, 0, tester.bfter.limiter.score(peerID))
+
+	// a message far behind the committed block is surely stale
+	committed = &types.BlockInfo{Number: big.NewInt(1349)}
+	tester.bfter.Vote(peerID, vote(1300, 4))
+	assert.Equal(t, -staleMessagePenalty, tester.bfter.limiter.score(peerID))
+	tester.bfter.Vote(peerID, vote(1400, 5))
+	assert.Equal(t, -staleMessagePenalty, tester.bfter.limiter.score(peerID))
+
+	// a message with an invalid signature is surely invalid
+	tester.bfter.consensus.verifyVote = func(consensus.ChainReader, *types.Vote) (bool, error) {
+		return false, &utils.ErrInvalidSignature{Err: fmt.Errorf("invalid signature length")}
+	}
+	tester.bfter.Vote(peerID, vote(1351, 6))
+	assert.Equal(t, -staleMessagePenalty-invalidMessagePenalty, tester.bfter.limiter.score(peerID))
+}
@@ -0,0 +1,157 @@
+package bft
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+)
+
+const (
+	peerMessageRate  = 100 // Consensus messages a peer can send per second on average
+	peerMessageBurst = 500 // Consensus messages a peer can send at once, enough for a round of votes of all masternodes
+
+	rateLimitPenalty      = 1    // Score lost by a peer for a message over its rate limit
+	staleMessagePenalty   = 1    // Score lost by a peer for a message far behind the latest committed block
+	invalidMessagePenalty = 10   // Score lost by a peer for a message with an invalid signature
+	dropPeerScore         = -100 // Score at which a peer is disconnected
+
+	maxFilterRounds        = 16   // Number of recent rounds whose messages are remembered
+	maxFilterRoundMessages = 4096 // Number of messages remembered per round
+)
+
+// peerLimit is the token bucket and the score of a peer.
+type peerLimit struct {
+	tokens  float64
+	updated time.Time
+	score   int
+}
+
+// peerLimiter rate limits the consensus messages of each peer with a token bucket,
+// and scores the peers by the messages they send. A valid message restores the
+// score of a peer by one, up to zero, so only the peers that keep sending bad
+// messages are dropped.
+type peerLimiter struct {
+	rate  float64 // Tokens refilled per second
+	burst float64 // Maximum tokens of a bucket
+
+	peers map[string]*peerLimit
+	lock  sync.Mutex
+}
+
+func newPeerLimiter(rate float64, burst float64) *peerLimiter {
+	return &peerLimiter{
+		rate:  rate,
+		burst: burst,
+		peers: make(map[string]*peerLimit),
+	}
+}
+
+func (l *peerLimiter) peer(id string, now time.Time) *peerLimit {
+	p, ok := l.peers[id]
+	if !ok {
+		p = &peerLimit{tokens: l.burst, updated: now}
+		l.peers[id] = p
+	}
+	return p
+}
+
+// allow takes a token from the bucket of the peer, it reports false if the bucket is empty.
+func (l *peerLimiter) allow(id string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	p := l.peer(id, now)
+	p.tokens = math.Min(l.burst, p.tokens+now.Sub(p.updated).Seconds()*l.rate)
+	p.updated = now
+	if p.tokens < 1 {
+		return false
+	}
+	p.tokens--
+	return true
+}
+
+// penalise lowers the score of the peer, it reports true once the peer has to be dropped.
+func (l *peerLimiter) penalise(id string, penalty int) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	p := l.peer(id, time.Now())
+	p.score -= penalty
+	return p.score <= dropPeerScore
+}
+
+// reward restores the score of the peer by one, up to zero.
+func (l *peerLimiter) reward(id string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if p, ok := l.peers[id]; ok && p.score < 0 {
+		p.score++
+	}
+}
+
+// score returns the current score of the peer.
+func (l *peerLimiter) score(id string) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if p, ok := l.peers[id]; ok {
+		return p.score
+	}
+	return 0
+}
+
+// remove forgets the bucket and the score of a disconnected peer.
+func (l *peerLimiter) remove(id string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.peers, id)
+}
+
+// roundFilter remembers the messages of the recent rounds, so the duplicated ones
+// relayed by different peers are dropped before their signatures are recovered.
+type roundFilter struct {
+	rounds map[types.Round]map[common.Hash]struct{}
+	lock   sync.Mutex
+}
+
+func newRoundFilter() *roundFilter {
+	return &roundFilter{rounds: make(map[types.Round]map[common.Hash]struct{})}
+}
+
+// add remembers the message of the round, it reports false if the message is already known.
+func (f *roundFilter) add(round types.Round, hash common.Hash) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	messages, ok := f.rounds[round]
+	if !ok {
+		// forget the lowest round, messages of old rounds are dropped as stale anyway
+		if len(f.rounds) >= maxFilterRounds {
+			lowest := round
+			for r := range f.rounds {
+				if r < lowest {
+					lowest = r
+				}
+			}
+			if lowest == round {
+				return true
+			}
+			delete(f.rounds, lowest)
+		}
+		messages = make(map[common.Hash]struct{})
+		f.rounds[round] = messages
+	}
+	if _, ok := messages[hash]; ok {
+		return false
+	}
+	if len(messages) < maxFilterRoundMessages {
+		messages[hash] = struct{}{}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+// Contains the metrics collected by the bft handler.
+
+package bft
+
+import (
+	"github.com/XinFinOrg/XDC-Subnet/metrics"
+)
+
+var (
+	voteInMeter          = metrics.NewRegisteredMeter("eth/bft/votes/in", nil)
+	voteDropRateMeter    = metrics.NewRegisteredMeter("eth/bft/votes/drop/rate", nil)
+	voteDropStaleMeter   = metrics.NewRegisteredMeter("eth/bft/votes/drop/stale", nil)
+	voteDropKnownMeter   = metrics.NewRegisteredMeter("eth/bft/votes/drop/known", nil)
+	voteDropInvalidMeter = metrics.NewRegisteredMeter("eth/bft/votes/drop/invalid", nil)
+
+	timeoutInMeter          = metrics.NewRegisteredMeter("eth/bft/timeouts/in", nil)
+	timeoutDropRateMeter    = metrics.NewRegisteredMeter("eth/bft/timeouts/drop/rate", nil)
+	timeoutDropStaleMeter   = metrics.NewRegisteredMeter("eth/bft/timeouts/drop/stale", nil)
+	timeoutDropKnownMeter   = metrics.NewRegisteredMeter("eth/bft/timeouts/drop/known", nil)
+	timeoutDropInvalidMeter = metrics.NewRegisteredMeter("eth/bft/timeouts/drop/invalid", nil)
+
+	syncInfoInMeter          = metrics.NewRegisteredMeter("eth/bft/syncinfos/in", nil)
+	syncInfoDropRateMeter    = metrics.NewRegisteredMeter("eth/bft/syncinfos/drop/rate", nil)
+	syncInfoDropStaleMeter   = metrics.NewRegisteredMeter("eth/bft/syncinfos/drop/stale", nil)
+	syncInfoDropKnownMeter   = metrics.NewRegisteredMeter("eth/bft/syncinfos/drop/known", nil)
+	syncInfoDropInvalidMeter = metrics.NewRegisteredMeter("eth/bft/syncinfos/drop/invalid", nil)
+
+	peerDropMeter = metrics.NewRegisteredMeter("eth/bft/peers/drop", nil)
+)
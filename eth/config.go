@@ -83,6 +83,14 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// State pruning options, the stale states are pruned in the background if
+	// StatePruneEpochs is set
+	StatePruneEpochs    uint64 `toml:",omitempty"` // Number of finalized epochs of states to keep
+	StatePruneBloomSize uint64 `toml:",omitempty"` // Size of the bloom filter of the live states in megabytes
+
+	// Trusted XDPoS v2 checkpoint to fast sync from, as returned by XDPoS_getCheckpoint
+	CheckpointFile string `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
@@ -91,14 +99,17 @@ type Config struct {
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string
 	TrieCache          int
 	TrieTimeout        time.Duration
+	SnapshotCache      int `toml:",omitempty"` // Megabytes of memory for the state snapshot entries (0 = snapshot disabled)
 
 	// Mining-related options
 	Etherbase    common.Address `toml:",omitempty"`
 	MinerThreads int            `toml:",omitempty"`
 	ExtraData    []byte         `toml:",omitempty"`
 	GasPrice     *big.Int
+	BLSKeyFile   string `toml:",omitempty"` // File of the BLS secret key signing votes and timeouts after the BLS fork
 
 	// Ethash options
 	Ethash ethash.Config
@@ -112,6 +123,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Number of goroutines executing the transactions of a block in parallel (0 = sequential)
+	ParallelTxs int `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"math/big"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+)
+
+// Checkpoint is a trusted XDPoS v2 epoch switch header a fast sync can start
+// from instead of the genesis block. The consensus engine verifies the headers
+// after it by their QCs, and the state is only synced at the pivot block.
+type Checkpoint struct {
+	Number uint64      // Number of the epoch switch block
+	Hash   common.Hash // Hash of the epoch switch block
+	Td     *big.Int    // Total difficulty of the epoch switch block
+	Epoch  uint64      // Number of blocks per epoch, headers are imported epoch by epoch
+}
+
+// SetCheckpoint sets the trusted checkpoint to fast sync from, it must be called
+// before any synchronisation starts.
+func (d *Downloader) SetCheckpoint(checkpoint *Checkpoint) {
+	d.checkpoint = checkpoint
+}
+
+// checkpointSync reports whether the fast sync has to start from the checkpoint,
+// as the local chain doesn't reach it yet.
+func (d *Downloader) checkpointSync() bool {
+	return d.mode == FastSync && d.checkpoint != nil && d.lightchain.CurrentHeader().Number.Uint64() < d.checkpoint.Number
+}
+
+// importCheckpoint retrieves the checkpoint header from the remote peer and
+// writes it as the local head header, the headers before it are never synced.
+func (d *Downloader) importCheckpoint(p *peerConnection) error {
+	header, err := d.fetchHeight(p, d.checkpoint.Hash)
+	if err != nil {
+		return err
+	}
+	if header.Hash() != d.checkpoint.Hash || header.Number.Uint64() != d.checkpoint.Number {
+		p.log.Warn("Invalid checkpoint header", "number", header.Number, "hash", header.Hash(), "checkpoint", d.checkpoint.Hash)
+		return errBadPeer
+	}
+	if err := d.blockchain.WriteCheckpointHeader(header, d.checkpoint.Td); err != nil {
+		return err
+	}
+	log.Info("Fast sync from trusted checkpoint", "number", d.checkpoint.Number, "hash", d.checkpoint.Hash)
+	return nil
+}
+
+// checkpointOrigin moves the sync origin and the pivot after the checkpoint, as
+// neither the headers nor the state before it are available locally.
+func (d *Downloader) checkpointOrigin(origin uint64, pivot uint64) (uint64, uint64) {
+	if d.checkpoint == nil || origin >= d.checkpoint.Number || !d.lightchain.HasHeader(d.checkpoint.Hash, d.checkpoint.Number) {
+		return origin, pivot
+	}
+	origin = d.checkpoint.Number
+	if d.mode == FastSync {
+		pivot = d.checkpointPivot(pivot)
+	}
+	return origin, pivot
+}
+
+// checkpointPivot moves the pivot block after the checkpoint.
+func (d *Downloader) checkpointPivot(pivot uint64) uint64 {
+	if d.checkpoint != nil && pivot <= d.checkpoint.Number {
+		return d.checkpoint.Number + 1
+	}
+	return pivot
+}
+
+// checkpointLimit ends a chunk of headers to import before the next epoch switch
+// header. Its masternodes are verified against the candidates recorded in the
+// epoch before, which the consensus engine reads from the imported headers.
+func (d *Downloader) checkpointLimit(headers []*types.Header, limit int) int {
+	if d.checkpoint == nil || d.checkpoint.Epoch == 0 {
+		return limit
+	}
+	for i := 1; i < limit; i++ {
+		if headers[i].Number.Uint64()%d.checkpoint.Epoch == 0 {
+			return i
+		}
+	}
+	return limit
+}
@@ -115,6 +115,7 @@ type Downloader struct {
 
 	lightchain LightChain
 	blockchain BlockChain
+	checkpoint *Checkpoint // Trusted checkpoint to fast sync from instead of the genesis block
 
 	// Callbacks
 	dropPeer            peerDropFn            // Drops a peer for misbehaving
@@ -195,6 +196,9 @@ type BlockChain interface {
 	// FastSyncCommitHead directly commits the head block to a certain entity.
 	FastSyncCommitHead(common.Hash) error
 
+	// WriteCheckpointHeader writes a trusted header without its ancestors as the head header.
+	WriteCheckpointHeader(*types.Header, *big.Int) error
+
 	// InsertChain inserts a batch of blocks into the local chain.
 	InsertChain(types.Blocks) (int, error)
 
@@ -433,9 +437,22 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	height := latest.Number.Uint64()
 
-	origin, err := d.findAncestor(p, height)
-	if err != nil {
-		return err
+	var origin uint64
+	if d.checkpointSync() {
+		// The local chain is empty, start from the trusted checkpoint instead of a common ancestor
+		if height <= d.checkpoint.Number {
+			p.log.Debug("Remote head before checkpoint", "number", height, "checkpoint", d.checkpoint.Number)
+			return nil
+		}
+		if err := d.importCheckpoint(p); err != nil {
+			return err
+		}
+		origin = d.checkpoint.Number
+	} else {
+		origin, err = d.findAncestor(p, height)
+		if err != nil {
+			return err
+		}
 	}
 	d.syncStatsLock.Lock()
 	if d.syncStatsChainHeight <= origin || d.syncStatsChainOrigin > origin {
@@ -456,6 +473,10 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 			}
 		}
 	}
+	origin, pivot = d.checkpointOrigin(origin, pivot)
+	if d.checkpoint != nil && origin >= height {
+		return nil
+	}
 	d.committed = 1
 	if d.mode == FastSync && pivot != 0 {
 		d.committed = 0
@@ -1255,6 +1276,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				if limit > len(headers) {
 					limit = len(headers)
 				}
+				limit = d.checkpointLimit(headers, limit)
 				chunk := headers[:limit]
 
 				// In case of header only syncing, validate the chunk immediately
@@ -1426,6 +1448,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 	if height := latest.Number.Uint64(); height > uint64(fsMinFullBlocks) {
 		pivot = height - uint64(fsMinFullBlocks)
 	}
+	pivot = d.checkpointPivot(pivot)
 	// To cater for moving pivot points, track the pivot block and subsequently
 	// accumulated download results separatey.
 	var (
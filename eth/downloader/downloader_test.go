@@ -64,6 +64,8 @@ type downloadTester struct {
 	ownReceipts map[common.Hash]types.Receipts // Receipts belonging to the tester
 	ownChainTd  map[common.Hash]*big.Int       // Total difficulties of the blocks in the local chain
 
+	ownCheckpoint common.Hash // Trusted header imported without its ancestors
+
 	peerHashes   map[string][]common.Hash                  // Hash chain belonging to different test peers
 	peerHeaders  map[string]map[common.Hash]*types.Header  // Headers belonging to different test peers
 	peerBlocks   map[string]map[common.Hash]*types.Block   // Blocks belonging to different test peers
@@ -363,7 +365,7 @@ func (dl *downloadTester) InsertReceiptChain(blocks types.Blocks, receipts []typ
 		if _, ok := dl.ownHeaders[blocks[i].Hash()]; !ok {
 			return i, errors.New("unknown owner")
 		}
-		if _, ok := dl.ownBlocks[blocks[i].ParentHash()]; !ok {
+		if _, ok := dl.ownBlocks[blocks[i].ParentHash()]; !ok && blocks[i].ParentHash() != dl.ownCheckpoint {
 			return i, errors.New("unknown parent")
 		}
 		dl.ownBlocks[blocks[i].Hash()] = blocks[i]
@@ -372,6 +374,22 @@ func (dl *downloadTester) InsertReceiptChain(blocks types.Blocks, receipts []typ
 	return len(blocks), nil
 }
 
+// WriteCheckpointHeader injects a trusted header without its ancestors into the
+// simulated chain.
+func (dl *downloadTester) WriteCheckpointHeader(header *types.Header, td *big.Int) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.ownHeaders[dl.ownHashes[len(dl.ownHashes)-1]].Number.Uint64() >= header.Number.Uint64() {
+		return errors.New("local chain beyond checkpoint")
+	}
+	dl.ownHashes = append(dl.ownHashes, header.Hash())
+	dl.ownHeaders[header.Hash()] = header
+	dl.ownChainTd[header.Hash()] = td
+	dl.ownCheckpoint = header.Hash()
+	return nil
+}
+
 // Rollback removes some recently added elements from the chain.
 func (dl *downloadTester) Rollback(hashes []common.Hash) {
 	dl.lock.Lock()
@@ -697,6 +715,53 @@ func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	assertOwnChain(t, tester, targetBlocks+1)
 }
 
+// Tests that a fast sync from a trusted checkpoint imports the chain after it
+// without retrieving any of the headers before it.
+func TestCheckpointSynchronisation63(t *testing.T) { testCheckpointSynchronisation(t, 63) }
+func TestCheckpointSynchronisation64(t *testing.T) { testCheckpointSynchronisation(t, 64) }
+
+func testCheckpointSynchronisation(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := 4 * MaxHeaderFetch
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+
+	// Start from an epoch switch block in the middle of the chain, hashes are ordered head to genesis
+	number := 2 * MaxHeaderFetch
+	hash := hashes[targetBlocks-number]
+	tester.downloader.SetCheckpoint(&Checkpoint{
+		Number: uint64(number),
+		Hash:   hash,
+		Td:     tester.peerChainTds["peer"][hash],
+		Epoch:  uint64(MaxHeaderFetch / 4),
+	})
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	for i := 1; i < number; i++ {
+		if tester.HasHeader(hashes[targetBlocks-i], uint64(i)) {
+			t.Fatalf("header %d before checkpoint retrieved", i)
+		}
+	}
+	if head := tester.CurrentHeader().Number.Int64(); head != int64(targetBlocks) {
+		t.Fatalf("head header mismatch: have %v, want %v", head, targetBlocks)
+	}
+	if head := tester.CurrentBlock().NumberU64(); head != uint64(targetBlocks) {
+		t.Fatalf("head block mismatch: have %v, want %v", head, targetBlocks)
+	}
+	if td := tester.GetTd(hashes[0], uint64(targetBlocks)); td.Cmp(tester.peerChainTds["peer"][hashes[0]]) != 0 {
+		t.Fatalf("head td mismatch: have %v, want %v", td, tester.peerChainTds["peer"][hashes[0]])
+	}
+	// Syncing again from the same peer must not import the checkpoint twice
+	if err := tester.sync("peer", nil, FastSync); err != nil {
+		t.Fatalf("failed to resynchronise: %v", err)
+	}
+}
+
 // Tests that if a large batch of blocks are being downloaded, it is throttled
 // until the cached blocks are retrieved.
 func TestThrottling62(t *testing.T)     { testThrottling(t, 62, FullSync) }
@@ -0,0 +1,228 @@
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/misc"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/rpc"
+)
+
+const (
+	// maxFeeHistory is the maximum number of blocks that can be retrieved for a
+	// fee history request.
+	maxFeeHistory = 1024
+	// maxBlockFetchers is the max number of goroutines to spin up to pull blocks
+	// for the fee history calculation.
+	maxBlockFetchers = 4
+)
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// blockFees represents a single block for processing
+type blockFees struct {
+	number  uint64
+	block   *types.Block
+	header  *types.Header
+	results processedFees
+	err     error
+}
+
+// processedFees contains the results of a processed block
+type processedFees struct {
+	reward       []*big.Int
+	baseFee      *big.Int
+	nextBaseFee  *big.Int
+	gasUsedRatio float64
+}
+
+// txGasAndReward is sorted in ascending order based on reward
+type txGasAndReward struct {
+	gasUsed uint64
+	reward  *big.Int
+}
+
+type sortGasAndReward []txGasAndReward
+
+func (s sortGasAndReward) Len() int           { return len(s) }
+func (s sortGasAndReward) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortGasAndReward) Less(i, j int) bool { return s[i].reward.Cmp(s[j].reward) < 0 }
+
+// processBlock takes a blockFees structure with the blockNumber, the header and optionally
+// the block field filled in, retrieves the block from the backend if not present yet and
+// fills in the rest of the fields.
+func (gpo *Oracle) processBlock(ctx context.Context, bf *blockFees, percentiles []float64) {
+	config := gpo.backend.ChainConfig()
+	if bf.results.baseFee = bf.header.BaseFee; bf.results.baseFee == nil {
+		bf.results.baseFee = new(big.Int)
+	}
+	if config.IsEIP1559(new(big.Int).Add(bf.header.Number, big.NewInt(1))) {
+		bf.results.nextBaseFee = misc.CalcBaseFee(config, bf.header)
+	} else {
+		bf.results.nextBaseFee = new(big.Int)
+	}
+	bf.results.gasUsedRatio = float64(bf.header.GasUsed) / float64(bf.header.GasLimit)
+	if len(percentiles) == 0 {
+		// rewards were not requested, return null
+		return
+	}
+	if bf.block == nil {
+		bf.block, bf.err = gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(bf.number))
+		if bf.block == nil || bf.err != nil {
+			return
+		}
+	}
+	bf.results.reward = make([]*big.Int, len(percentiles))
+	if len(bf.block.Transactions()) == 0 {
+		// return an all zero row if there are no transactions to gather data from
+		for i := range bf.results.reward {
+			bf.results.reward[i] = new(big.Int)
+		}
+		return
+	}
+	receipts, err := gpo.backend.GetReceipts(ctx, bf.block.Hash())
+	if err != nil {
+		bf.err = err
+		return
+	}
+	if len(receipts) != len(bf.block.Transactions()) {
+		bf.err = fmt.Errorf("receipts of block %d do not match its transactions", bf.number)
+		return
+	}
+	sorter := make(sortGasAndReward, len(bf.block.Transactions()))
+	for i, tx := range bf.block.Transactions() {
+		reward := tx.EffectiveGasTipValue(bf.block.BaseFee())
+		sorter[i] = txGasAndReward{gasUsed: receipts[i].GasUsed, reward: reward}
+	}
+	sort.Stable(sorter)
+
+	var txIndex int
+	sumGasUsed := sorter[0].gasUsed
+
+	for i, p := range percentiles {
+		thresholdGasUsed := uint64(float64(bf.block.GasUsed()) * p / 100)
+		for sumGasUsed < thresholdGasUsed && txIndex < len(bf.block.Transactions())-1 {
+			txIndex++
+			sumGasUsed += sorter[txIndex].gasUsed
+		}
+		bf.results.reward[i] = sorter[txIndex].reward
+	}
+}
+
+// resolveBlockRange resolves the specified block range to absolute block numbers while also
+// enforcing backend specific limitations. It returns the header of the last block, the last
+// block number and the number of blocks in the range.
+func (gpo *Oracle) resolveBlockRange(ctx context.Context, lastBlock rpc.BlockNumber, blocks int) (*types.Header, uint64, int, error) {
+	var (
+		headBlock *types.Header
+		header    *types.Header
+		err       error
+	)
+	// Get the chain's current head.
+	if headBlock, err = gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber); err != nil {
+		return nil, 0, 0, err
+	}
+	head := rpc.BlockNumber(headBlock.Number.Uint64())
+
+	// Fail if request block is beyond the chain's current head.
+	if head < lastBlock {
+		return nil, 0, 0, fmt.Errorf("%w: requested %d, head %d", errRequestBeyondHead, lastBlock, head)
+	}
+	// Resolve the latest and pending tags to the head, the pending block of a subnet is not exposed.
+	if lastBlock < 0 {
+		lastBlock = head
+	}
+	if header, err = gpo.backend.HeaderByNumber(ctx, lastBlock); header == nil || err != nil {
+		return nil, 0, 0, err
+	}
+	// Ensure not trying to retrieve before genesis.
+	if uint64(lastBlock+1) < uint64(blocks) {
+		blocks = int(lastBlock + 1)
+	}
+	return header, uint64(lastBlock), blocks, nil
+}
+
+// FeeHistory returns data relevant for fee estimation based on the specified range of blocks.
+// The range can be specified either with absolute block numbers or ending with the latest
+// block.
+//
+// FeeHistory returns the oldest block of the range, the rewards paid at the given
+// percentiles of the gas used by each block, the base fee of each block and of the
+// block following the range, and the gas used ratio of each block. The base fee is
+// zero before the EIP1559 fork, the whole gas price is rewarded to the miner.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, unresolvedLastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
+	if blocks < 1 {
+		return common.Big0, nil, nil, nil, nil // returning with no data and no error means there are no retrievable blocks
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%w: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < rewardPercentiles[i-1] {
+			return common.Big0, nil, nil, nil, fmt.Errorf("%w: #%d:%f > #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
+		}
+	}
+	lastHeader, lastBlock, blocks, err := gpo.resolveBlockRange(ctx, unresolvedLastBlock, blocks)
+	if err != nil || blocks == 0 {
+		return common.Big0, nil, nil, nil, err
+	}
+	oldestBlock := lastBlock + 1 - uint64(blocks)
+
+	var (
+		reward       = make([][]*big.Int, blocks)
+		baseFee      = make([]*big.Int, blocks+1)
+		gasUsedRatio = make([]float64, blocks)
+		next         = oldestBlock
+		results      = make(chan *blockFees, blocks)
+	)
+	fetchers := maxBlockFetchers
+	if blocks < fetchers {
+		fetchers = blocks
+	}
+	jobs := make(chan uint64, blocks)
+	for ; next <= lastBlock; next++ {
+		jobs <- next
+	}
+	close(jobs)
+	for i := 0; i < fetchers; i++ {
+		go func() {
+			for blockNumber := range jobs {
+				fees := &blockFees{number: blockNumber}
+				if blockNumber == lastBlock {
+					fees.header = lastHeader
+				} else {
+					fees.header, fees.err = gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(blockNumber))
+				}
+				if fees.header != nil && fees.err == nil {
+					gpo.processBlock(ctx, fees, rewardPercentiles)
+				} else if fees.err == nil {
+					fees.err = fmt.Errorf("header of block %d not found", blockNumber)
+				}
+				results <- fees
+			}
+		}()
+	}
+	for i := 0; i < blocks; i++ {
+		fees := <-results
+		if fees.err != nil {
+			return common.Big0, nil, nil, nil, fees.err
+		}
+		i := int(fees.number - oldestBlock)
+		reward[i], baseFee[i], baseFee[i+1], gasUsedRatio[i] = fees.results.reward, fees.results.baseFee, fees.results.nextBaseFee, fees.results.gasUsedRatio
+	}
+	if len(rewardPercentiles) == 0 {
+		reward = nil
+	}
+	return new(big.Int).SetUint64(oldestBlock), reward, baseFee, gasUsedRatio, nil
+}
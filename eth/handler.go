@@ -254,7 +254,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		Timeout:  manager.BroadcastTimeout,
 		SyncInfo: manager.BroadcastSyncInfo,
 	}
-	manager.bft = bft.New(broadcasts, blockchain, heighter)
+	manager.bft = bft.New(broadcasts, blockchain, heighter, manager.removePeer)
 	if blockchain.Config().XDPoS != nil {
 		manager.bft.InitEpochNumber()
 		manager.bft.SetConsensusFuns(engine)
@@ -279,6 +279,7 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 	// Unregister the peer from the downloader and Ethereum peer set
 	pm.downloader.UnregisterPeer(id)
+	pm.bft.UnregisterPeer(id)
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Debug("Peer removal failed", "peer", id, "err", err)
 	}
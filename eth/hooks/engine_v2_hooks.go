@@ -54,6 +54,13 @@ func AttachConsensusV2Hooks(adaptor *XDPoS.XDPoS, bc *core.BlockChain, chainConf
 			}
 		}
 
+		// the penalty thresholds of the config only apply from their block on
+		penaltyConfig := config.PenaltyAt(number)
+		minBlocksPerEpoch := common.MinimunMinerBlockPerEpoch
+		if penaltyConfig != nil && penaltyConfig.MinBlocksPerEpoch > 0 {
+			minBlocksPerEpoch = penaltyConfig.MinBlocksPerEpoch
+		}
+
 		penalties := []common.Address{}
 		prevPenalties := []common.Address{}
 		// get list block hash & stats total created block
@@ -83,8 +90,8 @@ func AttachConsensusV2Hooks(adaptor *XDPoS.XDPoS, bc *core.BlockChain, chainConf
 						log.Info("[HookPenalty] Find a node do not create any block", "addr", addr.Hex())
 						penalties = append(penalties, addr)
 					} else {
-						if total < common.MinimunMinerBlockPerEpoch {
-							log.Info("[HookPenalty] Find a node does not create enough block", "addr", addr.Hex(), "total", total, "require", common.MinimunMinerBlockPerEpoch)
+						if total < minBlocksPerEpoch {
+							log.Info("[HookPenalty] Find a node does not create enough block", "addr", addr.Hex(), "total", total, "require", minBlocksPerEpoch)
 							penalties = append(penalties, addr)
 						}
 					}
@@ -105,6 +112,20 @@ func AttachConsensusV2Hooks(adaptor *XDPoS.XDPoS, bc *core.BlockChain, chainConf
 		// Add previous penalty
 		penalties = append(penalties, prevPenalties...)
 
+		// Masternodes failing the participation thresholds are jailed, signing txs can't bring them back
+		jailed := map[common.Address]bool{}
+		if penaltyConfig != nil && (penaltyConfig.MinSignaturePercent > 0 || penaltyConfig.MaxMissedRounds > 0) {
+			jailedMasternodes, err := GetJailedMasternodes(adaptor, chain, chain.GetHeader(currentHash, number.Uint64()-1), penaltyConfig)
+			if err != nil {
+				log.Error("[HookPenalty] Fail to get jailed masternodes", "err", err)
+				return []common.Address{}, err
+			}
+			for _, addr := range jailedMasternodes {
+				jailed[addr] = true
+			}
+			penalties = append(penalties, jailedMasternodes...)
+		}
+
 		// Loop for each block to check signing tx, tx signer can be removed from penalty
 		comebacks := map[common.Address]bool{}
 		mapBlockHash := map[common.Hash]bool{}
@@ -142,12 +163,28 @@ func AttachConsensusV2Hooks(adaptor *XDPoS.XDPoS, bc *core.BlockChain, chainConf
 		mapForDedup := map[common.Address]bool{}
 		penaltiesDedup := []common.Address{}
 		for _, p := range penalties {
-			if !mapForDedup[p] && !comebacks[p] {
+			if !mapForDedup[p] && (!comebacks[p] || jailed[p]) {
 				penaltiesDedup = append(penaltiesDedup, p)
 				mapForDedup[p] = true
 				log.Info("[HookPenalty] Final penalty contains", "addr", p)
 			}
 		}
+		// jailed masternodes are penalized until the jail ends, signing txs can't bring them back
+		if config.V2.IsSlashing(number) && config.V2.Slashing.Policy == params.SlashingPolicyJail {
+			parentHeader := chain.GetHeader(currentHash, number.Uint64()-1)
+			statedb, err := bc.StateAt(parentHeader.Root)
+			if err != nil {
+				log.Error("[HookPenalty] Fail to get state for jailed masternodes", "number", parentHeader.Number, "err", err)
+				return []common.Address{}, err
+			}
+			for _, candidate := range candidates {
+				if !mapForDedup[candidate] && state.GetJailedUntil(statedb, candidate) > number.Uint64() {
+					penaltiesDedup = append(penaltiesDedup, candidate)
+					mapForDedup[candidate] = true
+					log.Info("[HookPenalty] Final penalty contains jailed masternode", "addr", candidate)
+				}
+			}
+		}
 		// sort it to ensure same order for all nodes
 		sort.Slice(penaltiesDedup, func(i, j int) bool {
 			return penaltiesDedup[i].Hex() < penaltiesDedup[j].Hex()
@@ -185,6 +222,15 @@ func AttachConsensusV2Hooks(adaptor *XDPoS.XDPoS, bc *core.BlockChain, chainConf
 			return nil, err
 		}
 		rewards["signers"] = signers
+		// The base fees collected in the pool since the last epoch switch are shared as the block reward
+		if chain.Config().XDPoS.BaseFeeDestination == params.BaseFeeDestinationReward && len(signers) > 0 {
+			pool := common.HexToAddress(common.BaseFeePoolAddr)
+			if baseFees := new(big.Int).Set(stateBlock.GetBalance(pool)); baseFees.Sign() > 0 {
+				stateBlock.SubBalance(pool, baseFees)
+				chainReward = new(big.Int).Add(chainReward, baseFees)
+				rewards["baseFees"] = baseFees
+			}
+		}
 		rewardSigners, err := contracts.CalculateRewardForSigner(chainReward, signers, *totalSigner)
 		if err != nil {
 			log.Error("[HookReward] Fail to calculate reward for signers", "error", err)
@@ -211,6 +257,105 @@ func AttachConsensusV2Hooks(adaptor *XDPoS.XDPoS, bc *core.BlockChain, chainConf
 		log.Debug("Time Calculated HookReward ", "block", header.Number.Uint64(), "time", common.PrettyDuration(time.Since(start)))
 		return rewards, nil
 	}
+
+	// Hook reads the consensus config voted in the config contract at the gap block
+	adaptor.EngineV2.HookEpochConfig = func(chain consensus.ChainReader, header *types.Header) (*types.EpochConfig, error) {
+		statedb, err := bc.StateAt(header.Root)
+		if err != nil {
+			log.Error("[HookEpochConfig] Fail to get state at gap block", "number", header.Number, "err", err)
+			return nil, err
+		}
+		return state.GetEpochConfig(statedb), nil
+	}
+
+	// Hook proves the BLS keys registered by the masternodes in the state of the gap block
+	adaptor.EngineV2.HookBLSKeysProof = func(chain consensus.ChainReader, header *types.Header, masternodes []common.Address) (*types.BLSKeysProof, error) {
+		statedb, err := bc.StateAt(header.Root)
+		if err != nil {
+			log.Error("[HookBLSKeysProof] Fail to get state at gap block", "number", header.Number, "err", err)
+			return nil, err
+		}
+		accountProof, storageProof, err := state.GetBLSPublicKeysProof(statedb, masternodes)
+		if err != nil {
+			return nil, err
+		}
+		proof := &types.BLSKeysProof{GapHeader: header}
+		for _, node := range accountProof {
+			proof.AccountProof = append(proof.AccountProof, node)
+		}
+		for _, node := range storageProof {
+			proof.StorageProof = append(proof.StorageProof, node)
+		}
+		return proof, nil
+	}
+}
+
+// GetJailedMasternodes returns the masternodes failing the participation thresholds in any of the last JailEpochs epochs,
+// the latest one is evaluated from its epoch switch block up to header
+func GetJailedMasternodes(c *XDPoS.XDPoS, chain consensus.ChainReader, header *types.Header, penaltyConfig *params.PenaltyConfig) ([]common.Address, error) {
+	jailEpochs := penaltyConfig.JailEpochs
+	if jailEpochs == 0 {
+		jailEpochs = 1
+	}
+	jailed := []common.Address{}
+	for i := uint64(0); i < jailEpochs && header != nil; i++ {
+		offenders, epochSwitchHeader, err := getParticipationOffenders(c, chain, header, penaltyConfig)
+		if err != nil {
+			return nil, err
+		}
+		jailed = append(jailed, offenders...)
+		if epochSwitchHeader.Number.Cmp(chain.Config().XDPoS.V2.SwitchBlock) <= 0 {
+			break
+		}
+		header = chain.GetHeader(epochSwitchHeader.ParentHash, epochSwitchHeader.Number.Uint64()-1)
+	}
+	return jailed, nil
+}
+
+// Check QC signatures and missed rounds of the masternodes in the epoch of header, from the epoch switch block up to header.
+// It returns the offenders and the epoch switch header
+func getParticipationOffenders(c *XDPoS.XDPoS, chain consensus.ChainReader, header *types.Header, penaltyConfig *params.PenaltyConfig) ([]common.Address, *types.Header, error) {
+	missedRounds, err := c.CalculateMissingRounds(chain, header)
+	if err != nil {
+		log.Error("[getParticipationOffenders] Fail to calculate missing rounds", "number", header.Number, "err", err)
+		return nil, nil, err
+	}
+	masternodes := c.GetMasternodes(chain, header)
+
+	signed := map[common.Address]uint64{}
+	totalQCs := uint64(0)
+	for header.Number.Cmp(missedRounds.EpochBlockNumber) > 0 {
+		signers, err := c.GetQCSigners(chain, header)
+		if err != nil {
+			log.Error("[getParticipationOffenders] Fail to get QC signers", "number", header.Number, "err", err)
+			return nil, nil, err
+		}
+		for _, signer := range signers {
+			signed[signer]++
+		}
+		totalQCs++
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if header == nil {
+			return nil, nil, fmt.Errorf("header is missing when counting QC signatures")
+		}
+	}
+
+	missed := map[common.Address]int{}
+	for _, round := range missedRounds.MissedRounds {
+		missed[round.Miner]++
+	}
+
+	offenders := []common.Address{}
+	for _, addr := range masternodes {
+		if penaltyConfig.MinSignaturePercent > 0 && totalQCs > 0 && signed[addr]*100 < penaltyConfig.MinSignaturePercent*totalQCs {
+			log.Info("[getParticipationOffenders] Find a node does not sign enough QC", "addr", addr.Hex(), "signed", signed[addr], "total", totalQCs, "requirePercent", penaltyConfig.MinSignaturePercent)
+			offenders = append(offenders, addr)
+		} else if penaltyConfig.MaxMissedRounds > 0 && missed[addr] > penaltyConfig.MaxMissedRounds {
+			log.Info("[getParticipationOffenders] Find a node misses too many rounds", "addr", addr.Hex(), "missed", missed[addr], "max", penaltyConfig.MaxMissedRounds)
+			offenders = append(offenders, addr)
+		}
+	}
+	return offenders, header, nil
 }
 
 // get signing transaction sender count
@@ -0,0 +1,118 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+)
+
+// fourByteTracer is a native port of the JavaScript 4byte tracer, searching for
+// the 4byte identifiers of the methods called by a transaction, and collecting
+// them along with the size of the supplied call data.
+//
+// The result is a map of "<id>-<size>" keys to the number of occurrences, e.g.
+//
+//	{
+//		"0x27dc297e-128": 1,
+//		"0x38cc4831-0": 2
+//	}
+type fourByteTracer struct {
+	ids map[string]int // ids aggregates the 4byte ids found
+
+	interruptor
+}
+
+// newFourByteTracer creates a native 4byte tracer.
+func newFourByteTracer(cfg json.RawMessage) (Tracer, error) {
+	return &fourByteTracer{ids: make(map[string]int)}, nil
+}
+
+// store saves the given identifier and data size.
+func (t *fourByteTracer) store(id []byte, size int) {
+	t.ids[fmt.Sprintf("%#x-%d", id, size)]++
+}
+
+// CaptureTxStart implements the Tracer interface, the 4byte tracer doesn't need
+// the state before the transaction.
+func (t *fourByteTracer) CaptureTxStart(db vm.StateDB, from common.Address, to *common.Address) {}
+
+// CaptureTxEnd implements the Tracer interface, the 4byte tracer doesn't need
+// the state after the transaction.
+func (t *fourByteTracer) CaptureTxEnd(db vm.StateDB) {}
+
+// CaptureStart implements the vm.Tracer interface to record the identifier of
+// the outer call.
+func (t *fourByteTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	if len(input) >= 4 {
+		t.store(input[:4], len(input)-4)
+	}
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to record the identifiers of
+// the inner calls.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.interrupted() || err != nil {
+		return nil
+	}
+	// Locate the call data arguments on the stack
+	var off int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		off = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		off = 2
+	default:
+		return nil
+	}
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if isPrecompiled(peekAddress(stack, 1)) {
+		return nil
+	}
+	if size := peekUint64(stack, off+1); size >= 4 {
+		if id := memorySlice(memory, peekUint64(stack, off), 4); id != nil {
+			t.store(id, int(size-4))
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the json-encoded identifiers found, or the error that
+// interrupted the tracing.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	return json.Marshal(t.ids)
+}
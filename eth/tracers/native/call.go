@@ -0,0 +1,351 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+)
+
+// callLog is a log emitted by a call, reported by the call tracer if requested.
+type callLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+// callFrame is a single call of the transaction, in the same format as the one
+// reported by the JavaScript call tracer.
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    *common.Address `json:"from,omitempty"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes  `json:"input,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Time    string          `json:"time,omitempty"`
+	Calls   []*callFrame    `json:"calls,omitempty"`
+	Logs    []callLog       `json:"logs,omitempty"`
+
+	// Call details needed to complete the frame when the call returns
+	gasIn   uint64
+	gasCost uint64
+	outOff  uint64
+	outLen  uint64
+}
+
+// callTracerConfig are the options of the call tracer.
+type callTracerConfig struct {
+	OnlyTopCall bool `json:"onlyTopCall"` // If true, the internal calls are not traced
+	WithLog     bool `json:"withLog"`     // If true, the logs emitted by the calls are reported
+}
+
+// callTracer is a native port of the JavaScript call tracer, extracting all the
+// internal calls made by a transaction.
+type callTracer struct {
+	config callTracerConfig
+
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+
+	// Outer call details, reported at the top of the result
+	create  bool
+	from    common.Address
+	to      common.Address
+	input   []byte
+	gas     uint64
+	value   *big.Int
+	output  []byte
+	gasUsed uint64
+	elapsed time.Duration
+	fail    error
+
+	interruptor
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer(cfg json.RawMessage) (Tracer, error) {
+	t := &callTracer{callstack: []*callFrame{{}}}
+	if err := decodeConfig(cfg, &t.config); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// CaptureTxStart implements the Tracer interface, the call tracer doesn't need
+// the state before the transaction.
+func (t *callTracer) CaptureTxStart(db vm.StateDB, from common.Address, to *common.Address) {}
+
+// CaptureTxEnd implements the Tracer interface, the call tracer doesn't need
+// the state after the transaction.
+func (t *callTracer) CaptureTxEnd(db vm.StateDB) {}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create, t.from, t.to = create, from, to
+	t.input = common.CopyBytes(input)
+	t.gas = gas
+	t.value = new(big.Int).Set(value)
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// If tracing was interrupted, stop
+	if t.interrupted() {
+		return nil
+	}
+	if t.config.OnlyTopCall && depth > 1 {
+		return nil
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	if !t.config.OnlyTopCall {
+		switch op {
+		case vm.CREATE, vm.CREATE2:
+			// A new contract is being created, add to the call stack
+			input := memorySlice(memory, peekUint64(stack, 1), peekUint64(stack, 2))
+			t.push(&callFrame{
+				Type:    op.String(),
+				From:    addressPtr(contract.Address()),
+				Input:   (*hexutil.Bytes)(&input),
+				Value:   (*hexutil.Big)(peekUint256(stack, 0).ToBig()),
+				gasIn:   gas,
+				gasCost: cost,
+			})
+			return nil
+
+		case vm.SELFDESTRUCT:
+			// A contract is being self destructed, gather that as a subcall too
+			top := t.callstack[len(t.callstack)-1]
+			top.Calls = append(top.Calls, &callFrame{Type: op.String()})
+			return nil
+
+		case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+			// Skip any pre-compile invocations, those are just fancy opcodes
+			to := peekAddress(stack, 1)
+			if isPrecompiled(to) {
+				return nil
+			}
+			off := 1
+			if op == vm.DELEGATECALL || op == vm.STATICCALL {
+				off = 0
+			}
+			input := memorySlice(memory, peekUint64(stack, 2+off), peekUint64(stack, 3+off))
+			call := &callFrame{
+				Type:    op.String(),
+				From:    addressPtr(contract.Address()),
+				To:      addressPtr(to),
+				Input:   (*hexutil.Bytes)(&input),
+				gasIn:   gas,
+				gasCost: cost,
+				outOff:  peekUint64(stack, 4+off),
+				outLen:  peekUint64(stack, 5+off),
+			}
+			if op == vm.CALL || op == vm.CALLCODE {
+				call.Value = (*hexutil.Big)(peekUint256(stack, 2).ToBig())
+			}
+			t.push(call)
+			return nil
+		}
+	}
+	// If we've just descended into an inner call, retrieve its true allowance. We
+	// need to extract it from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	// Calls to plain accounts don't execute any code, their allowance is unknown.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.callstack[len(t.callstack)-1].Gas = uint64Ptr(gas)
+		}
+		t.descended = false
+	}
+	// If an inner call returned, pop it off the call stack
+	if depth == len(t.callstack)-1 {
+		t.pop(env, gas, stack, memory)
+	}
+	switch {
+	case op == vm.REVERT:
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+
+	case t.config.WithLog && op >= vm.LOG0 && op <= vm.LOG4:
+		topics := make([]common.Hash, int(op-vm.LOG0))
+		for i := range topics {
+			topics[i] = common.Hash(stack.Back(2 + i).Bytes32())
+		}
+		top := t.callstack[len(t.callstack)-1]
+		top.Logs = append(top.Logs, callLog{
+			Address: contract.Address(),
+			Topics:  topics,
+			Data:    memorySlice(memory, peekUint64(stack, 0), peekUint64(stack, 1)),
+		})
+	}
+	return nil
+}
+
+// push adds a new inner call to the call stack.
+func (t *callTracer) push(call *callFrame) {
+	t.callstack = append(t.callstack, call)
+	t.descended = true
+}
+
+// pop removes the returned inner call from the call stack, completing it with
+// the execution results and injecting it into its parent.
+func (t *callTracer) pop(env *vm.EVM, gas uint64, stack *vm.Stack, memory *vm.Memory) {
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	ret := stack.Back(0)
+	if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+		// If the call was a CREATE, retrieve the contract address and output code
+		call.GasUsed = uint64Ptr(call.gasIn - call.gasCost - gas)
+		if !ret.IsZero() {
+			addr := common.Address(ret.Bytes20())
+			code := hexutil.Bytes(env.StateDB.GetCode(addr))
+
+			call.To, call.Output = &addr, &code
+		} else if call.Error == "" {
+			call.Error = "internal failure"
+		}
+	} else if call.Gas != nil {
+		// If the call was a contract call, retrieve the gas usage and output
+		call.GasUsed = uint64Ptr(call.gasIn - call.gasCost + uint64(*call.Gas) - gas)
+		if !ret.IsZero() {
+			output := hexutil.Bytes(memorySlice(memory, call.outOff, call.outLen))
+			call.Output = &output
+		} else if call.Error == "" {
+			call.Error = "internal failure"
+		}
+	}
+	parent := t.callstack[len(t.callstack)-1]
+	parent.Calls = append(parent.Calls, call)
+}
+
+// fault handles the failure of the currently executing call.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call, consuming all available gas
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+
+	call.Error = err.Error()
+	if call.Gas != nil {
+		call.GasUsed = call.Gas
+	}
+	// Flatten the failed call into its parent
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.interrupted() || (t.config.OnlyTopCall && depth > 1) {
+		return nil
+	}
+	t.fault(err)
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	t.output = common.CopyBytes(output)
+	t.gasUsed = gasUsed
+	t.elapsed = elapsed
+	t.fail = err
+	return nil
+}
+
+// GetResult returns the json-encoded call tree of the transaction, or the error
+// that interrupted the tracing.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	var (
+		input  = hexutil.Bytes(t.input)
+		output = hexutil.Bytes(t.output)
+		result = &callFrame{
+			Type:    vm.CALL.String(),
+			From:    &t.from,
+			To:      &t.to,
+			Value:   (*hexutil.Big)(t.value),
+			Gas:     uint64Ptr(t.gas),
+			GasUsed: uint64Ptr(t.gasUsed),
+			Input:   &input,
+			Output:  &output,
+			Time:    t.elapsed.String(),
+			Calls:   t.callstack[0].Calls,
+			Logs:    t.callstack[0].Logs,
+		}
+	)
+	if t.create {
+		result.Type = vm.CREATE.String()
+	}
+	if t.value == nil {
+		result.Value = new(hexutil.Big)
+	}
+	if result.Error = t.callstack[0].Error; result.Error == "" && t.fail != nil {
+		result.Error = t.fail.Error()
+	}
+	if result.Error != "" {
+		result.Output = nil
+	}
+	// The logs of the failed calls were reverted, drop them
+	clearFailedLogs(result, false)
+	return json.Marshal(result)
+}
+
+// clearFailedLogs clears the logs of a call and all its subcalls if the call,
+// or one of its parents, failed.
+func clearFailedLogs(call *callFrame, parentFailed bool) {
+	failed := call.Error != "" || parentFailed
+	if failed {
+		call.Logs = nil
+	}
+	for _, sub := range call.Calls {
+		clearFailedLogs(sub, failed)
+	}
+}
+
+// addressPtr returns a pointer to a copy of the address.
+func addressPtr(addr common.Address) *common.Address {
+	return &addr
+}
+
+// uint64Ptr returns a pointer to the number as a hexutil.Uint64.
+func uint64Ptr(n uint64) *hexutil.Uint64 {
+	return (*hexutil.Uint64)(&n)
+}
@@ -0,0 +1,257 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+	"github.com/XinFinOrg/XDC-Subnet/crypto"
+)
+
+// account is the state of an account touched by the transaction. Empty fields
+// are omitted from the result.
+type account struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// empty returns whether the account has no balance, nonce, code nor storage.
+func (a *account) empty() bool {
+	return (a.Balance == nil || a.Balance.ToInt().Sign() == 0) && a.Nonce == 0 && len(a.Code) == 0 && len(a.Storage) == 0
+}
+
+// prestateTracerConfig are the options of the prestate tracer.
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // If true, the state modified by the transaction is returned too
+}
+
+// prestateTracer is a native port of the JavaScript prestate tracer, gathering
+// the state of all the accounts and storage slots touched by a transaction
+// before it was applied. In diff mode only the modified accounts and slots are
+// reported, both before and after the transaction.
+type prestateTracer struct {
+	config prestateTracerConfig
+
+	pre     map[common.Address]*account
+	post    map[common.Address]*account
+	created map[common.Address]bool // Accounts created by the transaction
+
+	interruptor
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer(cfg json.RawMessage) (Tracer, error) {
+	t := &prestateTracer{
+		pre:     make(map[common.Address]*account),
+		post:    make(map[common.Address]*account),
+		created: make(map[common.Address]bool),
+	}
+	if err := decodeConfig(cfg, &t.config); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// CaptureTxStart implements the Tracer interface to look up the sender and the
+// recipient, or created contract, before the gas is bought and the value moved.
+func (t *prestateTracer) CaptureTxStart(db vm.StateDB, from common.Address, to *common.Address) {
+	t.lookupAccount(db, from)
+	if to != nil {
+		t.lookupAccount(db, *to)
+		return
+	}
+	t.lookupCreated(db, crypto.CreateAddress(from, db.GetNonce(from)))
+}
+
+// CaptureTxEnd implements the Tracer interface to drop the accounts created by
+// the transaction from the pre state, and to compute the post state of the
+// touched accounts in diff mode.
+func (t *prestateTracer) CaptureTxEnd(db vm.StateDB) {
+	diff := t.config.DiffMode && t.err == nil
+
+	// Accounts created by the transaction didn't exist before, only report
+	// them in the post state if they survived the transaction
+	for addr := range t.created {
+		prev := t.pre[addr]
+		delete(t.pre, addr)
+
+		if !diff || db.HasSuicided(addr) || !db.Exist(addr) {
+			continue
+		}
+		post := &account{
+			Balance: (*hexutil.Big)(db.GetBalance(addr)),
+			Nonce:   db.GetNonce(addr),
+			Code:    db.GetCode(addr),
+		}
+		for key := range prev.Storage {
+			if val := db.GetState(addr, key); val != (common.Hash{}) {
+				if post.Storage == nil {
+					post.Storage = make(map[common.Hash]common.Hash)
+				}
+				post.Storage[key] = val
+			}
+		}
+		if !post.empty() {
+			t.post[addr] = post
+		}
+	}
+	if !diff {
+		return
+	}
+	for addr, prev := range t.pre {
+		// Deleted accounts only have a pre state
+		if db.HasSuicided(addr) || !db.Exist(addr) {
+			continue
+		}
+		var (
+			modified bool
+			post     = new(account)
+		)
+		if balance := db.GetBalance(addr); prev.Balance.ToInt().Cmp(balance) != 0 {
+			modified, post.Balance = true, (*hexutil.Big)(balance)
+		}
+		if nonce := db.GetNonce(addr); nonce != prev.Nonce {
+			modified, post.Nonce = true, nonce
+		}
+		if code := db.GetCode(addr); !bytes.Equal(code, prev.Code) {
+			modified, post.Code = true, code
+		}
+		for key, val := range prev.Storage {
+			// Unchanged slots are dropped from the pre state too
+			newVal := db.GetState(addr, key)
+			if newVal == val {
+				delete(prev.Storage, key)
+				continue
+			}
+			modified = true
+			if newVal != (common.Hash{}) {
+				if post.Storage == nil {
+					post.Storage = make(map[common.Hash]common.Hash)
+				}
+				post.Storage[key] = newVal
+			}
+		}
+		if modified {
+			t.post[addr] = post
+		} else {
+			delete(t.pre, addr)
+		}
+	}
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the vm.Tracer interface to look up the accounts and
+// storage slots accessed by a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.interrupted() || err != nil {
+		return nil
+	}
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.EXTCODEHASH, vm.BALANCE, vm.SELFDESTRUCT:
+		t.lookupAccount(env.StateDB, peekAddress(stack, 0))
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(env.StateDB, peekAddress(stack, 1))
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupCreated(env.StateDB, crypto.CreateAddress(from, env.StateDB.GetNonce(from)))
+
+	case vm.CREATE2:
+		code := memorySlice(memory, peekUint64(stack, 1), peekUint64(stack, 2))
+		t.lookupCreated(env.StateDB, crypto.CreateAddress2(contract.Address(), stack.Back(3).Bytes32(), crypto.Keccak256(code)))
+
+	case vm.SLOAD, vm.SSTORE:
+		t.lookupStorage(env.StateDB, contract.Address(), common.Hash(stack.Back(0).Bytes32()))
+	}
+	return nil
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the json-encoded pre state of the transaction, along with
+// the post state in diff mode, or the error that interrupted the tracing.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.config.DiffMode {
+		return json.Marshal(struct {
+			Pre  map[common.Address]*account `json:"pre"`
+			Post map[common.Address]*account `json:"post"`
+		}{t.pre, t.post})
+	}
+	return json.Marshal(t.pre)
+}
+
+// lookupAccount fetches the details of an account the first time it's touched.
+func (t *prestateTracer) lookupAccount(db vm.StateDB, addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	t.pre[addr] = &account{
+		Balance: (*hexutil.Big)(new(big.Int).Set(db.GetBalance(addr))),
+		Nonce:   db.GetNonce(addr),
+		Code:    common.CopyBytes(db.GetCode(addr)),
+	}
+}
+
+// lookupCreated fetches the details of an account about to be created, marking
+// it as such if it didn't exist before.
+func (t *prestateTracer) lookupCreated(db vm.StateDB, addr common.Address) {
+	if _, ok := t.pre[addr]; !ok && !db.Exist(addr) {
+		t.created[addr] = true
+	}
+	t.lookupAccount(db, addr)
+}
+
+// lookupStorage fetches a storage slot the first time it's accessed. Since the
+// slot is looked up before the access, the value is the one before the
+// transaction.
+func (t *prestateTracer) lookupStorage(db vm.StateDB, addr common.Address, key common.Hash) {
+	t.lookupAccount(db, addr)
+
+	acc := t.pre[addr]
+	if acc.Storage == nil {
+		acc.Storage = make(map[common.Hash]common.Hash)
+	}
+	if _, ok := acc.Storage[key]; ok {
+		return
+	}
+	acc.Storage[key] = db.GetState(addr, key)
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package native is a collection of transaction tracers written in Go, producing
+// the same results as their JavaScript counterparts without the cost of running
+// an interpreter on every opcode.
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+	"github.com/XinFinOrg/XDC-Subnet/log"
+	"github.com/holiman/uint256"
+)
+
+// ErrUnknownTracer is returned when no native tracer is registered by a name.
+var ErrUnknownTracer = errors.New("unknown native tracer")
+
+// Tracer is a transaction tracer implemented natively in Go.
+type Tracer interface {
+	vm.Tracer
+
+	// CaptureTxStart is called with the state before the transaction is applied,
+	// i.e. before the gas is bought and the nonce increased.
+	CaptureTxStart(db vm.StateDB, from common.Address, to *common.Address)
+
+	// CaptureTxEnd is called with the state after the transaction was applied,
+	// including the gas refund.
+	CaptureTxEnd(db vm.StateDB)
+
+	// GetResult returns the json-encoded result of the tracing, or the error
+	// that interrupted it.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the tracing at the next opcode with the given error.
+	Stop(err error)
+}
+
+// ctorFn is the constructor of a native tracer from its json-encoded config.
+type ctorFn func(cfg json.RawMessage) (Tracer, error)
+
+// ctors contains the constructors of all the native tracers by name.
+var ctors = map[string]ctorFn{
+	"callTracer":     newCallTracer,
+	"prestateTracer": newPrestateTracer,
+	"4byteTracer":    newFourByteTracer,
+}
+
+// New creates the native tracer registered by the given name, configured with
+// the optional json-encoded config. ErrUnknownTracer is returned if there's no
+// native tracer by that name.
+func New(name string, cfg json.RawMessage) (Tracer, error) {
+	ctor, ok := ctors[name]
+	if !ok {
+		return nil, ErrUnknownTracer
+	}
+	return ctor(cfg)
+}
+
+// interruptor implements the interruption of a tracer, embedded by all of the
+// native tracers. The tracing goroutine checks the flag at every step, while
+// Stop may be called concurrently by the timeout watcher.
+type interruptor struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+	err       error  // Error that interrupted the tracing, owned by the tracing goroutine
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (i *interruptor) Stop(err error) {
+	i.reason = err
+	atomic.StoreUint32(&i.interrupt, 1)
+}
+
+// interrupted returns whether the tracing was interrupted, recording the reason
+// as the tracing error at the first check after the interruption.
+func (i *interruptor) interrupted() bool {
+	if i.err != nil {
+		return true
+	}
+	if atomic.LoadUint32(&i.interrupt) > 0 {
+		i.err = i.reason
+		return true
+	}
+	return false
+}
+
+// decodeConfig decodes the json-encoded config of a tracer, if any was given.
+func decodeConfig(cfg json.RawMessage, v interface{}) error {
+	if len(cfg) == 0 {
+		return nil
+	}
+	return json.Unmarshal(cfg, v)
+}
+
+// isPrecompiled returns whether the address is a precompiled contract.
+func isPrecompiled(addr common.Address) bool {
+	_, ok := vm.PrecompiledContractsIstanbul[addr]
+	return ok
+}
+
+// peekAddress returns the n-th item from the top of the stack as an address.
+func peekAddress(stack *vm.Stack, n int) common.Address {
+	return common.Address(stack.Back(n).Bytes20())
+}
+
+// peekUint64 returns the n-th item from the top of the stack, truncated to an
+// uint64.
+func peekUint64(stack *vm.Stack, n int) uint64 {
+	return stack.Back(n).Uint64()
+}
+
+// peekUint256 returns a copy of the n-th item from the top of the stack.
+func peekUint256(stack *vm.Stack, n int) *uint256.Int {
+	return new(uint256.Int).Set(stack.Back(n))
+}
+
+// memorySlice returns a copy of the requested range of the memory, or nil if
+// the range is out of bounds.
+func memorySlice(memory *vm.Memory, offset, size uint64) []byte {
+	if size == 0 {
+		return []byte{}
+	}
+	if offset+size < offset || uint64(memory.Len()) < offset+size {
+		log.Warn("Tracer accessed out of bound memory", "available", memory.Len(), "offset", offset, "size", size)
+		return nil
+	}
+	return memory.GetCopy(int64(offset), int64(size))
+}
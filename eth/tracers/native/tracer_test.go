@@ -0,0 +1,315 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/common/hexutil"
+	"github.com/XinFinOrg/XDC-Subnet/common/math"
+	"github.com/XinFinOrg/XDC-Subnet/core"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+	"github.com/XinFinOrg/XDC-Subnet/eth/tracers"
+	"github.com/XinFinOrg/XDC-Subnet/eth/tracers/native"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+	"github.com/XinFinOrg/XDC-Subnet/rlp"
+	"github.com/XinFinOrg/XDC-Subnet/tests"
+)
+
+// tracerTest is a transaction to trace on top of a genesis state, in the format
+// of the JavaScript call tracer test suite.
+type tracerTest struct {
+	Genesis *core.Genesis `json:"genesis"`
+	Context struct {
+		Number     math.HexOrDecimal64   `json:"number"`
+		Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+		Time       math.HexOrDecimal64   `json:"timestamp"`
+		GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
+		Miner      common.Address        `json:"miner"`
+	} `json:"context"`
+	Input string `json:"input"`
+}
+
+// loadTests reads the JavaScript call tracer test suite.
+func loadTests(t *testing.T) map[string]*tracerTest {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "call_tracer_*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	suite := make(map[string]*tracerTest)
+	for _, file := range files {
+		blob, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read testcase: %v", err)
+		}
+		test := new(tracerTest)
+		if err := json.Unmarshal(blob, test); err != nil {
+			t.Fatalf("failed to parse testcase: %v", err)
+		}
+		suite[strings.TrimSuffix(filepath.Base(file), ".json")] = test
+	}
+	return suite
+}
+
+// run executes the test transaction with the given tracer, returning the state
+// after the execution.
+func (test *tracerTest) run(t *testing.T, tracer vm.Tracer) *state.StateDB {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	msg, err := tx.AsMessage(signer, nil, common.Big0, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      msg.From(),
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	return execute(t, context, tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc), test.Genesis.Config, msg, tracer)
+}
+
+// execute applies the message with the given tracer, the same way the tracing
+// API does.
+func execute(t *testing.T, context vm.Context, statedb *state.StateDB, config *params.ChainConfig, msg core.Message, tracer vm.Tracer) *state.StateDB {
+	if tracer, ok := tracer.(native.Tracer); ok {
+		tracer.CaptureTxStart(statedb, msg.From(), msg.To())
+	}
+	evm := vm.NewEVM(context, statedb, nil, config, vm.Config{Debug: true, Tracer: tracer})
+	if _, _, _, err, _ := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()), common.Address{}); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	if tracer, ok := tracer.(native.Tracer); ok {
+		tracer.CaptureTxEnd(statedb)
+	}
+	return statedb
+}
+
+// decode unmarshals a json-encoded trace result into a generic value, dropping
+// the execution time of the calls.
+func decode(t *testing.T, blob json.RawMessage) interface{} {
+	var res interface{}
+	if err := json.Unmarshal(blob, &res); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if call, ok := res.(map[string]interface{}); ok {
+		delete(call, "time")
+	}
+	return res
+}
+
+// Tests that the native tracers produce the same results as the JavaScript ones
+// on the call tracer test suite.
+func TestTracersMatchJavaScript(t *testing.T) {
+	for name, test := range loadTests(t) {
+		for _, tracerName := range []string{"callTracer", "4byteTracer"} {
+			jsTracer, err := tracers.New(tracerName)
+			if err != nil {
+				t.Fatalf("failed to create JavaScript %s: %v", tracerName, err)
+			}
+			test.run(t, jsTracer)
+			want, err := jsTracer.GetResult()
+			if err != nil {
+				t.Fatalf("%s, %s: failed to retrieve JavaScript trace result: %v", name, tracerName, err)
+			}
+			tracer, err := native.New(tracerName, nil)
+			if err != nil {
+				t.Fatalf("failed to create native %s: %v", tracerName, err)
+			}
+			test.run(t, tracer)
+			have, err := tracer.GetResult()
+			if err != nil {
+				t.Fatalf("%s, %s: failed to retrieve native trace result: %v", name, tracerName, err)
+			}
+			if !reflect.DeepEqual(decode(t, have), decode(t, want)) {
+				t.Errorf("%s, %s: trace mismatch:\nhave %s\nwant %s", name, tracerName, have, want)
+			}
+		}
+	}
+}
+
+// Tests that the call tracer only reports the outer call if requested, and that
+// the logs are only reported for the calls that didn't fail.
+func TestCallTracerOptions(t *testing.T) {
+	var (
+		caller = common.HexToAddress("0xcc")
+		outer  = common.HexToAddress("0xaa")
+		inner  = common.HexToAddress("0xbb")
+	)
+	alloc := core.GenesisAlloc{
+		caller: {Balance: big.NewInt(params.Ether)},
+		// LOG0 with no data, then CALL inner with all the gas
+		outer: {Code: common.FromHex("0x60006000a060006000600060006000600060bb5af100")},
+		// LOG1 of the word 42 with topic 1, then REVERT
+		inner: {Code: common.FromHex("0x602a600052600160206000a160006000fd")},
+	}
+	trace := func(cfg string) map[string]interface{} {
+		tracer, err := native.New("callTracer", json.RawMessage(cfg))
+		if err != nil {
+			t.Fatalf("failed to create call tracer: %v", err)
+		}
+		msg := types.NewMessage(caller, &outer, 0, new(big.Int), 100000, new(big.Int), new(big.Int), new(big.Int), nil, nil, false, nil, common.Big0)
+		context := vm.Context{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			Origin:      caller,
+			BlockNumber: big.NewInt(1),
+			Time:        big.NewInt(1),
+			Difficulty:  big.NewInt(1),
+			GasLimit:    1000000,
+			GasPrice:    new(big.Int),
+		}
+		execute(t, context, tests.MakePreState(rawdb.NewMemoryDatabase(), alloc), params.TestChainConfig, msg, tracer)
+
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve trace result: %v", err)
+		}
+		return decode(t, res).(map[string]interface{})
+	}
+	// The full trace has the reverted inner call, without its log
+	full := trace(`{"withLog": true}`)
+	calls, _ := full["calls"].([]interface{})
+	if len(calls) != 1 {
+		t.Fatalf("inner call count mismatch: have %d, want 1", len(calls))
+	}
+	call := calls[0].(map[string]interface{})
+	if call["to"] != strings.ToLower(inner.Hex()) {
+		t.Errorf("inner call recipient mismatch: have %v, want %v", call["to"], inner)
+	}
+	if call["error"] != "execution reverted" {
+		t.Errorf("inner call error mismatch: have %v, want %v", call["error"], "execution reverted")
+	}
+	if _, ok := call["logs"]; ok {
+		t.Errorf("reverted call logs reported: %v", call["logs"])
+	}
+	logs, _ := full["logs"].([]interface{})
+	if len(logs) != 1 {
+		t.Fatalf("outer call log count mismatch: have %d, want 1", len(logs))
+	}
+	if log := logs[0].(map[string]interface{}); log["data"] != "0x" || len(log["topics"].([]interface{})) != 0 {
+		t.Errorf("outer call log mismatch: have %v", log)
+	}
+	// The logs are only reported if requested
+	if _, ok := trace(`{}`)["logs"]; ok {
+		t.Errorf("logs reported without being requested")
+	}
+	// The top call trace has the same outer call, without the inner one
+	top := trace(`{"onlyTopCall": true, "withLog": true}`)
+	if _, ok := top["calls"]; ok {
+		t.Errorf("inner calls reported: %v", top["calls"])
+	}
+	delete(full, "calls")
+	if !reflect.DeepEqual(top, full) {
+		t.Errorf("top call mismatch:\nhave %v\nwant %v", top, full)
+	}
+}
+
+// Tests that the prestate tracer reports the genesis state of all the accounts
+// touched by the transactions, and the state after them in diff mode.
+func TestPrestateTracer(t *testing.T) {
+	for name, test := range loadTests(t) {
+		tracer, err := native.New("prestateTracer", nil)
+		if err != nil {
+			t.Fatalf("failed to create prestate tracer: %v", err)
+		}
+		test.run(t, tracer)
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("%s: failed to retrieve trace result: %v", name, err)
+		}
+		var pre map[common.Address]struct {
+			Balance *hexutil.Big                `json:"balance"`
+			Nonce   uint64                      `json:"nonce"`
+			Code    hexutil.Bytes               `json:"code"`
+			Storage map[common.Hash]common.Hash `json:"storage"`
+		}
+		if err := json.Unmarshal(res, &pre); err != nil {
+			t.Fatalf("%s: failed to unmarshal trace result: %v", name, err)
+		}
+		if len(pre) == 0 {
+			t.Errorf("%s: no accounts reported", name)
+		}
+		for addr, acc := range pre {
+			want := test.Genesis.Alloc[addr]
+			if want.Balance == nil {
+				want.Balance = new(big.Int)
+			}
+			if acc.Balance.ToInt().Cmp(want.Balance) != 0 || acc.Nonce != want.Nonce || !reflect.DeepEqual([]byte(acc.Code), want.Code) && len(acc.Code)+len(want.Code) > 0 {
+				t.Errorf("%s, account %x: pre state mismatch: have %v %d %x, want %v %d %x", name, addr, acc.Balance, acc.Nonce, acc.Code, want.Balance, want.Nonce, want.Code)
+			}
+			for key, val := range acc.Storage {
+				if want.Storage[key] != val {
+					t.Errorf("%s, account %x, slot %x: pre state mismatch: have %x, want %x", name, addr, key, val, want.Storage[key])
+				}
+			}
+		}
+		// In diff mode, the post state must match the state after the transaction
+		tracer, _ = native.New("prestateTracer", json.RawMessage(`{"diffMode": true}`))
+		statedb := test.run(t, tracer)
+		if res, err = tracer.GetResult(); err != nil {
+			t.Fatalf("%s: failed to retrieve diff result: %v", name, err)
+		}
+		var diff struct {
+			Post map[common.Address]struct {
+				Balance *hexutil.Big                `json:"balance"`
+				Storage map[common.Hash]common.Hash `json:"storage"`
+			} `json:"post"`
+		}
+		if err := json.Unmarshal(res, &diff); err != nil {
+			t.Fatalf("%s: failed to unmarshal diff result: %v", name, err)
+		}
+		if len(diff.Post) == 0 {
+			t.Errorf("%s: no modified accounts reported", name)
+		}
+		for addr, acc := range diff.Post {
+			if acc.Balance != nil && acc.Balance.ToInt().Cmp(statedb.GetBalance(addr)) != 0 {
+				t.Errorf("%s, account %x: post balance mismatch: have %v, want %v", name, addr, acc.Balance, statedb.GetBalance(addr))
+			}
+			for key, val := range acc.Storage {
+				if have := statedb.GetState(addr, key); have != val {
+					t.Errorf("%s, account %x, slot %x: post state mismatch: have %x, want %x", name, addr, key, val, have)
+				}
+			}
+		}
+	}
+}
+
+// Tests that unknown tracers are reported as such, for the JavaScript fallback.
+func TestUnknownTracer(t *testing.T) {
+	if _, err := native.New("{step: function() {}}", nil); err != native.ErrUnknownTracer {
+		t.Fatalf("unknown tracer error mismatch: have %v, want %v", err, native.ErrUnknownTracer)
+	}
+}
@@ -0,0 +1,97 @@
+package ethapi
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/core/rawdb"
+	"github.com/XinFinOrg/XDC-Subnet/core/state"
+	"github.com/XinFinOrg/XDC-Subnet/core/vm"
+)
+
+// Tests that the state overrides replace the account fields, the whole storage
+// with 'state' and only the given slots with 'stateDiff'.
+func TestStateOverrideApply(t *testing.T) {
+	var (
+		full = common.HexToAddress("0x01")
+		diff = common.HexToAddress("0x02")
+		key1 = common.HexToHash("0x01")
+		key2 = common.HexToHash("0x02")
+	)
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	for _, addr := range []common.Address{full, diff} {
+		statedb.SetState(addr, key1, common.HexToHash("0xaa"))
+		statedb.SetState(addr, key2, common.HexToHash("0xbb"))
+	}
+	root, _ := statedb.Commit(false)
+	statedb, _ = state.New(root, statedb.Database())
+
+	var overrides StateOverride
+	if err := json.Unmarshal([]byte(`{
+		"0x0000000000000000000000000000000000000001": {"nonce": "0x5", "balance": "0x64", "code": "0x6001", "state": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x00000000000000000000000000000000000000000000000000000000000000cc"}},
+		"0x0000000000000000000000000000000000000002": {"stateDiff": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x00000000000000000000000000000000000000000000000000000000000000cc"}}
+	}`), &overrides); err != nil {
+		t.Fatalf("failed to decode overrides: %v", err)
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		t.Fatalf("failed to apply overrides: %v", err)
+	}
+	if nonce := statedb.GetNonce(full); nonce != 5 {
+		t.Errorf("nonce mismatch: have %d, want %d", nonce, 5)
+	}
+	if balance := statedb.GetBalance(full); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, 100)
+	}
+	if code := statedb.GetCode(full); common.Bytes2Hex(code) != "6001" {
+		t.Errorf("code mismatch: have %x, want %s", code, "6001")
+	}
+	tests := []struct {
+		addr common.Address
+		key  common.Hash
+		want common.Hash
+	}{
+		{full, key1, common.HexToHash("0xcc")},
+		{full, key2, common.Hash{}}, // The whole storage was replaced
+		{diff, key1, common.HexToHash("0xcc")},
+		{diff, key2, common.HexToHash("0xbb")}, // Only the given slot was changed
+	}
+	for i, tt := range tests {
+		if have := statedb.GetState(tt.addr, tt.key); have != tt.want {
+			t.Errorf("test %d: slot mismatch: have %x, want %x", i, have, tt.want)
+		}
+	}
+	// Both kinds of storage overrides at once are rejected
+	state := map[common.Hash]common.Hash{key1: {}}
+	invalid := StateOverride{full: {State: &state, StateDiff: &state}}
+	if err := invalid.Apply(statedb); err == nil {
+		t.Errorf("conflicting storage overrides applied")
+	}
+	// No overrides leave the state untouched
+	if err := (*StateOverride)(nil).Apply(statedb); err != nil {
+		t.Errorf("failed to apply no overrides: %v", err)
+	}
+}
+
+// Tests that the block overrides only replace the given context fields.
+func TestBlockOverridesApply(t *testing.T) {
+	context := vm.Context{
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(2),
+		Difficulty:  big.NewInt(3),
+		GasLimit:    4,
+	}
+	var overrides BlockOverrides
+	if err := json.Unmarshal([]byte(`{"number": "0x10", "gasLimit": "0x20", "coinbase": "0x00000000000000000000000000000000000000cc"}`), &overrides); err != nil {
+		t.Fatalf("failed to decode overrides: %v", err)
+	}
+	overrides.Apply(&context)
+
+	if context.BlockNumber.Uint64() != 16 || context.GasLimit != 32 || context.Coinbase != common.HexToAddress("0xcc") {
+		t.Errorf("overridden fields mismatch: number %v, gas limit %d, coinbase %x", context.BlockNumber, context.GasLimit, context.Coinbase)
+	}
+	if context.Time.Uint64() != 2 || context.Difficulty.Uint64() != 3 {
+		t.Errorf("untouched fields changed: time %v, difficulty %v", context.Time, context.Difficulty)
+	}
+}
@@ -26,6 +26,15 @@ func (n *proofPairList) Delete(key []byte) error {
 	panic("not supported")
 }
 
+// toHexSlice creates a slice of hex-strings from the nodes of a proof.
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
+
 // modified from core/types/derive_sha.go
 func deriveTrie(list types.DerivableList) *trie.Trie {
 	keybuf := new(bytes.Buffer)
@@ -92,6 +92,8 @@ type Work struct {
 	txs      []*types.Transaction
 	receipts []*types.Receipt
 
+	speculator *core.TxSpeculator // executes the pending transactions in parallel, nil if disabled
+
 	createdAt time.Time
 }
 
@@ -322,7 +324,7 @@ func (self *worker) update() {
 				acc, _ := types.Sender(self.current.signer, ev.Tx)
 				txs := map[common.Address]types.Transactions{acc: {ev.Tx}}
 				feeCapacity := state.GetTRC21FeeCapacityFromState(self.current.state)
-				txset, specialTxs := types.NewTransactionsByPriceAndNonce(self.current.signer, txs, nil, feeCapacity)
+				txset, specialTxs := types.NewTransactionsByPriceAndNonce(self.current.signer, txs, nil, feeCapacity, self.current.header.BaseFee)
 				self.current.commitTransactions(self.mux, feeCapacity, txset, specialTxs, self.chain, self.coinbase)
 				self.currentMu.Unlock()
 			} else {
@@ -479,9 +481,17 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 		}
 	}
 
+	// Typed transactions are only accepted once the EIP-2930 fork is active, dynamic fee ones once EIP-1559 is
+	var signer types.Signer = types.NewEIP155Signer(self.config.ChainId)
+	switch {
+	case self.config.IsEIP1559(header.Number):
+		signer = types.NewEIP1559Signer(self.config.ChainId)
+	case self.config.IsEIP2930(header.Number):
+		signer = types.NewEIP2930Signer(self.config.ChainId)
+	}
 	work := &Work{
 		config:       self.config,
-		signer:       types.NewEIP155Signer(self.config.ChainId),
+		signer:       signer,
 		state:        state,
 		parentState:  state.Copy(),
 		tradingState: XDCxState,
@@ -577,6 +587,10 @@ func (self *worker) commitNewWork() {
 		Extra:      self.extra,
 		Time:       big.NewInt(tstamp),
 	}
+	// Set baseFee if we are on an EIP-1559 chain
+	if self.config.IsEIP1559(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(self.config, parent.Header())
+	}
 	// Only set the coinbase if we are mining (avoid spurious block rewards)
 	if atomic.LoadInt32(&self.mining) == 1 {
 		header.Coinbase = self.coinbase
@@ -630,6 +644,8 @@ func (self *worker) commitNewWork() {
 		updatedTrades                                                        map[common.Hash]*lendingstate.LendingTrade
 		liquidatedTrades, autoRepayTrades, autoTopUpTrades, autoRecallTrades []*lendingstate.LendingTrade
 		lendingFinalizedTradeTransaction                                     *types.Transaction
+		expiredOrders                                                        []*tradingstate.OrderItem
+		expiredLendingItems                                                  []*lendingstate.LendingItem
 	)
 	feeCapacity := state.GetTRC21FeeCapacityFromStateWithCache(parent.Root(), work.state)
 	if self.config.XDPoS != nil {
@@ -643,7 +659,7 @@ func (self *worker) commitNewWork() {
 				log.Error("Failed to fetch pending transactions", "err", err)
 				return
 			}
-			txs, specialTxs = types.NewTransactionsByPriceAndNonce(self.current.signer, pending, signers, feeCapacity)
+			txs, specialTxs = types.NewTransactionsByPriceAndNonce(self.current.signer, pending, signers, feeCapacity, header.BaseFee)
 		}
 	}
 	if atomic.LoadInt32(&self.mining) == 1 {
@@ -673,12 +689,27 @@ func (self *worker) commitNewWork() {
 					log.Debug("Start processing order pending")
 					tradingOrderPending, _ := self.eth.OrderPool().Pending()
 					log.Debug("Start processing order pending", "len", len(tradingOrderPending))
+					// the matching transactions are signed afterwards, record their logs under the placeholder hashes until then
+					work.tradingState.Prepare(common.HexToHash(common.XDCXAddr))
 					tradingTxMatches, tradingMatchingResults = XDCX.ProcessOrderPending(header, self.coinbase, self.chain, tradingOrderPending, work.state, work.tradingState)
 					log.Debug("trading transaction matches found", "tradingTxMatches", len(tradingTxMatches))
 
 					lendingOrderPending, _ := self.eth.LendingPool().Pending()
+					work.tradingState.Prepare(common.HexToHash(common.XDCXLendingAddress))
 					lendingInput, lendingMatchingResults = XDCXLending.ProcessOrderPending(header, self.coinbase, self.chain, lendingOrderPending, work.state, work.lendingState, work.tradingState)
 					log.Debug("lending transaction matches found", "lendingInput", len(lendingInput), "lendingMatchingResults", len(lendingMatchingResults))
+					if self.config.IsXDCxOrderExpiry(header.Number) {
+						expiredOrders, err = XDCX.ProcessExpiredOrders(header, work.state, work.tradingState)
+						if err != nil {
+							log.Error("Fail when process expired orders", "error", err)
+							return
+						}
+						expiredLendingItems, err = XDCXLending.ProcessExpiredOrders(header, work.state, work.tradingState, work.lendingState)
+						if err != nil {
+							log.Error("Fail when process expired lending items", "error", err)
+							return
+						}
+					}
 					if header.Number.Uint64()%self.config.XDPoS.Epoch == common.LiquidateLendingTradeBlock {
 						updatedTrades, liquidatedTrades, autoRepayTrades, autoTopUpTrades, autoRecallTrades, err = XDCXLending.ProcessLiquidationData(header, self.chain, work.state, work.tradingState, work.lendingState)
 						if err != nil {
@@ -707,6 +738,7 @@ func (self *worker) commitNewWork() {
 						return
 					} else {
 						tradingTransaction = txM
+						work.tradingState.MoveLogs(common.HexToHash(common.XDCXAddr), tradingTransaction.Hash())
 						if XDCX.IsSDKNode() {
 							self.chain.AddMatchingResult(tradingTransaction.Hash(), tradingMatchingResults)
 						}
@@ -737,6 +769,7 @@ func (self *worker) commitNewWork() {
 						return
 					} else {
 						lendingTransaction = signedLendingTx
+						work.tradingState.MoveLogs(common.HexToHash(common.XDCXLendingAddress), lendingTransaction.Hash())
 						if XDCX.IsSDKNode() {
 							self.chain.AddLendingResult(lendingTransaction.Hash(), lendingMatchingResults)
 						}
@@ -779,6 +812,9 @@ func (self *worker) commitNewWork() {
 				log.Error("Fail to create tx state root", "error", err)
 				return
 			}
+			if XDCX.IsSDKNode() {
+				self.chain.AddExpiredOrders(txStateRoot.Hash(), expiredOrders, expiredLendingItems)
+			}
 			specialTxs = append(specialTxs, txStateRoot)
 		}
 	}
@@ -840,6 +876,13 @@ func (env *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Ad
 	balanceUpdated := map[common.Address]*big.Int{}
 	totalFeeUsed := big.NewInt(0)
 	var coalescedLogs []*types.Log
+	env.speculator = core.NewTxSpeculator(env.config, bc, &coinbase, env.state, env.tradingState, env.header, vm.Config{}, bc.GetVMConfig().ParallelTxs)
+	if env.speculator != nil {
+		defer func() {
+			env.speculator.Close()
+			env.speculator = nil
+		}()
+	}
 	// first priority for special Txs
 	for _, tx := range specialTxs {
 
@@ -1015,6 +1058,10 @@ func (env *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Ad
 			txs.Pop()
 			continue
 		}
+		// Speculate the next transaction of every account once the speculated ones are applied
+		if env.speculator != nil && !env.speculator.Speculated(tx) {
+			env.speculator.Speculate(txs.Heads(), balanceFee)
+		}
 		err, logs, tokenFeeUsed, gas := env.commitTransaction(balanceFee, tx, bc, coinbase, gp)
 		switch err {
 		case core.ErrGasLimitReached:
@@ -1081,7 +1128,17 @@ func (env *Work) commitTransactions(mux *event.TypeMux, balanceFee map[common.Ad
 func (env *Work) commitTransaction(balanceFee map[common.Address]*big.Int, tx *types.Transaction, bc *core.BlockChain, coinbase common.Address, gp *core.GasPool) (error, []*types.Log, bool, uint64) {
 	snap := env.state.Snapshot()
 
-	receipt, gas, err, tokenFeeUsed := core.ApplyTransaction(env.config, balanceFee, bc, &coinbase, gp, env.state, env.tradingState, env.header, tx, &env.header.GasUsed, vm.Config{})
+	var (
+		receipt      *types.Receipt
+		gas          uint64
+		err          error
+		tokenFeeUsed bool
+	)
+	if env.speculator != nil {
+		receipt, gas, err, tokenFeeUsed = env.speculator.Apply(balanceFee, gp, tx, &env.header.GasUsed)
+	} else {
+		receipt, gas, err, tokenFeeUsed = core.ApplyTransaction(env.config, balanceFee, bc, &coinbase, gp, env.state, env.tradingState, env.header, tx, &env.header.GasUsed, vm.Config{})
+	}
 	if err != nil {
 		env.state.RevertToSnapshot(snap)
 		return err, nil, false, 0
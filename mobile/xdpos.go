@@ -0,0 +1,91 @@
+// Contains all the wrappers from the XDPoS lightclient package.
+
+package geth
+
+import (
+	"encoding/json"
+
+	"github.com/XinFinOrg/XDC-Subnet/common"
+	"github.com/XinFinOrg/XDC-Subnet/consensus/XDPoS/lightclient"
+	"github.com/XinFinOrg/XDC-Subnet/core/types"
+	"github.com/XinFinOrg/XDC-Subnet/params"
+)
+
+// EpochMasternodes represents the masternodes of an epoch with the BLS public keys
+// they registered, the keys are empty before the BLS fork.
+type EpochMasternodes struct {
+	epoch *lightclient.EpochMasternodes
+}
+
+// NewEpochMasternodes creates the masternodes of a trusted epoch, without BLS keys.
+func NewEpochMasternodes(masternodes *Addresses) *EpochMasternodes {
+	return &EpochMasternodes{
+		epoch: &lightclient.EpochMasternodes{
+			Masternodes: common.CopyAddresses(masternodes.addresses),
+			BLSKeys:     make(map[common.Address][]byte),
+		},
+	}
+}
+
+// GetMasternodes returns the masternodes of the epoch.
+func (e *EpochMasternodes) GetMasternodes() *Addresses {
+	return &Addresses{addresses: common.CopyAddresses(e.epoch.Masternodes)}
+}
+
+// GetBLSKey returns the BLS public key registered by the masternode, nil if there is none.
+func (e *EpochMasternodes) GetBLSKey(address *Address) []byte {
+	return common.CopyBytes(e.epoch.BLSKeys[address.address])
+}
+
+// SetBLSKey sets the BLS public key registered by a masternode of a trusted epoch.
+func (e *EpochMasternodes) SetBLSKey(address *Address, key []byte) {
+	if e.epoch.BLSKeys == nil {
+		e.epoch.BLSKeys = make(map[common.Address][]byte)
+	}
+	e.epoch.BLSKeys[address.address] = common.CopyBytes(key)
+}
+
+// EpochProofVerifier verifies the XDPoS epoch proofs of a subnet, so the masternodes
+// can be followed epoch by epoch from a trusted one.
+type EpochProofVerifier struct {
+	config   *params.V2
+	verifier *lightclient.Verifier
+}
+
+// NewEpochProofVerifier creates a verifier with the consensus parameters of the subnet,
+// the cert threshold is the ratio of masternodes needed to form a quorum certificate
+// until another one is added by AddCertThreshold.
+func NewEpochProofVerifier(epoch int64, gap int64, certThreshold float64, maxMasternodes int) *EpochProofVerifier {
+	config := &params.V2{
+		AllConfigs: map[uint64]*params.V2Config{0: {CertThreshold: certThreshold}},
+	}
+	config.BuildConfigIndex()
+	return &EpochProofVerifier{
+		config: config,
+		verifier: lightclient.NewVerifier(&lightclient.Config{
+			Epoch:          uint64(epoch),
+			Gap:            uint64(gap),
+			V2:             config,
+			MaxMasternodes: maxMasternodes,
+		}),
+	}
+}
+
+// AddCertThreshold registers a cert threshold of the subnet, in use after the switch round.
+func (v *EpochProofVerifier) AddCertThreshold(switchRound int64, certThreshold float64) {
+	v.config.AddConfig(&params.V2Config{SwitchRound: uint64(switchRound), CertThreshold: certThreshold})
+}
+
+// VerifyEpochProof verifies a JSON encoded proof returned by XDPoS_getEpochProof with the
+// masternodes of the previous epoch, and returns the masternodes of the proven epoch.
+func (v *EpochProofVerifier) VerifyEpochProof(proof string, previous *EpochMasternodes) (*EpochMasternodes, error) {
+	var epochProof types.EpochProof
+	if err := json.Unmarshal([]byte(proof), &epochProof); err != nil {
+		return nil, err
+	}
+	epoch, err := v.verifier.VerifyEpochProof(&epochProof, previous.epoch)
+	if err != nil {
+		return nil, err
+	}
+	return &EpochMasternodes{epoch: epoch}, nil
+}
@@ -334,6 +334,34 @@ type ChainConfig struct {
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
 
+	// EIP2930 enables the typed transaction envelope (EIP-2718) with access lists (EIP-2930),
+	// and the cold and warm access gas of EIP-2929 they rely on
+	EIP2930Block *big.Int `json:"eip2930Block,omitempty"` // EIP2930 switch block (nil = no fork, 0 = already activated)
+
+	// EIP1559 enables the dynamic fee transactions and the base fee of the headers,
+	// it must not be activated before EIP2930
+	EIP1559Block *big.Int `json:"eip1559Block,omitempty"` // EIP1559 switch block (nil = no fork, 0 = already activated)
+
+	// XDCxLogs adds the logs of the XDCx and lending matches to the receipts of
+	// the matching transactions
+	XDCxLogsBlock *big.Int `json:"xdcxLogsBlock,omitempty"` // XDCxLogs switch block (nil = no fork, 0 = already activated)
+
+	// XDCxOrderFlags accepts the time in force and the post-only flag of the
+	// XDCx orders
+	XDCxOrderFlagsBlock *big.Int `json:"xdcxOrderFlagsBlock,omitempty"` // XDCxOrderFlags switch block (nil = no fork, 0 = already activated)
+
+	// XDCxTriggerOrders accepts the stop-loss and take-profit XDCx orders and
+	// activates them once the last price reaches their trigger price
+	XDCxTriggerOrdersBlock *big.Int `json:"xdcxTriggerOrdersBlock,omitempty"` // XDCxTriggerOrders switch block (nil = no fork, 0 = already activated)
+
+	// XDCxOrderExpiry accepts the good-till-time XDCx and lending orders and
+	// cancels them once their expiry time is reached
+	XDCxOrderExpiryBlock *big.Int `json:"xdcxOrderExpiryBlock,omitempty"` // XDCxOrderExpiry switch block (nil = no fork, 0 = already activated)
+
+	// XDCxSelfTrade accepts the self-trade prevention mode of the XDCx orders
+	// and applies it when a taker meets a maker of the same user
+	XDCxSelfTradeBlock *big.Int `json:"xdcxSelfTradeBlock,omitempty"` // XDCxSelfTrade switch block (nil = no fork, 0 = already activated)
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
@@ -361,29 +389,111 @@ func (c *CliqueConfig) String() string {
 
 // XDPoSConfig is the consensus engine configs for delegated-proof-of-stake based sealing.
 type XDPoSConfig struct {
-	NetworkName         string         `json:"networkName"`         // The chain network name
-	Denom               string         `json:"denom"`               // The denomination of the chain currency
-	Period              uint64         `json:"period"`              // Number of seconds between blocks to enforce
-	Epoch               uint64         `json:"epoch"`               // Epoch length to reset votes and checkpoint
-	Reward              uint64         `json:"reward"`              // Block reward - unit Ether
-	RewardCheckpoint    uint64         `json:"rewardCheckpoint"`    // Checkpoint block for calculate rewards.
-	Gap                 uint64         `json:"gap"`                 // Gap time preparing for the next epoch
-	FoudationWalletAddr common.Address `json:"foudationWalletAddr"` // Foundation Address Wallet
+	NetworkName         string         `json:"networkName"`                  // The chain network name
+	Denom               string         `json:"denom"`                        // The denomination of the chain currency
+	Period              uint64         `json:"period"`                       // Number of seconds between blocks to enforce
+	Epoch               uint64         `json:"epoch"`                        // Epoch length to reset votes and checkpoint
+	Reward              uint64         `json:"reward"`                       // Block reward - unit Ether
+	RewardCheckpoint    uint64         `json:"rewardCheckpoint"`             // Checkpoint block for calculate rewards.
+	Gap                 uint64         `json:"gap"`                          // Gap time preparing for the next epoch
+	FoudationWalletAddr common.Address `json:"foudationWalletAddr"`          // Foundation Address Wallet
+	BaseFeeDestination  string         `json:"baseFeeDestination,omitempty"` // Where the EIP-1559 base fee goes, empty means burned
 	SkipV1Validation    bool           //Skip Block Validation for testing purpose, V1 consensus only
 	V2                  *V2            `json:"v2"`
+	Penalty             *PenaltyConfig `json:"penalty,omitempty"` // Penalty thresholds of the subnet, nil keeps the default mined blocks rule
 }
 
+// Penalty thresholds used at gap block to decide the penalized masternodes, a zero value disables the check
+type PenaltyConfig struct {
+	Block               *big.Int `json:"block,omitempty"`     // The thresholds apply from this block on, nil keeps the default mined blocks rule
+	MinBlocksPerEpoch   int      `json:"minBlocksPerEpoch"`   // Masternodes mining less blocks are penalized, default to common.MinimunMinerBlockPerEpoch
+	MinSignaturePercent uint64   `json:"minSignaturePercent"` // Masternodes signing less percent of the epoch QCs are jailed
+	MaxMissedRounds     int      `json:"maxMissedRounds"`     // Masternodes missing more rounds as leader in the epoch are jailed
+	JailEpochs          uint64   `json:"jailEpochs"`          // Number of epochs a jailed masternode can't come back by signing txs, at least 1
+}
+
+// PenaltyAt returns the penalty thresholds applied at the block with the given number, nil if the
+// default mined blocks rule applies
+func (c *XDPoSConfig) PenaltyAt(num *big.Int) *PenaltyConfig {
+	if c == nil || c.Penalty == nil || !isForked(c.Penalty.Block, num) {
+		return nil
+	}
+	return c.Penalty
+}
+
+// Masternode selection policies, used to pick the next epoch masternodes out of the snapshot candidates
+const (
+	MasternodeSelectionDefault  = ""         // Keep the order recorded in the snapshot
+	MasternodeSelectionStake    = "stake"    // Order candidates by their stake in the validator contract
+	MasternodeSelectionRotation = "rotation" // Rotate standby nodes into the masternode seats every epoch
+	MasternodeSelectionShuffle  = "shuffle"  // Shuffle candidates deterministically, seeded from the gap block hash
+)
+
 type V2 struct {
 	lock sync.RWMutex // Protects the signer fields
 
-	SwitchBlock   *big.Int             `json:"switchBlock"`
-	CurrentConfig *V2Config            `json:"config"`
-	AllConfigs    map[uint64]*V2Config `json:"allConfigs"`
-	configIndex   []uint64             //list of switch block of configs
+	SwitchBlock         *big.Int             `json:"switchBlock"`
+	CurrentConfig       *V2Config            `json:"config"`
+	AllConfigs          map[uint64]*V2Config `json:"allConfigs"`
+	MasternodeSelection string               `json:"masternodeSelection,omitempty"` // Policy to select masternodes from candidates, empty means snapshot order
+	Slashing            *SlashingConfig      `json:"slashing,omitempty"`            // Penalty applied on proven vote equivocation, nil disables slashing
+	BLSBlock            *big.Int             `json:"blsBlock,omitempty"`            // QC and TC are BLS aggregated from the epoch whose gap block is at or after it, nil disables
+	configIndex         []uint64             //list of switch block of configs
 
 	SkipV2Validation bool //Skip Block Validation for testing purpose, V2 consensus only
 }
 
+// Base fee destinations, the base fee paid by the transactions after the EIP1559 fork
+// is either burned, sent to the foundation wallet or added to the epoch reward
+const (
+	BaseFeeDestinationBurn       = ""           // Burn the base fee
+	BaseFeeDestinationFoundation = "foundation" // Send the base fee to FoudationWalletAddr
+	BaseFeeDestinationReward     = "reward"     // Collect the base fee in common.BaseFeePoolAddr, distributed with the reward at the epoch switch
+)
+
+// BaseFeeRecipient returns the account credited with the base fee paid by the transactions,
+// it reports false if the base fee is burned
+func (c *XDPoSConfig) BaseFeeRecipient() (common.Address, bool) {
+	if c == nil {
+		return common.Address{}, false
+	}
+	switch c.BaseFeeDestination {
+	case BaseFeeDestinationFoundation:
+		return c.FoudationWalletAddr, true
+	case BaseFeeDestinationReward:
+		return common.HexToAddress(common.BaseFeePoolAddr), true
+	}
+	return common.Address{}, false
+}
+
+// CheckConfig checks the XDPoS settings that can't be detected by the consensus,
+// such as an unknown base fee destination which would burn the base fee silently
+func (c *XDPoSConfig) CheckConfig() error {
+	if c == nil {
+		return nil
+	}
+	switch c.BaseFeeDestination {
+	case BaseFeeDestinationBurn, BaseFeeDestinationFoundation, BaseFeeDestinationReward:
+	default:
+		return fmt.Errorf("unknown XDPoS base fee destination %q", c.BaseFeeDestination)
+	}
+	return nil
+}
+
+// Slashing policies, applied to a masternode once its vote equivocation is proven on chain
+const (
+	SlashingPolicyBurn   = "burn"   // Burn a percentage of the candidate stake
+	SlashingPolicyJail   = "jail"   // Exclude the candidate from masternodes for a number of epochs
+	SlashingPolicyRemove = "remove" // Remove the candidate from the validator contract
+)
+
+type SlashingConfig struct {
+	Block       *big.Int `json:"block,omitempty"` // Slashing txs are applied from this block on, nil disables slashing
+	Policy      string   `json:"policy"`          // One of burn, jail or remove
+	BurnPercent uint64   `json:"burnPercent"`     // Percentage of the stake to burn, used by burn policy
+	JailEpochs  uint64   `json:"jailEpochs"`      // Number of epochs to jail the candidate, used by jail policy
+}
+
 type V2Config struct {
 	SwitchRound          uint64  `json:"switchRound"`          // v1 to v2 switch block number
 	MinePeriod           int     `json:"minePeriod"`           // Miner mine period to mine a block
@@ -396,6 +506,16 @@ func (c *XDPoSConfig) String() string {
 	return "XDPoS"
 }
 
+// IsSlashing returns whether the slashing txs are applied in the block with the given number
+func (v *V2) IsSlashing(num *big.Int) bool {
+	return v != nil && v.Slashing != nil && isForked(v.Slashing.Block, num)
+}
+
+// IsBLS returns whether votes, timeouts, QC and TC of the epoch with the gap number are signed with BLS
+func (v *V2) IsBLS(gapNumber uint64) bool {
+	return v.BLSBlock != nil && v.BLSBlock.Uint64() <= gapNumber
+}
+
 func (v *V2) UpdateConfig(round uint64) {
 	v.lock.Lock()
 	defer v.lock.Unlock()
@@ -415,6 +535,9 @@ func (v *V2) UpdateConfig(round uint64) {
 }
 
 func (v *V2) Config(round uint64) *V2Config {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
 	configRound := round - 1 //start from next block from SwitchRound number
 	var index uint64
 
@@ -428,7 +551,38 @@ func (v *V2) Config(round uint64) *V2Config {
 	return v.AllConfigs[index]
 }
 
+// AddConfig registers a config taking effect after its switch round, it replaces the config of the same switch round
+func (v *V2) AddConfig(config *V2Config) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	// copy on write, the configs map may be shared with other chain configs
+	allConfigs := make(map[uint64]*V2Config, len(v.AllConfigs)+1)
+	for round, c := range v.AllConfigs {
+		allConfigs[round] = c
+	}
+	allConfigs[config.SwitchRound] = config
+	v.AllConfigs = allConfigs
+	v.buildConfigIndex()
+}
+
+// SetConfigs replaces all the configs, the map is copied on the next AddConfig so it can be shared
+func (v *V2) SetConfigs(configs map[uint64]*V2Config) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.AllConfigs = configs
+	v.buildConfigIndex()
+}
+
 func (v *V2) BuildConfigIndex() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.buildConfigIndex()
+}
+
+func (v *V2) buildConfigIndex() {
 	var list []uint64
 
 	for i := range v.AllConfigs {
@@ -449,6 +603,9 @@ func (v *V2) BuildConfigIndex() {
 }
 
 func (v *V2) ConfigIndex() []uint64 {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
 	return v.configIndex
 }
 
@@ -463,7 +620,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Istanbul: %v  BerlinBlock: %v LondonBlock: %v MergeBlock: %v ShanghaiBlock: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v DAO: %v DAOSupport: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Istanbul: %v  BerlinBlock: %v LondonBlock: %v MergeBlock: %v ShanghaiBlock: %v EIP2930: %v EIP1559: %v XDCxLogs: %v XDCxOrderFlags: %v XDCxTriggerOrders: %v XDCxOrderExpiry: %v XDCxSelfTrade: %v Engine: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.DAOForkBlock,
@@ -478,6 +635,13 @@ func (c *ChainConfig) String() string {
 		common.LondonBlock,
 		common.MergeBlock,
 		common.ShanghaiBlock,
+		c.EIP2930Block,
+		c.EIP1559Block,
+		c.XDCxLogsBlock,
+		c.XDCxOrderFlagsBlock,
+		c.XDCxTriggerOrdersBlock,
+		c.XDCxOrderExpiryBlock,
+		c.XDCxSelfTradeBlock,
 		engine,
 	)
 }
@@ -545,6 +709,41 @@ func (c *ChainConfig) IsShanghai(num *big.Int) bool {
 	return isForked(common.ShanghaiBlock, num)
 }
 
+// IsEIP2930 returns whether num is either equal to the EIP2930 fork block or greater.
+func (c *ChainConfig) IsEIP2930(num *big.Int) bool {
+	return isForked(c.EIP2930Block, num)
+}
+
+// IsEIP1559 returns whether num is either equal to the EIP1559 fork block or greater.
+func (c *ChainConfig) IsEIP1559(num *big.Int) bool {
+	return isForked(c.EIP1559Block, num)
+}
+
+// IsXDCxLogs returns whether num is either equal to the XDCxLogs fork block or greater.
+func (c *ChainConfig) IsXDCxLogs(num *big.Int) bool {
+	return isForked(c.XDCxLogsBlock, num)
+}
+
+// IsXDCxOrderFlags returns whether num is either equal to the XDCxOrderFlags fork block or greater.
+func (c *ChainConfig) IsXDCxOrderFlags(num *big.Int) bool {
+	return isForked(c.XDCxOrderFlagsBlock, num)
+}
+
+// IsXDCxTriggerOrders returns whether num is either equal to the XDCxTriggerOrders fork block or greater.
+func (c *ChainConfig) IsXDCxTriggerOrders(num *big.Int) bool {
+	return isForked(c.XDCxTriggerOrdersBlock, num)
+}
+
+// IsXDCxOrderExpiry returns whether num is either equal to the XDCxOrderExpiry fork block or greater.
+func (c *ChainConfig) IsXDCxOrderExpiry(num *big.Int) bool {
+	return isForked(c.XDCxOrderExpiryBlock, num)
+}
+
+// IsXDCxSelfTrade returns whether num is either equal to the XDCxSelfTrade fork block or greater.
+func (c *ChainConfig) IsXDCxSelfTrade(num *big.Int) bool {
+	return isForked(c.XDCxSelfTradeBlock, num)
+}
+
 func (c *ChainConfig) IsTIP2019(num *big.Int) bool {
 	return isForked(common.TIP2019Block, num)
 }
@@ -639,6 +838,27 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.EIP2930Block, newcfg.EIP2930Block, head) {
+		return newCompatError("EIP2930 fork block", c.EIP2930Block, newcfg.EIP2930Block)
+	}
+	if isForkIncompatible(c.EIP1559Block, newcfg.EIP1559Block, head) {
+		return newCompatError("EIP1559 fork block", c.EIP1559Block, newcfg.EIP1559Block)
+	}
+	if isForkIncompatible(c.XDCxLogsBlock, newcfg.XDCxLogsBlock, head) {
+		return newCompatError("XDCxLogs fork block", c.XDCxLogsBlock, newcfg.XDCxLogsBlock)
+	}
+	if isForkIncompatible(c.XDCxOrderFlagsBlock, newcfg.XDCxOrderFlagsBlock, head) {
+		return newCompatError("XDCxOrderFlags fork block", c.XDCxOrderFlagsBlock, newcfg.XDCxOrderFlagsBlock)
+	}
+	if isForkIncompatible(c.XDCxTriggerOrdersBlock, newcfg.XDCxTriggerOrdersBlock, head) {
+		return newCompatError("XDCxTriggerOrders fork block", c.XDCxTriggerOrdersBlock, newcfg.XDCxTriggerOrdersBlock)
+	}
+	if isForkIncompatible(c.XDCxOrderExpiryBlock, newcfg.XDCxOrderExpiryBlock, head) {
+		return newCompatError("XDCxOrderExpiry fork block", c.XDCxOrderExpiryBlock, newcfg.XDCxOrderExpiryBlock)
+	}
+	if isForkIncompatible(c.XDCxSelfTradeBlock, newcfg.XDCxSelfTradeBlock, head) {
+		return newCompatError("XDCxSelfTrade fork block", c.XDCxSelfTradeBlock, newcfg.XDCxSelfTradeBlock)
+	}
 	return nil
 }
 
@@ -708,6 +928,7 @@ type Rules struct {
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
 	IsBerlin, IsLondon                                      bool
 	IsMerge, IsShanghai                                     bool
+	IsEIP2930, IsEIP1559                                    bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -729,5 +950,7 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		IsLondon:         c.IsLondon(num),
 		IsMerge:          c.IsMerge(num),
 		IsShanghai:       c.IsShanghai(num),
+		IsEIP2930:        c.IsEIP2930(num),
+		IsEIP1559:        c.IsEIP1559(num),
 	}
 }
@@ -95,6 +95,8 @@ type Database struct {
 	childrenSize  common.StorageSize // Storage size of the external children tracking
 	preimagesSize common.StorageSize // Storage size of the preimages Cache
 
+	onFlush func(hash common.Hash) // Callback invoked before flushing a Node to disk
+
 	Lock sync.RWMutex
 }
 
@@ -304,6 +306,24 @@ func (db *Database) DiskDB() ethdb.KeyValueReader {
 	return db.diskdb
 }
 
+// SetFlushCallback sets a callback invoked with the hash of every Node (and blob)
+// right before it's written to disk by Cap or Commit, nil to remove it. The flushes
+// already running keep the callback they started with.
+func (db *Database) SetFlushCallback(callback func(hash common.Hash)) {
+	db.Lock.Lock()
+	defer db.Lock.Unlock()
+
+	db.onFlush = callback
+}
+
+// flushCallback retrieves the callback to invoke on the nodes being flushed.
+func (db *Database) flushCallback() func(hash common.Hash) {
+	db.Lock.RLock()
+	defer db.Lock.RUnlock()
+
+	return db.onFlush
+}
+
 // InsertBlob writes a new reference tracked blob to the memory database if it's
 // yet unknown. This method should only be used for non-trie nodes that require
 // reference counting, since trie nodes are garbage collected directly through
@@ -591,6 +611,7 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	// by only uncaching existing data when the database write finalizes.
 	nodes, storage, start := len(db.dirties), db.dirtiesSize, time.Now()
 	batch := db.diskdb.NewBatch()
+	onFlush := db.flushCallback()
 
 	// Db.dirtiesSize only contains the useful data in the Cache, but when reporting
 	// the total memory consumption, the maintenance metadata is also needed to be
@@ -626,6 +647,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	for size > limit && oldest != (common.Hash{}) {
 		// Fetch the oldest referenced Node and push into the batch
 		node := db.dirties[oldest]
+		if onFlush != nil {
+			onFlush(oldest)
+		}
 		if err := batch.Put(oldest[:], node.rlp()); err != nil {
 			return err
 		}
@@ -731,7 +755,7 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 	nodes, storage := len(db.dirties), db.dirtiesSize
 
 	uncacher := &cleaner{db}
-	if err := db.commit(node, batch, uncacher); err != nil {
+	if err := db.commit(node, batch, uncacher, db.flushCallback()); err != nil {
 		log.Error("Failed to commit trie from trie database", "err", err)
 		return err
 	}
@@ -770,7 +794,7 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 }
 
 // commit is the private locked version of Commit.
-func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleaner) error {
+func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleaner, onFlush func(hash common.Hash)) error {
 	// If the Node does not exist, it's a previously committed Node
 	node, ok := db.dirties[hash]
 	if !ok {
@@ -779,12 +803,15 @@ func (db *Database) commit(hash common.Hash, batch ethdb.Batch, uncacher *cleane
 	var err error
 	node.forChilds(func(child common.Hash) {
 		if err == nil {
-			err = db.commit(child, batch, uncacher)
+			err = db.commit(child, batch, uncacher, onFlush)
 		}
 	})
 	if err != nil {
 		return err
 	}
+	if onFlush != nil {
+		onFlush(hash)
+	}
 	if err := batch.Put(hash[:], node.rlp()); err != nil {
 		return err
 	}